@@ -1,7 +1,22 @@
 package main
 
-import "github.com/lookingcoolonavespa/go_crochess_backend/src/app"
+import (
+	"log"
+	"os"
+
+	"github.com/lookingcoolonavespa/go_crochess_backend/src/app"
+)
 
 func main() {
-	app.Run()
+	if len(os.Args) < 2 {
+		app.Run()
+		return
+	}
+
+	switch os.Args[1] {
+	case "rebuild-explorer":
+		app.RebuildExplorer()
+	default:
+		log.Fatalf("unknown command %q, available commands: rebuild-explorer", os.Args[1])
+	}
 }
@@ -3,20 +3,46 @@ package app
 import (
 	"context"
 	"database/sql"
+	"expvar"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
+	"github.com/julienschmidt/httprouter"
 	"github.com/lookingcoolonavespa/go_crochess_backend/src/database"
+	"github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	delivery_http_bughouse "github.com/lookingcoolonavespa/go_crochess_backend/src/services/bughouse/delivery/http"
+	delivery_ws_bughouse "github.com/lookingcoolonavespa/go_crochess_backend/src/services/bughouse/delivery/ws"
+	usecase_bughouse "github.com/lookingcoolonavespa/go_crochess_backend/src/services/bughouse/usecase"
+	repository_chat "github.com/lookingcoolonavespa/go_crochess_backend/src/services/chat/repository"
+	usecase_chat "github.com/lookingcoolonavespa/go_crochess_backend/src/services/chat/usecase"
+	services_engine "github.com/lookingcoolonavespa/go_crochess_backend/src/services/engine"
+	delivery_http_explorer "github.com/lookingcoolonavespa/go_crochess_backend/src/services/explorer/delivery/http"
+	repository_explorer "github.com/lookingcoolonavespa/go_crochess_backend/src/services/explorer/repository"
+	usecase_explorer "github.com/lookingcoolonavespa/go_crochess_backend/src/services/explorer/usecase"
+	delivery_http_game "github.com/lookingcoolonavespa/go_crochess_backend/src/services/game/delivery/http"
 	delivery_ws_game "github.com/lookingcoolonavespa/go_crochess_backend/src/services/game/delivery/ws"
 	repository_game "github.com/lookingcoolonavespa/go_crochess_backend/src/services/game/repository"
 	usecase_game "github.com/lookingcoolonavespa/go_crochess_backend/src/services/game/usecase"
 	delivery_ws_gameseeks "github.com/lookingcoolonavespa/go_crochess_backend/src/services/gameseeks/delivery/ws"
 	repository_gameseeks "github.com/lookingcoolonavespa/go_crochess_backend/src/services/gameseeks/repository"
+	delivery_ws_live "github.com/lookingcoolonavespa/go_crochess_backend/src/services/live/delivery/ws"
+	usecase_live "github.com/lookingcoolonavespa/go_crochess_backend/src/services/live/usecase"
+	delivery_ws_puzzle "github.com/lookingcoolonavespa/go_crochess_backend/src/services/puzzle/delivery/ws"
+	repository_puzzle "github.com/lookingcoolonavespa/go_crochess_backend/src/services/puzzle/repository"
+	usecase_puzzle "github.com/lookingcoolonavespa/go_crochess_backend/src/services/puzzle/usecase"
+	delivery_http_simul "github.com/lookingcoolonavespa/go_crochess_backend/src/services/simul/delivery/http"
+	delivery_ws_simul "github.com/lookingcoolonavespa/go_crochess_backend/src/services/simul/delivery/ws"
+	usecase_simul "github.com/lookingcoolonavespa/go_crochess_backend/src/services/simul/usecase"
+	delivery_http_tournament "github.com/lookingcoolonavespa/go_crochess_backend/src/services/tournament/delivery/http"
+	delivery_ws_tournament "github.com/lookingcoolonavespa/go_crochess_backend/src/services/tournament/delivery/ws"
+	repository_tournament "github.com/lookingcoolonavespa/go_crochess_backend/src/services/tournament/repository"
+	usecase_tournament "github.com/lookingcoolonavespa/go_crochess_backend/src/services/tournament/usecase"
 
 	domain_websocket "github.com/lookingcoolonavespa/go_crochess_backend/src/websocket"
 	"github.com/spf13/viper"
@@ -33,6 +59,29 @@ func Run() {
 	initHandlers(db)
 }
 
+// RebuildExplorer recreates the opening explorer from every finished game
+func RebuildExplorer() {
+	initConfig()
+
+	db, err := initDB()
+	if err != nil {
+		log.Fatalf("%s: %v", "Error on connect to database", err)
+	}
+	defer db.Close()
+
+	explorerUseCase := usecase_explorer.NewExplorerUseCase(
+		repository_explorer.NewExplorerRepo(db),
+		repository_game.NewGameRepo(db),
+	)
+
+	indexedGames, err := explorerUseCase.Rebuild(context.Background())
+	if err != nil {
+		log.Fatalf("error rebuilding explorer: %v", err)
+	}
+
+	log.Printf("rebuilt explorer from %d games", indexedGames)
+}
+
 func initConfig() {
 	viper.SetConfigType("toml")
 
@@ -70,6 +119,34 @@ func initHandlers(db *sql.DB) {
 	gameRepo := repository_game.NewGameRepo(db)
 	gameUseCase := usecase_game.NewGameUseCase(db, gameRepo)
 
+	explorerUseCase := usecase_explorer.NewExplorerUseCase(
+		repository_explorer.NewExplorerRepo(db),
+		gameRepo,
+	)
+	gameUseCase.OnGameOver(func(g domain.Game) {
+		if err := explorerUseCase.OnGameOver(context.Background(), g); err != nil {
+			log.Printf("error adding game %d to explorer: %v", g.ID, err)
+		}
+	})
+
+	var engine domain.Engine
+	if enginePath := viper.GetString("engine.path"); enginePath != "" {
+		uciEngine, err := services_engine.NewUCIEngine(enginePath, viper.GetInt("engine.depth"))
+		if err != nil {
+			log.Printf("error starting engine, puzzles will not be generated: %v", err)
+		} else {
+			defer uciEngine.Close()
+			engine = uciEngine
+		}
+	}
+
+	puzzleUseCase := usecase_puzzle.NewPuzzleUseCase(repository_puzzle.NewPuzzleRepo(db), engine)
+	gameUseCase.OnGameOver(func(g domain.Game) {
+		if err := puzzleUseCase.OnGameOver(context.Background(), g); err != nil {
+			log.Printf("error queueing game %d for puzzles: %v", g.ID, err)
+		}
+	})
+
 	gameTopic, err := domain_websocket.NewTopic(fmt.Sprint(domain_websocket.GameTopic, "/id"))
 	if err != nil {
 		log.Printf("error instantiating game topic: %v", err)
@@ -77,12 +154,18 @@ func initHandlers(db *sql.DB) {
 	}
 	gameHandler := delivery_ws_game.NewGameHandler(
 		gameUseCase,
+		usecase_chat.NewChatUseCase(repository_chat.NewChatRepo(db)),
+		viper.GetDuration("game.spectator_delay"),
 	)
 	gameTopic.RegisterEvent(domain_websocket.SubscribeEvent, gameHandler.HandlerOnSubscribe)
 	gameTopic.RegisterEvent(domain_websocket.UnsubscribeEvent, gameHandler.HandlerOnUnsubscribe)
 	gameTopic.RegisterEvent(domain_websocket.MakeMoveEvent, gameHandler.HandlerMakeMove)
 	gameTopic.RegisterEvent(domain_websocket.UpdateDrawEvent, gameHandler.HandlerUpdateDraw)
 	gameTopic.RegisterEvent(domain_websocket.UpdateResultEvent, gameHandler.HandlerUpdateResult)
+	gameTopic.RegisterEvent(domain_websocket.BerserkEvent, gameHandler.HandlerBerserk)
+	gameTopic.RegisterEvent(domain_websocket.PremoveEvent, gameHandler.HandlerPremove)
+	gameTopic.RegisterEvent(domain_websocket.ChatEvent, gameHandler.HandlerChat)
+	gameTopic.RegisterEvent(domain_websocket.MuteChatEvent, gameHandler.HandlerMuteChat)
 
 	gameseeksTopic, err := domain_websocket.NewTopic(domain_websocket.GameseeksTopic)
 	if err != nil {
@@ -92,6 +175,11 @@ func initHandlers(db *sql.DB) {
 	gameseeksHandler := delivery_ws_gameseeks.NewGameseeksHandler(
 		gameseeksRepo,
 		gameUseCase,
+		usecase_chat.NewLobbyChatUseCase(
+			repository_chat.NewChatRepo(db),
+			viper.GetStringSlice("chat.moderators"),
+			viper.GetStringSlice("chat.banned_words"),
+		),
 		gameTopic.(domain_websocket.TopicWithParam),
 	)
 	gameseeksTopic.RegisterEvent(domain_websocket.SubscribeEvent, gameseeksHandler.HandlerOnSubscribe)
@@ -99,6 +187,200 @@ func initHandlers(db *sql.DB) {
 	gameseeksTopic.RegisterEvent(domain_websocket.UnsubscribeEvent, gameseeksHandler.HandlerOnUnsubscribe)
 	gameseeksTopic.RegisterEvent(domain_websocket.AcceptEvent, gameseeksHandler.HandlerAcceptGameseek)
 	gameseeksTopic.RegisterEvent(domain_websocket.StartEngineGameEvent, gameseeksHandler.HandlerStartEngineGame)
+	gameseeksTopic.RegisterEvent(domain_websocket.ChatEvent, gameseeksHandler.HandlerChat)
+	gameseeksTopic.RegisterEvent(domain_websocket.ModerateEvent, gameseeksHandler.HandlerModerate)
+
+	puzzlesTopic, err := domain_websocket.NewTopic(domain_websocket.PuzzlesTopic)
+	if err != nil {
+		log.Printf("error instantiating puzzles topic: %v", err)
+		return
+	}
+	puzzleHandler := delivery_ws_puzzle.NewPuzzleHandler(puzzleUseCase)
+	puzzlesTopic.RegisterEvent(domain_websocket.SubscribeEvent, puzzleHandler.HandlerOnSubscribe)
+	puzzlesTopic.RegisterEvent(domain_websocket.UnsubscribeEvent, puzzleHandler.HandlerOnUnsubscribe)
+	puzzlesTopic.RegisterEvent(domain_websocket.NextPuzzleEvent, puzzleHandler.HandlerNextPuzzle)
+	puzzlesTopic.RegisterEvent(domain_websocket.PuzzleMoveEvent, puzzleHandler.HandlerPuzzleMove)
+
+	liveTopic, err := domain_websocket.NewTopic(domain_websocket.LiveTopic)
+	if err != nil {
+		log.Printf("error instantiating live topic: %v", err)
+		return
+	}
+	liveUseCase := usecase_live.NewLiveUseCase(
+		liveTopic.(domain_websocket.TopicWithoutParm).GetRoom(),
+		func(gameID int) int {
+			room, ok := gameTopic.(domain_websocket.TopicWithParam).GetRoom(strconv.Itoa(gameID))
+			if !ok {
+				return 0
+			}
+			return room.CountSpectators()
+		},
+	)
+	gameUseCase.OnGameCreated(liveUseCase.OnGameCreated)
+	gameUseCase.OnMove(liveUseCase.OnMove)
+	gameUseCase.OnGameOver(liveUseCase.OnGameOver)
+	liveHandler := delivery_ws_live.NewLiveHandler(liveUseCase)
+	liveTopic.RegisterEvent(domain_websocket.SubscribeEvent, liveHandler.HandlerOnSubscribe)
+	liveTopic.RegisterEvent(domain_websocket.UnsubscribeEvent, liveHandler.HandlerOnUnsubscribe)
+
+	tournamentTopic, err := domain_websocket.NewTopic(fmt.Sprint(domain_websocket.TournamentTopic, "/id"))
+	if err != nil {
+		log.Printf("error instantiating tournament topic: %v", err)
+		return
+	}
+	var arenaSchedule *usecase_tournament.ArenaSchedule
+	if viper.IsSet("arena.time") {
+		arenaSchedule = &usecase_tournament.ArenaSchedule{
+			Name:      viper.GetString("arena.name"),
+			Time:      viper.GetInt("arena.time"),
+			Increment: viper.GetInt("arena.increment"),
+			Duration:  viper.GetDuration("arena.duration"),
+		}
+	}
+	tournamentRepo := repository_tournament.NewTournamentRepo(db)
+	startGame := func(ctx context.Context, g domain.Game) (int, error) {
+		gameRoom := domain_websocket.NewRoom([]domain.Client{}, "")
+		gameRoom.SetPlayers(g.WhiteID, g.BlackID)
+		gameID, err := gameUseCase.OnAccept(ctx, g, gameRoom)
+		if err != nil {
+			return 0, err
+		}
+
+		gameRoom.ChangeParam(fmt.Sprint(gameID))
+		return gameID, gameTopic.(domain_websocket.TopicWithParam).PushNewRoom(gameRoom)
+	}
+	tournamentRoom := func(tournamentID int) (domain.Room, bool) {
+		return tournamentTopic.(domain_websocket.TopicWithParam).GetRoom(strconv.Itoa(tournamentID))
+	}
+	arenaUseCase := usecase_tournament.NewArenaUseCase(
+		tournamentRepo,
+		startGame,
+		tournamentRoom,
+		arenaSchedule,
+	)
+	swissUseCase := usecase_tournament.NewSwissUseCase(
+		tournamentRepo,
+		startGame,
+		tournamentRoom,
+	)
+	eventTopic, err := domain_websocket.NewTopic(fmt.Sprint(domain_websocket.EventTopic, "/id"))
+	if err != nil {
+		log.Printf("error instantiating event topic: %v", err)
+		return
+	}
+	eventUseCase := usecase_tournament.NewEventUseCase(
+		tournamentRepo,
+		startGame,
+		func(tournamentID int) (domain.Room, bool) {
+			return eventTopic.(domain_websocket.TopicWithParam).GetRoom(strconv.Itoa(tournamentID))
+		},
+		gameseeksTopic.(domain_websocket.TopicWithoutParm).GetRoom(),
+	)
+	if err := arenaUseCase.Load(context.Background()); err != nil {
+		log.Printf("error loading arenas: %v", err)
+	}
+	if err := swissUseCase.Load(context.Background()); err != nil {
+		log.Printf("error loading swiss tournaments: %v", err)
+	}
+	if err := eventUseCase.Load(context.Background()); err != nil {
+		log.Printf("error loading events: %v", err)
+	}
+	tournamentGameOverHooks := []func(context.Context, domain.Game) error{
+		arenaUseCase.OnGameOver,
+		swissUseCase.OnGameOver,
+		eventUseCase.OnGameOver,
+	}
+	for _, onGameOver := range tournamentGameOverHooks {
+		onGameOver := onGameOver
+		gameUseCase.OnGameOver(func(g domain.Game) {
+			if err := onGameOver(context.Background(), g); err != nil {
+				log.Printf("error scoring game %d in tournament %d: %v", g.ID, g.TournamentID, err)
+			}
+		})
+	}
+	tournamentTicker := time.NewTicker(2 * time.Second)
+	defer tournamentTicker.Stop()
+	go func() {
+		for range tournamentTicker.C {
+			arenaUseCase.Tick(context.Background())
+			swissUseCase.Tick(context.Background())
+		}
+	}()
+	tournamentHandler := delivery_ws_tournament.NewTournamentHandler(arenaUseCase, swissUseCase)
+	tournamentTopic.RegisterEvent(domain_websocket.SubscribeEvent, tournamentHandler.HandlerOnSubscribe)
+	tournamentTopic.RegisterEvent(domain_websocket.UnsubscribeEvent, tournamentHandler.HandlerOnUnsubscribe)
+	tournamentTopic.RegisterEvent(domain_websocket.JoinEvent, tournamentHandler.HandlerJoin)
+	tournamentTopic.RegisterEvent(domain_websocket.WithdrawEvent, tournamentHandler.HandlerWithdraw)
+	tournamentTopic.RegisterEvent(domain_websocket.PairingsEvent, tournamentHandler.HandlerPairings)
+	eventHandler := delivery_ws_tournament.NewEventHandler(eventUseCase)
+	eventTopic.RegisterEvent(domain_websocket.SubscribeEvent, eventHandler.HandlerOnSubscribe)
+	eventTopic.RegisterEvent(domain_websocket.UnsubscribeEvent, eventHandler.HandlerOnUnsubscribe)
+	eventTopic.RegisterEvent(domain_websocket.AddPlayerEvent, eventHandler.HandlerAddPlayer)
+	eventTopic.RegisterEvent(domain_websocket.RemovePlayerEvent, eventHandler.HandlerRemovePlayer)
+	eventTopic.RegisterEvent(domain_websocket.StartEvent, eventHandler.HandlerStart)
+	eventTopic.RegisterEvent(domain_websocket.NextRoundEvent, eventHandler.HandlerNextRound)
+	eventTopic.RegisterEvent(domain_websocket.ForfeitEvent, eventHandler.HandlerForfeit)
+	eventTopic.RegisterEvent(domain_websocket.AcceptChallengeEvent, eventHandler.HandlerAcceptChallenge)
+	eventTopic.RegisterEvent(domain_websocket.PairingsEvent, eventHandler.HandlerPairings)
+
+	simulTopic, err := domain_websocket.NewTopic(fmt.Sprint(domain_websocket.SimulTopic, "/id"))
+	if err != nil {
+		log.Printf("error instantiating simul topic: %v", err)
+		return
+	}
+	simulUseCase := usecase_simul.NewSimulUseCase(
+		startGame,
+		func(ctx context.Context, gameID int, result string) error {
+			changes, updated, err := gameUseCase.UpdateResult(ctx, gameID, "TimeOut", result)
+			if err != nil || !updated {
+				return err
+			}
+
+			gameRoom, ok := gameTopic.(domain_websocket.TopicWithParam).GetRoom(strconv.Itoa(gameID))
+			if !ok {
+				return nil
+			}
+			jsonData, err := domain_websocket.NewOutboundMessage(
+				fmt.Sprint(domain_websocket.GameTopic, "/", gameID),
+				domain_websocket.UpdateResultEvent,
+				changes,
+			).ToJSON("App/Simul, error converting result to json: %v\n")
+			if err != nil {
+				return err
+			}
+			gameRoom.BroadcastMessage(jsonData)
+			return nil
+		},
+		func(simulID int) (domain.Room, bool) {
+			return simulTopic.(domain_websocket.TopicWithParam).GetRoom(strconv.Itoa(simulID))
+		},
+	)
+	gameUseCase.OnMove(simulUseCase.OnMove)
+	gameUseCase.OnGameOver(simulUseCase.OnGameOver)
+	simulHandler := delivery_ws_simul.NewSimulHandler(simulUseCase)
+	simulTopic.RegisterEvent(domain_websocket.SubscribeEvent, simulHandler.HandlerOnSubscribe)
+	simulTopic.RegisterEvent(domain_websocket.UnsubscribeEvent, simulHandler.HandlerOnUnsubscribe)
+	simulTopic.RegisterEvent(domain_websocket.JoinEvent, simulHandler.HandlerJoin)
+	simulTopic.RegisterEvent(domain_websocket.WithdrawEvent, simulHandler.HandlerWithdraw)
+	simulTopic.RegisterEvent(domain_websocket.StartEvent, simulHandler.HandlerStart)
+
+	bughouseTopic, err := domain_websocket.NewTopic(fmt.Sprint(domain_websocket.BughouseTopic, "/id"))
+	if err != nil {
+		log.Printf("error instantiating bughouse topic: %v", err)
+		return
+	}
+	bughouseUseCase := usecase_bughouse.NewBughouseUseCase(
+		gameRepo,
+		repository_game.NewGameGroupRepo(db),
+		func(groupID int) (domain.Room, bool) {
+			return bughouseTopic.(domain_websocket.TopicWithParam).GetRoom(strconv.Itoa(groupID))
+		},
+	)
+	bughouseHandler := delivery_ws_bughouse.NewBughouseHandler(bughouseUseCase)
+	bughouseTopic.RegisterEvent(domain_websocket.SubscribeEvent, bughouseHandler.HandlerOnSubscribe)
+	bughouseTopic.RegisterEvent(domain_websocket.UnsubscribeEvent, bughouseHandler.HandlerOnUnsubscribe)
+	bughouseTopic.RegisterEvent(domain_websocket.MakeMoveEvent, bughouseHandler.HandlerMakeMove)
+	bughouseTopic.RegisterEvent(domain_websocket.UpdateResultEvent, bughouseHandler.HandlerResign)
 
 	webSocketRouter, err := domain_websocket.NewWebSocketRouter()
 	if err != nil {
@@ -107,15 +389,59 @@ func initHandlers(db *sql.DB) {
 	}
 	webSocketRouter.PushNewRoute(gameTopic)
 	webSocketRouter.PushNewRoute(gameseeksTopic)
+	webSocketRouter.PushNewRoute(puzzlesTopic)
+	webSocketRouter.PushNewRoute(liveTopic)
+	webSocketRouter.PushNewRoute(tournamentTopic)
+	webSocketRouter.PushNewRoute(eventTopic)
+	webSocketRouter.PushNewRoute(simulTopic)
+	webSocketRouter.PushNewRoute(bughouseTopic)
+	webSocketRouter.Use(domain_websocket.Metrics, domain_websocket.Recover)
+	webSocketRouter.SetRateLimit(
+		domain_websocket.GameseeksTopic,
+		domain_websocket.InsertEvent,
+		domain_websocket.RateLimit{Events: 5, Per: time.Minute},
+	)
+	webSocketRouter.SetRateLimit(
+		"",
+		domain_websocket.ChatEvent,
+		domain_websocket.RateLimit{Events: 1, Per: time.Second},
+	)
+
+	webSocketServer := domain_websocket.NewWebSocketServer(
+		webSocketRouter,
+		gameseeksRepo,
+		viper.GetDuration("websocket.ping_period"),
+		viper.GetDuration("websocket.pong_timeout"),
+		viper.GetInt64("websocket.max_message_size"),
+	)
 
-	webSocketServer := domain_websocket.NewWebSocketServer(webSocketRouter, gameseeksRepo)
+	expvar.Publish("websocket_connections", expvar.Func(func() any {
+		return webSocketServer.Stats()
+	}))
 
-	http.HandleFunc("/ws", webSocketServer.HandleWS)
+	router := httprouter.New()
+	router.HandlerFunc(http.MethodGet, "/ws", webSocketServer.HandleWS)
+
+	gameHTTPHandler := delivery_http_game.NewGameHandler(gameRepo)
+	gameHTTPHandler.RegisterRoutes(router)
+
+	explorerHTTPHandler := delivery_http_explorer.NewExplorerHandler(explorerUseCase)
+	explorerHTTPHandler.RegisterRoutes(router)
+
+	tournamentHTTPHandler := delivery_http_tournament.NewTournamentHandler(arenaUseCase, swissUseCase, eventUseCase)
+	tournamentHTTPHandler.RegisterRoutes(router)
+
+	simulHTTPHandler := delivery_http_simul.NewSimulHandler(simulUseCase)
+	simulHTTPHandler.RegisterRoutes(router)
+
+	bughouseHTTPHandler := delivery_http_bughouse.NewBughouseHandler(bughouseUseCase)
+	bughouseHTTPHandler.RegisterRoutes(router)
 
 	log.Printf("listening on port %d\n", viper.GetInt("app.port"))
 	log.Printf("allowed origin: %v", viper.GetStringSlice(fmt.Sprintf("%s.origin", os.Getenv("APP_ENV"))))
 	srv := &http.Server{
-		Addr: fmt.Sprintf(":%d", viper.GetInt("app.port")),
+		Addr:    fmt.Sprintf(":%d", viper.GetInt("app.port")),
+		Handler: router,
 	}
 
 	go func() {
@@ -124,7 +450,26 @@ func initHandlers(db *sql.DB) {
 		}
 	}()
 
-	quit := make(chan os.Signal)
+	// the metrics are only served on the admin address, which shouldn't be
+	// reachable from outside, like "127.0.0.1:6060"
+	var adminSrv *http.Server
+	if adminAddr := viper.GetString("app.admin_addr"); adminAddr != "" {
+		adminRouter := httprouter.New()
+		adminRouter.Handler(http.MethodGet, "/debug/vars", expvar.Handler())
+		adminSrv = &http.Server{
+			Addr:    adminAddr,
+			Handler: adminRouter,
+		}
+
+		log.Printf("admin listening on %s\n", adminAddr)
+		go func() {
+			if err := adminSrv.ListenAndServe(); err != nil {
+				log.Printf("admin listen: %s\n", err)
+			}
+		}()
+	}
+
+	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
@@ -134,6 +479,9 @@ func initHandlers(db *sql.DB) {
 	defer cancel()
 	webSocketServer.Close()
 
+	if adminSrv != nil {
+		adminSrv.Shutdown(ctx)
+	}
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Fatal("Server forced to shutdown:", err)
 	}
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS crochess.game_moves (
+    id SERIAL PRIMARY KEY,
+    game_id INTEGER NOT NULL REFERENCES crochess.game (id) ON DELETE CASCADE,
+    ply INTEGER NOT NULL CHECK (ply > 0),
+    uci VARCHAR(10) NOT NULL,
+    san VARCHAR(10) NOT NULL,
+    fen TEXT NOT NULL,
+    clock INTEGER NOT NULL,
+    time_stamp BIGINT NOT NULL,
+    UNIQUE (game_id, ply)
+);
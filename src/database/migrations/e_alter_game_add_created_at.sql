@@ -0,0 +1,6 @@
+ALTER TABLE crochess.game
+    ADD COLUMN IF NOT EXISTS created_at BIGINT NOT NULL
+        DEFAULT (EXTRACT(EPOCH FROM NOW()) * 1000)::BIGINT;
+
+CREATE INDEX IF NOT EXISTS game_white_id_idx ON crochess.game (white_id, id);
+CREATE INDEX IF NOT EXISTS game_black_id_idx ON crochess.game (black_id, id);
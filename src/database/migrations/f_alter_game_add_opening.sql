@@ -0,0 +1,5 @@
+ALTER TABLE crochess.game
+    ADD COLUMN IF NOT EXISTS eco VARCHAR(3) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS opening VARCHAR(255) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS game_eco_idx ON crochess.game (eco);
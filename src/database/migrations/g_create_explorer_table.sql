@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS crochess.explorer (
+    fen TEXT NOT NULL,
+    uci VARCHAR(10) NOT NULL,
+    san VARCHAR(10) NOT NULL,
+    games INTEGER NOT NULL DEFAULT 0,
+    white_wins INTEGER NOT NULL DEFAULT 0,
+    draws INTEGER NOT NULL DEFAULT 0,
+    black_wins INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (fen, uci)
+);
@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS crochess.puzzles (
+    id SERIAL PRIMARY KEY,
+    game_id INTEGER NOT NULL REFERENCES crochess.game (id) ON DELETE CASCADE,
+    fen TEXT NOT NULL,
+    last_move VARCHAR(10) NOT NULL,
+    solution TEXT[] NOT NULL,
+    themes TEXT[] NOT NULL DEFAULT '{}',
+    rating INTEGER NOT NULL DEFAULT 1500,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    solves INTEGER NOT NULL DEFAULT 0,
+    UNIQUE (game_id, fen)
+);
+
+CREATE INDEX IF NOT EXISTS puzzles_rating_idx ON crochess.puzzles (rating);
+
+CREATE TABLE IF NOT EXISTS crochess.puzzle_attempts (
+    id SERIAL PRIMARY KEY,
+    puzzle_id INTEGER NOT NULL REFERENCES crochess.puzzles (id) ON DELETE CASCADE,
+    player_id VARCHAR(10) NOT NULL,
+    solved BOOLEAN NOT NULL,
+    created_at BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS puzzle_attempts_player_idx ON crochess.puzzle_attempts (player_id, puzzle_id);
+
+CREATE TABLE IF NOT EXISTS crochess.puzzle_players (
+    player_id VARCHAR(10) PRIMARY KEY,
+    rating INTEGER NOT NULL DEFAULT 1500
+);
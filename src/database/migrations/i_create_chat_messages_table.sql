@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS crochess.chat_messages (
+    id SERIAL PRIMARY KEY,
+    game_id INTEGER NOT NULL REFERENCES crochess.game (id) ON DELETE CASCADE,
+    channel VARCHAR(10) NOT NULL,
+    sender_id VARCHAR(10) NOT NULL,
+    body VARCHAR(140) NOT NULL,
+    created_at BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS chat_messages_game_idx ON crochess.chat_messages (game_id, id);
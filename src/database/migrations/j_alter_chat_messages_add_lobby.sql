@@ -0,0 +1,15 @@
+ALTER TABLE crochess.chat_messages ALTER COLUMN game_id DROP NOT NULL;
+ALTER TABLE crochess.chat_messages ADD COLUMN IF NOT EXISTS hidden BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE crochess.chat_messages ADD COLUMN IF NOT EXISTS deleted BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS chat_messages_lobby_idx ON crochess.chat_messages (id) WHERE game_id IS NULL;
+
+CREATE TABLE IF NOT EXISTS crochess.moderation_actions (
+    id SERIAL PRIMARY KEY,
+    moderator_id VARCHAR(10) NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    target_id VARCHAR(10) NOT NULL DEFAULT '',
+    message_id INTEGER REFERENCES crochess.chat_messages (id) ON DELETE SET NULL,
+    duration BIGINT NOT NULL DEFAULT 0,
+    created_at BIGINT NOT NULL
+);
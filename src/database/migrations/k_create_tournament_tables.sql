@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS crochess.tournaments (
+    id SERIAL PRIMARY KEY,
+    kind VARCHAR(20) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    time INTEGER NOT NULL,
+    increment INTEGER NOT NULL,
+    starts_at BIGINT NOT NULL,
+    ends_at BIGINT NOT NULL,
+    status VARCHAR(10) NOT NULL DEFAULT 'created'
+);
+
+CREATE INDEX IF NOT EXISTS tournaments_unfinished_idx ON crochess.tournaments (kind) WHERE status <> 'finished';
+
+CREATE TABLE IF NOT EXISTS crochess.tournament_players (
+    tournament_id INTEGER NOT NULL REFERENCES crochess.tournaments (id) ON DELETE CASCADE,
+    player_id VARCHAR(10) NOT NULL,
+    score INTEGER NOT NULL DEFAULT 0,
+    results TEXT NOT NULL DEFAULT '',
+    withdrawn BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (tournament_id, player_id)
+);
+
+ALTER TABLE crochess.game ADD COLUMN IF NOT EXISTS tournament_id INTEGER REFERENCES crochess.tournaments (id) ON DELETE SET NULL;
+ALTER TABLE crochess.game ADD COLUMN IF NOT EXISTS white_berserk BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE crochess.game ADD COLUMN IF NOT EXISTS black_berserk BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,14 @@
+ALTER TABLE crochess.tournaments ADD COLUMN IF NOT EXISTS rounds INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS crochess.tournament_games (
+    id SERIAL PRIMARY KEY,
+    tournament_id INTEGER NOT NULL REFERENCES crochess.tournaments (id) ON DELETE CASCADE,
+    round INTEGER NOT NULL,
+    white_id VARCHAR(10) NOT NULL,
+    black_id VARCHAR(10) NOT NULL DEFAULT '',
+    game_id INTEGER REFERENCES crochess.game (id) ON DELETE SET NULL,
+    result VARCHAR(7) NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS tournament_games_tournament_idx ON crochess.tournament_games (tournament_id, round);
+CREATE UNIQUE INDEX IF NOT EXISTS tournament_games_game_idx ON crochess.tournament_games (game_id);
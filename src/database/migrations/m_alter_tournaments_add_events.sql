@@ -0,0 +1,5 @@
+ALTER TABLE crochess.tournaments ADD COLUMN IF NOT EXISTS organizer_id VARCHAR(10) NOT NULL DEFAULT '';
+ALTER TABLE crochess.tournaments ADD COLUMN IF NOT EXISTS armageddon_white_time INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE crochess.tournaments ADD COLUMN IF NOT EXISTS armageddon_black_time INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE crochess.tournament_games ADD COLUMN IF NOT EXISTS armageddon BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS crochess.game_groups (
+    id SERIAL PRIMARY KEY,
+    variant VARCHAR(20) NOT NULL,
+    result VARCHAR(7) NOT NULL DEFAULT '',
+    method VARCHAR(20) NOT NULL DEFAULT '',
+    created_at BIGINT NOT NULL
+);
+
+ALTER TABLE crochess.game ADD COLUMN IF NOT EXISTS group_id INTEGER REFERENCES crochess.game_groups (id) ON DELETE SET NULL;
+ALTER TABLE crochess.game ADD COLUMN IF NOT EXISTS pockets VARCHAR(64) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS game_group_id_idx ON crochess.game (group_id) WHERE group_id IS NOT NULL;
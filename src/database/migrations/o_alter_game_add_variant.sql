@@ -0,0 +1,9 @@
+ALTER TABLE crochess.game
+    ADD COLUMN IF NOT EXISTS variant VARCHAR(20) NOT NULL DEFAULT 'standard'
+        CHECK (variant IN ('standard', 'crazyhouse', 'bughouse'));
+
+UPDATE crochess.game SET variant = 'bughouse' WHERE group_id IS NOT NULL;
+
+ALTER TABLE crochess.gameseeks
+    ADD COLUMN IF NOT EXISTS variant VARCHAR(20) NOT NULL DEFAULT 'standard'
+        CHECK (variant IN ('standard', 'crazyhouse'));
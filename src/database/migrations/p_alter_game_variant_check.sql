@@ -0,0 +1,7 @@
+ALTER TABLE crochess.game DROP CONSTRAINT IF EXISTS game_variant_check;
+ALTER TABLE crochess.game ADD CONSTRAINT game_variant_check
+    CHECK (variant IN ('standard', 'crazyhouse', 'bughouse', 'threecheck', 'kingofthehill', 'atomic'));
+
+ALTER TABLE crochess.gameseeks DROP CONSTRAINT IF EXISTS gameseeks_variant_check;
+ALTER TABLE crochess.gameseeks ADD CONSTRAINT gameseeks_variant_check
+    CHECK (variant IN ('standard', 'crazyhouse', 'threecheck', 'kingofthehill', 'atomic'));
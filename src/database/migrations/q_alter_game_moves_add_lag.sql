@@ -0,0 +1,2 @@
+ALTER TABLE crochess.game_moves
+    ADD COLUMN IF NOT EXISTS lag INTEGER NOT NULL DEFAULT 0 CHECK (lag >= 0);
@@ -0,0 +1,102 @@
+package domain
+
+import (
+	"context"
+	"errors"
+)
+
+const (
+	ChatChannelPlayers    = "players"
+	ChatChannelSpectators = "spectators"
+	ChatChannelLobby      = "lobby"
+	// ChatMessageMaxLength is the most characters a chat message can have
+	ChatMessageMaxLength = 140
+	// ChatHistoryLength is how many messages are replayed on subscribe
+	ChatHistoryLength = 50
+)
+
+var (
+	ErrChatMessageEmpty   = errors.New("chat message is empty")
+	ErrChatMessageTooLong = errors.New("chat message is too long")
+	ErrChatRateLimited    = errors.New("sending chat messages too fast, slow down")
+	ErrChatBannedWords    = errors.New("chat message contains banned words")
+	ErrChatTimedOut       = errors.New("you are timed out from the chat")
+	ErrNotModerator       = errors.New("only moderators can moderate the chat")
+	ErrInvalidModeration  = errors.New("invalid moderation action")
+)
+
+const (
+	ModerationTimeout    = "timeout"
+	ModerationDelete     = "delete"
+	ModerationShadowMute = "shadow mute"
+	ModerationUnmute     = "unmute"
+)
+
+type (
+	// ChatMessage is a message sent in a game's chat or in the lobby, where
+	// GameID is 0. In games, players write to the players channel and
+	// spectators to the spectators channel. Hidden messages come from shadow
+	// muted clients and are only shown to their sender.
+	ChatMessage struct {
+		ID        int    `json:"id"`
+		GameID    int    `json:"game_id,omitempty"`
+		Channel   string `json:"channel"`
+		SenderID  string `json:"sender_id"`
+		Body      string `json:"body"`
+		CreatedAt int64  `json:"created_at"`
+		Hidden    bool   `json:"-"`
+	}
+
+	// ModerationAction is an entry of the lobby chat's audit trail. TargetID
+	// is set for timeouts and mutes, MessageID for deletions and Duration, in
+	// milliseconds, for timeouts.
+	ModerationAction struct {
+		ID          int    `json:"id"`
+		ModeratorID string `json:"moderator_id"`
+		Action      string `json:"action"`
+		TargetID    string `json:"target_id,omitempty"`
+		MessageID   int    `json:"message_id,omitempty"`
+		Duration    int64  `json:"duration,omitempty"`
+		CreatedAt   int64  `json:"created_at"`
+	}
+
+	ChatRepo interface {
+		Insert(ctx context.Context, m ChatMessage) (messageID int, err error)
+		// ListRecent returns the last limit messages of a game, oldest first
+		ListRecent(ctx context.Context, gameID int, limit int) ([]ChatMessage, error)
+		// ListLobby returns the last limit lobby messages viewerID can see,
+		// oldest first
+		ListLobby(ctx context.Context, viewerID string, limit int) ([]ChatMessage, error)
+		Delete(ctx context.Context, messageID int) error
+		InsertModeration(ctx context.Context, action ModerationAction) error
+	}
+
+	ChatUseCase interface {
+		Send(
+			ctx context.Context,
+			gameID int,
+			senderID string,
+			player bool,
+			body string,
+		) (ChatMessage, error)
+		// History returns the recent messages of a game the viewer can read
+		History(
+			ctx context.Context,
+			gameID int,
+			viewerID string,
+			player bool,
+		) ([]ChatMessage, error)
+		// CanRead reports whether a viewer should receive messages sent on
+		// channel. Spectators only read their own channel while players read
+		// both, unless they muted the spectators.
+		CanRead(gameID int, viewerID string, player bool, channel string) bool
+		SetSpectatorsMuted(gameID int, playerID string, muted bool)
+	}
+)
+
+type LobbyChatUseCase interface {
+	Send(ctx context.Context, senderID string, body string) (ChatMessage, error)
+	History(ctx context.Context, viewerID string) ([]ChatMessage, error)
+	// Moderate applies action and records it in the audit trail
+	Moderate(ctx context.Context, action ModerationAction) (ModerationAction, error)
+}
@@ -0,0 +1,186 @@
+package domain_drop
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type Color int8
+
+const (
+	White Color = iota
+	Black
+)
+
+func (c Color) Other() Color {
+	return 1 - c
+}
+
+type PieceType int8
+
+const (
+	NoPieceType PieceType = iota
+	Pawn
+	Knight
+	Bishop
+	Rook
+	Queen
+	King
+)
+
+// pieceLetters is indexed by PieceType
+const pieceLetters = " pnbrqk"
+
+func pieceTypeFromLetter(letter byte) PieceType {
+	i := strings.IndexByte(pieceLetters, toLower(letter))
+	if i <= 0 {
+		return NoPieceType
+	}
+
+	return PieceType(i)
+}
+
+func toLower(letter byte) byte {
+	if letter >= 'A' && letter <= 'Z' {
+		return letter + 'a' - 'A'
+	}
+
+	return letter
+}
+
+func toUpper(letter byte) byte {
+	if letter >= 'a' && letter <= 'z' {
+		return letter - 'a' + 'A'
+	}
+
+	return letter
+}
+
+// Piece is empty when its Type is NoPieceType
+type Piece struct {
+	Type  PieceType
+	Color Color
+}
+
+func (p Piece) letter() byte {
+	letter := pieceLetters[p.Type]
+	if p.Color == White {
+		return toUpper(letter)
+	}
+
+	return letter
+}
+
+// Square numbers the squares from a1 to h8, rank by rank
+type Square int8
+
+const NoSquare Square = -1
+
+func (s Square) File() int {
+	return int(s) % 8
+}
+
+func (s Square) Rank() int {
+	return int(s) / 8
+}
+
+func (s Square) String() string {
+	if s == NoSquare {
+		return "-"
+	}
+
+	return string([]byte{byte('a' + s.File()), byte('1' + s.Rank())})
+}
+
+func newSquare(file int, rank int) (Square, bool) {
+	if file < 0 || file > 7 || rank < 0 || rank > 7 {
+		return NoSquare, false
+	}
+
+	return Square(rank*8 + file), true
+}
+
+func parseSquare(s string) (Square, bool) {
+	if len(s) != 2 {
+		return NoSquare, false
+	}
+
+	return newSquare(int(s[0])-'a', int(s[1])-'1')
+}
+
+// Pocket counts the pieces a player can drop by type
+type Pocket [King]int
+
+func (p Pocket) String(c Color) string {
+	var b strings.Builder
+	for t := Queen; t >= Pawn; t-- {
+		letter := Piece{t, c}.letter()
+		for i := 0; i < p[t]; i++ {
+			b.WriteByte(letter)
+		}
+	}
+
+	return b.String()
+}
+
+// Move is a drop when Drop is set, From is NoSquare then
+type Move struct {
+	From      Square
+	To        Square
+	Promotion PieceType
+	Drop      PieceType
+}
+
+func (m Move) IsDrop() bool {
+	return m.Drop != NoPieceType
+}
+
+// String returns the move in UCI notation, which writes drops like N@f3
+func (m Move) String() string {
+	if m.IsDrop() {
+		return fmt.Sprintf("%c@%s", toUpper(pieceLetters[m.Drop]), m.To)
+	}
+
+	s := m.From.String() + m.To.String()
+	if m.Promotion != NoPieceType {
+		s += string(pieceLetters[m.Promotion])
+	}
+
+	return s
+}
+
+var ErrInvalidMove = errors.New("invalid move")
+
+func ParseMove(s string) (Move, error) {
+	if len(s) == 4 && s[1] == '@' {
+		t := pieceTypeFromLetter(s[0])
+		to, ok := parseSquare(s[2:])
+		if t == NoPieceType || t == King || !ok {
+			return Move{}, ErrInvalidMove
+		}
+
+		return Move{From: NoSquare, To: to, Drop: t}, nil
+	}
+
+	if len(s) != 4 && len(s) != 5 {
+		return Move{}, ErrInvalidMove
+	}
+	from, ok := parseSquare(s[:2])
+	if !ok {
+		return Move{}, ErrInvalidMove
+	}
+	to, ok := parseSquare(s[2:4])
+	if !ok {
+		return Move{}, ErrInvalidMove
+	}
+	m := Move{From: from, To: to}
+	if len(s) == 5 {
+		m.Promotion = pieceTypeFromLetter(s[4])
+		if m.Promotion == NoPieceType || m.Promotion == Pawn || m.Promotion == King {
+			return Move{}, ErrInvalidMove
+		}
+	}
+
+	return m, nil
+}
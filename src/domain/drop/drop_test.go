@@ -0,0 +1,149 @@
+package domain_drop
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func perft(p Position, depth int) int {
+	if depth == 0 {
+		return 1
+	}
+
+	moves := p.LegalMoves()
+	if depth == 1 {
+		return len(moves)
+	}
+
+	nodes := 0
+	for _, m := range moves {
+		next, _ := p.Play(m)
+		nodes += perft(next, depth-1)
+	}
+
+	return nodes
+}
+
+func TestPerft(t *testing.T) {
+	tests := []struct {
+		name  string
+		fen   string
+		depth int
+		nodes int
+	}{
+		{"starting position", StartFEN, 3, 8902},
+		{"kiwipete", "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", 2, 2039},
+		{"en passant and pins", "8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1", 3, 2812},
+		{"promotions and castling", "r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1", 3, 9467},
+		{"discovered checks", "rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8", 2, 1486},
+		{"knight drops", "4k3/8/8/8/8/8/8/4K3[N] w - - 0 1", 1, 5 + 62},
+		{"pawn drops skip the first and last rank", "4k3/8/8/8/8/8/8/4K3[P] w - - 0 1", 1, 5 + 48},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ParseFEN(tt.fen)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.nodes, perft(p, tt.depth))
+		})
+	}
+}
+
+func TestFEN(t *testing.T) {
+	fens := []string{
+		StartFEN,
+		"r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R[Pn] w KQkq - 2 3",
+		"3q~k3/8/8/8/8/8/8/3QK3[] b - e3 0 40",
+	}
+
+	for _, fen := range fens {
+		p, err := ParseFEN(fen)
+		assert.NoError(t, err)
+		assert.Equal(t, fen, p.FEN())
+	}
+
+	_, err := ParseFEN("8/8/8/8/8/8/8/8[K] w - - 0 1")
+	assert.Error(t, err)
+}
+
+func TestPosition_Move(t *testing.T) {
+	t.Run("Drops a piece from the pocket", func(t *testing.T) {
+		p, _ := ParseFEN("4k3/8/8/8/8/8/8/4K3[N] w - - 0 1")
+		m, err := ParseMove("N@f3")
+		assert.NoError(t, err)
+		assert.Equal(t, "N@f3", m.String())
+
+		next, _, err := p.Move(m)
+		assert.NoError(t, err)
+		assert.Equal(t, "4k3/8/8/8/8/5N2/8/4K3[] b - - 1 1", next.FEN())
+
+		_, _, err = next.Move(Move{From: NoSquare, To: 0, Drop: Knight})
+		assert.ErrorIs(t, err, ErrInvalidMove)
+	})
+
+	t.Run("Captured promoted pieces are pawns", func(t *testing.T) {
+		p, _ := ParseFEN("3q~k3/8/8/8/8/8/8/3QK3[] w - - 0 1")
+		m, _ := ParseMove("d1d8")
+
+		_, captured, err := p.Move(m)
+		assert.NoError(t, err)
+		assert.Equal(t, Pawn, captured)
+	})
+
+	t.Run("Castles", func(t *testing.T) {
+		p, _ := ParseFEN("r3k2r/8/8/8/8/8/8/R3K2R[] w KQkq - 0 1")
+		m, _ := ParseMove("e1c1")
+
+		next, _, err := p.Move(m)
+		assert.NoError(t, err)
+		assert.Equal(t, "r3k2r/8/8/8/8/8/8/2KR3R[] b kq - 1 1", next.FEN())
+	})
+}
+
+func TestPosition_Status(t *testing.T) {
+	t.Run("A mate a drop could block is over without waiting", func(t *testing.T) {
+		p, _ := ParseFEN("R5k1/5ppp/8/8/8/8/8/6K1[] b - - 1 1")
+		assert.Equal(t, Checkmate, p.Status(false))
+		assert.Equal(t, Ongoing, p.Status(true))
+	})
+
+	t.Run("Drops in the pocket get out of mate", func(t *testing.T) {
+		p, _ := ParseFEN("R5k1/5ppp/8/8/8/8/8/6K1[n] b - - 1 1")
+		assert.Equal(t, Ongoing, p.Status(false))
+	})
+
+	t.Run("A knight's mate can't be blocked", func(t *testing.T) {
+		p, _ := ParseFEN("6rk/5Npp/8/8/8/8/8/6K1[] b - - 0 1")
+		assert.Equal(t, Checkmate, p.Status(true))
+	})
+
+	t.Run("Stalemate", func(t *testing.T) {
+		p, _ := ParseFEN("k7/2Q5/1K6/8/8/8/8/8[] b - - 0 1")
+		assert.Equal(t, Stalemate, p.Status(true))
+	})
+}
+
+func TestPosition_SAN(t *testing.T) {
+	tests := []struct {
+		fen  string
+		move string
+		san  string
+	}{
+		{StartFEN, "g1f3", "Nf3"},
+		{"4k3/8/8/8/8/8/8/R3K2R[] w KQ - 0 1", "e1g1", "O-O"},
+		{"4k3/8/8/8/8/8/8/R4R1K[] w - - 0 1", "a1c1", "Rac1"},
+		{"4k3/8/8/8/R7/8/8/R6K[] w - - 0 1", "a1a2", "R1a2"},
+		{"4k3/8/8/3pP3/8/8/8/4K3[] w - d6 0 1", "e5d6", "exd6"},
+		{"4k3/P7/8/8/8/8/8/4K3[] w - - 0 1", "a7a8q", "a8=Q+"},
+		{"6k1/5ppp/8/8/8/8/8/4K3[R] w - - 0 1", "R@e8", "R@e8#"},
+	}
+
+	for _, tt := range tests {
+		p, err := ParseFEN(tt.fen)
+		assert.NoError(t, err)
+		m, err := ParseMove(tt.move)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.san, p.SAN(m))
+	}
+}
@@ -0,0 +1,424 @@
+package domain_drop
+
+type Status int8
+
+const (
+	Ongoing Status = iota
+	Checkmate
+	Stalemate
+)
+
+var (
+	knightSteps = [8][2]int{{1, 2}, {2, 1}, {2, -1}, {1, -2}, {-1, -2}, {-2, -1}, {-2, 1}, {-1, 2}}
+	kingSteps   = [8][2]int{{1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1}, {0, -1}, {1, -1}}
+	rookDirs    = [4][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+	bishopDirs  = [4][2]int{{1, 1}, {1, -1}, {-1, 1}, {-1, -1}}
+)
+
+func offset(sq Square, df int, dr int) (Square, bool) {
+	return newSquare(sq.File()+df, sq.Rank()+dr)
+}
+
+func forward(c Color) int {
+	if c == White {
+		return 1
+	}
+
+	return -1
+}
+
+func homeRank(c Color) int {
+	if c == White {
+		return 0
+	}
+
+	return 7
+}
+
+// KingSquare returns the square of the king of c, NoSquare if it has none
+func (p Position) KingSquare(c Color) Square {
+	for sq := Square(0); sq < 64; sq++ {
+		if p.board[sq] == (Piece{King, c}) {
+			return sq
+		}
+	}
+
+	return NoSquare
+}
+
+// Attacked tells if a piece of color by attacks sq
+func (p Position) Attacked(sq Square, by Color) bool {
+	for _, df := range []int{-1, 1} {
+		from, ok := offset(sq, df, -forward(by))
+		if ok && p.board[from] == (Piece{Pawn, by}) {
+			return true
+		}
+	}
+
+	for _, step := range knightSteps {
+		from, ok := offset(sq, step[0], step[1])
+		if ok && p.board[from] == (Piece{Knight, by}) {
+			return true
+		}
+	}
+
+	for _, step := range kingSteps {
+		from, ok := offset(sq, step[0], step[1])
+		if ok && p.board[from] == (Piece{King, by}) {
+			return true
+		}
+	}
+
+	slides := func(dirs [4][2]int, t PieceType) bool {
+		for _, dir := range dirs {
+			from, ok := offset(sq, dir[0], dir[1])
+			for ok {
+				piece := p.board[from]
+				if piece.Type != NoPieceType {
+					if piece.Color == by && (piece.Type == t || piece.Type == Queen) {
+						return true
+					}
+					break
+				}
+				from, ok = offset(from, dir[0], dir[1])
+			}
+		}
+		return false
+	}
+
+	return slides(rookDirs, Rook) || slides(bishopDirs, Bishop)
+}
+
+// InCheck tells if the king of the player to move is attacked
+func (p Position) InCheck() bool {
+	king := p.KingSquare(p.turn)
+	return king != NoSquare && p.Attacked(king, p.turn.Other())
+}
+
+// LegalMoves returns the moves of the player to move, drops included
+func (p Position) LegalMoves() []Move {
+	moves := make([]Move, 0, 64)
+	for _, m := range p.PseudoLegalMoves() {
+		next, _ := p.Play(m)
+		king := next.KingSquare(p.turn)
+		if king != NoSquare && next.Attacked(king, p.turn.Other()) {
+			continue
+		}
+		moves = append(moves, m)
+	}
+
+	return moves
+}
+
+// PseudoLegalMoves returns the moves of the player to move without checking
+// if they leave their king in check. Castling through check is already left
+// out.
+func (p Position) PseudoLegalMoves() []Move {
+	moves := make([]Move, 0, 64)
+	us := p.turn
+
+	for from := Square(0); from < 64; from++ {
+		piece := p.board[from]
+		if piece.Type == NoPieceType || piece.Color != us {
+			continue
+		}
+
+		switch piece.Type {
+		case Pawn:
+			moves = p.pawnMoves(moves, from)
+		case Knight:
+			moves = p.stepMoves(moves, from, knightSteps)
+		case King:
+			moves = p.stepMoves(moves, from, kingSteps)
+			moves = p.castlingMoves(moves, from)
+		case Bishop:
+			moves = p.slideMoves(moves, from, bishopDirs)
+		case Rook:
+			moves = p.slideMoves(moves, from, rookDirs)
+		case Queen:
+			moves = p.slideMoves(moves, from, bishopDirs)
+			moves = p.slideMoves(moves, from, rookDirs)
+		}
+	}
+
+	return p.dropMoves(moves)
+}
+
+func (p Position) addPawnMove(moves []Move, from Square, to Square) []Move {
+	if to.Rank() == homeRank(p.turn.Other()) {
+		for _, t := range []PieceType{Queen, Rook, Bishop, Knight} {
+			moves = append(moves, Move{From: from, To: to, Promotion: t})
+		}
+		return moves
+	}
+
+	return append(moves, Move{From: from, To: to})
+}
+
+func (p Position) pawnMoves(moves []Move, from Square) []Move {
+	dir := forward(p.turn)
+
+	if to, ok := offset(from, 0, dir); ok && p.board[to].Type == NoPieceType {
+		moves = p.addPawnMove(moves, from, to)
+
+		startRank := homeRank(p.turn) + dir
+		if to2, ok := offset(to, 0, dir); ok && from.Rank() == startRank && p.board[to2].Type == NoPieceType {
+			moves = append(moves, Move{From: from, To: to2})
+		}
+	}
+
+	for _, df := range []int{-1, 1} {
+		to, ok := offset(from, df, dir)
+		if !ok {
+			continue
+		}
+		target := p.board[to]
+		if (target.Type != NoPieceType && target.Color != p.turn) || to == p.enPassant {
+			moves = p.addPawnMove(moves, from, to)
+		}
+	}
+
+	return moves
+}
+
+func (p Position) stepMoves(moves []Move, from Square, steps [8][2]int) []Move {
+	for _, step := range steps {
+		to, ok := offset(from, step[0], step[1])
+		if !ok {
+			continue
+		}
+		target := p.board[to]
+		if target.Type == NoPieceType || target.Color != p.turn {
+			moves = append(moves, Move{From: from, To: to})
+		}
+	}
+
+	return moves
+}
+
+func (p Position) slideMoves(moves []Move, from Square, dirs [4][2]int) []Move {
+	for _, dir := range dirs {
+		to, ok := offset(from, dir[0], dir[1])
+		for ok {
+			target := p.board[to]
+			if target.Type != NoPieceType {
+				if target.Color != p.turn {
+					moves = append(moves, Move{From: from, To: to})
+				}
+				break
+			}
+			moves = append(moves, Move{From: from, To: to})
+			to, ok = offset(to, dir[0], dir[1])
+		}
+	}
+
+	return moves
+}
+
+func (p Position) castlingMoves(moves []Move, from Square) []Move {
+	rank := homeRank(p.turn)
+	if from.Rank() != rank || from.File() != 4 || p.InCheck() {
+		return moves
+	}
+
+	them := p.turn.Other()
+	sides := []struct {
+		side     int
+		rookFile int
+		kingFile int
+		// empty are the files between the king and the rook, safe the ones
+		// the king passes
+		empty []int
+		safe  []int
+	}{
+		{kingSide, 7, 6, []int{5, 6}, []int{5, 6}},
+		{queenSide, 0, 2, []int{1, 2, 3}, []int{2, 3}},
+	}
+	for _, s := range sides {
+		if !p.castling[p.turn][s.side] {
+			continue
+		}
+		rookSquare, _ := newSquare(s.rookFile, rank)
+		if p.board[rookSquare] != (Piece{Rook, p.turn}) {
+			continue
+		}
+
+		allowed := true
+		for _, f := range s.empty {
+			sq, _ := newSquare(f, rank)
+			if p.board[sq].Type != NoPieceType {
+				allowed = false
+			}
+		}
+		for _, f := range s.safe {
+			sq, _ := newSquare(f, rank)
+			if p.Attacked(sq, them) {
+				allowed = false
+			}
+		}
+		if allowed {
+			to, _ := newSquare(s.kingFile, rank)
+			moves = append(moves, Move{From: from, To: to})
+		}
+	}
+
+	return moves
+}
+
+func (p Position) dropMoves(moves []Move) []Move {
+	pocket := p.pockets[p.turn]
+	for t := Pawn; t < King; t++ {
+		if pocket[t] == 0 {
+			continue
+		}
+		for to := Square(0); to < 64; to++ {
+			if p.board[to].Type != NoPieceType {
+				continue
+			}
+			if t == Pawn && (to.Rank() == 0 || to.Rank() == 7) {
+				continue
+			}
+			moves = append(moves, Move{From: NoSquare, To: to, Drop: t})
+		}
+	}
+
+	return moves
+}
+
+// Move plays a legal move and returns the new position and the type of the
+// captured piece. Captured promoted pieces are returned as pawns.
+func (p Position) Move(m Move) (Position, PieceType, error) {
+	for _, legal := range p.LegalMoves() {
+		if legal == m {
+			next, captured := p.Play(m)
+			return next, captured, nil
+		}
+	}
+
+	return Position{}, NoPieceType, ErrInvalidMove
+}
+
+// Play plays a move without checking if it's legal
+func (p Position) Play(m Move) (Position, PieceType) {
+	next := p
+	us := p.turn
+	next.enPassant = NoSquare
+	next.halfMoves++
+
+	if m.IsDrop() {
+		next.board[m.To] = Piece{m.Drop, us}
+		next.promoted[m.To] = false
+		next.pockets[us][m.Drop]--
+		if m.Drop == Pawn {
+			next.halfMoves = 0
+		}
+		next.endTurn()
+		return next, NoPieceType
+	}
+
+	piece := p.board[m.From]
+	captureSquare := m.To
+	if piece.Type == Pawn && m.To == p.enPassant && p.board[m.To].Type == NoPieceType {
+		captureSquare, _ = offset(m.To, 0, -forward(us))
+	}
+	captured := p.board[captureSquare]
+	capturedType := captured.Type
+	if capturedType != NoPieceType && p.promoted[captureSquare] {
+		capturedType = Pawn
+	}
+	next.board[captureSquare] = Piece{}
+	next.promoted[captureSquare] = false
+
+	next.board[m.To] = piece
+	next.promoted[m.To] = p.promoted[m.From]
+	next.board[m.From] = Piece{}
+	next.promoted[m.From] = false
+	if m.Promotion != NoPieceType {
+		next.board[m.To] = Piece{m.Promotion, us}
+		next.promoted[m.To] = true
+	}
+
+	if piece.Type == King {
+		next.castling[us] = [2]bool{}
+		rank := homeRank(us)
+		if m.From.File() == 4 && (m.To.File() == 6 || m.To.File() == 2) {
+			rookFrom, _ := newSquare(7, rank)
+			rookTo, _ := newSquare(5, rank)
+			if m.To.File() == 2 {
+				rookFrom, _ = newSquare(0, rank)
+				rookTo, _ = newSquare(3, rank)
+			}
+			next.board[rookTo] = next.board[rookFrom]
+			next.promoted[rookTo] = next.promoted[rookFrom]
+			next.board[rookFrom] = Piece{}
+			next.promoted[rookFrom] = false
+		}
+	}
+	for _, sq := range []Square{m.From, m.To} {
+		for _, c := range []Color{White, Black} {
+			if sq.Rank() != homeRank(c) {
+				continue
+			}
+			if sq.File() == 7 {
+				next.castling[c][kingSide] = false
+			} else if sq.File() == 0 {
+				next.castling[c][queenSide] = false
+			}
+		}
+	}
+
+	if piece.Type == Pawn {
+		next.halfMoves = 0
+		if m.To.Rank()-m.From.Rank() == 2*forward(us) {
+			next.enPassant, _ = offset(m.From, 0, forward(us))
+		}
+	}
+	if capturedType != NoPieceType {
+		next.halfMoves = 0
+	}
+
+	next.endTurn()
+	return next, capturedType
+}
+
+func (p *Position) endTurn() {
+	if p.turn == Black {
+		p.fullMoves++
+	}
+	p.turn = p.turn.Other()
+}
+
+// Status tells if the player to move is mated or stalemated. With
+// waitForDrops, a mate that a dropped piece could block isn't over yet, since
+// the player can wait for their partner to send them one like in bughouse.
+func (p Position) Status(waitForDrops bool) Status {
+	if len(p.LegalMoves()) > 0 {
+		return Ongoing
+	}
+	if !p.InCheck() {
+		return Stalemate
+	}
+	if waitForDrops && p.blockableByDrop() {
+		return Ongoing
+	}
+
+	return Checkmate
+}
+
+// blockableByDrop tells if dropping any piece on some empty square would get
+// the player to move out of check
+func (p Position) blockableByDrop() bool {
+	king := p.KingSquare(p.turn)
+	for sq := Square(0); sq < 64; sq++ {
+		if p.board[sq].Type != NoPieceType {
+			continue
+		}
+		blocked := p
+		blocked.board[sq] = Piece{Knight, p.turn}
+		if !blocked.Attacked(king, p.turn.Other()) {
+			return true
+		}
+	}
+
+	return false
+}
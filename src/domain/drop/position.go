@@ -0,0 +1,271 @@
+package domain_drop
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// StartFEN is the starting position with empty pockets
+const StartFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR[] w KQkq - 0 1"
+
+const (
+	kingSide  = 0
+	queenSide = 1
+)
+
+// Position is a chess position where pieces can be dropped from the pockets
+// of the players. Promoted pieces are remembered since they go back to a
+// pocket as pawns when they are captured. Positions are values, so moves
+// return a new one.
+type Position struct {
+	board     [64]Piece
+	promoted  [64]bool
+	turn      Color
+	castling  [2][2]bool
+	enPassant Square
+	halfMoves int
+	fullMoves int
+	pockets   [2]Pocket
+}
+
+func StartingPosition() Position {
+	p, err := ParseFEN(StartFEN)
+	if err != nil {
+		panic(err)
+	}
+
+	return p
+}
+
+// ParseFEN reads a FEN where the pockets follow the board in brackets and
+// promoted pieces are marked with a ~, like in crazyhouse FENs. The pockets
+// can be left out.
+func ParseFEN(fen string) (Position, error) {
+	fields := strings.Fields(fen)
+	if len(fields) != 6 {
+		return Position{}, errors.New(fmt.Sprintf("fen needs 6 fields: %s", fen))
+	}
+
+	p := Position{enPassant: NoSquare}
+
+	board := fields[0]
+	if open := strings.IndexByte(board, '['); open != -1 {
+		if !strings.HasSuffix(board, "]") {
+			return Position{}, errors.New("fen has an unclosed pocket")
+		}
+		for i := open + 1; i < len(board)-1; i++ {
+			t := pieceTypeFromLetter(board[i])
+			if t == NoPieceType || t == King {
+				return Position{}, errors.New(fmt.Sprintf("%c can't be in a pocket", board[i]))
+			}
+			p.pockets[colorOfLetter(board[i])][t]++
+		}
+		board = board[:open]
+	}
+
+	ranks := strings.Split(board, "/")
+	if len(ranks) != 8 {
+		return Position{}, errors.New("fen board needs 8 ranks")
+	}
+	for i, rank := range ranks {
+		r := 7 - i
+		file := 0
+		for j := 0; j < len(rank); j++ {
+			c := rank[j]
+			switch {
+			case c >= '1' && c <= '8':
+				file += int(c - '0')
+			case c == '~':
+				sq, ok := newSquare(file-1, r)
+				if !ok || p.board[sq].Type == NoPieceType {
+					return Position{}, errors.New("fen marks an empty square as promoted")
+				}
+				p.promoted[sq] = true
+			default:
+				t := pieceTypeFromLetter(c)
+				sq, ok := newSquare(file, r)
+				if t == NoPieceType || !ok {
+					return Position{}, errors.New(fmt.Sprintf("invalid fen rank: %s", rank))
+				}
+				p.board[sq] = Piece{t, colorOfLetter(c)}
+				file++
+			}
+		}
+		if file != 8 {
+			return Position{}, errors.New(fmt.Sprintf("invalid fen rank: %s", rank))
+		}
+	}
+
+	switch fields[1] {
+	case "w":
+		p.turn = White
+	case "b":
+		p.turn = Black
+	default:
+		return Position{}, errors.New("fen turn has to be w or b")
+	}
+
+	if fields[2] != "-" {
+		for _, c := range fields[2] {
+			switch c {
+			case 'K':
+				p.castling[White][kingSide] = true
+			case 'Q':
+				p.castling[White][queenSide] = true
+			case 'k':
+				p.castling[Black][kingSide] = true
+			case 'q':
+				p.castling[Black][queenSide] = true
+			default:
+				return Position{}, errors.New(fmt.Sprintf("invalid fen castling rights: %s", fields[2]))
+			}
+		}
+	}
+
+	if fields[3] != "-" {
+		sq, ok := parseSquare(fields[3])
+		if !ok {
+			return Position{}, errors.New(fmt.Sprintf("invalid fen en passant square: %s", fields[3]))
+		}
+		p.enPassant = sq
+	}
+
+	var err error
+	p.halfMoves, err = strconv.Atoi(fields[4])
+	if err != nil {
+		return Position{}, errors.New("fen half moves have to be a number")
+	}
+	p.fullMoves, err = strconv.Atoi(fields[5])
+	if err != nil {
+		return Position{}, errors.New("fen full moves have to be a number")
+	}
+
+	return p, nil
+}
+
+func colorOfLetter(letter byte) Color {
+	if letter >= 'A' && letter <= 'Z' {
+		return White
+	}
+
+	return Black
+}
+
+func (p Position) FEN() string {
+	var b strings.Builder
+	for r := 7; r >= 0; r-- {
+		empty := 0
+		for f := 0; f < 8; f++ {
+			sq, _ := newSquare(f, r)
+			piece := p.board[sq]
+			if piece.Type == NoPieceType {
+				empty++
+				continue
+			}
+			if empty > 0 {
+				b.WriteByte(byte('0' + empty))
+				empty = 0
+			}
+			b.WriteByte(piece.letter())
+			if p.promoted[sq] {
+				b.WriteByte('~')
+			}
+		}
+		if empty > 0 {
+			b.WriteByte(byte('0' + empty))
+		}
+		if r > 0 {
+			b.WriteByte('/')
+		}
+	}
+
+	b.WriteByte('[')
+	b.WriteString(p.pockets[White].String(White))
+	b.WriteString(p.pockets[Black].String(Black))
+	b.WriteByte(']')
+
+	if p.turn == White {
+		b.WriteString(" w ")
+	} else {
+		b.WriteString(" b ")
+	}
+
+	castling := ""
+	for _, right := range []struct {
+		color  Color
+		side   int
+		letter string
+	}{
+		{White, kingSide, "K"},
+		{White, queenSide, "Q"},
+		{Black, kingSide, "k"},
+		{Black, queenSide, "q"},
+	} {
+		if p.castling[right.color][right.side] {
+			castling += right.letter
+		}
+	}
+	if castling == "" {
+		castling = "-"
+	}
+	b.WriteString(castling)
+
+	fmt.Fprintf(&b, " %s %d %d", p.enPassant, p.halfMoves, p.fullMoves)
+
+	return b.String()
+}
+
+func (p Position) Turn() Color {
+	return p.turn
+}
+
+// HalfMoveClock returns the number of moves since the last capture or pawn
+// move, for the fifty move rule
+func (p Position) HalfMoveClock() int {
+	return p.halfMoves
+}
+
+// PieceAt returns the piece on a square, which is empty if its type is
+// NoPieceType
+func (p Position) PieceAt(sq Square) Piece {
+	return p.board[sq]
+}
+
+func (p Position) Pocket(c Color) Pocket {
+	return p.pockets[c]
+}
+
+// WithPocket returns the position with the pocket of c replaced
+func (p Position) WithPocket(c Color, pocket Pocket) Position {
+	p.pockets[c] = pocket
+	return p
+}
+
+// AddToPocket returns the position with a piece added to the pocket of c,
+// like one captured by their partner in bughouse
+func (p Position) AddToPocket(c Color, t PieceType) Position {
+	p.pockets[c][t]++
+	return p
+}
+
+// ParsePockets reads pockets written like in a FEN, without the brackets
+func ParsePockets(s string) ([2]Pocket, error) {
+	var pockets [2]Pocket
+	for i := 0; i < len(s); i++ {
+		t := pieceTypeFromLetter(s[i])
+		if t == NoPieceType || t == King {
+			return pockets, errors.New(fmt.Sprintf("%c can't be in a pocket", s[i]))
+		}
+		pockets[colorOfLetter(s[i])][t]++
+	}
+
+	return pockets, nil
+}
+
+// PocketsString writes the pockets of both players like in a FEN, without the
+// brackets
+func (p Position) PocketsString() string {
+	return p.pockets[White].String(White) + p.pockets[Black].String(Black)
+}
@@ -0,0 +1,78 @@
+package domain_drop
+
+// SAN returns a legal move in standard algebraic notation. Drops are written
+// like in UCI, N@f3.
+func (p Position) SAN(m Move) string {
+	var san string
+
+	var piece Piece
+	if !m.IsDrop() {
+		piece = p.board[m.From]
+	}
+	switch {
+	case m.IsDrop():
+		san = m.String()
+	case piece.Type == King && m.From.File() == 4 && m.To.File() == 6:
+		san = "O-O"
+	case piece.Type == King && m.From.File() == 4 && m.To.File() == 2:
+		san = "O-O-O"
+	default:
+		capture := p.board[m.To].Type != NoPieceType ||
+			(piece.Type == Pawn && m.To == p.enPassant)
+
+		if piece.Type == Pawn {
+			if capture {
+				san = string(byte('a'+m.From.File())) + "x"
+			}
+		} else {
+			san = string(toUpper(pieceLetters[piece.Type])) + p.disambiguation(m)
+			if capture {
+				san += "x"
+			}
+		}
+		san += m.To.String()
+		if m.Promotion != NoPieceType {
+			san += "=" + string(toUpper(pieceLetters[m.Promotion]))
+		}
+	}
+
+	next, _ := p.Play(m)
+	if next.InCheck() {
+		if next.Status(false) == Checkmate {
+			return san + "#"
+		}
+		return san + "+"
+	}
+
+	return san
+}
+
+// disambiguation returns the file, rank or square of the origin of a move
+// when another piece of the same type could move to the same square
+func (p Position) disambiguation(m Move) string {
+	piece := p.board[m.From]
+	sameFile, sameRank, ambiguous := false, false, false
+	for _, other := range p.LegalMoves() {
+		if other.IsDrop() || other.To != m.To || other.From == m.From || p.board[other.From] != piece {
+			continue
+		}
+		ambiguous = true
+		if other.From.File() == m.From.File() {
+			sameFile = true
+		}
+		if other.From.Rank() == m.From.Rank() {
+			sameRank = true
+		}
+	}
+
+	switch {
+	case !ambiguous:
+		return ""
+	case !sameFile:
+		return string(byte('a' + m.From.File()))
+	case !sameRank:
+		return string(byte('1' + m.From.Rank()))
+	}
+
+	return m.From.String()
+}
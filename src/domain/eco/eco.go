@@ -0,0 +1,78 @@
+package domain_eco
+
+import (
+	"bufio"
+	_ "embed"
+	"log"
+	"strings"
+)
+
+// eco.tsv is generated from https://github.com/lichess-org/chess-openings
+// and holds one opening per line: code, name and the UCI moves leading to it
+//
+//go:embed eco.tsv
+var ecoTSV string
+
+type Opening struct {
+	Code string `json:"eco"`
+	Name string `json:"opening"`
+}
+
+type node struct {
+	children map[string]*node
+	opening  *Opening
+}
+
+var root = buildTree(ecoTSV)
+
+func newNode() *node {
+	return &node{children: make(map[string]*node)}
+}
+
+func buildTree(tsv string) *node {
+	tree := newNode()
+
+	scanner := bufio.NewScanner(strings.NewReader(tsv))
+	// skip header
+	scanner.Scan()
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) != 3 {
+			log.Printf("Domain/Eco/buildTree, skipping malformed line: %s", scanner.Text())
+			continue
+		}
+
+		n := tree
+		for _, move := range strings.Fields(fields[2]) {
+			child, ok := n.children[move]
+			if !ok {
+				child = newNode()
+				n.children[move] = child
+			}
+			n = child
+		}
+		n.opening = &Opening{fields[0], fields[1]}
+	}
+
+	return tree
+}
+
+// Classify returns the deepest opening reached by following moves, which are
+// in UCI notation, from the starting position. ok is false if the first move
+// is not part of any known opening.
+func Classify(moves []string) (opening Opening, ok bool) {
+	n := root
+	for _, move := range moves {
+		child, exists := n.children[move]
+		if !exists {
+			break
+		}
+		n = child
+		if n.opening != nil {
+			opening = *n.opening
+			ok = true
+		}
+	}
+
+	return opening, ok
+}
@@ -0,0 +1,48 @@
+package domain_eco
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name     string
+		moves    string
+		expected Opening
+		ok       bool
+	}{
+		{
+			name:     "exact line",
+			moves:    "e2e4 e7e5 g1f3 b8c6 f1b5",
+			expected: Opening{"C60", "Ruy Lopez"},
+			ok:       true,
+		},
+		{
+			name:     "deepest match after leaving the book",
+			moves:    "e2e4 e7e5 g1f3 b8c6 f1b5 h7h6 h2h3 h6h5",
+			expected: Opening{"C60", "Ruy Lopez"},
+			ok:       true,
+		},
+		{
+			name:  "unknown first move",
+			moves: "a7a5",
+			ok:    false,
+		},
+		{
+			name:  "no moves",
+			moves: "",
+			ok:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opening, ok := Classify(strings.Fields(tt.moves))
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expected, opening)
+		})
+	}
+}
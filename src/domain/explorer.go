@@ -0,0 +1,50 @@
+package domain
+
+import (
+	"context"
+	"strings"
+)
+
+type (
+	// ExplorerMove holds the results of every finished game where UCI was
+	// played from the position FEN.
+	ExplorerMove struct {
+		FEN       string `json:"-"`
+		UCI       string `json:"uci"`
+		SAN       string `json:"san"`
+		Games     int    `json:"games"`
+		WhiteWins int    `json:"white"`
+		Draws     int    `json:"draws"`
+		BlackWins int    `json:"black"`
+	}
+
+	ExplorerPosition struct {
+		FEN   string         `json:"fen"`
+		Moves []ExplorerMove `json:"moves"`
+	}
+
+	ExplorerRepo interface {
+		Get(ctx context.Context, fen string) ([]ExplorerMove, error)
+		// Add increments the stats of every move by the stats given
+		Add(ctx context.Context, moves []ExplorerMove) error
+		// Replace deletes the whole index and saves moves in its place
+		Replace(ctx context.Context, moves []ExplorerMove) error
+	}
+
+	ExplorerUseCase interface {
+		Get(ctx context.Context, fen string) (ExplorerPosition, error)
+		OnGameOver(ctx context.Context, g Game) error
+		Rebuild(ctx context.Context) (indexedGames int, err error)
+	}
+)
+
+// NormalizeFEN strips the halfmove and fullmove clocks from fen so the same
+// position reached at different points of a game has the same key.
+func NormalizeFEN(fen string) string {
+	fields := strings.Fields(fen)
+	if len(fields) > 4 {
+		fields = fields[:4]
+	}
+
+	return strings.Join(fields, " ")
+}
@@ -2,10 +2,17 @@ package domain
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/lookingcoolonavespa/go_crochess_backend/src/utils"
 )
 
+var (
+	ErrPremoveOnTurn  = errors.New("premoves can only be made on the opponent's turn")
+	ErrPremoveIllegal = errors.New("premove is not legal anymore")
+)
+
 type GameFieldJsonTag string
 
 const (
@@ -23,6 +30,32 @@ const (
 	GameVersionJsonTag         GameFieldJsonTag = "version"
 	GameWhiteDrawStatusJsonTag GameFieldJsonTag = "white_draw_status"
 	GameBlackDrawStatusJsonTag GameFieldJsonTag = "black_draw_status"
+	GameCreatedAtJsonTag       GameFieldJsonTag = "created_at"
+	GameEcoJsonTag             GameFieldJsonTag = "eco"
+	GameOpeningJsonTag         GameFieldJsonTag = "opening"
+	GameTournamentIDJsonTag    GameFieldJsonTag = "tournament_id"
+	GameWhiteBerserkJsonTag    GameFieldJsonTag = "white_berserk"
+	GameBlackBerserkJsonTag    GameFieldJsonTag = "black_berserk"
+	GameGroupIDJsonTag         GameFieldJsonTag = "group_id"
+	GamePocketsJsonTag         GameFieldJsonTag = "pockets"
+	GameVariantJsonTag         GameFieldJsonTag = "variant"
+)
+
+// variants of chess a game can be played in
+const (
+	GameVariantStandard      = "standard"
+	GameVariantCrazyhouse    = "crazyhouse"
+	GameVariantBughouse      = "bughouse"
+	GameVariantThreeCheck    = "threecheck"
+	GameVariantKingOfTheHill = "kingofthehill"
+	GameVariantAtomic        = "atomic"
+)
+
+// results of a game from the point of view of GameFilter.PlayerID
+const (
+	GameResultWin  = "win"
+	GameResultLoss = "loss"
+	GameResultDraw = "draw"
 )
 
 type (
@@ -41,6 +74,88 @@ type (
 		Version              int    `db:"version"`
 		WhiteDrawStatus      bool   `json:"white_draw_status"`
 		BlackDrawStatus      bool   `json:"black_draw_status"`
+		CreatedAt            int64  `json:"created_at"`
+		Eco                  string `json:"eco"`
+		Opening              string `json:"opening"`
+		// TournamentID is 0 for games outside of tournaments
+		TournamentID int  `json:"tournament_id,omitempty"`
+		WhiteBerserk bool `json:"white_berserk"`
+		BlackBerserk bool `json:"black_berserk"`
+		// GroupID is 0 for games that aren't part of a GameGroup. Pockets
+		// holds the pieces the players can drop, written like in a FEN.
+		GroupID int    `json:"group_id,omitempty"`
+		Pockets string `json:"pockets,omitempty"`
+		// Variant is empty for games that were never stored, which are
+		// standard games
+		Variant string `json:"variant"`
+		// UntimedColor is the color whose clock doesn't run, like the host's
+		// in a simul. It isn't stored, so it only holds while the server that
+		// started the game is up.
+		UntimedColor Color `json:"-"`
+		// BerserkAllowed lets the players go berserk, like in an arena. It
+		// isn't stored either.
+		BerserkAllowed bool `json:"-"`
+	}
+
+	// GameFilter narrows down the games returned by GameRepo.List. Zero values
+	// are ignored and an empty PlayerID lists the games of every player. Games
+	// are returned newest first and After is the id of the last game of the
+	// previous page.
+	GameFilter struct {
+		PlayerID  string
+		Finished  bool
+		Result    string
+		Color     Color
+		Time      *int
+		Increment *int
+		From      int64
+		To        int64
+		Opponent  string
+		// Eco matches every opening whose code starts with it, e.g. "C4"
+		Eco   string
+		After int
+		Limit int
+	}
+
+	PlayerStats struct {
+		PlayerID string `json:"player_id"`
+		Games    int    `json:"games"`
+		Wins     int    `json:"wins"`
+		Losses   int    `json:"losses"`
+		Draws    int    `json:"draws"`
+		AsWhite  int    `json:"as_white"`
+		AsBlack  int    `json:"as_black"`
+	}
+
+	// GameMove is one entry of a game's move history. Clock is the mover's
+	// remaining time in milliseconds after the move (increment included) and
+	// TimeStamp is the server time in unix milliseconds when the move was made.
+	// Lag is the milliseconds credited back to the mover for network lag.
+	GameMove struct {
+		GameID    int    `json:"game_id"`
+		Ply       int    `json:"ply"`
+		UCI       string `json:"uci"`
+		SAN       string `json:"san"`
+		FEN       string `json:"fen"`
+		Clock     int    `json:"clock"`
+		TimeStamp int64  `json:"time_stamp"`
+		Lag       int    `json:"lag"`
+	}
+
+	// MoveLag is what is known about the time a move spent in transit.
+	// Latency is the round trip time of the mover's connection, 0 if it was
+	// never measured, and MoveTime the milliseconds the mover's client says
+	// the move took, 0 if it didn't say.
+	MoveLag struct {
+		Latency  time.Duration
+		MoveTime int
+	}
+
+	// Premove is a move a player made on the opponent's turn, to be played
+	// as soon as the player's turn starts
+	Premove struct {
+		PlayerID string `json:"player_id"`
+		Move     string `json:"move"`
 	}
 
 	GameRepo interface {
@@ -50,7 +165,12 @@ type (
 			id int,
 			version int,
 			changes GameChanges,
+			move *GameMove,
 		) (updated bool, err error)
+		ListMoves(ctx context.Context, gameID int) ([]GameMove, error)
+		List(ctx context.Context, filter GameFilter) ([]Game, error)
+		// Stats counts the finished games of the player
+		Stats(ctx context.Context, playerID string) (PlayerStats, error)
 		Insert(
 			ctx context.Context,
 			g Game,
@@ -59,13 +179,27 @@ type (
 
 	GameUseCase interface {
 		Get(ctx context.Context, id int) (Game, error)
+		// GetAsOf returns the game like it was at at, in unix milliseconds
+		GetAsOf(ctx context.Context, id int, at int64) (Game, error)
 		UpdateOnMove(
 			ctx context.Context,
 			gameID int,
 			playerID string,
 			move string,
+			lag MoveLag,
 			room Room,
 		) (changes GameChanges, updated bool, err error)
+		Premove(
+			ctx context.Context,
+			gameID int,
+			playerID string,
+			move string,
+		) error
+		UpdateOnPremove(
+			ctx context.Context,
+			gameID int,
+			room Room,
+		) (changes GameChanges, premove Premove, updated bool, err error)
 		UpdateDraw(
 			ctx context.Context,
 			gameID int,
@@ -78,11 +212,36 @@ type (
 			method string,
 			result string,
 		) (changes GameChanges, updated bool, err error)
+		Berserk(
+			ctx context.Context,
+			gameID int,
+			playerID string,
+			room Room,
+		) (changes GameChanges, updated bool, err error)
 	}
 )
 
 type GameChanges utils.Changes[GameFieldJsonTag]
 
+func IsGameVariant(variant string) bool {
+	switch variant {
+	case GameVariantStandard,
+		GameVariantCrazyhouse,
+		GameVariantBughouse,
+		GameVariantThreeCheck,
+		GameVariantKingOfTheHill,
+		GameVariantAtomic:
+		return true
+	}
+
+	return false
+}
+
+// IsStandard returns whether g is played with the standard rules of chess
+func (g Game) IsStandard() bool {
+	return g.Variant == "" || g.Variant == GameVariantStandard
+}
+
 func (g Game) IsFilledForInsert() (bool, []string) {
 	missingFields := make([]string, 0)
 	if g.WhiteID == "" {
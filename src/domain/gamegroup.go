@@ -0,0 +1,57 @@
+package domain
+
+import "context"
+
+const GameGroupVariantBughouse = "bughouse"
+
+type (
+	// GameGroup links games that are played as one, like the two boards of a
+	// bughouse game. Games are in board order. Team A plays white on the
+	// first board and black on the second one, and Result is the team
+	// result, 1-0 when team A won.
+	GameGroup struct {
+		ID        int    `json:"id"`
+		Variant   string `json:"variant"`
+		GameIDs   []int  `json:"game_ids"`
+		Result    string `json:"result"`
+		Method    string `json:"method"`
+		CreatedAt int64  `json:"created_at"`
+	}
+
+	// BughouseBoard is a board of a bughouse game with its position, pockets
+	// included
+	BughouseBoard struct {
+		Game
+		FEN string `json:"fen"`
+	}
+
+	BughouseState struct {
+		GameGroup
+		Boards []BughouseBoard `json:"boards"`
+	}
+
+	// BughouseMove is a move on one of the boards of a bughouse game
+	BughouseMove struct {
+		Board int    `json:"board"`
+		Move  string `json:"move"`
+	}
+
+	GameGroupRepo interface {
+		Insert(ctx context.Context, g GameGroup) (groupID int, err error)
+		// Get returns the group with the ids of its games
+		Get(ctx context.Context, id int) (GameGroup, error)
+		// UpdateResult sets the result of a group that has none yet
+		UpdateResult(ctx context.Context, id int, result string, method string) (updated bool, err error)
+	}
+
+	BughouseUseCase interface {
+		// Start creates a bughouse game where team A plays white on the first
+		// board and black on the second one
+		Start(ctx context.Context, teamA [2]string, teamB [2]string, time int, increment int) (BughouseState, error)
+		Get(ctx context.Context, groupID int) (BughouseState, error)
+		// Move plays a move on a board and returns the boards that changed,
+		// the partner's board gets the captured piece
+		Move(ctx context.Context, groupID int, playerID string, move BughouseMove) ([]BughouseBoard, error)
+		Resign(ctx context.Context, groupID int, playerID string) ([]BughouseBoard, error)
+	}
+)
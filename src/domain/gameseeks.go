@@ -11,6 +11,7 @@ type (
 		Time      int    `json:"time"`
 		Increment int    `json:"increment"`
 		Seeker    string `json:"seeker"`
+		Variant   string `json:"variant"`
 	}
 	GameseeksRepo interface {
 		List(context.Context) ([]Gameseek, error)
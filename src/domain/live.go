@@ -0,0 +1,31 @@
+package domain
+
+type (
+	// LiveGame is the summary of an ongoing game shown in the live listing
+	LiveGame struct {
+		ID                   int    `json:"id"`
+		WhiteID              string `json:"white_id"`
+		BlackID              string `json:"black_id"`
+		Time                 int    `json:"time"`
+		Increment            int    `json:"increment"`
+		FEN                  string `json:"fen"`
+		LastMove             string `json:"last_move"`
+		WhiteTime            int    `json:"white_time"`
+		BlackTime            int    `json:"black_time"`
+		TimeStampAtTurnStart int64  `json:"time_stamp_at_turn_start"`
+		Spectators           int    `json:"spectators"`
+	}
+
+	LiveGames struct {
+		Games []LiveGame `json:"games"`
+		// Featured is the id of the featured game, 0 when there are no games
+		Featured int `json:"featured"`
+	}
+
+	LiveUseCase interface {
+		List() LiveGames
+		OnGameCreated(g Game)
+		OnMove(g Game, move GameMove)
+		OnGameOver(g Game)
+	}
+)
@@ -0,0 +1,83 @@
+package domain
+
+import "context"
+
+const DefaultPuzzleRating = 1500
+
+type (
+	// Puzzle is a position taken from a finished game right after LastMove,
+	// a mistake by the opponent of the side to move. Solution alternates
+	// between the solver's moves and the opponent's replies, in UCI notation.
+	Puzzle struct {
+		ID       int      `json:"id"`
+		GameID   int      `json:"game_id"`
+		FEN      string   `json:"fen"`
+		LastMove string   `json:"last_move"`
+		Solution []string `json:"-"`
+		Themes   []string `json:"themes"`
+		Rating   int      `json:"rating"`
+		Attempts int      `json:"attempts"`
+		Solves   int      `json:"solves"`
+	}
+
+	PuzzleAttempt struct {
+		PuzzleID  int    `json:"puzzle_id"`
+		PlayerID  string `json:"player_id"`
+		Solved    bool   `json:"solved"`
+		CreatedAt int64  `json:"created_at"`
+	}
+
+	// PuzzleMoveResult is the answer to a move made while solving a puzzle.
+	// Reply is the opponent's answer when the puzzle continues, the rest is
+	// only filled once the puzzle is done.
+	PuzzleMoveResult struct {
+		PuzzleID     int      `json:"puzzle_id"`
+		Correct      bool     `json:"correct"`
+		Reply        string   `json:"reply,omitempty"`
+		Done         bool     `json:"done"`
+		Solution     []string `json:"solution,omitempty"`
+		PlayerRating int      `json:"player_rating,omitempty"`
+		PuzzleRating int      `json:"puzzle_rating,omitempty"`
+	}
+
+	PuzzleRepo interface {
+		Get(ctx context.Context, id int) (Puzzle, error)
+		// Next returns the puzzle closest to rating the player hasn't tried
+		Next(ctx context.Context, playerID string, rating int) (Puzzle, error)
+		Insert(ctx context.Context, p Puzzle) (puzzleID int, err error)
+		GetPlayerRating(ctx context.Context, playerID string) (int, error)
+		RecordAttempt(
+			ctx context.Context,
+			attempt PuzzleAttempt,
+			puzzleRating int,
+			playerRating int,
+		) error
+	}
+
+	PuzzleUseCase interface {
+		Next(ctx context.Context, playerID string) (Puzzle, error)
+		Move(
+			ctx context.Context,
+			playerID string,
+			puzzleID int,
+			move string,
+		) (PuzzleMoveResult, error)
+		Abandon(playerID string)
+		OnGameOver(ctx context.Context, g Game) error
+	}
+
+	// EngineAnalysis is the result of an engine search. Scores are from the
+	// point of view of the side to move and Mate is 0 when no mate was found.
+	EngineAnalysis struct {
+		BestMove string
+		PV       []string
+		CP       int
+		Mate     int
+	}
+
+	Engine interface {
+		// Analyse searches the position fen, only considering searchMoves
+		// when it isn't empty.
+		Analyse(fen string, searchMoves []string) (EngineAnalysis, error)
+	}
+)
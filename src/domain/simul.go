@@ -0,0 +1,93 @@
+package domain
+
+import (
+	"context"
+	"errors"
+)
+
+// clocks of the host of a simul. With an individual clock the host plays every
+// game on its own clock like any player, with none the host's clocks don't
+// run and with a shared clock a single clock runs while any board waits for
+// the host's move.
+const (
+	SimulClockIndividual = "individual"
+	SimulClockNone       = "none"
+	SimulClockShared     = "shared"
+)
+
+const (
+	SimulStatusCreated  = "created"
+	SimulStatusStarted  = "started"
+	SimulStatusFinished = "finished"
+)
+
+var (
+	ErrSimulNotFound = errors.New("simul not found")
+	ErrNotSimulHost  = errors.New("only the host of the simul can do this")
+	ErrSimulStarted  = errors.New("the simul already started")
+)
+
+type (
+	// Simul is a simultaneous exhibition where the host plays HostColor
+	// against every participant at once. Times are in milliseconds. HostTime
+	// is the time on the host's shared clock and defaults to Time per board.
+	Simul struct {
+		ID           int      `json:"id"`
+		HostID       string   `json:"host_id"`
+		HostColor    Color    `json:"host_color"`
+		Time         int      `json:"time"`
+		Increment    int      `json:"increment"`
+		HostClock    string   `json:"host_clock"`
+		HostTime     int      `json:"host_time,omitempty"`
+		Status       string   `json:"status"`
+		Participants []string `json:"participants"`
+	}
+
+	// SimulBoard is the state of one of the games of a simul
+	SimulBoard struct {
+		GameID     int    `json:"game_id"`
+		OpponentID string `json:"opponent_id"`
+		FEN        string `json:"fen"`
+		LastMove   string `json:"last_move"`
+		WhiteTime  int    `json:"white_time"`
+		BlackTime  int    `json:"black_time"`
+		HostToMove bool   `json:"host_to_move"`
+		Result     string `json:"result"`
+	}
+
+	// SimulResults are counted from the host's side. Score is in points.
+	SimulResults struct {
+		Wins   int     `json:"wins"`
+		Draws  int     `json:"draws"`
+		Losses int     `json:"losses"`
+		Score  float64 `json:"score"`
+	}
+
+	// SimulState is the combined feed of a simul. Results are only set once
+	// every game is over.
+	SimulState struct {
+		Simul
+		Boards  []SimulBoard  `json:"boards"`
+		Results *SimulResults `json:"results,omitempty"`
+	}
+
+	// SimulPairing is sent to a participant when their game starts
+	SimulPairing struct {
+		SimulID int    `json:"simul_id"`
+		GameID  int    `json:"game_id"`
+		Color   Color  `json:"color"`
+		HostID  string `json:"host_id"`
+	}
+
+	SimulUseCase interface {
+		Create(s Simul) (Simul, error)
+		Get(simulID int) (SimulState, bool)
+		List() []Simul
+		Join(simulID int, playerID string) error
+		Leave(simulID int, playerID string) error
+		// Start creates a game between the host and every participant
+		Start(ctx context.Context, simulID int, hostID string) error
+		OnMove(g Game, move GameMove)
+		OnGameOver(g Game)
+	}
+)
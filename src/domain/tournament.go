@@ -0,0 +1,194 @@
+package domain
+
+import (
+	"context"
+	"errors"
+)
+
+const (
+	TournamentKindArena = "arena"
+	TournamentKindSwiss = "swiss"
+	// round robins and knockouts are club events run by an organizer
+	TournamentKindRoundRobin = "round robin"
+	TournamentKindKnockout   = "knockout"
+)
+
+const (
+	TournamentStatusCreated  = "created"
+	TournamentStatusStarted  = "started"
+	TournamentStatusFinished = "finished"
+)
+
+// results of a tournament game as recorded in TournamentPlayer.Results
+const (
+	TournamentWin  = 'W'
+	TournamentDraw = 'D'
+	TournamentLoss = 'L'
+)
+
+var (
+	ErrNotOrganizer        = errors.New("only the organizer of the event can do this")
+	ErrChallengeNotFound   = errors.New("challenge not found")
+	ErrRoundNotOver        = errors.New("the current round isn't over")
+	ErrEventAlreadyStarted = errors.New("the event already started")
+)
+
+type (
+	// Tournament times are in milliseconds, StartsAt and EndsAt in unix
+	// milliseconds. Rounds is only used by swiss tournaments. Swiss
+	// tournaments and club events have no EndsAt. In a knockout, the armageddon times are the clocks
+	// of the tie-break game played after a draw.
+	Tournament struct {
+		ID        int    `json:"id"`
+		Kind      string `json:"kind"`
+		Name      string `json:"name"`
+		Time      int    `json:"time"`
+		Increment int    `json:"increment"`
+		StartsAt  int64  `json:"starts_at"`
+		EndsAt    int64  `json:"ends_at,omitempty"`
+		Rounds    int    `json:"rounds,omitempty"`
+		Status    string `json:"status"`
+
+		OrganizerID         string `json:"organizer_id,omitempty"`
+		ArmageddonWhiteTime int    `json:"armageddon_white_time,omitempty"`
+		ArmageddonBlackTime int    `json:"armageddon_black_time,omitempty"`
+	}
+
+	// TournamentPlayer is a player's entry in a tournament. Results holds one
+	// of TournamentWin, TournamentDraw or TournamentLoss per finished game.
+	// Swiss scores are counted in half points.
+	TournamentPlayer struct {
+		TournamentID int    `json:"tournament_id"`
+		PlayerID     string `json:"player_id"`
+		Score        int    `json:"score"`
+		Results      string `json:"results"`
+		Withdrawn    bool   `json:"withdrawn"`
+	}
+
+	// TournamentStanding is a line of a tournament's standings. Fire is true
+	// while a player's arena streak doubles their points. The swiss tie-breaks
+	// are in half points like the score.
+	TournamentStanding struct {
+		TournamentPlayer
+		Rank            int     `json:"rank"`
+		Fire            bool    `json:"fire"`
+		Playing         bool    `json:"playing"`
+		Buchholz        float64 `json:"buchholz,omitempty"`
+		SonnebornBerger float64 `json:"sonneborn_berger,omitempty"`
+	}
+
+	TournamentStandings struct {
+		Tournament Tournament           `json:"tournament"`
+		Round      int                  `json:"round,omitempty"`
+		Standings  []TournamentStanding `json:"standings"`
+	}
+
+	// TournamentGame is a pairing of a swiss round or a scheduled game of a
+	// club event. A bye has no BlackID and no GameID, and a scheduled game has
+	// no GameID until both players accepted its challenge. Result is empty
+	// until the game is over.
+	TournamentGame struct {
+		ID           int    `json:"id"`
+		TournamentID int    `json:"tournament_id"`
+		Round        int    `json:"round"`
+		WhiteID      string `json:"white_id"`
+		BlackID      string `json:"black_id,omitempty"`
+		GameID       int    `json:"game_id,omitempty"`
+		Result       string `json:"result"`
+		// Armageddon is true for the tie-break game of a knockout match. Black
+		// wins the match when it is drawn.
+		Armageddon bool `json:"armageddon,omitempty"`
+	}
+
+	TournamentRound struct {
+		TournamentID int              `json:"tournament_id"`
+		Round        int              `json:"round"`
+		Games        []TournamentGame `json:"games"`
+	}
+
+	// TournamentPairing tells a player about the game they were paired in
+	TournamentPairing struct {
+		TournamentID int    `json:"tournament_id"`
+		GameID       int    `json:"game_id"`
+		Color        Color  `json:"color"`
+		OpponentID   string `json:"opponent_id"`
+	}
+
+	TournamentRepo interface {
+		Insert(ctx context.Context, t Tournament) (tournamentID int, err error)
+		UpdateStatus(ctx context.Context, tournamentID int, status string) error
+		// ListUnfinished returns the tournaments of kind that didn't finish
+		ListUnfinished(ctx context.Context, kind string) ([]Tournament, error)
+		ListPlayers(ctx context.Context, tournamentID int) ([]TournamentPlayer, error)
+		// SavePlayer adds the player to the tournament or updates their entry
+		SavePlayer(ctx context.Context, p TournamentPlayer) error
+		InsertGames(ctx context.Context, games []TournamentGame) (ids []int, err error)
+		ListGames(ctx context.Context, tournamentID int) ([]TournamentGame, error)
+		SetGameResult(ctx context.Context, gameID int, result string) error
+		// UpdateGame saves the GameID and Result of a tournament game
+		UpdateGame(ctx context.Context, g TournamentGame) error
+	}
+
+	// TournamentUseCase is what every kind of tournament has in common
+	TournamentUseCase interface {
+		Create(ctx context.Context, t Tournament) (Tournament, error)
+		// Load restores the unfinished tournaments after a restart
+		Load(ctx context.Context) error
+		List() []Tournament
+		Standings(tournamentID int) (TournamentStandings, bool)
+		Join(ctx context.Context, tournamentID int, playerID string) error
+		Withdraw(ctx context.Context, tournamentID int, playerID string) error
+		OnGameOver(ctx context.Context, g Game) error
+		// Tick starts and ends tournaments on schedule and pairs players
+		Tick(ctx context.Context)
+	}
+
+	ArenaUseCase interface {
+		TournamentUseCase
+	}
+
+	// EventChallenge is a scheduled game of a club event, sent to both players
+	// as a direct challenge. The game starts once both accepted it.
+	EventChallenge struct {
+		ID           int    `json:"id"`
+		TournamentID int    `json:"tournament_id"`
+		Round        int    `json:"round"`
+		WhiteID      string `json:"white_id"`
+		BlackID      string `json:"black_id"`
+		WhiteTime    int    `json:"white_time"`
+		BlackTime    int    `json:"black_time"`
+		Increment    int    `json:"increment"`
+		Armageddon   bool   `json:"armageddon,omitempty"`
+	}
+
+	// ClubEventUseCase runs round robins and knockouts. Everything but
+	// accepting challenges is done by the organizer.
+	ClubEventUseCase interface {
+		Create(ctx context.Context, t Tournament) (Tournament, error)
+		Load(ctx context.Context) error
+		List() []Tournament
+		Standings(tournamentID int) (TournamentStandings, bool)
+		Round(tournamentID int, round int) (TournamentRound, bool)
+		AddPlayer(ctx context.Context, tournamentID int, organizerID string, playerID string) error
+		RemovePlayer(ctx context.Context, tournamentID int, organizerID string, playerID string) error
+		// Start schedules the event and challenges the players of the first
+		// round. seeds ranks the players, best first, and defaults to their ids
+		// in order.
+		Start(ctx context.Context, tournamentID int, organizerID string, seeds []string) error
+		NextRound(ctx context.Context, tournamentID int, organizerID string) error
+		// Forfeit sets the result of a challenge that wasn't played or ended
+		// without a result
+		Forfeit(ctx context.Context, tournamentID int, organizerID string, challengeID int, result string) error
+		AcceptChallenge(ctx context.Context, tournamentID int, playerID string, challengeID int) error
+		// Challenges returns the challenges of the current round a player
+		// didn't accept yet
+		Challenges(tournamentID int, playerID string) []EventChallenge
+		OnGameOver(ctx context.Context, g Game) error
+	}
+
+	SwissUseCase interface {
+		TournamentUseCase
+		// Round returns the pairings of a round, the current one when round is 0
+		Round(tournamentID int, round int) (TournamentRound, bool)
+	}
+)
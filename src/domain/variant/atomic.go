@@ -0,0 +1,441 @@
+package domain_variant
+
+import (
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+// atomicPosition is a position of atomic chess. A capture explodes the
+// capturing piece and every piece but the pawns next to the captured one, and
+// exploding the other king wins. Kings can't capture and kings that touch
+// can't be checked, since taking one would explode the other.
+type atomicPosition struct {
+	pos *chess.Position
+}
+
+// atomicMove is a pseudo legal move, which is legal when it doesn't leave
+// its own king exploded or attacked
+type atomicMove struct {
+	from  chess.Square
+	to    chess.Square
+	promo chess.PieceType
+}
+
+func (m atomicMove) String() string {
+	return m.from.String() + m.to.String() + m.promo.String()
+}
+
+var (
+	knightSteps   = [8][2]int{{1, 2}, {2, 1}, {2, -1}, {1, -2}, {-1, -2}, {-2, -1}, {-2, 1}, {-1, 2}}
+	kingSteps     = [8][2]int{{0, 1}, {1, 1}, {1, 0}, {1, -1}, {0, -1}, {-1, -1}, {-1, 0}, {-1, 1}}
+	rookRays      = [4][2]int{{0, 1}, {1, 0}, {0, -1}, {-1, 0}}
+	bishopRays    = [4][2]int{{1, 1}, {1, -1}, {-1, -1}, {-1, 1}}
+	promotionType = [4]chess.PieceType{chess.Queen, chess.Rook, chess.Bishop, chess.Knight}
+)
+
+func atomicStartingPosition() Position {
+	return atomicPosition{chess.StartingPosition()}
+}
+
+func parseAtomicFEN(fen string) (atomicPosition, error) {
+	p, err := parseStandardFEN(fen)
+	if err != nil {
+		return atomicPosition{}, err
+	}
+
+	return atomicPosition{p.pos}, nil
+}
+
+// step returns the square df files and dr ranks away from sq
+func step(sq chess.Square, df int, dr int) (chess.Square, bool) {
+	f := int(sq.File()) + df
+	r := int(sq.Rank()) + dr
+	if f < 0 || f > 7 || r < 0 || r > 7 {
+		return chess.NoSquare, false
+	}
+
+	return chess.NewSquare(chess.File(f), chess.Rank(r)), true
+}
+
+func pawnDirection(c chess.Color) int {
+	if c == chess.White {
+		return 1
+	}
+
+	return -1
+}
+
+func kingSquare(b *chess.Board, c chess.Color) chess.Square {
+	king := chess.NewPiece(chess.King, c)
+	for sq := chess.A1; sq <= chess.H8; sq++ {
+		if b.Piece(sq) == king {
+			return sq
+		}
+	}
+
+	return chess.NoSquare
+}
+
+func kingsTouch(b *chess.Board) bool {
+	white := kingSquare(b, chess.White)
+	black := kingSquare(b, chess.Black)
+	if white == chess.NoSquare || black == chess.NoSquare {
+		return false
+	}
+
+	df := int(white.File()) - int(black.File())
+	dr := int(white.Rank()) - int(black.Rank())
+	return df >= -1 && df <= 1 && dr >= -1 && dr <= 1
+}
+
+// attacked returns whether a piece of color by other than its king attacks sq
+func attacked(b *chess.Board, sq chess.Square, by chess.Color) bool {
+	for _, df := range []int{-1, 1} {
+		from, ok := step(sq, df, -pawnDirection(by))
+		if ok && b.Piece(from) == chess.NewPiece(chess.Pawn, by) {
+			return true
+		}
+	}
+
+	for _, s := range knightSteps {
+		from, ok := step(sq, s[0], s[1])
+		if ok && b.Piece(from) == chess.NewPiece(chess.Knight, by) {
+			return true
+		}
+	}
+
+	rayAttacks := func(rays [4][2]int, slider chess.PieceType) bool {
+		for _, ray := range rays {
+			for from, ok := step(sq, ray[0], ray[1]); ok; from, ok = step(from, ray[0], ray[1]) {
+				piece := b.Piece(from)
+				if piece == chess.NoPiece {
+					continue
+				}
+				if piece.Color() == by && (piece.Type() == slider || piece.Type() == chess.Queen) {
+					return true
+				}
+				break
+			}
+		}
+		return false
+	}
+
+	return rayAttacks(rookRays, chess.Rook) || rayAttacks(bishopRays, chess.Bishop)
+}
+
+// inCheck returns whether the king of c is attacked by a piece that could
+// take it
+func (p atomicPosition) inCheck(c chess.Color) bool {
+	b := p.pos.Board()
+	king := kingSquare(b, c)
+	if king == chess.NoSquare || kingsTouch(b) {
+		return false
+	}
+
+	return attacked(b, king, c.Other())
+}
+
+func (p atomicPosition) pseudoLegalMoves() []atomicMove {
+	b := p.pos.Board()
+	turn := p.pos.Turn()
+	moves := make([]atomicMove, 0, 48)
+
+	for from := chess.A1; from <= chess.H8; from++ {
+		piece := b.Piece(from)
+		if piece == chess.NoPiece || piece.Color() != turn {
+			continue
+		}
+
+		switch piece.Type() {
+		case chess.Pawn:
+			moves = p.pawnMoves(moves, from)
+		case chess.Knight:
+			for _, s := range knightSteps {
+				if to, ok := step(from, s[0], s[1]); ok && b.Piece(to).Color() != turn {
+					moves = append(moves, atomicMove{from, to, chess.NoPieceType})
+				}
+			}
+		case chess.King:
+			// kings can't capture, it would explode them
+			for _, s := range kingSteps {
+				if to, ok := step(from, s[0], s[1]); ok && b.Piece(to) == chess.NoPiece {
+					moves = append(moves, atomicMove{from, to, chess.NoPieceType})
+				}
+			}
+			moves = p.castlingMoves(moves, from)
+		default:
+			rays := make([][2]int, 0, 8)
+			if piece.Type() != chess.Bishop {
+				rays = append(rays, rookRays[:]...)
+			}
+			if piece.Type() != chess.Rook {
+				rays = append(rays, bishopRays[:]...)
+			}
+			for _, ray := range rays {
+				for to, ok := step(from, ray[0], ray[1]); ok; to, ok = step(to, ray[0], ray[1]) {
+					target := b.Piece(to)
+					if target.Color() == turn {
+						break
+					}
+					moves = append(moves, atomicMove{from, to, chess.NoPieceType})
+					if target != chess.NoPiece {
+						break
+					}
+				}
+			}
+		}
+	}
+
+	return moves
+}
+
+func (p atomicPosition) pawnMoves(moves []atomicMove, from chess.Square) []atomicMove {
+	b := p.pos.Board()
+	turn := p.pos.Turn()
+	dir := pawnDirection(turn)
+	lastRank := chess.Rank8
+	startRank := chess.Rank2
+	if turn == chess.Black {
+		lastRank = chess.Rank1
+		startRank = chess.Rank7
+	}
+
+	add := func(to chess.Square) {
+		if to.Rank() != lastRank {
+			moves = append(moves, atomicMove{from, to, chess.NoPieceType})
+			return
+		}
+		for _, promo := range promotionType {
+			moves = append(moves, atomicMove{from, to, promo})
+		}
+	}
+
+	if to, ok := step(from, 0, dir); ok && b.Piece(to) == chess.NoPiece {
+		add(to)
+		if double, ok := step(to, 0, dir); ok && from.Rank() == startRank && b.Piece(double) == chess.NoPiece {
+			add(double)
+		}
+	}
+	for _, df := range []int{-1, 1} {
+		to, ok := step(from, df, dir)
+		if !ok {
+			continue
+		}
+		if target := b.Piece(to); target != chess.NoPiece && target.Color() != turn || to == p.pos.EnPassantSquare() {
+			add(to)
+		}
+	}
+
+	return moves
+}
+
+// castlingMoves adds the castling moves of the king on from. The king can't
+// castle out of, through or into check.
+func (p atomicPosition) castlingMoves(moves []atomicMove, from chess.Square) []atomicMove {
+	b := p.pos.Board()
+	turn := p.pos.Turn()
+	rank := chess.Rank1
+	if turn == chess.Black {
+		rank = chess.Rank8
+	}
+	if from != chess.NewSquare(chess.FileE, rank) || p.inCheck(turn) {
+		return moves
+	}
+
+	sides := []struct {
+		side   chess.Side
+		rook   chess.File
+		empty  []chess.File
+		passes []chess.File
+	}{
+		{chess.KingSide, chess.FileH, []chess.File{chess.FileF, chess.FileG}, []chess.File{chess.FileF, chess.FileG}},
+		{chess.QueenSide, chess.FileA, []chess.File{chess.FileB, chess.FileC, chess.FileD}, []chess.File{chess.FileD, chess.FileC}},
+	}
+	for _, s := range sides {
+		if !p.pos.CastleRights().CanCastle(turn, s.side) ||
+			b.Piece(chess.NewSquare(s.rook, rank)) != chess.NewPiece(chess.Rook, turn) {
+			continue
+		}
+
+		free := true
+		for _, f := range s.empty {
+			if b.Piece(chess.NewSquare(f, rank)) != chess.NoPiece {
+				free = false
+			}
+		}
+		for _, f := range s.passes {
+			if free && p.kingAttackedOn(from, chess.NewSquare(f, rank)) {
+				free = false
+			}
+		}
+		if free {
+			moves = append(moves, atomicMove{from, chess.NewSquare(s.passes[len(s.passes)-1], rank), chess.NoPieceType})
+		}
+	}
+
+	return moves
+}
+
+// kingAttackedOn returns whether the king on from would be in check on to
+func (p atomicPosition) kingAttackedOn(from chess.Square, to chess.Square) bool {
+	squares := p.pos.Board().SquareMap()
+	squares[to] = squares[from]
+	delete(squares, from)
+	b := chess.NewBoard(squares)
+
+	return !kingsTouch(b) && attacked(b, to, p.pos.Turn().Other())
+}
+
+// play makes a pseudo legal move, exploding the pieces around a capture
+func (p atomicPosition) play(m atomicMove) atomicPosition {
+	cm, err := chess.UCINotation{}.Decode(p.pos, m.String())
+	if err != nil {
+		panic(err)
+	}
+	next := p.pos.Update(cm)
+	if !cm.HasTag(chess.Capture) {
+		return atomicPosition{next}
+	}
+
+	squares := next.Board().SquareMap()
+	delete(squares, m.to)
+	for _, s := range kingSteps {
+		if sq, ok := step(m.to, s[0], s[1]); ok && squares[sq].Type() != chess.Pawn {
+			delete(squares, sq)
+		}
+	}
+
+	// a rook or king that exploded can't castle anymore
+	b := chess.NewBoard(squares)
+	rights := ""
+	for _, r := range []struct {
+		right string
+		king  chess.Square
+		rook  chess.Square
+		color chess.Color
+	}{
+		{"K", chess.E1, chess.H1, chess.White},
+		{"Q", chess.E1, chess.A1, chess.White},
+		{"k", chess.E8, chess.H8, chess.Black},
+		{"q", chess.E8, chess.A8, chess.Black},
+	} {
+		if strings.Contains(next.CastleRights().String(), r.right) &&
+			b.Piece(r.king) == chess.NewPiece(chess.King, r.color) &&
+			b.Piece(r.rook) == chess.NewPiece(chess.Rook, r.color) {
+			rights += r.right
+		}
+	}
+	if rights == "" {
+		rights = "-"
+	}
+
+	fields := strings.Fields(next.String())
+	fields[0] = b.String()
+	fields[2] = rights
+	exploded, err := parseAtomicFEN(strings.Join(fields, " "))
+	if err != nil {
+		panic(err)
+	}
+
+	return exploded
+}
+
+// legal returns whether the player who made a move that led to next didn't
+// leave their king exploded or in check
+func legal(next atomicPosition) bool {
+	mover := next.pos.Turn().Other()
+	b := next.pos.Board()
+	if kingSquare(b, mover) == chess.NoSquare {
+		return false
+	}
+	if kingSquare(b, mover.Other()) == chess.NoSquare {
+		return true
+	}
+
+	return !next.inCheck(mover)
+}
+
+func (p atomicPosition) legalMoves() []atomicMove {
+	if kingSquare(p.pos.Board(), p.pos.Turn()) == chess.NoSquare {
+		return []atomicMove{}
+	}
+
+	moves := make([]atomicMove, 0, 48)
+	for _, m := range p.pseudoLegalMoves() {
+		if legal(p.play(m)) {
+			moves = append(moves, m)
+		}
+	}
+
+	return moves
+}
+
+func (p atomicPosition) Turn() chess.Color {
+	return p.pos.Turn()
+}
+
+func (p atomicPosition) LegalMoves() []string {
+	moves := p.legalMoves()
+	legal := make([]string, len(moves))
+	for i, m := range moves {
+		legal[i] = m.String()
+	}
+
+	return legal
+}
+
+func (p atomicPosition) Play(move string) (Position, string, error) {
+	for _, m := range p.legalMoves() {
+		if m.String() != move {
+			continue
+		}
+
+		next := p.play(m)
+		return next, p.san(m, next), nil
+	}
+
+	return nil, "", ErrIllegalMove
+}
+
+// san writes a move like chess.AlgebraicNotation, with checks and mates that
+// follow the atomic rules
+func (p atomicPosition) san(m atomicMove, next atomicPosition) string {
+	cm, _ := chess.UCINotation{}.Decode(p.pos, m.String())
+	san := strings.TrimRight(chess.AlgebraicNotation{}.Encode(p.pos, cm), "+#")
+
+	if outcome, _ := next.Outcome(); outcome != chess.NoOutcome && outcome != chess.Draw {
+		return san + "#"
+	}
+	if next.inCheck(next.Turn()) {
+		return san + "+"
+	}
+
+	return san
+}
+
+func (p atomicPosition) Outcome() (chess.Outcome, string) {
+	b := p.pos.Board()
+	if kingSquare(b, p.pos.Turn()) == chess.NoSquare {
+		return won(p.pos.Turn().Other()), MethodExplosion
+	}
+	if len(b.SquareMap()) == 2 {
+		return chess.Draw, chess.InsufficientMaterial.String()
+	}
+	if len(p.legalMoves()) == 0 {
+		if p.inCheck(p.pos.Turn()) {
+			return won(p.pos.Turn().Other()), chess.Checkmate.String()
+		}
+		return chess.Draw, chess.Stalemate.String()
+	}
+
+	return chess.NoOutcome, chess.NoMethod.String()
+}
+
+func (p atomicPosition) HalfMoveClock() int {
+	return p.pos.HalfMoveClock()
+}
+
+func (p atomicPosition) FEN() string {
+	return p.pos.String()
+}
@@ -0,0 +1,77 @@
+package domain_variant
+
+import (
+	domain_drop "github.com/lookingcoolonavespa/go_crochess_backend/src/domain/drop"
+	"github.com/notnil/chess"
+)
+
+// crazyhousePosition is a position of crazyhouse, where a captured piece goes
+// to the pocket of the player who captured it
+type crazyhousePosition struct {
+	pos domain_drop.Position
+}
+
+func crazyhouseStartingPosition() Position {
+	return crazyhousePosition{domain_drop.StartingPosition()}
+}
+
+func (p crazyhousePosition) Turn() chess.Color {
+	if p.pos.Turn() == domain_drop.Black {
+		return chess.Black
+	}
+
+	return chess.White
+}
+
+func (p crazyhousePosition) LegalMoves() []string {
+	moves := p.pos.LegalMoves()
+	legal := make([]string, len(moves))
+	for i, m := range moves {
+		legal[i] = m.String()
+	}
+
+	return legal
+}
+
+func (p crazyhousePosition) Play(move string) (Position, string, error) {
+	m, err := domain_drop.ParseMove(move)
+	if err != nil {
+		return nil, "", err
+	}
+
+	mover := p.pos.Turn()
+	next, captured, err := p.pos.Move(m)
+	if err != nil {
+		return nil, "", ErrIllegalMove
+	}
+	if captured != domain_drop.NoPieceType {
+		next = next.AddToPocket(mover, captured)
+	}
+
+	return crazyhousePosition{next}, p.pos.SAN(m), nil
+}
+
+// Outcome only counts mates no drop gets out of. There is always enough
+// material to mate since captured pieces come back.
+func (p crazyhousePosition) Outcome() (chess.Outcome, string) {
+	switch p.pos.Status(false) {
+	case domain_drop.Checkmate:
+		return won(p.Turn().Other()), chess.Checkmate.String()
+	case domain_drop.Stalemate:
+		return chess.Draw, chess.Stalemate.String()
+	}
+
+	return chess.NoOutcome, chess.NoMethod.String()
+}
+
+func (p crazyhousePosition) HalfMoveClock() int {
+	return p.pos.HalfMoveClock()
+}
+
+func (p crazyhousePosition) FEN() string {
+	return p.pos.FEN()
+}
+
+func (p crazyhousePosition) Pockets() string {
+	return p.pos.PocketsString()
+}
@@ -0,0 +1,60 @@
+package domain_variant
+
+import (
+	"github.com/notnil/chess"
+)
+
+// hill is the center of the board a king wins on in king of the hill
+var hill = [4]chess.Square{chess.D4, chess.E4, chess.D5, chess.E5}
+
+// kingOfTheHillPosition is a position of king of the hill, where a king that
+// reaches the center of the board wins
+type kingOfTheHillPosition struct {
+	standardPosition
+}
+
+func kingOfTheHillStartingPosition() Position {
+	return kingOfTheHillPosition{standardPosition{chess.StartingPosition()}}
+}
+
+func (p kingOfTheHillPosition) kingOnHill() (chess.Color, bool) {
+	b := p.pos.Board()
+	for _, sq := range hill {
+		if piece := b.Piece(sq); piece.Type() == chess.King {
+			return piece.Color(), true
+		}
+	}
+
+	return chess.NoColor, false
+}
+
+func (p kingOfTheHillPosition) LegalMoves() []string {
+	if _, over := p.kingOnHill(); over {
+		return []string{}
+	}
+
+	return p.standardPosition.LegalMoves()
+}
+
+func (p kingOfTheHillPosition) Play(move string) (Position, string, error) {
+	if _, over := p.kingOnHill(); over {
+		return nil, "", ErrIllegalMove
+	}
+
+	next, _, san, err := p.standardPosition.play(move)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return kingOfTheHillPosition{next}, san, nil
+}
+
+// Outcome doesn't draw for insufficient material, since a king can always
+// walk to the hill
+func (p kingOfTheHillPosition) Outcome() (chess.Outcome, string) {
+	if c, over := p.kingOnHill(); over {
+		return won(c), MethodKingOfTheHill
+	}
+
+	return p.mateOrStalemate()
+}
@@ -0,0 +1,137 @@
+package domain_variant
+
+import (
+	"github.com/notnil/chess"
+)
+
+// standardPosition is a position of standard chess, which the variants that
+// only change how a game is won build on
+type standardPosition struct {
+	pos *chess.Position
+}
+
+func standardStartingPosition() Position {
+	return standardPosition{chess.StartingPosition()}
+}
+
+func parseStandardFEN(fen string) (standardPosition, error) {
+	pos := &chess.Position{}
+	if err := pos.UnmarshalText([]byte(fen)); err != nil {
+		return standardPosition{}, err
+	}
+
+	return standardPosition{pos}, nil
+}
+
+func (p standardPosition) Turn() chess.Color {
+	return p.pos.Turn()
+}
+
+func (p standardPosition) LegalMoves() []string {
+	moves := p.pos.ValidMoves()
+	legal := make([]string, len(moves))
+	for i, m := range moves {
+		legal[i] = m.String()
+	}
+
+	return legal
+}
+
+// legalMove returns the legal move written as move, with the tags the
+// position gives it
+func (p standardPosition) legalMove(move string) (*chess.Move, error) {
+	for _, m := range p.pos.ValidMoves() {
+		if m.String() == move {
+			return m, nil
+		}
+	}
+
+	return nil, ErrIllegalMove
+}
+
+func (p standardPosition) play(move string) (standardPosition, *chess.Move, string, error) {
+	m, err := p.legalMove(move)
+	if err != nil {
+		return standardPosition{}, nil, "", err
+	}
+
+	san := chess.AlgebraicNotation{}.Encode(p.pos, m)
+	return standardPosition{p.pos.Update(m)}, m, san, nil
+}
+
+func (p standardPosition) Play(move string) (Position, string, error) {
+	next, _, san, err := p.play(move)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return next, san, nil
+}
+
+func (p standardPosition) Outcome() (chess.Outcome, string) {
+	if outcome, method := p.mateOrStalemate(); outcome != chess.NoOutcome {
+		return outcome, method
+	}
+	if !hasSufficientMaterial(p.pos.Board()) {
+		return chess.Draw, chess.InsufficientMaterial.String()
+	}
+
+	return chess.NoOutcome, chess.NoMethod.String()
+}
+
+func (p standardPosition) mateOrStalemate() (chess.Outcome, string) {
+	switch p.pos.Status() {
+	case chess.Checkmate:
+		return won(p.pos.Turn().Other()), chess.Checkmate.String()
+	case chess.Stalemate:
+		return chess.Draw, chess.Stalemate.String()
+	}
+
+	return chess.NoOutcome, chess.NoMethod.String()
+}
+
+func (p standardPosition) HalfMoveClock() int {
+	return p.pos.HalfMoveClock()
+}
+
+func (p standardPosition) FEN() string {
+	return p.pos.String()
+}
+
+func won(c chess.Color) chess.Outcome {
+	if c == chess.White {
+		return chess.WhiteWon
+	}
+
+	return chess.BlackWon
+}
+
+// hasSufficientMaterial returns false when neither player has the pieces to
+// mate, the same way a chess.Game decides it
+func hasSufficientMaterial(b *chess.Board) bool {
+	count := make(map[chess.PieceType]int)
+	bishopSquareColors := make(map[bool]bool)
+	for sq, piece := range b.SquareMap() {
+		switch piece.Type() {
+		case chess.Queen, chess.Rook, chess.Pawn:
+			return true
+		case chess.Bishop:
+			bishopSquareColors[(int(sq.File())+int(sq.Rank()))%2 == 0] = true
+		}
+		count[piece.Type()]++
+	}
+
+	// a test position without both kings
+	if count[chess.King] != 2 {
+		return true
+	}
+	if count[chess.Knight]+count[chess.Bishop] <= 1 {
+		return false
+	}
+	// bishops that are all on squares of the same color
+	if count[chess.Knight] == 0 && len(bishopSquareColors) == 1 {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,105 @@
+package domain_variant
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+// threeCheckPosition is a position of three-check, where the third check
+// given by a player wins. The checks are written after the FEN like +1+0,
+// white's first.
+type threeCheckPosition struct {
+	standardPosition
+	checks [2]int
+}
+
+func threeCheckStartingPosition() Position {
+	return threeCheckPosition{standardPosition{chess.StartingPosition()}, [2]int{}}
+}
+
+func parseThreeCheckFEN(fen string) (threeCheckPosition, error) {
+	fields := strings.Fields(fen)
+	if len(fields) != 7 {
+		return threeCheckPosition{}, errors.New(fmt.Sprintf("three-check fen needs 7 fields: %s", fen))
+	}
+
+	var checks [2]int
+	_, err := fmt.Sscanf(fields[6], "+%d+%d", &checks[0], &checks[1])
+	if err != nil {
+		return threeCheckPosition{}, err
+	}
+
+	p, err := parseStandardFEN(strings.Join(fields[:6], " "))
+	if err != nil {
+		return threeCheckPosition{}, err
+	}
+
+	return threeCheckPosition{p, checks}, nil
+}
+
+func checkIndex(c chess.Color) int {
+	if c == chess.White {
+		return 0
+	}
+
+	return 1
+}
+
+func (p threeCheckPosition) winner() (chess.Color, bool) {
+	for _, c := range []chess.Color{chess.White, chess.Black} {
+		if p.checks[checkIndex(c)] >= 3 {
+			return c, true
+		}
+	}
+
+	return chess.NoColor, false
+}
+
+func (p threeCheckPosition) LegalMoves() []string {
+	if _, over := p.winner(); over {
+		return []string{}
+	}
+
+	return p.standardPosition.LegalMoves()
+}
+
+func (p threeCheckPosition) Play(move string) (Position, string, error) {
+	if _, over := p.winner(); over {
+		return nil, "", ErrIllegalMove
+	}
+
+	next, m, san, err := p.standardPosition.play(move)
+	if err != nil {
+		return nil, "", err
+	}
+
+	checks := p.checks
+	if m.HasTag(chess.Check) {
+		checks[checkIndex(p.Turn())]++
+	}
+
+	return threeCheckPosition{next, checks}, san, nil
+}
+
+// Outcome doesn't draw for insufficient material, since a lone minor piece
+// can still give checks
+func (p threeCheckPosition) Outcome() (chess.Outcome, string) {
+	if c, over := p.winner(); over {
+		return won(c), MethodThreeChecks
+	}
+	if outcome, method := p.mateOrStalemate(); outcome != chess.NoOutcome {
+		return outcome, method
+	}
+	if len(p.pos.Board().SquareMap()) == 2 {
+		return chess.Draw, chess.InsufficientMaterial.String()
+	}
+
+	return chess.NoOutcome, chess.NoMethod.String()
+}
+
+func (p threeCheckPosition) FEN() string {
+	return fmt.Sprintf("%s +%d+%d", p.standardPosition.FEN(), p.checks[0], p.checks[1])
+}
@@ -0,0 +1,171 @@
+package domain_variant
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	"github.com/notnil/chess"
+)
+
+// methods of the wins that only exist in a variant
+const (
+	MethodThreeChecks   = "ThreeChecks"
+	MethodKingOfTheHill = "KingOfTheHill"
+	MethodExplosion     = "Explosion"
+)
+
+var ErrIllegalMove = errors.New("illegal move")
+
+// Position is a position of a variant. Positions are immutable, playing a move
+// returns a new one. Moves are written in UCI, drops like N@f3.
+type Position interface {
+	Turn() chess.Color
+	LegalMoves() []string
+	// Play returns the position after a legal move and the move in standard
+	// algebraic notation
+	Play(move string) (Position, string, error)
+	// Outcome returns the result of the position and how it was reached,
+	// NoOutcome while the game goes on. Draws that depend on the moves
+	// before the position are left to Game.
+	Outcome() (chess.Outcome, string)
+	HalfMoveClock() int
+	FEN() string
+}
+
+// PocketPosition is a Position where pieces can be dropped from pockets
+type PocketPosition interface {
+	Position
+	// Pockets returns the pockets of both players like in a FEN, without the
+	// brackets
+	Pockets() string
+}
+
+// Variant is a set of rules a game of chess can be played with
+type Variant interface {
+	Name() string
+	StartingPosition() Position
+}
+
+type variant struct {
+	name  string
+	start func() Position
+}
+
+func (v variant) Name() string {
+	return v.name
+}
+
+func (v variant) StartingPosition() Position {
+	return v.start()
+}
+
+var variants = map[string]Variant{
+	domain.GameVariantStandard:      variant{domain.GameVariantStandard, standardStartingPosition},
+	domain.GameVariantCrazyhouse:    variant{domain.GameVariantCrazyhouse, crazyhouseStartingPosition},
+	domain.GameVariantThreeCheck:    variant{domain.GameVariantThreeCheck, threeCheckStartingPosition},
+	domain.GameVariantKingOfTheHill: variant{domain.GameVariantKingOfTheHill, kingOfTheHillStartingPosition},
+	domain.GameVariantAtomic:        variant{domain.GameVariantAtomic, atomicStartingPosition},
+}
+
+// Get returns the rules of a variant of domain.Game. Games without a variant
+// are standard games.
+func Get(name string) (Variant, error) {
+	if name == "" {
+		name = domain.GameVariantStandard
+	}
+
+	v, ok := variants[name]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("%s is not a variant that can be played on one board", name))
+	}
+
+	return v, nil
+}
+
+// Game is a game of a variant with the positions it went through, which
+// decide draws by repetition
+type Game struct {
+	positions []Position
+	moves     []string
+}
+
+func NewGame(v Variant) *Game {
+	return &Game{
+		positions: []Position{v.StartingPosition()},
+		moves:     make([]string, 0),
+	}
+}
+
+// Position returns the current position
+func (g *Game) Position() Position {
+	return g.positions[len(g.positions)-1]
+}
+
+func (g *Game) Moves() []string {
+	return append([]string(nil), g.moves...)
+}
+
+// Move plays a legal move and returns it in standard algebraic notation
+func (g *Game) Move(move string) (string, error) {
+	if outcome, _ := g.Outcome(); outcome != chess.NoOutcome {
+		return "", errors.New("the game is over")
+	}
+
+	next, san, err := g.Position().Play(move)
+	if err != nil {
+		return "", err
+	}
+
+	g.positions = append(g.positions, next)
+	g.moves = append(g.moves, move)
+	return san, nil
+}
+
+// Outcome returns the outcome of the current position, or a draw by fivefold
+// repetition or the seventy-five move rule
+func (g *Game) Outcome() (chess.Outcome, string) {
+	position := g.Position()
+	if outcome, method := position.Outcome(); outcome != chess.NoOutcome {
+		return outcome, method
+	}
+
+	if g.repetitions() >= 5 {
+		return chess.Draw, chess.FivefoldRepetition.String()
+	}
+	if position.HalfMoveClock() >= 150 {
+		return chess.Draw, chess.SeventyFiveMoveRule.String()
+	}
+
+	return chess.NoOutcome, chess.NoMethod.String()
+}
+
+// CanClaimDraw returns whether a player can claim a draw by threefold
+// repetition or the fifty move rule
+func (g *Game) CanClaimDraw() bool {
+	return g.repetitions() >= 3 || g.Position().HalfMoveClock() >= 100
+}
+
+// repetitions returns how many times the current position was reached
+func (g *Game) repetitions() int {
+	key := repetitionKey(g.Position())
+	count := 0
+	for _, p := range g.positions {
+		if repetitionKey(p) == key {
+			count++
+		}
+	}
+
+	return count
+}
+
+// repetitionKey is the FEN of a position without its move counters
+func repetitionKey(p Position) string {
+	fields := strings.Fields(p.FEN())
+	if len(fields) < 6 {
+		return p.FEN()
+	}
+
+	return strings.Join(append(fields[:4:4], fields[6:]...), " ")
+}
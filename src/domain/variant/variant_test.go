@@ -0,0 +1,178 @@
+package domain_variant
+
+import (
+	"testing"
+
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	"github.com/notnil/chess"
+	"github.com/stretchr/testify/assert"
+)
+
+func perft(p Position, depth int) int {
+	if depth == 0 {
+		return 1
+	}
+
+	moves := p.LegalMoves()
+	if depth == 1 {
+		return len(moves)
+	}
+
+	nodes := 0
+	for _, m := range moves {
+		next, _, _ := p.Play(m)
+		nodes += perft(next, depth-1)
+	}
+
+	return nodes
+}
+
+func TestPerft(t *testing.T) {
+	kiwipete := "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1"
+
+	parse := func(variant string, fen string) Position {
+		var p Position
+		var err error
+		switch variant {
+		case domain.GameVariantThreeCheck:
+			p, err = parseThreeCheckFEN(fen)
+		case domain.GameVariantKingOfTheHill:
+			var s standardPosition
+			s, err = parseStandardFEN(fen)
+			p = kingOfTheHillPosition{s}
+		case domain.GameVariantAtomic:
+			p, err = parseAtomicFEN(fen)
+		default:
+			p, err = parseStandardFEN(fen)
+		}
+		assert.NoError(t, err)
+		return p
+	}
+
+	tests := []struct {
+		name    string
+		variant string
+		fen     string
+		depth   int
+		nodes   int
+	}{
+		{"standard", domain.GameVariantStandard, chess.StartingPosition().String(), 3, 8902},
+		{"standard kiwipete", domain.GameVariantStandard, kiwipete, 2, 2039},
+		{"three-check", domain.GameVariantThreeCheck, chess.StartingPosition().String() + " +0+0", 4, 197281},
+		{"three-check ends on the third check", domain.GameVariantThreeCheck, "4k3/8/8/8/8/8/3R4/4K3 w - - 0 1 +2+0", 1, 18},
+		{"king of the hill", domain.GameVariantKingOfTheHill, chess.StartingPosition().String(), 4, 197281},
+		{"king of the hill ends on the hill", domain.GameVariantKingOfTheHill, "8/8/8/8/8/4K3/8/k7 w - - 0 1", 2, 18},
+		{"atomic", domain.GameVariantAtomic, chess.StartingPosition().String(), 3, 8902},
+		{"atomic explosions", domain.GameVariantAtomic, "rn2kb1r/1pp1p2p/p2q1pp1/3P4/2P3b1/4PN2/PP3PPP/R2QKB1R b KQkq - 0 1", 2, 1238},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.nodes, perft(parse(tt.variant, tt.fen), tt.depth))
+		})
+	}
+}
+
+func TestThreeCheck(t *testing.T) {
+	p, err := parseThreeCheckFEN("4k3/8/8/8/8/8/3R4/4K3 w - - 0 1 +2+0")
+	assert.NoError(t, err)
+
+	next, san, err := p.Play("d2e2")
+	assert.NoError(t, err)
+	assert.Equal(t, "Re2+", san)
+	assert.Equal(t, "4k3/8/8/8/8/8/4R3/4K3 b - - 1 1 +3+0", next.FEN())
+
+	outcome, method := next.Outcome()
+	assert.Equal(t, chess.WhiteWon, outcome)
+	assert.Equal(t, MethodThreeChecks, method)
+}
+
+func TestKingOfTheHill(t *testing.T) {
+	p := kingOfTheHillPosition{standardPosition{}}
+	s, err := parseStandardFEN("8/8/8/8/8/4K3/8/k7 w - - 0 1")
+	assert.NoError(t, err)
+	p.standardPosition = s
+
+	next, _, err := p.Play("e3e4")
+	assert.NoError(t, err)
+
+	outcome, method := next.Outcome()
+	assert.Equal(t, chess.WhiteWon, outcome)
+	assert.Equal(t, MethodKingOfTheHill, method)
+	assert.Empty(t, next.LegalMoves())
+}
+
+func TestAtomic(t *testing.T) {
+	t.Run("A capture explodes the pieces around it but pawns", func(t *testing.T) {
+		p, err := parseAtomicFEN("r3k3/pb6/1p6/8/8/8/8/R3K3 w Qq - 0 1")
+		assert.NoError(t, err)
+
+		next, san, err := p.Play("a1a7")
+		assert.NoError(t, err)
+		assert.Equal(t, "Rxa7", san)
+		assert.Equal(t, "4k3/8/1p6/8/8/8/8/4K3 b - - 0 1", next.FEN())
+	})
+
+	t.Run("Exploding the king wins", func(t *testing.T) {
+		p, err := parseAtomicFEN("4k3/3p4/8/8/8/8/8/3RK3 w - - 0 1")
+		assert.NoError(t, err)
+
+		next, san, err := p.Play("d1d7")
+		assert.NoError(t, err)
+		assert.Equal(t, "Rxd7#", san)
+
+		outcome, method := next.Outcome()
+		assert.Equal(t, chess.WhiteWon, outcome)
+		assert.Equal(t, MethodExplosion, method)
+		assert.Empty(t, next.LegalMoves())
+	})
+
+	t.Run("Kings can't capture", func(t *testing.T) {
+		p, err := parseAtomicFEN("4k3/8/8/8/8/8/4p3/4K3 w - - 0 1")
+		assert.NoError(t, err)
+
+		_, _, err = p.Play("e1e2")
+		assert.ErrorIs(t, err, ErrIllegalMove)
+	})
+
+	t.Run("Touching kings can't be checked", func(t *testing.T) {
+		p, err := parseAtomicFEN("8/8/8/8/8/3kK3/7r/4r3 w - - 0 1")
+		assert.NoError(t, err)
+
+		outcome, _ := p.Outcome()
+		assert.Equal(t, chess.NoOutcome, outcome)
+		assert.False(t, p.inCheck(chess.White))
+		assert.Contains(t, p.LegalMoves(), "e3e2")
+		assert.NotContains(t, p.LegalMoves(), "e3f2")
+	})
+}
+
+func TestGame(t *testing.T) {
+	t.Run("Fivefold repetition is a draw", func(t *testing.T) {
+		v, err := Get(domain.GameVariantThreeCheck)
+		assert.NoError(t, err)
+		g := NewGame(v)
+
+		for i := 0; i < 4; i++ {
+			for _, m := range []string{"g1f3", "g8f6", "f3g1", "f6g8"} {
+				_, err := g.Move(m)
+				assert.NoError(t, err)
+			}
+			if i == 1 {
+				assert.True(t, g.CanClaimDraw())
+			}
+		}
+
+		outcome, method := g.Outcome()
+		assert.Equal(t, chess.Draw, outcome)
+		assert.Equal(t, chess.FivefoldRepetition.String(), method)
+
+		_, err = g.Move("e2e4")
+		assert.Error(t, err)
+	})
+
+	t.Run("Bughouse is not played on one board", func(t *testing.T) {
+		_, err := Get(domain.GameVariantBughouse)
+		assert.Error(t, err)
+	})
+}
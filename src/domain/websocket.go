@@ -1,14 +1,26 @@
 package domain
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type Room interface {
 	BroadcastMessage(message []byte)
+	BroadcastToPlayers(message []byte)
+	BroadcastToSpectators(message []byte)
+	BroadcastWhere(message []byte, include func(id string, player bool) bool)
+	BroadcastReliably(message []byte)
+	BroadcastToPlayersReliably(message []byte)
 	RegisterClient(client Client) error
 	UnregisterClient(client Client)
 	ChangeParam(param string)
 	GetParam() (string, error)
 	GetClient(id string) (Client, bool)
+	SetPlayers(ids ...string)
+	IsPlayer(id string) bool
+	CountSpectators() int
+	OnSpectatorCountChange(callback func(count int))
 }
 
 type Client interface {
@@ -16,9 +28,11 @@ type Client interface {
 	Subscribe(room Room) error
 	Unsubscribe(room Room)
 	SendBytes(bytes []byte)
+	SendReliably(message []byte)
 	SendMessage(topic string, event string, payload interface{}, logFormat string) error
 	SendError(errorMsg string, logFormat string) error
 	HandleClose(ctx context.Context, err error)
+	Latency() time.Duration
 	ReadPump(ctx context.Context)
 	WritePump(ctx context.Context)
 }
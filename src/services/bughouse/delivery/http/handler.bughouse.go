@@ -0,0 +1,66 @@
+package delivery_http_bughouse
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+)
+
+type BughouseHandler struct {
+	bughouse domain.BughouseUseCase
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type startRequest struct {
+	TeamA     [2]string `json:"team_a"`
+	TeamB     [2]string `json:"team_b"`
+	Time      int       `json:"time"`
+	Increment int       `json:"increment"`
+}
+
+func NewBughouseHandler(bughouse domain.BughouseUseCase) BughouseHandler {
+	return BughouseHandler{
+		bughouse,
+	}
+}
+
+func (b BughouseHandler) RegisterRoutes(router *httprouter.Router) {
+	router.POST("/api/bughouse", b.HandlerStartBughouse)
+}
+
+// HandlerStartBughouse starts a bughouse game between two teams of two. The
+// players then play on the game's bughouse topic.
+func (b BughouseHandler) HandlerStartBughouse(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var body startRequest
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "body is not a valid bughouse game")
+		return
+	}
+
+	state, err := b.bughouse.Start(r.Context(), body.TeamA, body.TeamB, body.Time, body.Increment)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, state)
+}
+
+func writeJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		log.Printf("Handler/HTTP/Bughouse/writeJSON, error encoding response: %v\n", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, errorResponse{message})
+}
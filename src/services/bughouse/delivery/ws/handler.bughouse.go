@@ -0,0 +1,143 @@
+package delivery_ws_bughouse
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	domain_websocket "github.com/lookingcoolonavespa/go_crochess_backend/src/websocket"
+)
+
+const jsonErrorMessage = "Handler/Bughouse, Failed to convert message to json: %v\n"
+
+type BughouseHandler struct {
+	bughouse domain.BughouseUseCase
+}
+
+func NewBughouseHandler(bughouse domain.BughouseUseCase) BughouseHandler {
+	return BughouseHandler{
+		bughouse,
+	}
+}
+
+func groupID(room domain.Room) (int, error) {
+	param, err := room.GetParam()
+	if err != nil {
+		log.Printf("Handler/Bughouse: room is missing param")
+		return 0, err
+	}
+
+	id, err := strconv.Atoi(param)
+	if err != nil {
+		log.Printf("Handler/Bughouse: param is not a valid int\nroom.Param: %v", param)
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func broadcastBoards(room domain.Room, id int, event string, boards []domain.BughouseBoard) error {
+	jsonData, err := domain_websocket.NewOutboundMessage(
+		fmt.Sprint(domain_websocket.BughouseTopic, "/", id),
+		event,
+		boards,
+	).ToJSON(jsonErrorMessage)
+	if err != nil {
+		return err
+	}
+
+	room.BroadcastMessage(jsonData)
+	return nil
+}
+
+// HandlerOnSubscribe sends both boards of the game with their pockets
+func (b BughouseHandler) HandlerOnSubscribe(
+	ctx context.Context,
+	room domain.Room,
+	client domain.Client,
+	_ []byte,
+) error {
+	id, err := groupID(room)
+	if err != nil {
+		return err
+	}
+
+	state, err := b.bughouse.Get(ctx, id)
+	if err != nil {
+		return client.SendError(
+			fmt.Sprintf("bughouse game %d could not be found", id),
+			jsonErrorMessage,
+		)
+	}
+
+	err = client.Subscribe(room)
+	if err != nil {
+		return err
+	}
+
+	return client.SendMessage(
+		fmt.Sprint(domain_websocket.BughouseTopic, "/", id),
+		domain_websocket.InitEvent,
+		state,
+		"Handler/Bughouse/HandlerOnSubscribe: error turning game into json\nerr: %v",
+	)
+}
+
+func (b BughouseHandler) HandlerOnUnsubscribe(
+	ctx context.Context,
+	room domain.Room,
+	client domain.Client,
+	_ []byte,
+) error {
+	client.Unsubscribe(room)
+	return nil
+}
+
+// HandlerMakeMove plays a move and broadcasts the boards it changed, the
+// partner's board when a piece was captured
+func (b BughouseHandler) HandlerMakeMove(
+	ctx context.Context,
+	room domain.Room,
+	client domain.Client,
+	message []byte,
+) error {
+	id, err := groupID(room)
+	if err != nil {
+		return err
+	}
+
+	var move domain.BughouseMove
+	err = json.Unmarshal(message, &move)
+	if err != nil {
+		return client.SendError("payload is not a valid move", jsonErrorMessage)
+	}
+
+	boards, err := b.bughouse.Move(ctx, id, client.GetID(), move)
+	if err != nil {
+		return client.SendError(err.Error(), jsonErrorMessage)
+	}
+
+	return broadcastBoards(room, id, domain_websocket.MakeMoveEvent, boards)
+}
+
+func (b BughouseHandler) HandlerResign(
+	ctx context.Context,
+	room domain.Room,
+	client domain.Client,
+	_ []byte,
+) error {
+	id, err := groupID(room)
+	if err != nil {
+		return err
+	}
+
+	boards, err := b.bughouse.Resign(ctx, id, client.GetID())
+	if err != nil {
+		return client.SendError(err.Error(), jsonErrorMessage)
+	}
+
+	return broadcastBoards(room, id, domain_websocket.UpdateResultEvent, boards)
+}
@@ -0,0 +1,531 @@
+package usecase_bughouse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	domain_drop "github.com/lookingcoolonavespa/go_crochess_backend/src/domain/drop"
+	domain_timerManager "github.com/lookingcoolonavespa/go_crochess_backend/src/domain/timerManager"
+	domain_websocket "github.com/lookingcoolonavespa/go_crochess_backend/src/websocket"
+	"github.com/notnil/chess"
+)
+
+var timeNow = time.Now
+
+var (
+	ErrGameOver      = errors.New("the game is over")
+	ErrInvalidPlayer = errors.New("Invalid player.")
+)
+
+const (
+	teamA = 0
+	teamB = 1
+)
+
+// bughouseUseCase plays bughouse games, two boards whose captured pieces go
+// to the partner's pocket. Games are kept in memory once loaded and the moves
+// of a game are made one at a time, since a capture changes both boards.
+type bughouseUseCase struct {
+	gameRepo     domain.GameRepo
+	groupRepo    domain.GameGroupRepo
+	timerManager *domain_timerManager.TimerManager
+	// room returns the room of a bughouse game's topic, if anyone subscribed
+	room  func(groupID int) (domain.Room, bool)
+	games *bughouseGames
+}
+
+type bughouseGames struct {
+	mutex sync.Mutex
+	byID  map[int]*bughouseGame
+}
+
+type bughouseGame struct {
+	mutex     sync.Mutex
+	group     domain.GameGroup
+	boards    [2]domain.Game
+	positions [2]domain_drop.Position
+}
+
+func NewBughouseUseCase(
+	gameRepo domain.GameRepo,
+	groupRepo domain.GameGroupRepo,
+	room func(groupID int) (domain.Room, bool),
+) bughouseUseCase {
+	return bughouseUseCase{
+		gameRepo,
+		groupRepo,
+		domain_timerManager.NewTimerManager(),
+		room,
+		&bughouseGames{byID: make(map[int]*bughouseGame)},
+	}
+}
+
+// teamOf returns the team of the player of a color on a board
+func teamOf(board int, color domain_drop.Color) int {
+	if (board == 0) == (color == domain_drop.White) {
+		return teamA
+	}
+
+	return teamB
+}
+
+// boardResult turns a team result into the result of a board
+func boardResult(board int, teamResult string) string {
+	if board == 0 || teamResult == chess.Draw.String() {
+		return teamResult
+	}
+	if teamResult == chess.WhiteWon.String() {
+		return chess.BlackWon.String()
+	}
+
+	return chess.WhiteWon.String()
+}
+
+// teamWon returns the team result of a win of team
+func teamWon(team int) string {
+	if team == teamA {
+		return chess.WhiteWon.String()
+	}
+
+	return chess.BlackWon.String()
+}
+
+func (c bughouseUseCase) Start(
+	ctx context.Context,
+	a [2]string,
+	b [2]string,
+	gameTime int,
+	increment int,
+) (domain.BughouseState, error) {
+	players := map[string]bool{}
+	for _, id := range []string{a[0], a[1], b[0], b[1]} {
+		if id == "" || players[id] {
+			return domain.BughouseState{}, errors.New("bughouse needs four different players")
+		}
+		players[id] = true
+	}
+	if gameTime <= 0 {
+		return domain.BughouseState{}, errors.New("bughouse needs a time control")
+	}
+
+	now := timeNow().UnixMilli()
+	group := domain.GameGroup{
+		Variant:   domain.GameGroupVariantBughouse,
+		CreatedAt: now,
+	}
+	groupID, err := c.groupRepo.Insert(ctx, group)
+	if err != nil {
+		return domain.BughouseState{}, err
+	}
+	group.ID = groupID
+
+	bg := &bughouseGame{group: group}
+	seats := [2][2]string{{a[0], b[0]}, {b[1], a[1]}}
+	for i, seat := range seats {
+		g := domain.Game{
+			WhiteID:              seat[0],
+			BlackID:              seat[1],
+			Time:                 gameTime,
+			Increment:            increment,
+			WhiteTime:            gameTime,
+			BlackTime:            gameTime,
+			TimeStampAtTurnStart: now,
+			Version:              1,
+			GroupID:              groupID,
+			Variant:              domain.GameVariantBughouse,
+		}
+		g.ID, err = c.gameRepo.Insert(ctx, g)
+		if err != nil {
+			return domain.BughouseState{}, err
+		}
+
+		bg.group.GameIDs = append(bg.group.GameIDs, g.ID)
+		bg.boards[i] = g
+		bg.positions[i] = domain_drop.StartingPosition()
+	}
+
+	c.games.mutex.Lock()
+	c.games.byID[groupID] = bg
+	c.games.mutex.Unlock()
+
+	bg.mutex.Lock()
+	defer bg.mutex.Unlock()
+	for i := range bg.boards {
+		c.startTimer(bg, i)
+	}
+
+	return bg.state(), nil
+}
+
+func (c bughouseUseCase) Get(ctx context.Context, groupID int) (domain.BughouseState, error) {
+	bg, err := c.load(ctx, groupID)
+	if err != nil {
+		return domain.BughouseState{}, err
+	}
+
+	bg.mutex.Lock()
+	defer bg.mutex.Unlock()
+	return bg.state(), nil
+}
+
+// load returns a bughouse game, from the database if it isn't in memory. The
+// board of a game is its last position with the pockets as stored now.
+func (c bughouseUseCase) load(ctx context.Context, groupID int) (*bughouseGame, error) {
+	c.games.mutex.Lock()
+	defer c.games.mutex.Unlock()
+
+	if bg, ok := c.games.byID[groupID]; ok {
+		return bg, nil
+	}
+
+	group, err := c.groupRepo.Get(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if group.Variant != domain.GameGroupVariantBughouse || len(group.GameIDs) != 2 {
+		return nil, errors.New(fmt.Sprintf("game group %d is not a bughouse game", groupID))
+	}
+
+	bg := &bughouseGame{group: group}
+	for i, gameID := range group.GameIDs {
+		g, err := c.gameRepo.Get(ctx, gameID)
+		if err != nil {
+			return nil, err
+		}
+		moves, err := c.gameRepo.ListMoves(ctx, gameID)
+		if err != nil {
+			return nil, err
+		}
+
+		position := domain_drop.StartingPosition()
+		if len(moves) > 0 {
+			position, err = domain_drop.ParseFEN(moves[len(moves)-1].FEN)
+			if err != nil {
+				return nil, err
+			}
+		}
+		pockets, err := domain_drop.ParsePockets(g.Pockets)
+		if err != nil {
+			return nil, err
+		}
+
+		bg.boards[i] = g
+		bg.positions[i] = position.
+			WithPocket(domain_drop.White, pockets[domain_drop.White]).
+			WithPocket(domain_drop.Black, pockets[domain_drop.Black])
+	}
+
+	if group.Result == "" {
+		c.games.byID[groupID] = bg
+		// the clocks stopped when the game was dropped from memory
+		for i := range bg.boards {
+			c.startTimer(bg, i)
+		}
+	}
+
+	return bg, nil
+}
+
+// Move plays a move on a board. A captured piece goes to the pocket of the
+// capturing player's partner, who plays the other color on the other board.
+// A mate that a drop could block doesn't end the game, the player can wait
+// for a piece instead.
+func (c bughouseUseCase) Move(
+	ctx context.Context,
+	groupID int,
+	playerID string,
+	move domain.BughouseMove,
+) ([]domain.BughouseBoard, error) {
+	bg, err := c.load(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	bg.mutex.Lock()
+	defer bg.mutex.Unlock()
+
+	if bg.group.Result != "" {
+		return nil, ErrGameOver
+	}
+	if move.Board != 0 && move.Board != 1 {
+		return nil, errors.New(fmt.Sprintf("bughouse has no board %d", move.Board))
+	}
+
+	b := move.Board
+	g := bg.boards[b]
+	position := bg.positions[b]
+	mover := position.Turn()
+	if mover == domain_drop.White && g.WhiteID != playerID ||
+		mover == domain_drop.Black && g.BlackID != playerID {
+		return nil, ErrInvalidPlayer
+	}
+
+	m, err := domain_drop.ParseMove(move.Move)
+	if err != nil {
+		return nil, err
+	}
+	next, captured, err := position.Move(m)
+	if err != nil {
+		return nil, err
+	}
+	san := position.SAN(m)
+
+	now := timeNow().UnixMilli()
+	changes := make(domain.GameChanges)
+	clockField := domain.GameWhiteTimeJsonTag
+	activeTime := g.WhiteTime
+	if mover == domain_drop.Black {
+		clockField = domain.GameBlackTimeJsonTag
+		activeTime = g.BlackTime
+	}
+	clock := activeTime - int(now-g.TimeStampAtTurnStart) + g.Increment*1000
+	changes[clockField] = clock
+	changes[domain.GameTimeStampJsonTag] = now
+	changes[domain.GameMovesJsonTag] = m.String()
+	changes[domain.GamePocketsJsonTag] = next.PocketsString()
+
+	record := &domain.GameMove{
+		GameID:    g.ID,
+		Ply:       len(strings.Fields(g.Moves)) + 1,
+		UCI:       m.String(),
+		SAN:       san,
+		FEN:       next.FEN(),
+		Clock:     clock,
+		TimeStamp: now,
+	}
+
+	updated, err := c.gameRepo.Update(ctx, g.ID, g.Version, changes, record)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, errors.New("the board changed before the move could be made")
+	}
+
+	g.Version++
+	g.Moves = strings.TrimSpace(g.Moves + " " + m.String())
+	g.TimeStampAtTurnStart = now
+	g.Pockets = next.PocketsString()
+	if mover == domain_drop.White {
+		g.WhiteTime = clock
+	} else {
+		g.BlackTime = clock
+	}
+	bg.boards[b] = g
+	bg.positions[b] = next
+	changed := []int{b}
+
+	if captured != domain_drop.NoPieceType {
+		partner := 1 - b
+		err := c.passPiece(ctx, bg, partner, mover.Other(), captured)
+		if err != nil {
+			log.Printf("Usecase/Bughouse/Move, error passing piece to board %d of game %d: %v", partner, groupID, err)
+		} else {
+			changed = append(changed, partner)
+		}
+	}
+
+	switch next.Status(true) {
+	case domain_drop.Checkmate:
+		err = c.endGame(ctx, bg, teamWon(teamOf(b, mover)), chess.Checkmate.String())
+		changed = []int{0, 1}
+	case domain_drop.Stalemate:
+		err = c.endGame(ctx, bg, chess.Draw.String(), chess.Stalemate.String())
+		changed = []int{0, 1}
+	default:
+		c.startTimer(bg, b)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	boards := make([]domain.BughouseBoard, len(changed))
+	for i, board := range changed {
+		boards[i] = bg.board(board)
+	}
+
+	return boards, nil
+}
+
+// passPiece adds a captured piece to a pocket on a board. It has to be called
+// with the game's mutex held.
+func (c bughouseUseCase) passPiece(
+	ctx context.Context,
+	bg *bughouseGame,
+	board int,
+	color domain_drop.Color,
+	piece domain_drop.PieceType,
+) error {
+	g := bg.boards[board]
+	position := bg.positions[board].AddToPocket(color, piece)
+
+	changes := domain.GameChanges{
+		domain.GamePocketsJsonTag: position.PocketsString(),
+	}
+	updated, err := c.gameRepo.Update(ctx, g.ID, g.Version, changes, nil)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return errors.New("the board changed before the piece could be passed")
+	}
+
+	g.Version++
+	g.Pockets = position.PocketsString()
+	bg.boards[board] = g
+	bg.positions[board] = position
+	return nil
+}
+
+// Resign makes the team of the player lose
+func (c bughouseUseCase) Resign(
+	ctx context.Context,
+	groupID int,
+	playerID string,
+) ([]domain.BughouseBoard, error) {
+	bg, err := c.load(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	bg.mutex.Lock()
+	defer bg.mutex.Unlock()
+
+	if bg.group.Result != "" {
+		return nil, ErrGameOver
+	}
+
+	team := -1
+	for i, g := range bg.boards {
+		if g.WhiteID == playerID {
+			team = teamOf(i, domain_drop.White)
+		} else if g.BlackID == playerID {
+			team = teamOf(i, domain_drop.Black)
+		}
+	}
+	if team == -1 {
+		return nil, ErrInvalidPlayer
+	}
+
+	err = c.endGame(ctx, bg, teamWon(1-team), "Resign")
+	if err != nil {
+		return nil, err
+	}
+
+	return []domain.BughouseBoard{bg.board(0), bg.board(1)}, nil
+}
+
+// startTimer starts the timer of the player to move on a board. It has to be
+// called with the game's mutex held, or before anyone else has the game.
+func (c bughouseUseCase) startTimer(bg *bughouseGame, board int) {
+	g := bg.boards[board]
+	mover := bg.positions[board].Turn()
+	remaining := g.WhiteTime
+	if mover == domain_drop.Black {
+		remaining = g.BlackTime
+	}
+	remaining -= int(timeNow().UnixMilli() - g.TimeStampAtTurnStart)
+
+	groupID := bg.group.ID
+	plies := len(strings.Fields(g.Moves))
+	c.timerManager.StartTimer(g.ID, time.Duration(remaining)*time.Millisecond, func() {
+		c.onTimeOut(groupID, board, plies, mover)
+	})
+}
+
+// onTimeOut makes the team of the player whose clock ran out lose, if no move
+// was made on the board since the timer started. A piece passed to the board
+// doesn't count, it doesn't stop the clock.
+func (c bughouseUseCase) onTimeOut(groupID int, board int, plies int, mover domain_drop.Color) {
+	c.games.mutex.Lock()
+	bg, ok := c.games.byID[groupID]
+	c.games.mutex.Unlock()
+	if !ok {
+		return
+	}
+
+	bg.mutex.Lock()
+	if bg.group.Result != "" || len(strings.Fields(bg.boards[board].Moves)) != plies {
+		bg.mutex.Unlock()
+		return
+	}
+	err := c.endGame(context.Background(), bg, teamWon(1-teamOf(board, mover)), "TimeOut")
+	if err != nil {
+		log.Printf("Usecase/Bughouse/onTimeOut, error ending game %d: %v", groupID, err)
+		bg.mutex.Unlock()
+		return
+	}
+	boards := []domain.BughouseBoard{bg.board(0), bg.board(1)}
+	bg.mutex.Unlock()
+
+	room, ok := c.room(groupID)
+	if !ok {
+		return
+	}
+	jsonData, err := domain_websocket.NewOutboundMessage(
+		fmt.Sprint(domain_websocket.BughouseTopic, "/", groupID),
+		domain_websocket.TimeOutEvent,
+		boards,
+	).ToJSON("UseCase/Bughouse/onTimeOut, error converting data to json, err: %v\n")
+	if err != nil {
+		return
+	}
+	room.BroadcastMessage(jsonData)
+}
+
+// endGame sets the team result and the matching result of both boards. It
+// has to be called with the game's mutex held.
+func (c bughouseUseCase) endGame(ctx context.Context, bg *bughouseGame, teamResult string, method string) error {
+	for i, g := range bg.boards {
+		c.timerManager.StopAndDeleteTimer(g.ID)
+
+		result := boardResult(i, teamResult)
+		changes := domain.GameChanges{
+			domain.GameResultJsonTag: result,
+			domain.GameMethodJsonTag: method,
+		}
+		updated, err := c.gameRepo.Update(ctx, g.ID, g.Version, changes, nil)
+		if err != nil {
+			return err
+		}
+		if updated {
+			g.Version++
+			g.Result = result
+			g.Method = method
+			bg.boards[i] = g
+		}
+	}
+
+	_, err := c.groupRepo.UpdateResult(ctx, bg.group.ID, teamResult, method)
+	if err != nil {
+		return err
+	}
+	bg.group.Result = teamResult
+	bg.group.Method = method
+
+	c.games.mutex.Lock()
+	delete(c.games.byID, bg.group.ID)
+	c.games.mutex.Unlock()
+
+	return nil
+}
+
+func (bg *bughouseGame) board(i int) domain.BughouseBoard {
+	return domain.BughouseBoard{
+		Game: bg.boards[i],
+		FEN:  bg.positions[i].FEN(),
+	}
+}
+
+func (bg *bughouseGame) state() domain.BughouseState {
+	return domain.BughouseState{
+		GameGroup: bg.group,
+		Boards:    []domain.BughouseBoard{bg.board(0), bg.board(1)},
+	}
+}
@@ -0,0 +1,220 @@
+package usecase_bughouse
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	repository_game_mock "github.com/lookingcoolonavespa/go_crochess_backend/src/services/game/repository/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestBughouseUseCase(t *testing.T) {
+	now := time.Date(2023, time.October, 10, 2, 10, 10, 10, time.UTC)
+	timeNow = func() time.Time {
+		return now
+	}
+
+	ctx := context.Background()
+	gameRepo := new(repository_game_mock.GameMockRepo)
+	groupRepo := new(repository_game_mock.GameGroupMockRepo)
+	groupRepo.On("Insert", ctx, mock.Anything).Return(7, nil)
+	gameRepo.On("Insert", ctx, mock.Anything).Return(20, nil).Once()
+	gameRepo.On("Insert", ctx, mock.Anything).Return(21, nil).Once()
+	gameRepo.On("Update", ctx, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
+
+	u := NewBughouseUseCase(gameRepo, groupRepo, func(groupID int) (domain.Room, bool) {
+		return nil, false
+	})
+
+	state, err := u.Start(ctx, [2]string{"a1", "a2"}, [2]string{"b1", "b2"}, 600000, 0)
+	assert.NoError(t, err)
+
+	t.Run("Team A plays white on the first board and black on the second", func(t *testing.T) {
+		assert.Equal(t, []int{20, 21}, state.GameIDs)
+		assert.Equal(t, "a1", state.Boards[0].WhiteID)
+		assert.Equal(t, "b1", state.Boards[0].BlackID)
+		assert.Equal(t, "b2", state.Boards[1].WhiteID)
+		assert.Equal(t, "a2", state.Boards[1].BlackID)
+		assert.Equal(t, 7, state.Boards[1].GroupID)
+	})
+
+	t.Run("Only the player to move moves", func(t *testing.T) {
+		_, err := u.Move(ctx, 7, "b1", domain.BughouseMove{Board: 0, Move: "e2e4"})
+		assert.ErrorIs(t, err, ErrInvalidPlayer)
+	})
+
+	t.Run("A capture goes to the partner's pocket", func(t *testing.T) {
+		for _, m := range []struct {
+			player string
+			move   string
+		}{{"a1", "e2e4"}, {"b1", "d7d5"}} {
+			boards, err := u.Move(ctx, 7, m.player, domain.BughouseMove{Board: 0, Move: m.move})
+			assert.NoError(t, err)
+			assert.Len(t, boards, 1)
+		}
+
+		boards, err := u.Move(ctx, 7, "a1", domain.BughouseMove{Board: 0, Move: "e4d5"})
+		assert.NoError(t, err)
+		assert.Len(t, boards, 2)
+		assert.Equal(t, "p", boards[1].Pockets)
+		assert.Equal(t, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR[p] w KQkq - 0 1", boards[1].FEN)
+	})
+
+	t.Run("The partner drops the piece", func(t *testing.T) {
+		_, err := u.Move(ctx, 7, "b2", domain.BughouseMove{Board: 1, Move: "g1f3"})
+		assert.NoError(t, err)
+
+		boards, err := u.Move(ctx, 7, "a2", domain.BughouseMove{Board: 1, Move: "P@e5"})
+		assert.NoError(t, err)
+		assert.Len(t, boards, 1)
+		assert.Equal(t, "", boards[0].Pockets)
+		assert.Equal(t, "e5", boards[0].Moves[len(boards[0].Moves)-2:])
+	})
+
+	t.Run("A resignation loses for the team", func(t *testing.T) {
+		groupRepo.On("UpdateResult", ctx, 7, "1-0", "Resign").Return(true, nil)
+
+		boards, err := u.Resign(ctx, 7, "b2")
+		assert.NoError(t, err)
+		assert.Equal(t, "1-0", boards[0].Result)
+		assert.Equal(t, "0-1", boards[1].Result)
+
+		groupRepo.On("Get", ctx, 7).Return(domain.GameGroup{}, assert.AnError)
+		_, err = u.Move(ctx, 7, "b1", domain.BughouseMove{Board: 0, Move: "d8d5"})
+		assert.Error(t, err, "finished games aren't kept in memory")
+	})
+}
+
+func TestBughouseUseCase_TimeOut(t *testing.T) {
+	now := time.Date(2023, time.October, 10, 2, 10, 10, 10, time.UTC)
+	timeNow = func() time.Time {
+		return now
+	}
+
+	ctx := context.Background()
+	gameRepo := new(repository_game_mock.GameMockRepo)
+	groupRepo := new(repository_game_mock.GameGroupMockRepo)
+	groupRepo.On("Insert", ctx, mock.Anything).Return(7, nil)
+	gameRepo.On("Insert", ctx, mock.Anything).Return(20, nil).Once()
+	gameRepo.On("Insert", ctx, mock.Anything).Return(21, nil).Once()
+	gameRepo.On("Update", ctx, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
+	flagged := make(chan string, 1)
+	groupRepo.On("UpdateResult", ctx, 7, mock.Anything, "TimeOut").Return(true, nil).Run(func(args mock.Arguments) {
+		flagged <- args.String(2)
+	})
+
+	u := NewBughouseUseCase(gameRepo, groupRepo, func(groupID int) (domain.Room, bool) {
+		return nil, false
+	})
+
+	t.Run("Capture passes a piece, then the partner flags", func(t *testing.T) {
+		// the increment keeps the clocks of the first board running longer
+		// than the one of b2, who never moves
+		_, err := u.Start(ctx, [2]string{"a1", "a2"}, [2]string{"b1", "b2"}, 100, 2)
+		assert.NoError(t, err)
+
+		for _, m := range []struct {
+			player string
+			move   string
+		}{{"a1", "e2e4"}, {"b1", "d7d5"}, {"a1", "e4d5"}, {"b1", "d8d5"}} {
+			_, err := u.Move(ctx, 7, m.player, domain.BughouseMove{Board: 0, Move: m.move})
+			assert.NoError(t, err)
+		}
+
+		select {
+		case result := <-flagged:
+			assert.Equal(t, "1-0", result, "b2 ran out of time")
+		case <-time.After(time.Second):
+			t.Error("the clock of b2 never ran out")
+		}
+	})
+}
+
+func TestBughouseUseCase_Load(t *testing.T) {
+	now := time.Date(2023, time.October, 10, 2, 10, 10, 10, time.UTC)
+	timeNow = func() time.Time {
+		return now
+	}
+
+	ctx := context.Background()
+
+	t.Run("Rebuilds the positions of the boards", func(t *testing.T) {
+		gameRepo := new(repository_game_mock.GameMockRepo)
+		groupRepo := new(repository_game_mock.GameGroupMockRepo)
+		groupRepo.On("Get", ctx, 7).Return(domain.GameGroup{
+			ID:      7,
+			Variant: domain.GameGroupVariantBughouse,
+			GameIDs: []int{20, 21},
+		}, nil)
+		gameRepo.On("Get", ctx, 20).Return(domain.Game{
+			ID:                   20,
+			Moves:                "e2e4",
+			Pockets:              "N",
+			WhiteTime:            600000,
+			BlackTime:            600000,
+			TimeStampAtTurnStart: now.UnixMilli(),
+		}, nil)
+		gameRepo.On("Get", ctx, 21).Return(domain.Game{
+			ID:                   21,
+			WhiteTime:            600000,
+			BlackTime:            600000,
+			TimeStampAtTurnStart: now.UnixMilli(),
+		}, nil)
+		gameRepo.On("ListMoves", ctx, 20).Return([]domain.GameMove{
+			{Ply: 1, UCI: "e2e4", FEN: "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR[] b KQkq e3 0 1"},
+		}, nil)
+		gameRepo.On("ListMoves", ctx, 21).Return([]domain.GameMove{}, nil)
+
+		u := NewBughouseUseCase(gameRepo, groupRepo, nil)
+
+		state, err := u.Get(ctx, 7)
+		assert.NoError(t, err)
+		assert.Equal(t, "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR[N] b KQkq e3 0 1", state.Boards[0].FEN, "the pockets come from the game, not the last move")
+		assert.Equal(t, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR[] w KQkq - 0 1", state.Boards[1].FEN)
+	})
+
+	t.Run("Starts the clocks again", func(t *testing.T) {
+		gameRepo := new(repository_game_mock.GameMockRepo)
+		groupRepo := new(repository_game_mock.GameGroupMockRepo)
+		groupRepo.On("Get", ctx, 7).Return(domain.GameGroup{
+			ID:      7,
+			Variant: domain.GameGroupVariantBughouse,
+			GameIDs: []int{20, 21},
+		}, nil)
+		gameRepo.On("Get", ctx, 20).Return(domain.Game{
+			ID:                   20,
+			WhiteTime:            600000,
+			BlackTime:            600000,
+			TimeStampAtTurnStart: now.UnixMilli(),
+		}, nil)
+		gameRepo.On("Get", ctx, 21).Return(domain.Game{
+			ID:                   21,
+			WhiteTime:            50,
+			BlackTime:            600000,
+			TimeStampAtTurnStart: now.UnixMilli(),
+		}, nil)
+		gameRepo.On("ListMoves", ctx, mock.Anything).Return([]domain.GameMove{}, nil)
+		gameRepo.On("Update", ctx, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
+		flagged := make(chan string, 1)
+		groupRepo.On("UpdateResult", ctx, 7, mock.Anything, "TimeOut").Return(true, nil).Run(func(args mock.Arguments) {
+			flagged <- args.String(2)
+		})
+
+		u := NewBughouseUseCase(gameRepo, groupRepo, func(groupID int) (domain.Room, bool) {
+			return nil, false
+		})
+
+		_, err := u.Get(ctx, 7)
+		assert.NoError(t, err)
+
+		select {
+		case result := <-flagged:
+			assert.Equal(t, "1-0", result, "white on the second board ran out of time")
+		case <-time.After(time.Second):
+			t.Error("the clocks didn't start when the game was loaded")
+		}
+	})
+}
@@ -0,0 +1,45 @@
+package repository_chat_mock
+
+import (
+	"context"
+
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	"github.com/stretchr/testify/mock"
+)
+
+type ChatMockRepo struct {
+	mock.Mock
+}
+
+func (c *ChatMockRepo) Insert(ctx context.Context, m domain.ChatMessage) (int, error) {
+	args := c.Called(ctx, m)
+	result := args.Get(0)
+
+	return result.(int), args.Error(1)
+}
+
+func (c *ChatMockRepo) ListRecent(ctx context.Context, gameID int, limit int) ([]domain.ChatMessage, error) {
+	args := c.Called(ctx, gameID, limit)
+	result := args.Get(0)
+
+	return result.([]domain.ChatMessage), args.Error(1)
+}
+
+func (c *ChatMockRepo) ListLobby(ctx context.Context, viewerID string, limit int) ([]domain.ChatMessage, error) {
+	args := c.Called(ctx, viewerID, limit)
+	result := args.Get(0)
+
+	return result.([]domain.ChatMessage), args.Error(1)
+}
+
+func (c *ChatMockRepo) Delete(ctx context.Context, messageID int) error {
+	args := c.Called(ctx, messageID)
+
+	return args.Error(0)
+}
+
+func (c *ChatMockRepo) InsertModeration(ctx context.Context, action domain.ModerationAction) error {
+	args := c.Called(ctx, action)
+
+	return args.Error(0)
+}
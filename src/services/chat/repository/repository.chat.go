@@ -0,0 +1,182 @@
+package repository_chat
+
+import (
+	"context"
+	"database/sql"
+	"log"
+
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+)
+
+type chatRepo struct {
+	db *sql.DB
+}
+
+func NewChatRepo(db *sql.DB) chatRepo {
+	return chatRepo{db}
+}
+
+const insertStmt = `
+    INSERT INTO chat_messages (
+        game_id,
+        channel,
+        sender_id,
+        body,
+        created_at,
+        hidden
+    ) VALUES (
+        $1, $2, $3, $4, $5, $6
+    ) RETURNING id`
+
+const listRecentQuery = `SELECT id, game_id, channel, sender_id, body, created_at
+    FROM (
+        SELECT id, game_id, channel, sender_id, body, created_at
+        FROM chat_messages
+        WHERE game_id = $1
+        ORDER BY id DESC
+        LIMIT $2
+    ) recent
+    ORDER BY id`
+
+const listLobbyQuery = `SELECT id, channel, sender_id, body, created_at, hidden
+    FROM (
+        SELECT id, channel, sender_id, body, created_at, hidden
+        FROM chat_messages
+        WHERE game_id IS NULL
+        AND NOT deleted
+        AND (NOT hidden OR sender_id = $1)
+        ORDER BY id DESC
+        LIMIT $2
+    ) recent
+    ORDER BY id`
+
+const deleteStmt = `UPDATE chat_messages SET deleted = TRUE WHERE id = $1`
+
+const insertModerationStmt = `
+    INSERT INTO moderation_actions (
+        moderator_id,
+        action,
+        target_id,
+        message_id,
+        duration,
+        created_at
+    ) VALUES (
+        $1, $2, $3, $4, $5, $6
+    )`
+
+func (c chatRepo) Insert(ctx context.Context, m domain.ChatMessage) (messageID int, err error) {
+	err = c.db.QueryRowContext(
+		ctx,
+		insertStmt,
+		// lobby messages don't belong to a game
+		sql.NullInt64{Int64: int64(m.GameID), Valid: m.GameID != 0},
+		m.Channel,
+		m.SenderID,
+		m.Body,
+		m.CreatedAt,
+		m.Hidden,
+	).Scan(&messageID)
+	if err != nil {
+		log.Printf("Repo/Chat/Insert, error inserting message: %v\n", err)
+		return 0, err
+	}
+
+	return messageID, nil
+}
+
+func (c chatRepo) ListRecent(ctx context.Context, gameID int, limit int) ([]domain.ChatMessage, error) {
+	rows, err := c.db.QueryContext(ctx, listRecentQuery, gameID, limit)
+	if err != nil {
+		log.Printf("Repo/Chat/ListRecent, error querying messages: %v\n", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := make([]domain.ChatMessage, 0)
+	for rows.Next() {
+		var m domain.ChatMessage
+		err := rows.Scan(
+			&m.ID,
+			&m.GameID,
+			&m.Channel,
+			&m.SenderID,
+			&m.Body,
+			&m.CreatedAt,
+		)
+		if err != nil {
+			log.Printf("Repo/Chat/ListRecent, error scanning message: %v\n", err)
+			return nil, err
+		}
+
+		messages = append(messages, m)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Repo/Chat/ListRecent, error iterating messages: %v\n", err)
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+func (c chatRepo) ListLobby(ctx context.Context, viewerID string, limit int) ([]domain.ChatMessage, error) {
+	rows, err := c.db.QueryContext(ctx, listLobbyQuery, viewerID, limit)
+	if err != nil {
+		log.Printf("Repo/Chat/ListLobby, error querying messages: %v\n", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := make([]domain.ChatMessage, 0)
+	for rows.Next() {
+		var m domain.ChatMessage
+		err := rows.Scan(
+			&m.ID,
+			&m.Channel,
+			&m.SenderID,
+			&m.Body,
+			&m.CreatedAt,
+			&m.Hidden,
+		)
+		if err != nil {
+			log.Printf("Repo/Chat/ListLobby, error scanning message: %v\n", err)
+			return nil, err
+		}
+
+		messages = append(messages, m)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Repo/Chat/ListLobby, error iterating messages: %v\n", err)
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+func (c chatRepo) Delete(ctx context.Context, messageID int) error {
+	_, err := c.db.ExecContext(ctx, deleteStmt, messageID)
+	if err != nil {
+		log.Printf("Repo/Chat/Delete, error deleting message: %v\n", err)
+		return err
+	}
+
+	return nil
+}
+
+func (c chatRepo) InsertModeration(ctx context.Context, action domain.ModerationAction) error {
+	_, err := c.db.ExecContext(
+		ctx,
+		insertModerationStmt,
+		action.ModeratorID,
+		action.Action,
+		action.TargetID,
+		sql.NullInt64{Int64: int64(action.MessageID), Valid: action.MessageID != 0},
+		action.Duration,
+		action.CreatedAt,
+	)
+	if err != nil {
+		log.Printf("Repo/Chat/InsertModeration, error inserting moderation action: %v\n", err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package repository_chat
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func initMock() (*sql.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		log.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	return db, mock
+}
+
+func TestChatRepo_Insert(t *testing.T) {
+	db, mock := initMock()
+
+	defer db.Close()
+
+	m := domain.ChatMessage{
+		GameID:    3,
+		Channel:   domain.ChatChannelPlayers,
+		SenderID:  "abc",
+		Body:      "good luck",
+		CreatedAt: 100,
+	}
+
+	mock.ExpectQuery(insertStmt).
+		WithArgs(m.GameID, m.Channel, m.SenderID, m.Body, m.CreatedAt, false).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+
+	r := NewChatRepo(db)
+
+	id, err := r.Insert(context.Background(), m)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, id)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestChatRepo_InsertLobby(t *testing.T) {
+	db, mock := initMock()
+
+	defer db.Close()
+
+	m := domain.ChatMessage{
+		Channel:   domain.ChatChannelLobby,
+		SenderID:  "abc",
+		Body:      "anyone up for bullet?",
+		CreatedAt: 100,
+		Hidden:    true,
+	}
+
+	mock.ExpectQuery(insertStmt).
+		WithArgs(nil, m.Channel, m.SenderID, m.Body, m.CreatedAt, true).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(8))
+
+	r := NewChatRepo(db)
+
+	id, err := r.Insert(context.Background(), m)
+	assert.NoError(t, err)
+	assert.Equal(t, 8, id)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestChatRepo_ListRecent(t *testing.T) {
+	db, mock := initMock()
+
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"id", "game_id", "channel", "sender_id", "body", "created_at"}).
+		AddRow(1, 3, domain.ChatChannelPlayers, "abc", "good luck", 100).
+		AddRow(2, 3, domain.ChatChannelSpectators, "xyz", "hi", 200)
+
+	mock.ExpectQuery(listRecentQuery).
+		WithArgs(3, domain.ChatHistoryLength).
+		WillReturnRows(rows)
+
+	r := NewChatRepo(db)
+
+	messages, err := r.ListRecent(context.Background(), 3, domain.ChatHistoryLength)
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.ChatMessage{
+		{ID: 1, GameID: 3, Channel: domain.ChatChannelPlayers, SenderID: "abc", Body: "good luck", CreatedAt: 100},
+		{ID: 2, GameID: 3, Channel: domain.ChatChannelSpectators, SenderID: "xyz", Body: "hi", CreatedAt: 200},
+	}, messages)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestChatRepo_ListLobby(t *testing.T) {
+	db, mock := initMock()
+
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"id", "channel", "sender_id", "body", "created_at", "hidden"}).
+		AddRow(4, domain.ChatChannelLobby, "abc", "hi", 100, false)
+
+	mock.ExpectQuery(listLobbyQuery).
+		WithArgs("xyz", domain.ChatHistoryLength).
+		WillReturnRows(rows)
+
+	r := NewChatRepo(db)
+
+	messages, err := r.ListLobby(context.Background(), "xyz", domain.ChatHistoryLength)
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.ChatMessage{
+		{ID: 4, Channel: domain.ChatChannelLobby, SenderID: "abc", Body: "hi", CreatedAt: 100},
+	}, messages)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestChatRepo_InsertModeration(t *testing.T) {
+	db, mock := initMock()
+
+	defer db.Close()
+
+	mock.ExpectExec(insertModerationStmt).
+		WithArgs("mod", domain.ModerationDelete, "", 4, int64(0), int64(100)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	r := NewChatRepo(db)
+
+	err := r.InsertModeration(context.Background(), domain.ModerationAction{
+		ModeratorID: "mod",
+		Action:      domain.ModerationDelete,
+		MessageID:   4,
+		CreatedAt:   100,
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,44 @@
+package usecase_chat
+
+import (
+	"sync"
+	"time"
+)
+
+// floodLimiter allows each client at most limit messages within window
+type floodLimiter struct {
+	mutex  sync.Mutex
+	limit  int
+	window time.Duration
+	sent   map[string][]time.Time
+}
+
+func newFloodLimiter(limit int, window time.Duration) *floodLimiter {
+	return &floodLimiter{
+		limit:  limit,
+		window: window,
+		sent:   make(map[string][]time.Time),
+	}
+}
+
+// allow records a message from clientID at now and reports whether it is
+// within the limit. Rejected messages are not recorded.
+func (l *floodLimiter) allow(clientID string, now time.Time) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	recent := l.sent[clientID][:0]
+	for _, t := range l.sent[clientID] {
+		if now.Sub(t) < l.window {
+			recent = append(recent, t)
+		}
+	}
+
+	if len(recent) >= l.limit {
+		l.sent[clientID] = recent
+		return false
+	}
+
+	l.sent[clientID] = append(recent, now)
+	return true
+}
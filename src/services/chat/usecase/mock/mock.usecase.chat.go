@@ -0,0 +1,75 @@
+package mock_usecase_chat
+
+import (
+	"context"
+
+	"github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockChatUseCase struct {
+	mock.Mock
+}
+
+func (c *MockChatUseCase) Send(
+	ctx context.Context,
+	gameID int,
+	senderID string,
+	player bool,
+	body string,
+) (domain.ChatMessage, error) {
+	args := c.Called(ctx, gameID, senderID, player, body)
+	result := args.Get(0)
+
+	return result.(domain.ChatMessage), args.Error(1)
+}
+
+func (c *MockChatUseCase) History(
+	ctx context.Context,
+	gameID int,
+	viewerID string,
+	player bool,
+) ([]domain.ChatMessage, error) {
+	args := c.Called(ctx, gameID, viewerID, player)
+	result := args.Get(0)
+
+	return result.([]domain.ChatMessage), args.Error(1)
+}
+
+func (c *MockChatUseCase) CanRead(gameID int, viewerID string, player bool, channel string) bool {
+	args := c.Called(gameID, viewerID, player, channel)
+
+	return args.Bool(0)
+}
+
+func (c *MockChatUseCase) SetSpectatorsMuted(gameID int, playerID string, muted bool) {
+	c.Called(gameID, playerID, muted)
+}
+
+type MockLobbyChatUseCase struct {
+	mock.Mock
+}
+
+func (c *MockLobbyChatUseCase) Send(ctx context.Context, senderID string, body string) (domain.ChatMessage, error) {
+	args := c.Called(ctx, senderID, body)
+	result := args.Get(0)
+
+	return result.(domain.ChatMessage), args.Error(1)
+}
+
+func (c *MockLobbyChatUseCase) History(ctx context.Context, viewerID string) ([]domain.ChatMessage, error) {
+	args := c.Called(ctx, viewerID)
+	result := args.Get(0)
+
+	return result.([]domain.ChatMessage), args.Error(1)
+}
+
+func (c *MockLobbyChatUseCase) Moderate(
+	ctx context.Context,
+	action domain.ModerationAction,
+) (domain.ModerationAction, error) {
+	args := c.Called(ctx, action)
+	result := args.Get(0)
+
+	return result.(domain.ModerationAction), args.Error(1)
+}
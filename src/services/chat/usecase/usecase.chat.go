@@ -0,0 +1,142 @@
+package usecase_chat
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+)
+
+const (
+	floodLimit  = 5
+	floodWindow = 10 * time.Second
+)
+
+var timeNow = time.Now
+
+type chatUseCase struct {
+	chatRepo domain.ChatRepo
+	limiter  *floodLimiter
+	mutes    *mutes
+}
+
+// mutes are the players that muted the spectators, by game
+type mutes struct {
+	mutex  sync.Mutex
+	byGame map[int]map[string]bool
+}
+
+func NewChatUseCase(chatRepo domain.ChatRepo) chatUseCase {
+	return chatUseCase{
+		chatRepo,
+		newFloodLimiter(floodLimit, floodWindow),
+		&mutes{byGame: make(map[int]map[string]bool)},
+	}
+}
+
+func (c chatUseCase) Send(
+	ctx context.Context,
+	gameID int,
+	senderID string,
+	player bool,
+	body string,
+) (domain.ChatMessage, error) {
+	body, err := trimBody(body)
+	if err != nil {
+		return domain.ChatMessage{}, err
+	}
+
+	now := timeNow()
+	if !c.limiter.allow(senderID, now) {
+		return domain.ChatMessage{}, domain.ErrChatRateLimited
+	}
+
+	channel := domain.ChatChannelSpectators
+	if player {
+		channel = domain.ChatChannelPlayers
+	}
+
+	m := domain.ChatMessage{
+		GameID:    gameID,
+		Channel:   channel,
+		SenderID:  senderID,
+		Body:      body,
+		CreatedAt: now.UnixMilli(),
+	}
+
+	id, err := c.chatRepo.Insert(ctx, m)
+	if err != nil {
+		return domain.ChatMessage{}, err
+	}
+	m.ID = id
+
+	return m, nil
+}
+
+// trimBody trims the spaces around body and checks it isn't empty or too long
+func trimBody(body string) (string, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return "", domain.ErrChatMessageEmpty
+	}
+	if utf8.RuneCountInString(body) > domain.ChatMessageMaxLength {
+		return "", domain.ErrChatMessageTooLong
+	}
+
+	return body, nil
+}
+
+func (c chatUseCase) History(
+	ctx context.Context,
+	gameID int,
+	viewerID string,
+	player bool,
+) ([]domain.ChatMessage, error) {
+	messages, err := c.chatRepo.ListRecent(ctx, gameID, domain.ChatHistoryLength)
+	if err != nil {
+		return nil, err
+	}
+
+	readable := make([]domain.ChatMessage, 0, len(messages))
+	for _, m := range messages {
+		if c.CanRead(gameID, viewerID, player, m.Channel) {
+			readable = append(readable, m)
+		}
+	}
+
+	return readable, nil
+}
+
+func (c chatUseCase) CanRead(gameID int, viewerID string, player bool, channel string) bool {
+	if !player {
+		return channel == domain.ChatChannelSpectators
+	}
+	if channel == domain.ChatChannelPlayers {
+		return true
+	}
+
+	c.mutes.mutex.Lock()
+	defer c.mutes.mutex.Unlock()
+	return !c.mutes.byGame[gameID][viewerID]
+}
+
+func (c chatUseCase) SetSpectatorsMuted(gameID int, playerID string, muted bool) {
+	c.mutes.mutex.Lock()
+	defer c.mutes.mutex.Unlock()
+
+	if !muted {
+		delete(c.mutes.byGame[gameID], playerID)
+		if len(c.mutes.byGame[gameID]) == 0 {
+			delete(c.mutes.byGame, gameID)
+		}
+		return
+	}
+
+	if c.mutes.byGame[gameID] == nil {
+		c.mutes.byGame[gameID] = make(map[string]bool)
+	}
+	c.mutes.byGame[gameID][playerID] = true
+}
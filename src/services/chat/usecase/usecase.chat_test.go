@@ -0,0 +1,108 @@
+package usecase_chat
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	repository_chat_mock "github.com/lookingcoolonavespa/go_crochess_backend/src/services/chat/repository/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestChatUseCase_Send(t *testing.T) {
+	now := time.Date(2023, time.October, 10, 2, 10, 10, 10, time.UTC)
+	timeNow = func() time.Time {
+		return now
+	}
+
+	t.Run("Saves messages on the sender's channel", func(t *testing.T) {
+		mockRepo := new(repository_chat_mock.ChatMockRepo)
+		mockRepo.On("Insert", context.Background(), domain.ChatMessage{
+			GameID:    3,
+			Channel:   domain.ChatChannelPlayers,
+			SenderID:  "1",
+			Body:      "good luck",
+			CreatedAt: now.UnixMilli(),
+		}).Return(9, nil).Once()
+
+		u := NewChatUseCase(mockRepo)
+
+		m, err := u.Send(context.Background(), 3, "1", true, "  good luck ")
+		assert.NoError(t, err)
+		assert.Equal(t, 9, m.ID)
+		assert.Equal(t, domain.ChatChannelPlayers, m.Channel)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Rejects empty and long messages", func(t *testing.T) {
+		mockRepo := new(repository_chat_mock.ChatMockRepo)
+		u := NewChatUseCase(mockRepo)
+
+		_, err := u.Send(context.Background(), 3, "1", true, "   ")
+		assert.ErrorIs(t, err, domain.ErrChatMessageEmpty)
+
+		_, err = u.Send(context.Background(), 3, "1", true, strings.Repeat("a", domain.ChatMessageMaxLength+1))
+		assert.ErrorIs(t, err, domain.ErrChatMessageTooLong)
+
+		mockRepo.AssertNotCalled(t, "Insert")
+	})
+
+	t.Run("Limits how fast a client can send", func(t *testing.T) {
+		mockRepo := new(repository_chat_mock.ChatMockRepo)
+		mockRepo.On("Insert", context.Background(), mock.Anything).Return(1, nil)
+		u := NewChatUseCase(mockRepo)
+
+		for i := 0; i < floodLimit; i++ {
+			_, err := u.Send(context.Background(), 3, "4", false, "hi")
+			assert.NoError(t, err)
+		}
+
+		_, err := u.Send(context.Background(), 3, "4", false, "hi")
+		assert.ErrorIs(t, err, domain.ErrChatRateLimited)
+
+		_, err = u.Send(context.Background(), 3, "5", false, "hi")
+		assert.NoError(t, err)
+
+		now = now.Add(floodWindow)
+		_, err = u.Send(context.Background(), 3, "4", false, "hi")
+		assert.NoError(t, err)
+	})
+}
+
+func TestChatUseCase_History(t *testing.T) {
+	messages := []domain.ChatMessage{
+		{ID: 1, GameID: 3, Channel: domain.ChatChannelPlayers, SenderID: "1", Body: "good luck"},
+		{ID: 2, GameID: 3, Channel: domain.ChatChannelSpectators, SenderID: "4", Body: "hi"},
+	}
+
+	mockRepo := new(repository_chat_mock.ChatMockRepo)
+	mockRepo.On("ListRecent", context.Background(), 3, domain.ChatHistoryLength).Return(messages, nil)
+	u := NewChatUseCase(mockRepo)
+
+	t.Run("Spectators only read the spectators channel", func(t *testing.T) {
+		history, err := u.History(context.Background(), 3, "4", false)
+		assert.NoError(t, err)
+		assert.Equal(t, messages[1:], history)
+	})
+
+	t.Run("Players read both channels", func(t *testing.T) {
+		history, err := u.History(context.Background(), 3, "1", true)
+		assert.NoError(t, err)
+		assert.Equal(t, messages, history)
+	})
+
+	t.Run("Players that muted the spectators only read the players channel", func(t *testing.T) {
+		u.SetSpectatorsMuted(3, "1", true)
+
+		history, err := u.History(context.Background(), 3, "1", true)
+		assert.NoError(t, err)
+		assert.Equal(t, messages[:1], history)
+		assert.True(t, u.CanRead(3, "2", true, domain.ChatChannelSpectators))
+
+		u.SetSpectatorsMuted(3, "1", false)
+		assert.True(t, u.CanRead(3, "1", true, domain.ChatChannelSpectators))
+	})
+}
@@ -0,0 +1,177 @@
+package usecase_chat
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+)
+
+type lobbyChatUseCase struct {
+	chatRepo    domain.ChatRepo
+	moderators  map[string]bool
+	bannedWords map[string]bool
+	limiter     *floodLimiter
+	sanctions   *sanctions
+}
+
+// sanctions are the clients moderators timed out, with when their timeout
+// ends, and the clients they shadow muted
+type sanctions struct {
+	mutex       sync.Mutex
+	timeouts    map[string]time.Time
+	shadowMuted map[string]bool
+}
+
+func NewLobbyChatUseCase(
+	chatRepo domain.ChatRepo,
+	moderators []string,
+	bannedWords []string,
+) lobbyChatUseCase {
+	moderatorSet := make(map[string]bool, len(moderators))
+	for _, id := range moderators {
+		moderatorSet[id] = true
+	}
+
+	bannedWordSet := make(map[string]bool, len(bannedWords))
+	for _, word := range bannedWords {
+		bannedWordSet[strings.ToLower(word)] = true
+	}
+
+	return lobbyChatUseCase{
+		chatRepo,
+		moderatorSet,
+		bannedWordSet,
+		newFloodLimiter(floodLimit, floodWindow),
+		&sanctions{
+			timeouts:    make(map[string]time.Time),
+			shadowMuted: make(map[string]bool),
+		},
+	}
+}
+
+func (c lobbyChatUseCase) Send(ctx context.Context, senderID string, body string) (domain.ChatMessage, error) {
+	body, err := trimBody(body)
+	if err != nil {
+		return domain.ChatMessage{}, err
+	}
+	if c.containsBannedWords(body) {
+		return domain.ChatMessage{}, domain.ErrChatBannedWords
+	}
+
+	now := timeNow()
+
+	c.sanctions.mutex.Lock()
+	timedOutUntil, timedOut := c.sanctions.timeouts[senderID]
+	if timedOut && !now.Before(timedOutUntil) {
+		delete(c.sanctions.timeouts, senderID)
+		timedOut = false
+	}
+	hidden := c.sanctions.shadowMuted[senderID]
+	c.sanctions.mutex.Unlock()
+
+	if timedOut {
+		return domain.ChatMessage{}, domain.ErrChatTimedOut
+	}
+	if !c.limiter.allow(senderID, now) {
+		return domain.ChatMessage{}, domain.ErrChatRateLimited
+	}
+
+	m := domain.ChatMessage{
+		Channel:   domain.ChatChannelLobby,
+		SenderID:  senderID,
+		Body:      body,
+		CreatedAt: now.UnixMilli(),
+		Hidden:    hidden,
+	}
+
+	id, err := c.chatRepo.Insert(ctx, m)
+	if err != nil {
+		return domain.ChatMessage{}, err
+	}
+	m.ID = id
+
+	return m, nil
+}
+
+func (c lobbyChatUseCase) containsBannedWords(body string) bool {
+	if len(c.bannedWords) == 0 {
+		return false
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(body), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	for _, word := range words {
+		if c.bannedWords[word] {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c lobbyChatUseCase) History(ctx context.Context, viewerID string) ([]domain.ChatMessage, error) {
+	return c.chatRepo.ListLobby(ctx, viewerID, domain.ChatHistoryLength)
+}
+
+func (c lobbyChatUseCase) Moderate(
+	ctx context.Context,
+	action domain.ModerationAction,
+) (domain.ModerationAction, error) {
+	if !c.moderators[action.ModeratorID] {
+		return domain.ModerationAction{}, domain.ErrNotModerator
+	}
+
+	now := timeNow()
+	action.CreatedAt = now.UnixMilli()
+
+	switch action.Action {
+	case domain.ModerationTimeout:
+		if action.TargetID == "" || action.Duration <= 0 {
+			return domain.ModerationAction{}, domain.ErrInvalidModeration
+		}
+		action.MessageID = 0
+	case domain.ModerationShadowMute, domain.ModerationUnmute:
+		if action.TargetID == "" {
+			return domain.ModerationAction{}, domain.ErrInvalidModeration
+		}
+		action.MessageID = 0
+		action.Duration = 0
+	case domain.ModerationDelete:
+		if action.MessageID == 0 {
+			return domain.ModerationAction{}, domain.ErrInvalidModeration
+		}
+		action.TargetID = ""
+		action.Duration = 0
+
+		err := c.chatRepo.Delete(ctx, action.MessageID)
+		if err != nil {
+			return domain.ModerationAction{}, err
+		}
+	default:
+		return domain.ModerationAction{}, domain.ErrInvalidModeration
+	}
+
+	err := c.chatRepo.InsertModeration(ctx, action)
+	if err != nil {
+		return domain.ModerationAction{}, err
+	}
+
+	c.sanctions.mutex.Lock()
+	defer c.sanctions.mutex.Unlock()
+	switch action.Action {
+	case domain.ModerationTimeout:
+		c.sanctions.timeouts[action.TargetID] = now.Add(time.Duration(action.Duration) * time.Millisecond)
+	case domain.ModerationShadowMute:
+		c.sanctions.shadowMuted[action.TargetID] = true
+	case domain.ModerationUnmute:
+		delete(c.sanctions.timeouts, action.TargetID)
+		delete(c.sanctions.shadowMuted, action.TargetID)
+	}
+
+	return action, nil
+}
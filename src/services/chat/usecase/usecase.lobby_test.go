@@ -0,0 +1,162 @@
+package usecase_chat
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	repository_chat_mock "github.com/lookingcoolonavespa/go_crochess_backend/src/services/chat/repository/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestLobbyChatUseCase_Send(t *testing.T) {
+	now := time.Date(2023, time.October, 10, 2, 10, 10, 10, time.UTC)
+	timeNow = func() time.Time {
+		return now
+	}
+
+	t.Run("Rejects messages with banned words", func(t *testing.T) {
+		mockRepo := new(repository_chat_mock.ChatMockRepo)
+		u := NewLobbyChatUseCase(mockRepo, nil, []string{"Noob"})
+
+		_, err := u.Send(context.Background(), "1", "what a NOOB!")
+		assert.ErrorIs(t, err, domain.ErrChatBannedWords)
+
+		mockRepo.AssertNotCalled(t, "Insert")
+	})
+
+	t.Run("Saves lobby messages", func(t *testing.T) {
+		mockRepo := new(repository_chat_mock.ChatMockRepo)
+		mockRepo.On("Insert", context.Background(), domain.ChatMessage{
+			Channel:   domain.ChatChannelLobby,
+			SenderID:  "1",
+			Body:      "noobs welcome",
+			CreatedAt: now.UnixMilli(),
+		}).Return(3, nil).Once()
+		u := NewLobbyChatUseCase(mockRepo, nil, []string{"noob"})
+
+		m, err := u.Send(context.Background(), "1", "noobs welcome")
+		assert.NoError(t, err)
+		assert.Equal(t, 3, m.ID)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestLobbyChatUseCase_Moderate(t *testing.T) {
+	now := time.Date(2023, time.October, 10, 2, 10, 10, 10, time.UTC)
+	timeNow = func() time.Time {
+		return now
+	}
+
+	t.Run("Only moderators can moderate", func(t *testing.T) {
+		mockRepo := new(repository_chat_mock.ChatMockRepo)
+		u := NewLobbyChatUseCase(mockRepo, []string{"mod"}, nil)
+
+		_, err := u.Moderate(context.Background(), domain.ModerationAction{
+			ModeratorID: "1",
+			Action:      domain.ModerationShadowMute,
+			TargetID:    "2",
+		})
+		assert.ErrorIs(t, err, domain.ErrNotModerator)
+
+		mockRepo.AssertNotCalled(t, "InsertModeration")
+	})
+
+	t.Run("Timed out clients can't send until the timeout ends", func(t *testing.T) {
+		mockRepo := new(repository_chat_mock.ChatMockRepo)
+		mockRepo.On("InsertModeration", context.Background(), domain.ModerationAction{
+			ModeratorID: "mod",
+			Action:      domain.ModerationTimeout,
+			TargetID:    "2",
+			Duration:    60000,
+			CreatedAt:   now.UnixMilli(),
+		}).Return(nil).Once()
+		mockRepo.On("Insert", context.Background(), mock.Anything).Return(1, nil)
+		u := NewLobbyChatUseCase(mockRepo, []string{"mod"}, nil)
+
+		_, err := u.Moderate(context.Background(), domain.ModerationAction{
+			ModeratorID: "mod",
+			Action:      domain.ModerationTimeout,
+			TargetID:    "2",
+			Duration:    60000,
+		})
+		assert.NoError(t, err)
+
+		_, err = u.Send(context.Background(), "2", "hi")
+		assert.ErrorIs(t, err, domain.ErrChatTimedOut)
+
+		now = now.Add(time.Minute)
+		_, err = u.Send(context.Background(), "2", "hi")
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Shadow muted clients' messages are hidden", func(t *testing.T) {
+		mockRepo := new(repository_chat_mock.ChatMockRepo)
+		mockRepo.On("InsertModeration", context.Background(), mock.Anything).Return(nil).Twice()
+		mockRepo.On("Insert", context.Background(), mock.MatchedBy(func(m domain.ChatMessage) bool {
+			return m.Hidden
+		})).Return(1, nil).Once()
+		mockRepo.On("Insert", context.Background(), mock.MatchedBy(func(m domain.ChatMessage) bool {
+			return !m.Hidden
+		})).Return(2, nil).Once()
+		u := NewLobbyChatUseCase(mockRepo, []string{"mod"}, nil)
+
+		_, err := u.Moderate(context.Background(), domain.ModerationAction{
+			ModeratorID: "mod",
+			Action:      domain.ModerationShadowMute,
+			TargetID:    "2",
+		})
+		assert.NoError(t, err)
+
+		m, err := u.Send(context.Background(), "2", "hi")
+		assert.NoError(t, err)
+		assert.True(t, m.Hidden)
+
+		_, err = u.Moderate(context.Background(), domain.ModerationAction{
+			ModeratorID: "mod",
+			Action:      domain.ModerationUnmute,
+			TargetID:    "2",
+		})
+		assert.NoError(t, err)
+
+		m, err = u.Send(context.Background(), "2", "hi")
+		assert.NoError(t, err)
+		assert.False(t, m.Hidden)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Deletes messages", func(t *testing.T) {
+		mockRepo := new(repository_chat_mock.ChatMockRepo)
+		mockRepo.On("Delete", context.Background(), 7).Return(nil).Once()
+		mockRepo.On("InsertModeration", context.Background(), domain.ModerationAction{
+			ModeratorID: "mod",
+			Action:      domain.ModerationDelete,
+			MessageID:   7,
+			CreatedAt:   now.UnixMilli(),
+		}).Return(nil).Once()
+		u := NewLobbyChatUseCase(mockRepo, []string{"mod"}, nil)
+
+		_, err := u.Moderate(context.Background(), domain.ModerationAction{
+			ModeratorID: "mod",
+			Action:      domain.ModerationDelete,
+			MessageID:   7,
+			TargetID:    "ignored",
+		})
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Rejects unknown actions", func(t *testing.T) {
+		u := NewLobbyChatUseCase(new(repository_chat_mock.ChatMockRepo), []string{"mod"}, nil)
+
+		_, err := u.Moderate(context.Background(), domain.ModerationAction{
+			ModeratorID: "mod",
+			Action:      "ban",
+			TargetID:    "2",
+		})
+		assert.ErrorIs(t, err, domain.ErrInvalidModeration)
+	})
+}
@@ -0,0 +1,95 @@
+package services_engine
+
+import (
+	"errors"
+	"log"
+	"sync"
+
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/uci"
+)
+
+const defaultDepth = 16
+
+// UCIEngine runs searches on a local UCI engine such as stockfish. Searches
+// are serialized since the engine process can only run one at a time.
+type UCIEngine struct {
+	engine *uci.Engine
+	depth  int
+	mutex  sync.Mutex
+}
+
+// NewUCIEngine starts the engine at path. Searches stop at depth, or at
+// defaultDepth when depth isn't positive.
+func NewUCIEngine(path string, depth int) (*UCIEngine, error) {
+	if depth <= 0 {
+		depth = defaultDepth
+	}
+
+	engine, err := uci.New(path)
+	if err != nil {
+		return nil, err
+	}
+
+	err = engine.Run(uci.CmdUCI, uci.CmdIsReady, uci.CmdUCINewGame)
+	if err != nil {
+		engine.Close()
+		return nil, err
+	}
+
+	return &UCIEngine{
+		engine: engine,
+		depth:  depth,
+	}, nil
+}
+
+func (e *UCIEngine) Analyse(fen string, searchMoves []string) (domain.EngineAnalysis, error) {
+	fenOpt, err := chess.FEN(fen)
+	if err != nil {
+		return domain.EngineAnalysis{}, err
+	}
+	position := chess.NewGame(fenOpt).Position()
+
+	goCmd := uci.CmdGo{Depth: e.depth}
+	for _, s := range searchMoves {
+		m, err := chess.UCINotation{}.Decode(position, s)
+		if err != nil {
+			return domain.EngineAnalysis{}, err
+		}
+		goCmd.SearchMoves = append(goCmd.SearchMoves, m)
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	err = e.engine.Run(uci.CmdPosition{Position: position}, goCmd)
+	if err != nil {
+		log.Printf("Engine/UCI/Analyse, error running search: %v", err)
+		return domain.EngineAnalysis{}, err
+	}
+
+	results := e.engine.SearchResults()
+	if results.BestMove == nil {
+		return domain.EngineAnalysis{}, errors.New("engine did not return a move")
+	}
+
+	pv := make([]string, len(results.Info.PV))
+	for i, m := range results.Info.PV {
+		pv[i] = m.String()
+	}
+
+	return domain.EngineAnalysis{
+		BestMove: results.BestMove.String(),
+		PV:       pv,
+		CP:       results.Info.Score.CP,
+		Mate:     results.Info.Score.Mate,
+	}, nil
+}
+
+func (e *UCIEngine) Close() error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.engine.Close()
+}
@@ -0,0 +1,57 @@
+package delivery_http_explorer
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	"github.com/notnil/chess"
+)
+
+type ExplorerHandler struct {
+	usecase domain.ExplorerUseCase
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func NewExplorerHandler(usecase domain.ExplorerUseCase) ExplorerHandler {
+	return ExplorerHandler{
+		usecase,
+	}
+}
+
+func (e ExplorerHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/explorer", e.HandlerGetPosition)
+}
+
+// HandlerGetPosition returns the moves played from the position in the "fen"
+// query parameter, defaulting to the starting position.
+func (e ExplorerHandler) HandlerGetPosition(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	fen := r.URL.Query().Get("fen")
+	if fen == "" {
+		fen = chess.StartingPosition().String()
+	} else if _, err := chess.FEN(fen); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{"fen is not valid"})
+		return
+	}
+
+	position, err := e.usecase.Get(r.Context(), fen)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, errorResponse{"there was an error retrieving the position"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, position)
+}
+
+func writeJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		log.Printf("Handler/HTTP/Explorer/writeJSON, error encoding response: %v\n", err)
+	}
+}
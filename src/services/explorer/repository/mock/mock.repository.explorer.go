@@ -0,0 +1,31 @@
+package repository_explorer_mock
+
+import (
+	"context"
+
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	"github.com/stretchr/testify/mock"
+)
+
+type ExplorerMockRepo struct {
+	mock.Mock
+}
+
+func (c *ExplorerMockRepo) Get(ctx context.Context, fen string) ([]domain.ExplorerMove, error) {
+	args := c.Called(ctx, fen)
+	result := args.Get(0)
+
+	return result.([]domain.ExplorerMove), args.Error(1)
+}
+
+func (c *ExplorerMockRepo) Add(ctx context.Context, moves []domain.ExplorerMove) error {
+	args := c.Called(ctx, moves)
+
+	return args.Error(0)
+}
+
+func (c *ExplorerMockRepo) Replace(ctx context.Context, moves []domain.ExplorerMove) error {
+	args := c.Called(ctx, moves)
+
+	return args.Error(0)
+}
@@ -0,0 +1,133 @@
+package repository_explorer
+
+import (
+	"context"
+	"database/sql"
+	"log"
+
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+)
+
+type explorerRepo struct {
+	db *sql.DB
+}
+
+func NewExplorerRepo(db *sql.DB) explorerRepo {
+	return explorerRepo{db}
+}
+
+const upsertStmt = `
+    INSERT INTO explorer (
+        fen,
+        uci,
+        san,
+        games,
+        white_wins,
+        draws,
+        black_wins
+    ) VALUES (
+        $1, $2, $3, $4, $5, $6, $7
+    ) ON CONFLICT (fen, uci) DO UPDATE SET
+        games = explorer.games + EXCLUDED.games,
+        white_wins = explorer.white_wins + EXCLUDED.white_wins,
+        draws = explorer.draws + EXCLUDED.draws,
+        black_wins = explorer.black_wins + EXCLUDED.black_wins`
+
+func (c explorerRepo) Get(ctx context.Context, fen string) ([]domain.ExplorerMove, error) {
+	query := `
+    SELECT fen, uci, san, games, white_wins, draws, black_wins
+    FROM explorer
+    WHERE fen = $1
+    ORDER BY games DESC`
+
+	rows, err := c.db.QueryContext(ctx, query, fen)
+	if err != nil {
+		log.Printf("Repo/Explorer/Get, error getting moves: %v\n", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	moves := make([]domain.ExplorerMove, 0)
+	for rows.Next() {
+		var m domain.ExplorerMove
+		err := rows.Scan(
+			&m.FEN,
+			&m.UCI,
+			&m.SAN,
+			&m.Games,
+			&m.WhiteWins,
+			&m.Draws,
+			&m.BlackWins,
+		)
+		if err != nil {
+			log.Printf("Repo/Explorer/Get, error scanning move: %v\n", err)
+			return nil, err
+		}
+		moves = append(moves, m)
+	}
+
+	return moves, rows.Err()
+}
+
+func (c explorerRepo) Add(ctx context.Context, moves []domain.ExplorerMove) error {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("Repo/Explorer/Add, error starting transaction: %v\n", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := upsert(ctx, tx, moves); err != nil {
+		log.Printf("Repo/Explorer/Add, error saving moves: %v\n", err)
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (c explorerRepo) Replace(ctx context.Context, moves []domain.ExplorerMove) error {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("Repo/Explorer/Replace, error starting transaction: %v\n", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM explorer"); err != nil {
+		log.Printf("Repo/Explorer/Replace, error clearing explorer: %v\n", err)
+		return err
+	}
+
+	if err := upsert(ctx, tx, moves); err != nil {
+		log.Printf("Repo/Explorer/Replace, error saving moves: %v\n", err)
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func upsert(ctx context.Context, tx *sql.Tx, moves []domain.ExplorerMove) error {
+	stmt, err := tx.PrepareContext(ctx, upsertStmt)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, m := range moves {
+		_, err := stmt.ExecContext(
+			ctx,
+			m.FEN,
+			m.UCI,
+			m.SAN,
+			m.Games,
+			m.WhiteWins,
+			m.Draws,
+			m.BlackWins,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
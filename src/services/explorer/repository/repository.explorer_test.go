@@ -0,0 +1,48 @@
+package repository_explorer
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func initMock() (*sql.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		log.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	return db, mock
+}
+
+func TestExplorerRepo_Replace(t *testing.T) {
+	db, mock := initMock()
+
+	defer db.Close()
+
+	moves := []domain.ExplorerMove{
+		{FEN: "fen", UCI: "e2e4", SAN: "e4", Games: 3, WhiteWins: 1, Draws: 1, BlackWins: 1},
+		{FEN: "fen", UCI: "d2d4", SAN: "d4", Games: 1, WhiteWins: 1},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM explorer").WillReturnResult(sqlmock.NewResult(0, 10))
+	prepared := mock.ExpectPrepare(upsertStmt)
+	for _, m := range moves {
+		prepared.ExpectExec().
+			WithArgs(m.FEN, m.UCI, m.SAN, m.Games, m.WhiteWins, m.Draws, m.BlackWins).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+	}
+	mock.ExpectCommit()
+
+	r := NewExplorerRepo(db)
+
+	err := r.Replace(context.Background(), moves)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
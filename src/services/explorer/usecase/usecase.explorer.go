@@ -0,0 +1,167 @@
+package usecase_explorer
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	"github.com/notnil/chess"
+)
+
+const rebuildPageSize = 500
+
+type explorerUseCase struct {
+	explorerRepo domain.ExplorerRepo
+	gameRepo     domain.GameRepo
+}
+
+type moveKey struct {
+	fen string
+	uci string
+}
+
+func NewExplorerUseCase(
+	explorerRepo domain.ExplorerRepo,
+	gameRepo domain.GameRepo,
+) explorerUseCase {
+	return explorerUseCase{
+		explorerRepo,
+		gameRepo,
+	}
+}
+
+func (c explorerUseCase) Get(ctx context.Context, fen string) (domain.ExplorerPosition, error) {
+	fen = domain.NormalizeFEN(fen)
+
+	moves, err := c.explorerRepo.Get(ctx, fen)
+	if err != nil {
+		return domain.ExplorerPosition{}, err
+	}
+
+	return domain.ExplorerPosition{FEN: fen, Moves: moves}, nil
+}
+
+func (c explorerUseCase) OnGameOver(ctx context.Context, g domain.Game) error {
+	moves, err := indexGame(g)
+	if err != nil {
+		return err
+	}
+	if len(moves) == 0 {
+		return nil
+	}
+
+	return c.explorerRepo.Add(ctx, moves)
+}
+
+// Rebuild replaces the explorer with an index of every finished game in the
+// game table.
+func (c explorerUseCase) Rebuild(ctx context.Context) (indexedGames int, err error) {
+	index := make(map[moveKey]*domain.ExplorerMove)
+	order := make([]moveKey, 0)
+
+	filter := domain.GameFilter{Finished: true, Limit: rebuildPageSize}
+	for {
+		games, err := c.gameRepo.List(ctx, filter)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, g := range games {
+			moves, err := indexGame(g)
+			if err != nil {
+				log.Printf("Usecase/Explorer/Rebuild, skipping game %d: %v", g.ID, err)
+				continue
+			}
+			if len(moves) > 0 {
+				indexedGames++
+			}
+
+			for _, m := range moves {
+				key := moveKey{m.FEN, m.UCI}
+				existing, ok := index[key]
+				if !ok {
+					m := m
+					index[key] = &m
+					order = append(order, key)
+					continue
+				}
+				existing.Games += m.Games
+				existing.WhiteWins += m.WhiteWins
+				existing.Draws += m.Draws
+				existing.BlackWins += m.BlackWins
+			}
+		}
+
+		if len(games) < filter.Limit {
+			break
+		}
+		filter.After = games[len(games)-1].ID
+	}
+
+	moves := make([]domain.ExplorerMove, len(order))
+	for i, key := range order {
+		moves[i] = *index[key]
+	}
+
+	err = c.explorerRepo.Replace(ctx, moves)
+	if err != nil {
+		return 0, err
+	}
+
+	return indexedGames, nil
+}
+
+// indexGame returns one explorer entry per position and move of g. Games
+// without a decisive or drawn result are not indexed.
+func indexGame(g domain.Game) ([]domain.ExplorerMove, error) {
+	// the boards of game groups like bughouse aren't standard chess either
+	if !g.IsStandard() || g.GroupID != 0 {
+		return nil, nil
+	}
+
+	var white, draw, black int
+	switch g.Result {
+	case chess.WhiteWon.String():
+		white = 1
+	case chess.Draw.String():
+		draw = 1
+	case chess.BlackWon.String():
+		black = 1
+	default:
+		return nil, nil
+	}
+
+	gameState := chess.NewGame(chess.UseNotation(chess.UCINotation{}))
+	seen := make(map[moveKey]bool)
+	moves := make([]domain.ExplorerMove, 0)
+
+	for _, uci := range strings.Fields(g.Moves) {
+		position := gameState.Position()
+		m, err := chess.UCINotation{}.Decode(position, uci)
+		if err != nil {
+			return nil, err
+		}
+
+		key := moveKey{domain.NormalizeFEN(position.String()), uci}
+		// a position repeated in the same game still counts as one game
+		if !seen[key] {
+			seen[key] = true
+			moves = append(moves, domain.ExplorerMove{
+				FEN:       key.fen,
+				UCI:       uci,
+				SAN:       chess.AlgebraicNotation{}.Encode(position, m),
+				Games:     1,
+				WhiteWins: white,
+				Draws:     draw,
+				BlackWins: black,
+			})
+		}
+
+		if err := gameState.Move(m); err != nil {
+			return nil, err
+		}
+	}
+
+	return moves, nil
+}
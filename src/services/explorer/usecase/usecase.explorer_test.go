@@ -0,0 +1,123 @@
+package usecase_explorer
+
+import (
+	"context"
+	"testing"
+
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	repository_explorer_mock "github.com/lookingcoolonavespa/go_crochess_backend/src/services/explorer/repository/mock"
+	repository_game_mock "github.com/lookingcoolonavespa/go_crochess_backend/src/services/game/repository/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const startingFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -"
+
+func TestIndexGame(t *testing.T) {
+	t.Run("Indexes every move of a finished game", func(t *testing.T) {
+		moves, err := indexGame(domain.Game{Moves: "e2e4 e7e5", Result: "0-1"})
+		assert.NoError(t, err)
+
+		assert.Equal(t, []domain.ExplorerMove{
+			{
+				FEN:       startingFEN,
+				UCI:       "e2e4",
+				SAN:       "e4",
+				Games:     1,
+				BlackWins: 1,
+			},
+			{
+				FEN:       "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3",
+				UCI:       "e7e5",
+				SAN:       "e5",
+				Games:     1,
+				BlackWins: 1,
+			},
+		}, moves)
+	})
+
+	t.Run("Counts repeated positions once", func(t *testing.T) {
+		moves, err := indexGame(domain.Game{
+			Moves:  "g1f3 g8f6 f3g1 f6g8 g1f3",
+			Result: "1/2-1/2",
+		})
+		assert.NoError(t, err)
+
+		assert.Len(t, moves, 4)
+		for _, m := range moves {
+			assert.Equal(t, 1, m.Draws)
+		}
+	})
+
+	t.Run("Skips unfinished games", func(t *testing.T) {
+		moves, err := indexGame(domain.Game{Moves: "e2e4"})
+		assert.NoError(t, err)
+		assert.Empty(t, moves)
+	})
+
+	t.Run("Skips variants", func(t *testing.T) {
+		moves, err := indexGame(domain.Game{Moves: "e2e4 d7d5 e4d5 d8d5 P@e4", Result: "1-0", Variant: domain.GameVariantCrazyhouse})
+		assert.NoError(t, err)
+		assert.Empty(t, moves)
+	})
+
+	t.Run("Fails on illegal moves", func(t *testing.T) {
+		_, err := indexGame(domain.Game{Moves: "e2e5", Result: "1-0"})
+		assert.Error(t, err)
+	})
+}
+
+func TestExplorerUseCase_Rebuild(t *testing.T) {
+	mockExplorerRepo := new(repository_explorer_mock.ExplorerMockRepo)
+	mockGameRepo := new(repository_game_mock.GameMockRepo)
+
+	firstPage := make([]domain.Game, rebuildPageSize)
+	for i := range firstPage {
+		firstPage[i] = domain.Game{ID: 1000 - i, Moves: "e2e4", Result: "1-0"}
+	}
+
+	mockGameRepo.On("List", context.Background(), domain.GameFilter{
+		Finished: true,
+		Limit:    rebuildPageSize,
+	}).Return(firstPage, nil).Once()
+	mockGameRepo.On("List", context.Background(), domain.GameFilter{
+		Finished: true,
+		Limit:    rebuildPageSize,
+		After:    firstPage[rebuildPageSize-1].ID,
+	}).Return([]domain.Game{{ID: 3, Moves: "e2e4", Result: "1/2-1/2"}}, nil).Once()
+
+	mockExplorerRepo.On("Replace", context.Background(), []domain.ExplorerMove{
+		{
+			FEN:       startingFEN,
+			UCI:       "e2e4",
+			SAN:       "e4",
+			Games:     rebuildPageSize + 1,
+			WhiteWins: rebuildPageSize,
+			Draws:     1,
+		},
+	}).Return(nil).Once()
+
+	u := NewExplorerUseCase(mockExplorerRepo, mockGameRepo)
+
+	indexedGames, err := u.Rebuild(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, rebuildPageSize+1, indexedGames)
+
+	mockGameRepo.AssertExpectations(t)
+	mockExplorerRepo.AssertExpectations(t)
+}
+
+func TestExplorerUseCase_Get(t *testing.T) {
+	mockExplorerRepo := new(repository_explorer_mock.ExplorerMockRepo)
+	mockExplorerRepo.On("Get", mock.Anything, startingFEN).
+		Return([]domain.ExplorerMove{}, nil).
+		Once()
+
+	u := NewExplorerUseCase(mockExplorerRepo, nil)
+
+	position, err := u.Get(context.Background(), startingFEN+" 0 1")
+	assert.NoError(t, err)
+	assert.Equal(t, startingFEN, position.FEN)
+
+	mockExplorerRepo.AssertExpectations(t)
+}
@@ -0,0 +1,196 @@
+package delivery_http_game
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+)
+
+type GameHandler struct {
+	repo domain.GameRepo
+}
+
+type gameWithMoves struct {
+	domain.Game
+	History []domain.GameMove `json:"history"`
+}
+
+type gamesPage struct {
+	Games []domain.Game `json:"games"`
+	// Next is the cursor for the following page and is omitted on the last page
+	Next *int `json:"next,omitempty"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func NewGameHandler(repo domain.GameRepo) GameHandler {
+	return GameHandler{
+		repo,
+	}
+}
+
+func (g GameHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/games/:id", g.HandlerGetGame)
+	router.GET("/api/players/:id/games", g.HandlerListGames)
+	router.GET("/api/players/:id/stats", g.HandlerGetStats)
+}
+
+func (g GameHandler) HandlerGetGame(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	gameID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "game id must be an integer")
+		return
+	}
+
+	game, err := g.repo.Get(r.Context(), gameID)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("game %d not found", gameID))
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "there was an error retrieving the game")
+		return
+	}
+
+	moves, err := g.repo.ListMoves(r.Context(), gameID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "there was an error retrieving the game's moves")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, gameWithMoves{game, moves})
+}
+
+func (g GameHandler) HandlerListGames(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	filter, err := parseGameFilter(ps.ByName("id"), r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	games, err := g.repo.List(r.Context(), filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "there was an error retrieving games")
+		return
+	}
+
+	page := gamesPage{Games: games}
+	if len(games) == filter.Limit {
+		next := games[len(games)-1].ID
+		page.Next = &next
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (g GameHandler) HandlerGetStats(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	stats, err := g.repo.Stats(r.Context(), ps.ByName("id"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "there was an error retrieving stats")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, stats)
+}
+
+func parseGameFilter(playerID string, r *http.Request) (domain.GameFilter, error) {
+	query := r.URL.Query()
+	filter := domain.GameFilter{
+		PlayerID: playerID,
+		Opponent: query.Get("opponent"),
+		Eco:      strings.ToUpper(query.Get("eco")),
+		Limit:    defaultLimit,
+	}
+
+	switch result := query.Get("result"); result {
+	case "", domain.GameResultWin, domain.GameResultLoss, domain.GameResultDraw:
+		filter.Result = result
+	default:
+		return domain.GameFilter{}, fmt.Errorf(`"%s" is not a valid result`, result)
+	}
+
+	switch color := domain.Color(query.Get("color")); color {
+	case "", domain.White, domain.Black:
+		filter.Color = color
+	default:
+		return domain.GameFilter{}, fmt.Errorf(`"%s" is not a valid color`, color)
+	}
+
+	parseInt := func(name string) (*int, error) {
+		value := query.Get(name)
+		if value == "" {
+			return nil, nil
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("%s must be a positive integer", name)
+		}
+		return &n, nil
+	}
+
+	var err error
+	if filter.Time, err = parseInt("time"); err != nil {
+		return domain.GameFilter{}, err
+	}
+	if filter.Increment, err = parseInt("increment"); err != nil {
+		return domain.GameFilter{}, err
+	}
+
+	for name, dest := range map[string]*int64{"from": &filter.From, "to": &filter.To} {
+		n, err := parseInt(name)
+		if err != nil {
+			return domain.GameFilter{}, err
+		}
+		if n != nil {
+			*dest = int64(*n)
+		}
+	}
+
+	after, err := parseInt("after")
+	if err != nil {
+		return domain.GameFilter{}, err
+	}
+	if after != nil {
+		filter.After = *after
+	}
+
+	limit, err := parseInt("limit")
+	if err != nil {
+		return domain.GameFilter{}, err
+	}
+	if limit != nil {
+		if *limit == 0 || *limit > maxLimit {
+			return domain.GameFilter{}, errors.New(fmt.Sprintf("limit must be between 1 and %d", maxLimit))
+		}
+		filter.Limit = *limit
+	}
+
+	return filter, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		log.Printf("Handler/HTTP/Game/writeJSON, error encoding response: %v\n", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, errorResponse{message})
+}
@@ -0,0 +1,139 @@
+package delivery_http_game
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	repository_game_mock "github.com/lookingcoolonavespa/go_crochess_backend/src/services/game/repository/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupRouter(repo domain.GameRepo) *httprouter.Router {
+	router := httprouter.New()
+	NewGameHandler(repo).RegisterRoutes(router)
+
+	return router
+}
+
+func TestGameHandler_HandlerListGames(t *testing.T) {
+	t.Run("Success with filters and next cursor", func(t *testing.T) {
+		mockRepo := new(repository_game_mock.GameMockRepo)
+
+		gameTime := 300000
+		increment := 0
+		filter := domain.GameFilter{
+			PlayerID:  "4",
+			Result:    domain.GameResultDraw,
+			Color:     domain.Black,
+			Time:      &gameTime,
+			Increment: &increment,
+			From:      1000,
+			Opponent:  "5",
+			After:     90,
+			Limit:     2,
+		}
+		games := []domain.Game{{ID: 89}, {ID: 85}}
+		mockRepo.On("List", mock.Anything, filter).Return(games, nil).Once()
+
+		req := httptest.NewRequest(
+			http.MethodGet,
+			"/api/players/4/games?result=draw&color=black&time=300000&increment=0&from=1000&opponent=5&after=90&limit=2",
+			nil,
+		)
+		rec := httptest.NewRecorder()
+		setupRouter(mockRepo).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var page gamesPage
+		err := json.Unmarshal(rec.Body.Bytes(), &page)
+		assert.NoError(t, err)
+		assert.Len(t, page.Games, 2)
+		if assert.NotNil(t, page.Next) {
+			assert.Equal(t, 85, *page.Next)
+		}
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Last page has no cursor", func(t *testing.T) {
+		mockRepo := new(repository_game_mock.GameMockRepo)
+		mockRepo.On("List", mock.Anything, domain.GameFilter{PlayerID: "4", Limit: defaultLimit}).
+			Return([]domain.Game{{ID: 3}}, nil).
+			Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/api/players/4/games", nil)
+		rec := httptest.NewRecorder()
+		setupRouter(mockRepo).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.NotContains(t, rec.Body.String(), "next")
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Rejects invalid filters", func(t *testing.T) {
+		mockRepo := new(repository_game_mock.GameMockRepo)
+
+		for _, query := range []string{"result=won", "color=red", "time=abc", "limit=1000"} {
+			req := httptest.NewRequest(http.MethodGet, "/api/players/4/games?"+query, nil)
+			rec := httptest.NewRecorder()
+			setupRouter(mockRepo).ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+		}
+
+		mockRepo.AssertNotCalled(t, "List", mock.Anything, mock.Anything)
+	})
+}
+
+func TestGameHandler_HandlerGetGame(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		mockRepo := new(repository_game_mock.GameMockRepo)
+		mockRepo.On("Get", mock.Anything, 7).Return(domain.Game{ID: 7, Moves: "e2e4"}, nil).Once()
+		mockRepo.On("ListMoves", mock.Anything, 7).
+			Return([]domain.GameMove{{GameID: 7, Ply: 1, UCI: "e2e4", SAN: "e4"}}, nil).
+			Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/api/games/7", nil)
+		rec := httptest.NewRecorder()
+		setupRouter(mockRepo).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"san":"e4"`)
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		mockRepo := new(repository_game_mock.GameMockRepo)
+		mockRepo.On("Get", mock.Anything, 8).Return(domain.Game{}, sql.ErrNoRows).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/api/games/8", nil)
+		rec := httptest.NewRecorder()
+		setupRouter(mockRepo).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestGameHandler_HandlerGetStats(t *testing.T) {
+	mockRepo := new(repository_game_mock.GameMockRepo)
+	mockRepo.On("Stats", context.Background(), "4").
+		Return(domain.PlayerStats{}, errors.New("Unexpected")).
+		Once()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/players/4/stats", nil)
+	rec := httptest.NewRecorder()
+	setupRouter(mockRepo).ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
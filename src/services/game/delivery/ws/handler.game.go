@@ -8,6 +8,7 @@ import (
 	"log"
 	"strconv"
 	"strings"
+	"time"
 
 	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
 	domain_websocket "github.com/lookingcoolonavespa/go_crochess_backend/src/websocket"
@@ -16,15 +17,43 @@ import (
 const baseTopicName = "game"
 const jsonErrorMessage = "Handler/Game/HandlerUpdateDraw, Failed to convert message to json: %v\n"
 
+var timeNow = time.Now
+
 type GameHandler struct {
 	usecase domain.GameUseCase
+	chat    domain.ChatUseCase
+	// spectatorDelay holds back the game from spectators so its moves can't
+	// be relayed to a player while the game is live
+	spectatorDelay time.Duration
+}
+
+// delayedRoom broadcasts what is sent reliably to the spectators of a room
+// only after delay
+type delayedRoom struct {
+	domain.Room
+	delay time.Duration
+}
+
+func (r delayedRoom) BroadcastReliably(message []byte) {
+	r.Room.BroadcastToPlayersReliably(message)
+	time.AfterFunc(r.delay, func() {
+		r.Room.BroadcastToSpectators(message)
+	})
+}
+
+type Viewers struct {
+	Spectators int `json:"spectators"`
 }
 
 func NewGameHandler(
 	usecase domain.GameUseCase,
+	chat domain.ChatUseCase,
+	spectatorDelay time.Duration,
 ) GameHandler {
 	return GameHandler{
 		usecase,
+		chat,
+		spectatorDelay,
 	}
 
 }
@@ -35,8 +64,6 @@ func (g GameHandler) HandlerOnSubscribe(
 	client domain.Client,
 	_ []byte,
 ) error {
-	err := client.Subscribe(room)
-
 	param, err := room.GetParam()
 	if err != nil {
 		log.Printf("Handler/Game/HandlerGetGame: room is missing param")
@@ -55,14 +82,85 @@ func (g GameHandler) HandlerOnSubscribe(
 		return err
 	}
 
+	room.SetPlayers(game.WhiteID, game.BlackID)
+	if g.spectatorDelay > 0 && !room.IsPlayer(client.GetID()) {
+		// the moves made since are broadcast to the spectators once the
+		// delay is up
+		game, err = g.usecase.GetAsOf(ctx, gameID, timeNow().Add(-g.spectatorDelay).UnixMilli())
+		if err != nil {
+			log.Printf("Handler/Game/HandlerGetGame: ran into an error getting game\nerr: %v", err)
+			return err
+		}
+	}
+	room.OnSpectatorCountChange(func(count int) {
+		jsonData, err := domain_websocket.NewOutboundMessage(
+			fmt.Sprint(baseTopicName, "/", gameID),
+			domain_websocket.ViewersEvent,
+			Viewers{count},
+		).
+			ToJSON(jsonErrorMessage)
+		if err != nil {
+			return
+		}
+
+		room.BroadcastMessage(jsonData)
+	})
+
+	err = client.Subscribe(room)
+	if err != nil {
+		return err
+	}
+
 	err = client.SendMessage(
 		fmt.Sprintf("%s/%s", baseTopicName, param),
 		domain_websocket.InitEvent,
 		game,
 		"Handler/Game/HandlerGetGame: error turning game into json\nerr: %v",
 	)
+	if err != nil {
+		return err
+	}
+
+	history, err := g.chat.History(ctx, gameID, client.GetID(), room.IsPlayer(client.GetID()))
+	if err != nil {
+		log.Printf("Handler/Game/HandlerGetGame: ran into an error getting chat history\nerr: %v", err)
+		return err
+	}
+	if len(history) == 0 {
+		return nil
+	}
+
+	return client.SendMessage(
+		fmt.Sprintf("%s/%s", baseTopicName, param),
+		domain_websocket.ChatHistoryEvent,
+		history,
+		"Handler/Game/HandlerGetGame: error turning chat history into json\nerr: %v",
+	)
+}
+
+// rejectSpectator tells client it can't change the game and returns true if
+// client isn't one of the players of the game in room
+func rejectSpectator(room domain.Room, client domain.Client) bool {
+	if room.IsPlayer(client.GetID()) {
+		return false
+	}
+
+	client.SendError(
+		"spectators can not make changes to the game",
+		jsonErrorMessage,
+	)
+	return true
+}
 
-	return err
+// delayed returns room with the changes to the game, which are broadcast
+// reliably, sent to the players right away and to the spectators after
+// spectatorDelay. The usecase gets it too, for the timeouts.
+func (g GameHandler) delayed(room domain.Room) domain.Room {
+	if g.spectatorDelay <= 0 {
+		return room
+	}
+
+	return delayedRoom{room, g.spectatorDelay}
 }
 
 func (g GameHandler) HandlerOnUnsubscribe(
@@ -93,9 +191,16 @@ func (g GameHandler) HandlerMakeMove(
 		return err
 	}
 
+	if rejectSpectator(room, client) {
+		return nil
+	}
+
+	// MoveTime is the milliseconds the move took by the player's clock,
+	// which lets the lag of the move be credited back. The move is always
+	// made for the client that sent it.
 	type MovePayload struct {
-		PlayerID string `json:"player_id"`
 		Move     string `json:"move"`
+		MoveTime int    `json:"move_time"`
 	}
 	var movePayload MovePayload
 	err = json.Unmarshal(payload, &movePayload)
@@ -105,9 +210,6 @@ func (g GameHandler) HandlerMakeMove(
 	}
 
 	missingFields := make([]string, 0)
-	if movePayload.PlayerID == "" {
-		missingFields = append(missingFields, "player_id")
-	}
 	if movePayload.Move == "" {
 		missingFields = append(missingFields, "move")
 	}
@@ -127,9 +229,10 @@ func (g GameHandler) HandlerMakeMove(
 	changes, updated, err := g.usecase.UpdateOnMove(
 		ctx,
 		gameID,
-		movePayload.PlayerID,
+		client.GetID(),
 		movePayload.Move,
-		room,
+		domain.MoveLag{Latency: client.Latency(), MoveTime: movePayload.MoveTime},
+		g.delayed(room),
 	)
 	if err != nil {
 		return err
@@ -143,11 +246,25 @@ func (g GameHandler) HandlerMakeMove(
 		return nil
 	}
 
-	var event string
-	if changes[domain.GameResultJsonTag] == nil {
-		event = domain_websocket.MakeMoveEvent
-	} else {
+	gameOver, err := g.broadcastChanges(room, gameID, changes)
+	if err != nil || gameOver {
+		return err
+	}
+
+	return g.playPremoves(ctx, room, gameID)
+}
+
+// broadcastChanges broadcasts the changes a move made to the game and returns
+// whether the move ended it
+func (g GameHandler) broadcastChanges(
+	room domain.Room,
+	gameID int,
+	changes domain.GameChanges,
+) (gameOver bool, err error) {
+	event := domain_websocket.MakeMoveEvent
+	if changes[domain.GameResultJsonTag] != nil {
 		event = domain_websocket.GameOverEvent
+		gameOver = true
 	}
 
 	jsonData, err := domain_websocket.NewOutboundMessage(
@@ -157,12 +274,98 @@ func (g GameHandler) HandlerMakeMove(
 	).
 		ToJSON(jsonErrorMessage)
 	if err != nil {
+		return false, err
+	}
+
+	g.delayed(room).BroadcastReliably(jsonData)
+
+	return gameOver, nil
+}
+
+// playPremoves plays the premoves waiting for their player's turn to start,
+// telling the player when a premove isn't legal anymore
+func (g GameHandler) playPremoves(ctx context.Context, room domain.Room, gameID int) error {
+	for {
+		changes, premove, updated, err := g.usecase.UpdateOnPremove(ctx, gameID, g.delayed(room))
+		if errors.Is(err, domain.ErrPremoveIllegal) {
+			client, ok := room.GetClient(premove.PlayerID)
+			if !ok {
+				return nil
+			}
+
+			return client.SendMessage(
+				fmt.Sprint(baseTopicName, "/", gameID),
+				domain_websocket.DiscardPremoveEvent,
+				premove,
+				"Handler/Game/playPremoves: error turning premove into json\nerr: %v",
+			)
+		}
+		if err != nil || !updated {
+			return err
+		}
+
+		gameOver, err := g.broadcastChanges(room, gameID, changes)
+		if err != nil || gameOver {
+			return err
+		}
+	}
+}
+
+// HandlerPremove stores a move for the player to be played as soon as the
+// opponent moved. An empty move cancels the premove.
+func (g GameHandler) HandlerPremove(
+	ctx context.Context,
+	room domain.Room,
+	client domain.Client,
+	payload []byte,
+) error {
+	gID, err := room.GetParam()
+	if err != nil {
+		log.Printf("Handler/Game/HandlerPremove: room is missing param")
 		return err
 	}
 
-	room.BroadcastMessage(jsonData)
+	gameID, err := strconv.Atoi(gID)
+	if err != nil {
+		log.Printf("Handler/Game/HandlerPremove: param is not a valid int")
+		return err
+	}
 
-	return nil
+	if rejectSpectator(room, client) {
+		return nil
+	}
+
+	type PremovePayload struct {
+		Move string `json:"move"`
+	}
+	var premovePayload PremovePayload
+	err = json.Unmarshal(payload, &premovePayload)
+	if err != nil {
+		log.Printf("Handler/Game/HandlerPremove: failed to unmarshal payload, err: %v\n", err)
+		return err
+	}
+
+	err = g.usecase.Premove(ctx, gameID, client.GetID(), premovePayload.Move)
+	if errors.Is(err, domain.ErrPremoveOnTurn) {
+		client.SendError(err.Error(), jsonErrorMessage)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	err = client.SendMessage(
+		fmt.Sprint(baseTopicName, "/", gameID),
+		domain_websocket.PremoveEvent,
+		domain.Premove{PlayerID: client.GetID(), Move: premovePayload.Move},
+		"Handler/Game/HandlerPremove: error turning premove into json\nerr: %v",
+	)
+	if err != nil {
+		return err
+	}
+
+	// the opponent may have moved while the premove was stored
+	return g.playPremoves(ctx, room, gameID)
 }
 
 func (g GameHandler) HandlerUpdateDraw(
@@ -183,6 +386,10 @@ func (g GameHandler) HandlerUpdateDraw(
 		return err
 	}
 
+	if rejectSpectator(room, client) {
+		return nil
+	}
+
 	type UpdateDrawPayload struct {
 		White bool `json:"white"`
 		Black bool `json:"black"`
@@ -217,7 +424,7 @@ func (g GameHandler) HandlerUpdateDraw(
 		return err
 	}
 
-	room.BroadcastMessage(jsonData)
+	g.delayed(room).BroadcastReliably(jsonData)
 
 	return nil
 }
@@ -240,6 +447,10 @@ func (g GameHandler) HandlerUpdateResult(
 		return err
 	}
 
+	if rejectSpectator(room, client) {
+		return nil
+	}
+
 	type UpdateResultPayload struct {
 		Method string `json:"method"`
 		Result string `json:"result"`
@@ -279,7 +490,162 @@ func (g GameHandler) HandlerUpdateResult(
 		return err
 	}
 
-	room.BroadcastMessage(jsonData)
+	g.delayed(room).BroadcastReliably(jsonData)
 
 	return nil
 }
+
+func (g GameHandler) HandlerBerserk(
+	ctx context.Context,
+	room domain.Room,
+	client domain.Client,
+	_ []byte,
+) error {
+	gID, err := room.GetParam()
+	if err != nil {
+		log.Printf("Handler/Game/HandlerBerserk: room is missing param")
+		return err
+	}
+
+	gameID, err := strconv.Atoi(gID)
+	if err != nil {
+		log.Printf("Handler/Game/HandlerBerserk: param is not a valid int")
+		return err
+	}
+
+	if rejectSpectator(room, client) {
+		return nil
+	}
+
+	changes, updated, err := g.usecase.Berserk(ctx, gameID, client.GetID(), g.delayed(room))
+	if err != nil {
+		return err
+	}
+	if !updated {
+		client.SendError(
+			`Unable to go berserk because either the game is not part of an arena,
+            the game already started or you already went berserk`,
+			jsonErrorMessage,
+		)
+		return nil
+	}
+
+	jsonData, err := domain_websocket.NewOutboundMessage(
+		fmt.Sprint(baseTopicName, "/", gameID),
+		domain_websocket.BerserkEvent,
+		changes,
+	).
+		ToJSON(jsonErrorMessage)
+	if err != nil {
+		return err
+	}
+
+	g.delayed(room).BroadcastReliably(jsonData)
+
+	return nil
+}
+
+func (g GameHandler) HandlerChat(
+	ctx context.Context,
+	room domain.Room,
+	client domain.Client,
+	payload []byte,
+) error {
+	gID, err := room.GetParam()
+	if err != nil {
+		log.Printf("Handler/Game/HandlerChat: room is missing param")
+		return err
+	}
+
+	gameID, err := strconv.Atoi(gID)
+	if err != nil {
+		log.Printf("Handler/Game/HandlerChat: param is not a valid int")
+		return err
+	}
+
+	type ChatPayload struct {
+		Message string `json:"message"`
+	}
+	var chatPayload ChatPayload
+	err = json.Unmarshal(payload, &chatPayload)
+	if err != nil {
+		log.Printf("Handler/Game/HandlerChat: failed to unmarshal payload, err: %v\n", err)
+		return err
+	}
+
+	message, err := g.chat.Send(
+		ctx,
+		gameID,
+		client.GetID(),
+		room.IsPlayer(client.GetID()),
+		chatPayload.Message,
+	)
+	if errors.Is(err, domain.ErrChatMessageEmpty) ||
+		errors.Is(err, domain.ErrChatMessageTooLong) ||
+		errors.Is(err, domain.ErrChatRateLimited) {
+		client.SendError(err.Error(), jsonErrorMessage)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	jsonData, err := domain_websocket.NewOutboundMessage(
+		fmt.Sprint(baseTopicName, "/", gameID),
+		domain_websocket.ChatEvent,
+		message,
+	).
+		ToJSON(jsonErrorMessage)
+	if err != nil {
+		return err
+	}
+
+	room.BroadcastWhere(jsonData, func(id string, player bool) bool {
+		return g.chat.CanRead(gameID, id, player, message.Channel)
+	})
+
+	return nil
+}
+
+// HandlerMuteChat lets a player stop or resume receiving the spectators chat
+func (g GameHandler) HandlerMuteChat(
+	ctx context.Context,
+	room domain.Room,
+	client domain.Client,
+	payload []byte,
+) error {
+	gID, err := room.GetParam()
+	if err != nil {
+		log.Printf("Handler/Game/HandlerMuteChat: room is missing param")
+		return err
+	}
+
+	gameID, err := strconv.Atoi(gID)
+	if err != nil {
+		log.Printf("Handler/Game/HandlerMuteChat: param is not a valid int")
+		return err
+	}
+
+	if rejectSpectator(room, client) {
+		return nil
+	}
+
+	type MuteChatPayload struct {
+		Muted bool `json:"muted"`
+	}
+	var muteChatPayload MuteChatPayload
+	err = json.Unmarshal(payload, &muteChatPayload)
+	if err != nil {
+		log.Printf("Handler/Game/HandlerMuteChat: failed to unmarshal payload, err: %v\n", err)
+		return err
+	}
+
+	g.chat.SetSpectatorsMuted(gameID, client.GetID(), muteChatPayload.Muted)
+
+	return client.SendMessage(
+		fmt.Sprint(baseTopicName, "/", gameID),
+		domain_websocket.MuteChatEvent,
+		muteChatPayload,
+		"Handler/Game/HandlerMuteChat: error turning payload into json\nerr: %v",
+	)
+}
@@ -2,14 +2,18 @@ package delivery_ws_game
 
 import (
 	"context"
+	"fmt"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/bxcodec/faker"
 	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	mock_usecase_chat "github.com/lookingcoolonavespa/go_crochess_backend/src/services/chat/usecase/mock"
 	mock_usecase_game "github.com/lookingcoolonavespa/go_crochess_backend/src/services/game/usecase/mock"
 	domain_websocket "github.com/lookingcoolonavespa/go_crochess_backend/src/websocket"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestGameHandler_HandlerOnSubscribe(t *testing.T) {
@@ -17,6 +21,7 @@ func TestGameHandler_HandlerOnSubscribe(t *testing.T) {
 
 	err := faker.FakeData(&mockGame)
 	assert.NoError(t, err)
+	mockGame.WhiteID = "0"
 
 	gameID := 516
 
@@ -24,9 +29,12 @@ func TestGameHandler_HandlerOnSubscribe(t *testing.T) {
 
 	mockUseCase.On("Get", context.Background(), gameID).Return(mockGame, nil).Once()
 
+	mockChat := new(mock_usecase_chat.MockChatUseCase)
+	mockChat.On("History", context.Background(), gameID, "0", true).Return([]domain.ChatMessage{}, nil).Once()
+
 	gameIDStr := strconv.Itoa(gameID)
 
-	h := NewGameHandler(mockUseCase)
+	h := NewGameHandler(mockUseCase, mockChat, 0)
 
 	testChan := make(chan []byte)
 	client := domain_websocket.NewClient("0", testChan, nil, nil)
@@ -53,7 +61,7 @@ func TestGameHandler_HandlerOnUnsubscribe(t *testing.T) {
 
 	gameIDStr := strconv.Itoa(gameID)
 
-	h := NewGameHandler(mockUseCase)
+	h := NewGameHandler(mockUseCase, new(mock_usecase_chat.MockChatUseCase), 0)
 
 	testChan := make(chan []byte)
 	client := domain_websocket.NewClient("0", testChan, nil, nil)
@@ -66,3 +74,335 @@ func TestGameHandler_HandlerOnUnsubscribe(t *testing.T) {
 	_, subscribed := room.GetClient(client.GetID())
 	assert.False(t, subscribed)
 }
+
+func TestGameHandler_Spectators(t *testing.T) {
+	gameID := 517
+	gameIDStr := strconv.Itoa(gameID)
+	mockGame := domain.Game{ID: gameID, WhiteID: "1", BlackID: "2"}
+
+	t.Run("Broadcasts viewer count when a spectator subscribes", func(t *testing.T) {
+		mockUseCase := new(mock_usecase_game.MockGameUseCase)
+		mockUseCase.On("Get", context.Background(), gameID).Return(mockGame, nil).Twice()
+		mockChat := new(mock_usecase_chat.MockChatUseCase)
+		mockChat.On("History", context.Background(), gameID, mock.Anything, mock.Anything).
+			Return([]domain.ChatMessage{}, nil).
+			Twice()
+
+		h := NewGameHandler(mockUseCase, mockChat, 0)
+		room := domain_websocket.NewRoom([]domain.Client{}, gameIDStr)
+
+		playerChan := make(chan []byte, 2)
+		player := domain_websocket.NewClient("1", playerChan, nil, nil)
+		err := h.HandlerOnSubscribe(context.Background(), room, player, nil)
+		assert.NoError(t, err)
+		assert.Contains(t, string(<-playerChan), domain_websocket.InitEvent)
+
+		spectatorChan := make(chan []byte, 2)
+		spectator := domain_websocket.NewClient("3", spectatorChan, nil, nil)
+		err = h.HandlerOnSubscribe(context.Background(), room, spectator, nil)
+		assert.NoError(t, err)
+
+		select {
+		case message := <-playerChan:
+			assert.Contains(t, string(message), domain_websocket.ViewersEvent)
+			assert.Contains(t, string(message), `"spectators":1`)
+		case <-time.After(time.Second):
+			t.Fatal("viewer count was not broadcast")
+		}
+
+		assert.Equal(t, 1, room.CountSpectators())
+		assert.True(t, room.IsPlayer(player.GetID()))
+		assert.False(t, room.IsPlayer(spectator.GetID()))
+	})
+
+	t.Run("Rejects moves from spectators", func(t *testing.T) {
+		mockUseCase := new(mock_usecase_game.MockGameUseCase)
+		h := NewGameHandler(mockUseCase, new(mock_usecase_chat.MockChatUseCase), 0)
+
+		spectatorChan := make(chan []byte)
+		spectator := domain_websocket.NewClient("3", spectatorChan, nil, nil)
+		room := domain_websocket.NewRoom([]domain.Client{spectator}, gameIDStr)
+		room.SetPlayers(mockGame.WhiteID, mockGame.BlackID)
+
+		err := h.HandlerMakeMove(
+			context.Background(),
+			room,
+			spectator,
+			[]byte(`{"player_id": "1", "move": "e2e4"}`),
+		)
+		assert.NoError(t, err)
+
+		select {
+		case message := <-spectatorChan:
+			assert.Contains(t, string(message), domain_websocket.ErrorEvent)
+		case <-time.After(time.Second):
+			t.Fatal("spectator was not sent an error")
+		}
+
+		mockUseCase.AssertNotCalled(t, "UpdateOnMove")
+	})
+
+	t.Run("Makes the move for the client that sent it", func(t *testing.T) {
+		whiteChan := make(chan []byte, 1)
+		white := domain_websocket.NewClient(mockGame.WhiteID, whiteChan, nil, nil)
+		room := domain_websocket.NewRoom([]domain.Client{white}, gameIDStr)
+		room.SetPlayers(mockGame.WhiteID, mockGame.BlackID)
+
+		mockUseCase := new(mock_usecase_game.MockGameUseCase)
+		mockUseCase.On("UpdateOnMove", context.Background(), gameID, mockGame.WhiteID, "e7e5", mock.Anything, room).
+			Return(domain.GameChanges{}, false, nil).
+			Once()
+		h := NewGameHandler(mockUseCase, new(mock_usecase_chat.MockChatUseCase), 0)
+
+		err := h.HandlerMakeMove(
+			context.Background(),
+			room,
+			white,
+			[]byte(fmt.Sprintf(`{"player_id": "%s", "move": "e7e5"}`, mockGame.BlackID)),
+		)
+		assert.NoError(t, err)
+
+		mockUseCase.AssertExpectations(t)
+		mockUseCase.AssertNotCalled(t, "UpdateOnMove", context.Background(), gameID, mockGame.BlackID, "e7e5", mock.Anything, room)
+	})
+}
+
+func TestGameHandler_SpectatorDelay(t *testing.T) {
+	now := time.Date(2023, time.October, 10, 2, 10, 10, 10, time.UTC)
+	timeNow = func() time.Time {
+		return now
+	}
+
+	gameID := 518
+	gameIDStr := strconv.Itoa(gameID)
+	mockGame := domain.Game{ID: gameID, WhiteID: "1", BlackID: "2", Moves: "e2e4 e7e5"}
+	delay := time.Millisecond * 50
+
+	t.Run("Spectators start from the game like it was before the delay", func(t *testing.T) {
+		delayedGame := mockGame
+		delayedGame.Moves = "e2e4"
+
+		mockUseCase := new(mock_usecase_game.MockGameUseCase)
+		mockUseCase.On("Get", context.Background(), gameID).Return(mockGame, nil).Once()
+		mockUseCase.On("GetAsOf", context.Background(), gameID, now.Add(-delay).UnixMilli()).Return(delayedGame, nil).Once()
+		mockChat := new(mock_usecase_chat.MockChatUseCase)
+		mockChat.On("History", context.Background(), gameID, "3", false).Return([]domain.ChatMessage{}, nil).Once()
+
+		h := NewGameHandler(mockUseCase, mockChat, delay)
+		room := domain_websocket.NewRoom([]domain.Client{}, gameIDStr)
+
+		spectatorChan := make(chan []byte, 2)
+		spectator := domain_websocket.NewClient("3", spectatorChan, nil, nil)
+		err := h.HandlerOnSubscribe(context.Background(), room, spectator, nil)
+		assert.NoError(t, err)
+
+		assert.Contains(t, string(<-spectatorChan), domain_websocket.ViewersEvent)
+		message := string(<-spectatorChan)
+		assert.Contains(t, message, domain_websocket.InitEvent)
+		assert.Contains(t, message, `"moves":"e2e4"`)
+		mockUseCase.AssertExpectations(t)
+	})
+
+	t.Run("Holds back the result from spectators", func(t *testing.T) {
+		mockUseCase := new(mock_usecase_game.MockGameUseCase)
+		mockUseCase.On("UpdateResult", context.Background(), gameID, "Resign", "0-1").
+			Return(domain.GameChanges{domain.GameResultJsonTag: "0-1"}, true, nil).
+			Once()
+		h := NewGameHandler(mockUseCase, new(mock_usecase_chat.MockChatUseCase), delay)
+
+		whiteChan := make(chan []byte, 1)
+		white := domain_websocket.NewClient(mockGame.WhiteID, whiteChan, nil, nil)
+		spectatorChan := make(chan []byte, 1)
+		spectator := domain_websocket.NewClient("3", spectatorChan, nil, nil)
+		room := domain_websocket.NewRoom([]domain.Client{white, spectator}, gameIDStr)
+		room.SetPlayers(mockGame.WhiteID, mockGame.BlackID)
+
+		err := h.HandlerUpdateResult(
+			context.Background(),
+			room,
+			white,
+			[]byte(`{"method": "Resign", "result": "0-1"}`),
+		)
+		assert.NoError(t, err)
+		assert.Contains(t, string(<-whiteChan), domain_websocket.UpdateResultEvent)
+
+		select {
+		case <-spectatorChan:
+			t.Fatal("the spectator got the result before the delay")
+		case <-time.After(delay / 2):
+		}
+		select {
+		case message := <-spectatorChan:
+			assert.Contains(t, string(message), domain_websocket.UpdateResultEvent)
+		case <-time.After(time.Second):
+			t.Fatal("the spectator never got the result")
+		}
+	})
+}
+
+func TestGameHandler_Chat(t *testing.T) {
+	gameID := 518
+	gameIDStr := strconv.Itoa(gameID)
+
+	setup := func() (
+		*mock_usecase_chat.MockChatUseCase,
+		GameHandler,
+		*domain_websocket.Room,
+		chan []byte,
+		chan []byte,
+	) {
+		mockChat := new(mock_usecase_chat.MockChatUseCase)
+		h := NewGameHandler(new(mock_usecase_game.MockGameUseCase), mockChat, 0)
+
+		playerChan := make(chan []byte, 1)
+		player := domain_websocket.NewClient("1", playerChan, nil, nil)
+		spectatorChan := make(chan []byte, 1)
+		spectator := domain_websocket.NewClient("3", spectatorChan, nil, nil)
+
+		room := domain_websocket.NewRoom([]domain.Client{player, spectator}, gameIDStr)
+		room.SetPlayers("1", "2")
+
+		return mockChat, h, room, playerChan, spectatorChan
+	}
+
+	t.Run("Only sends players chat to players", func(t *testing.T) {
+		mockChat, h, room, playerChan, spectatorChan := setup()
+		message := domain.ChatMessage{ID: 1, GameID: gameID, Channel: domain.ChatChannelPlayers, SenderID: "1", Body: "gg"}
+		mockChat.On("Send", context.Background(), gameID, "1", true, "gg").Return(message, nil).Once()
+		mockChat.On("CanRead", gameID, "1", true, domain.ChatChannelPlayers).Return(true)
+		mockChat.On("CanRead", gameID, "3", false, domain.ChatChannelPlayers).Return(false)
+
+		player, _ := room.GetClient("1")
+		err := h.HandlerChat(context.Background(), room, player, []byte(`{"message": "gg"}`))
+		assert.NoError(t, err)
+
+		select {
+		case m := <-playerChan:
+			assert.Contains(t, string(m), domain_websocket.ChatEvent)
+			assert.Contains(t, string(m), `"body":"gg"`)
+		case <-time.After(time.Second):
+			t.Fatal("player did not receive the message")
+		}
+
+		select {
+		case <-spectatorChan:
+			t.Fatal("spectator received the players chat")
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+
+	t.Run("Sends rejected messages back as errors", func(t *testing.T) {
+		mockChat, h, room, _, spectatorChan := setup()
+		mockChat.On("Send", context.Background(), gameID, "3", false, "hi").
+			Return(domain.ChatMessage{}, domain.ErrChatRateLimited).
+			Once()
+
+		spectator, _ := room.GetClient("3")
+		err := h.HandlerChat(context.Background(), room, spectator, []byte(`{"message": "hi"}`))
+		assert.NoError(t, err)
+
+		select {
+		case m := <-spectatorChan:
+			assert.Contains(t, string(m), domain_websocket.ErrorEvent)
+		case <-time.After(time.Second):
+			t.Fatal("spectator was not sent an error")
+		}
+	})
+
+	t.Run("Lets players mute the spectators", func(t *testing.T) {
+		mockChat, h, room, playerChan, _ := setup()
+		mockChat.On("SetSpectatorsMuted", gameID, "1", true).Once()
+
+		player, _ := room.GetClient("1")
+		err := h.HandlerMuteChat(context.Background(), room, player, []byte(`{"muted": true}`))
+		assert.NoError(t, err)
+
+		select {
+		case m := <-playerChan:
+			assert.Contains(t, string(m), domain_websocket.MuteChatEvent)
+		case <-time.After(time.Second):
+			t.Fatal("player was not sent a confirmation")
+		}
+		mockChat.AssertExpectations(t)
+	})
+}
+
+func TestGameHandler_Premove(t *testing.T) {
+	gameID := 519
+	gameIDStr := strconv.Itoa(gameID)
+
+	setup := func() (*mock_usecase_game.MockGameUseCase, GameHandler, *domain_websocket.Room, chan []byte) {
+		mockUseCase := new(mock_usecase_game.MockGameUseCase)
+		h := NewGameHandler(mockUseCase, new(mock_usecase_chat.MockChatUseCase), 0)
+
+		playerChan := make(chan []byte, 4)
+		player := domain_websocket.NewClient("2", playerChan, nil, nil)
+		room := domain_websocket.NewRoom([]domain.Client{player}, gameIDStr)
+		room.SetPlayers("1", "2")
+
+		return mockUseCase, h, room, playerChan
+	}
+
+	receive := func(t *testing.T, c chan []byte, event string) string {
+		select {
+		case message := <-c:
+			assert.Contains(t, string(message), event)
+			return string(message)
+		case <-time.After(time.Second):
+			t.Fatalf("player was not sent %s", event)
+			return ""
+		}
+	}
+
+	t.Run("Stores the premove and plays it once it's the player's turn", func(t *testing.T) {
+		mockUseCase, h, room, playerChan := setup()
+		changes := domain.GameChanges{domain.GameMovesJsonTag: "e7e5"}
+		premove := domain.Premove{PlayerID: "2", Move: "e7e5"}
+		mockUseCase.On("Premove", context.Background(), gameID, "2", "e7e5").Return(nil).Once()
+		mockUseCase.On("UpdateOnPremove", context.Background(), gameID, room).
+			Return(changes, premove, true, nil).
+			Once()
+		mockUseCase.On("UpdateOnPremove", context.Background(), gameID, room).
+			Return(domain.GameChanges(nil), domain.Premove{}, false, nil).
+			Once()
+
+		player, _ := room.GetClient("2")
+		err := h.HandlerPremove(context.Background(), room, player, []byte(`{"move": "e7e5"}`))
+		assert.NoError(t, err)
+
+		receive(t, playerChan, domain_websocket.PremoveEvent)
+		assert.Contains(t, receive(t, playerChan, domain_websocket.MakeMoveEvent), "e7e5")
+		mockUseCase.AssertExpectations(t)
+	})
+
+	t.Run("Tells the player when the premove is discarded", func(t *testing.T) {
+		mockUseCase, h, room, playerChan := setup()
+		premove := domain.Premove{PlayerID: "2", Move: "d8h4"}
+		mockUseCase.On("Premove", context.Background(), gameID, "2", "d8h4").Return(nil).Once()
+		mockUseCase.On("UpdateOnPremove", context.Background(), gameID, room).
+			Return(domain.GameChanges(nil), premove, false, domain.ErrPremoveIllegal).
+			Once()
+
+		player, _ := room.GetClient("2")
+		err := h.HandlerPremove(context.Background(), room, player, []byte(`{"move": "d8h4"}`))
+		assert.NoError(t, err)
+
+		receive(t, playerChan, domain_websocket.PremoveEvent)
+		assert.Contains(t, receive(t, playerChan, domain_websocket.DiscardPremoveEvent), "d8h4")
+		mockUseCase.AssertExpectations(t)
+	})
+
+	t.Run("Rejects a premove on the player's turn", func(t *testing.T) {
+		mockUseCase, h, room, playerChan := setup()
+		mockUseCase.On("Premove", context.Background(), gameID, "2", "e7e5").
+			Return(domain.ErrPremoveOnTurn).
+			Once()
+
+		player, _ := room.GetClient("2")
+		err := h.HandlerPremove(context.Background(), room, player, []byte(`{"move": "e7e5"}`))
+		assert.NoError(t, err)
+
+		receive(t, playerChan, domain_websocket.ErrorEvent)
+		mockUseCase.AssertNotCalled(t, "UpdateOnPremove")
+	})
+}
@@ -23,13 +23,21 @@ func (c *GameMockRepo) Update(
 	id int,
 	version int,
 	changes domain.GameChanges,
+	move *domain.GameMove,
 ) (bool, error) {
-	args := c.Called(ctx, id, version, changes)
+	args := c.Called(ctx, id, version, changes, move)
 	result := args.Get(0)
 
 	return result.(bool), args.Error(1)
 }
 
+func (c *GameMockRepo) ListMoves(ctx context.Context, gameID int) ([]domain.GameMove, error) {
+	args := c.Called(ctx, gameID)
+	result := args.Get(0)
+
+	return result.([]domain.GameMove), args.Error(1)
+}
+
 func (c *GameMockRepo) Insert(
 	ctx context.Context,
 	g domain.Game,
@@ -39,3 +47,17 @@ func (c *GameMockRepo) Insert(
 
 	return gameID.(int), args.Error(1)
 }
+
+func (c *GameMockRepo) List(ctx context.Context, filter domain.GameFilter) ([]domain.Game, error) {
+	args := c.Called(ctx, filter)
+	result := args.Get(0)
+
+	return result.([]domain.Game), args.Error(1)
+}
+
+func (c *GameMockRepo) Stats(ctx context.Context, playerID string) (domain.PlayerStats, error) {
+	args := c.Called(ctx, playerID)
+	result := args.Get(0)
+
+	return result.(domain.PlayerStats), args.Error(1)
+}
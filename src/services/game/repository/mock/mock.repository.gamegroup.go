@@ -0,0 +1,36 @@
+package repository_game_mock
+
+import (
+	"context"
+
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	"github.com/stretchr/testify/mock"
+)
+
+type GameGroupMockRepo struct {
+	mock.Mock
+}
+
+func (c *GameGroupMockRepo) Insert(ctx context.Context, g domain.GameGroup) (int, error) {
+	args := c.Called(ctx, g)
+
+	return args.Int(0), args.Error(1)
+}
+
+func (c *GameGroupMockRepo) Get(ctx context.Context, id int) (domain.GameGroup, error) {
+	args := c.Called(ctx, id)
+	result := args.Get(0)
+
+	return result.(domain.GameGroup), args.Error(1)
+}
+
+func (c *GameGroupMockRepo) UpdateResult(
+	ctx context.Context,
+	id int,
+	result string,
+	method string,
+) (bool, error) {
+	args := c.Called(ctx, id, result, method)
+
+	return args.Bool(0), args.Error(1)
+}
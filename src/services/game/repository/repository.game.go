@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"sort"
+	"strings"
 	"time"
 
 	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
@@ -18,16 +20,35 @@ func NewGameRepo(db *sql.DB) gameRepo {
 	return gameRepo{db}
 }
 
-func (c gameRepo) Get(ctx context.Context, id int) (domain.Game, error) {
-	query :=
-		fmt.Sprintf(
-			`SELECT *
-            FROM game
-            WHERE id = $1`,
-		)
+const gameColumns = `id,
+        white_id,
+        black_id,
+        time,
+        increment,
+        result,
+        method,
+        version,
+        time_stamp_at_turn_start,
+        white_time,
+        black_time,
+        moves,
+        white_draw_status,
+        black_draw_status,
+        created_at,
+        eco,
+        opening,
+        COALESCE(tournament_id, 0),
+        white_berserk,
+        black_berserk,
+        COALESCE(group_id, 0),
+        pockets,
+        variant`
 
-	row := c.db.QueryRowContext(ctx, query, id)
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
 
+func scanGame(row scanner) (domain.Game, error) {
 	game := domain.Game{}
 	err := row.Scan(
 		&game.ID,
@@ -44,7 +65,32 @@ func (c gameRepo) Get(ctx context.Context, id int) (domain.Game, error) {
 		&game.Moves,
 		&game.WhiteDrawStatus,
 		&game.BlackDrawStatus,
+		&game.CreatedAt,
+		&game.Eco,
+		&game.Opening,
+		&game.TournamentID,
+		&game.WhiteBerserk,
+		&game.BlackBerserk,
+		&game.GroupID,
+		&game.Pockets,
+		&game.Variant,
 	)
+
+	return game, err
+}
+
+func (c gameRepo) Get(ctx context.Context, id int) (domain.Game, error) {
+	query :=
+		fmt.Sprintf(
+			`SELECT %s
+            FROM game
+            WHERE id = $1`,
+			gameColumns,
+		)
+
+	row := c.db.QueryRowContext(ctx, query, id)
+
+	game, err := scanGame(row)
 	if err != nil {
 		log.Printf("Repo/Game/Get, error getting game: %v\n", err)
 		return domain.Game{}, err
@@ -53,6 +99,130 @@ func (c gameRepo) Get(ctx context.Context, id int) (domain.Game, error) {
 	return game, nil
 }
 
+func (c gameRepo) List(ctx context.Context, filter domain.GameFilter) ([]domain.Game, error) {
+	args := make([]interface{}, 0)
+	conditions := make([]string, 0)
+
+	// win, loss and color are relative to the player so they are ignored
+	// when listing games of every player
+	if filter.PlayerID != "" {
+		args = append(args, filter.PlayerID)
+
+		switch filter.Color {
+		case domain.White:
+			conditions = append(conditions, "white_id = $1")
+		case domain.Black:
+			conditions = append(conditions, "black_id = $1")
+		default:
+			conditions = append(conditions, "(white_id = $1 OR black_id = $1)")
+		}
+
+		switch filter.Result {
+		case domain.GameResultWin:
+			conditions = append(conditions, "((white_id = $1 AND result = '1-0') OR (black_id = $1 AND result = '0-1'))")
+		case domain.GameResultLoss:
+			conditions = append(conditions, "((white_id = $1 AND result = '0-1') OR (black_id = $1 AND result = '1-0'))")
+		}
+	}
+
+	if filter.Result == domain.GameResultDraw {
+		conditions = append(conditions, "result = '1/2-1/2'")
+	}
+	if filter.Finished {
+		conditions = append(conditions, "result <> ''")
+	}
+
+	addCondition := func(format string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(format, len(args)))
+	}
+
+	if filter.Time != nil {
+		addCondition("time = $%d", *filter.Time)
+	}
+	if filter.Increment != nil {
+		addCondition("increment = $%d", *filter.Increment)
+	}
+	if filter.From != 0 {
+		addCondition("created_at >= $%d", filter.From)
+	}
+	if filter.To != 0 {
+		addCondition("created_at < $%d", filter.To)
+	}
+	if filter.Opponent != "" {
+		args = append(args, filter.Opponent)
+		conditions = append(conditions, fmt.Sprintf("(white_id = $%d OR black_id = $%d)", len(args), len(args)))
+	}
+	if filter.Eco != "" {
+		addCondition("eco LIKE $%d", filter.Eco+"%")
+	}
+	if filter.After != 0 {
+		addCondition("id < $%d", filter.After)
+	}
+
+	if len(conditions) == 0 {
+		conditions = append(conditions, "TRUE")
+	}
+
+	args = append(args, filter.Limit)
+	query := fmt.Sprintf(`SELECT %s
+            FROM game
+            WHERE %s
+            ORDER BY id DESC
+            LIMIT $%d`,
+		gameColumns,
+		strings.Join(conditions, " AND "),
+		len(args),
+	)
+
+	rows, err := c.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Printf("Repo/Game/List, error listing games: %v\n", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	games := make([]domain.Game, 0)
+	for rows.Next() {
+		game, err := scanGame(rows)
+		if err != nil {
+			log.Printf("Repo/Game/List, error scanning game: %v\n", err)
+			return nil, err
+		}
+		games = append(games, game)
+	}
+
+	return games, rows.Err()
+}
+
+func (c gameRepo) Stats(ctx context.Context, playerID string) (domain.PlayerStats, error) {
+	query := `SELECT
+            COUNT(*),
+            COUNT(*) FILTER (WHERE (white_id = $1 AND result = '1-0') OR (black_id = $1 AND result = '0-1')),
+            COUNT(*) FILTER (WHERE (white_id = $1 AND result = '0-1') OR (black_id = $1 AND result = '1-0')),
+            COUNT(*) FILTER (WHERE result = '1/2-1/2'),
+            COUNT(*) FILTER (WHERE white_id = $1),
+            COUNT(*) FILTER (WHERE black_id = $1)
+            FROM game
+            WHERE (white_id = $1 OR black_id = $1) AND result <> ''`
+
+	stats := domain.PlayerStats{PlayerID: playerID}
+	err := c.db.QueryRowContext(ctx, query, playerID).Scan(
+		&stats.Games,
+		&stats.Wins,
+		&stats.Losses,
+		&stats.Draws,
+		&stats.AsWhite,
+		&stats.AsBlack,
+	)
+	if err != nil {
+		log.Printf("Repo/Game/Stats, error getting stats: %v\n", err)
+		return domain.PlayerStats{}, err
+	}
+
+	return stats, nil
+}
+
 func (c gameRepo) Insert(
 	ctx context.Context,
 	g domain.Game,
@@ -66,9 +236,12 @@ func (c gameRepo) Insert(
         version,
         time_stamp_at_turn_start,
         white_time,
-        black_time
+        black_time,
+        tournament_id,
+        group_id,
+        variant
     ) VALUES (
-        $1, $2, $3, $4, $5, $6, $7, $8
+        $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
     ) RETURNING id`,
 	)
 
@@ -81,8 +254,11 @@ func (c gameRepo) Insert(
 		&g.Increment,
 		1,
 		time.Now().UnixMilli(),
-		&g.Time,
-		&g.Time,
+		&g.WhiteTime,
+		&g.BlackTime,
+		sql.NullInt64{Int64: int64(g.TournamentID), Valid: g.TournamentID != 0},
+		sql.NullInt64{Int64: int64(g.GroupID), Valid: g.GroupID != 0},
+		g.Variant,
 	)
 	if err != nil {
 		log.Printf("Repo/Game/Insert, error inserting game: %v\n", err)
@@ -101,15 +277,24 @@ func (c gameRepo) Update(
 	id int,
 	version int,
 	changes domain.GameChanges,
+	move *domain.GameMove,
 ) (updated bool, err error) {
 	newVersion := version + 1
 	updatedValues := []interface{}{newVersion}
 	// initialized with these values bc these are special cases
 	// - version isnt included in changes
 
+	// sort the fields so the generated statement is the same for the same changes
+	fields := make([]string, 0, len(changes))
+	for field := range changes {
+		fields = append(fields, string(field))
+	}
+	sort.Strings(fields)
+
 	var updateStr string
-	for field, value := range changes {
-		updatedValues = append(updatedValues, value)
+	for _, f := range fields {
+		field := domain.GameFieldJsonTag(f)
+		updatedValues = append(updatedValues, changes[field])
 		if field == domain.GameMovesJsonTag {
 			updateStr += fmt.Sprintf("%s = CASE WHEN %s = '' THEN $%d ELSE %s || ' ' || $%d END, ", field, field, len(updatedValues), field, len(updatedValues))
 		} else {
@@ -132,7 +317,14 @@ func (c gameRepo) Update(
 		version,
 	)
 
-	result, err := c.db.ExecContext(ctx, stmt, updatedValues...)
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("Repo/Game/Update, error starting transaction: %v\n", err)
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, stmt, updatedValues...)
 	if err != nil {
 		log.Printf("Repo/Game/Update, error updating game: sql: %s\nerr: %v\n", stmt, err)
 		return false, err
@@ -146,5 +338,81 @@ func (c gameRepo) Update(
 		return false, nil
 	}
 
+	if move != nil {
+		_, err = tx.ExecContext(
+			ctx,
+			insertMoveStmt,
+			id,
+			move.Ply,
+			move.UCI,
+			move.SAN,
+			move.FEN,
+			move.Clock,
+			move.TimeStamp,
+			move.Lag,
+		)
+		if err != nil {
+			log.Printf("Repo/Game/Update, error inserting move: %v\n", err)
+			return false, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("Repo/Game/Update, error committing transaction: %v\n", err)
+		return false, err
+	}
+
 	return true, nil
 }
+
+const insertMoveStmt = `
+    INSERT INTO game_moves (
+        game_id,
+        ply,
+        uci,
+        san,
+        fen,
+        clock,
+        time_stamp,
+        lag
+    ) VALUES (
+        $1, $2, $3, $4, $5, $6, $7, $8
+    )`
+
+func (c gameRepo) ListMoves(ctx context.Context, gameID int) ([]domain.GameMove, error) {
+	query := `
+    SELECT game_id, ply, uci, san, fen, clock, time_stamp, lag
+    FROM game_moves
+    WHERE game_id = $1
+    ORDER BY ply`
+
+	rows, err := c.db.QueryContext(ctx, query, gameID)
+	if err != nil {
+		log.Printf("Repo/Game/ListMoves, error getting moves: %v\n", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	moves := make([]domain.GameMove, 0)
+	for rows.Next() {
+		var m domain.GameMove
+		err := rows.Scan(
+			&m.GameID,
+			&m.Ply,
+			&m.UCI,
+			&m.SAN,
+			&m.FEN,
+			&m.Clock,
+			&m.TimeStamp,
+			&m.Lag,
+		)
+		if err != nil {
+			log.Printf("Repo/Game/ListMoves, error scanning move: %v\n", err)
+			return nil, err
+		}
+		moves = append(moves, m)
+	}
+
+	return moves, rows.Err()
+}
@@ -36,7 +36,7 @@ func TestGameRepo_Get(t *testing.T) {
 		"time",
 		"increment",
 		"result",
-		"winner",
+		"method",
 		"version",
 		"time_stamp_at_turn_start",
 		"white_time",
@@ -44,14 +44,24 @@ func TestGameRepo_Get(t *testing.T) {
 		"moves",
 		"white_draw_status",
 		"black_draw_status",
+		"created_at",
+		"eco",
+		"opening",
+		"tournament_id",
+		"white_berserk",
+		"black_berserk",
+		"group_id",
+		"pockets",
+		"variant",
 	}).
-		AddRow(gameID, 4, 5, 5000, 0, "", "", 0, time.Now().UnixMilli(), 5000, 5000, "", false, true)
+		AddRow(gameID, 4, 5, 5000, 0, "", "", 0, time.Now().UnixMilli(), 5000, 5000, "", false, true, time.Now().UnixMilli(), "", "", 0, false, false, 0, "", domain.GameVariantStandard)
 
 	query :=
 		fmt.Sprintf(
-			`SELECT *
+			`SELECT %s
             FROM game
             WHERE id = $1`,
+			gameColumns,
 		)
 
 	mock.ExpectQuery(query).WillReturnRows(rows)
@@ -66,10 +76,6 @@ func TestGameRepo_Get(t *testing.T) {
 }
 
 func TestGameRepo_Update(t *testing.T) {
-	db, mock := initMock()
-
-	defer db.Close()
-
 	mockGame := new(domain.Game)
 
 	err := faker.FakeData(mockGame)
@@ -83,33 +89,107 @@ func TestGameRepo_Update(t *testing.T) {
     UPDATE game 
     SET 
         version = $1,
-        %s = $2, %s = CONCAT(%s, $3)
+        %s = CASE WHEN %s = '' THEN $2 ELSE %s || ' ' || $2 END, %s = $3
     WHERE id = %d
     AND version = %d
     `,
-		domain.GameWhiteTimeJsonTag,
 		domain.GameMovesJsonTag,
 		domain.GameMovesJsonTag,
+		domain.GameMovesJsonTag,
+		domain.GameWhiteTimeJsonTag,
 		mockGame.ID,
 		mockGame.Version,
 	)
 
-	mock.ExpectExec(query).
-		WithArgs(newVersion, newWhiteTime, move).
-		WillReturnResult(sqlmock.NewResult(int64(mockGame.ID), 1))
-
-	r := NewGameRepo(db)
-
 	changes := make(domain.GameChanges)
 	changes[domain.GameWhiteTimeJsonTag] = newWhiteTime
 	changes[domain.GameMovesJsonTag] = move
 
-	assert.NoError(t, err)
-
-	updated, err := r.Update(context.Background(), mockGame.ID, mockGame.Version, changes)
+	t.Run("Success without move", func(t *testing.T) {
+		db, mock := initMock()
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(query).
+			WithArgs(newVersion, move, newWhiteTime).
+			WillReturnResult(sqlmock.NewResult(int64(mockGame.ID), 1))
+		mock.ExpectCommit()
+
+		r := NewGameRepo(db)
+
+		updated, err := r.Update(context.Background(), mockGame.ID, mockGame.Version, changes, nil)
+
+		assert.NoError(t, err)
+		assert.True(t, updated)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Success with move", func(t *testing.T) {
+		db, mock := initMock()
+		defer db.Close()
+
+		record := domain.GameMove{
+			GameID:    mockGame.ID,
+			Ply:       1,
+			UCI:       move,
+			SAN:       "e4",
+			FEN:       "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1",
+			Clock:     newWhiteTime,
+			TimeStamp: time.Now().UnixMilli(),
+			Lag:       120,
+		}
+
+		mock.ExpectBegin()
+		mock.ExpectExec(query).
+			WithArgs(newVersion, move, newWhiteTime).
+			WillReturnResult(sqlmock.NewResult(int64(mockGame.ID), 1))
+		mock.ExpectExec(insertMoveStmt).
+			WithArgs(
+				mockGame.ID,
+				record.Ply,
+				record.UCI,
+				record.SAN,
+				record.FEN,
+				record.Clock,
+				record.TimeStamp,
+				record.Lag,
+			).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		r := NewGameRepo(db)
+
+		updated, err := r.Update(context.Background(), mockGame.ID, mockGame.Version, changes, &record)
+
+		assert.NoError(t, err)
+		assert.True(t, updated)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Version conflict skips move", func(t *testing.T) {
+		db, mock := initMock()
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(query).
+			WithArgs(newVersion, move, newWhiteTime).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		r := NewGameRepo(db)
+
+		updated, err := r.Update(
+			context.Background(),
+			mockGame.ID,
+			mockGame.Version,
+			changes,
+			&domain.GameMove{Ply: 1, UCI: move},
+		)
 
-	assert.NoError(t, err)
-	assert.True(t, updated)
+		assert.NoError(t, err)
+		assert.False(t, updated)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
 }
 
 func TestGameRepo_Insert(t *testing.T) {
@@ -126,9 +206,12 @@ func TestGameRepo_Insert(t *testing.T) {
         version,
         time_stamp_at_turn_start,
         white_time,
-        black_time
+        black_time,
+        tournament_id,
+        group_id,
+        variant
     ) VALUES (
-        $1, $2, $3, $4, $5, $6, $7, $8
+        $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
     ) RETURNING id`,
 	)
 
@@ -141,7 +224,7 @@ func TestGameRepo_Insert(t *testing.T) {
 	version := 1
 	timeStampAtTurnStart := time.Now().UnixMilli()
 	whiteTime := timeData
-	blackTime := timeData
+	blackTime := 3000
 
 	rows := sqlmock.NewRows([]string{"id"}).AddRow(expectedGameID)
 
@@ -155,6 +238,9 @@ func TestGameRepo_Insert(t *testing.T) {
 			timeStampAtTurnStart,
 			whiteTime,
 			blackTime,
+			nil,
+			nil,
+			domain.GameVariantCrazyhouse,
 		).
 		WillReturnRows(rows)
 
@@ -171,8 +257,112 @@ func TestGameRepo_Insert(t *testing.T) {
 			TimeStampAtTurnStart: timeStampAtTurnStart,
 			WhiteTime:            whiteTime,
 			BlackTime:            blackTime,
+			Variant:              domain.GameVariantCrazyhouse,
 		})
 	assert.NoError(t, err)
 
 	assert.Equal(t, expectedGameID, gameID)
 }
+
+func TestGameRepo_List(t *testing.T) {
+	db, mock := initMock()
+
+	defer db.Close()
+
+	columns := []string{
+		"id",
+		"white_id",
+		"black_id",
+		"time",
+		"increment",
+		"result",
+		"method",
+		"version",
+		"time_stamp_at_turn_start",
+		"white_time",
+		"black_time",
+		"moves",
+		"white_draw_status",
+		"black_draw_status",
+		"created_at",
+		"eco",
+		"opening",
+		"tournament_id",
+		"white_berserk",
+		"black_berserk",
+		"group_id",
+		"pockets",
+		"variant",
+	}
+	now := time.Now().UnixMilli()
+	rows := sqlmock.NewRows(columns).
+		AddRow(40, "4", "5", 300000, 5, "1-0", "Checkmate", 30, now, 1000, 1000, "e2e4", false, false, now, "B00", "King's Pawn", 0, false, false, 0, "", domain.GameVariantStandard).
+		AddRow(38, "4", "5", 300000, 5, "1-0", "Resignation", 20, now, 1000, 1000, "d2d4", false, false, now, "A40", "Queen's Pawn Game", 3, true, false, 0, "", domain.GameVariantStandard)
+
+	gameTime := 300000
+	query := fmt.Sprintf(`SELECT %s
+            FROM game
+            WHERE white_id = $1 AND ((white_id = $1 AND result = '1-0') OR (black_id = $1 AND result = '0-1')) AND time = $2 AND (white_id = $3 OR black_id = $3) AND eco LIKE $4 AND id < $5
+            ORDER BY id DESC
+            LIMIT $6`,
+		gameColumns,
+	)
+
+	mock.ExpectQuery(query).
+		WithArgs("4", gameTime, "5", "A4%", 50, 2).
+		WillReturnRows(rows)
+
+	r := NewGameRepo(db)
+
+	games, err := r.List(context.Background(), domain.GameFilter{
+		PlayerID: "4",
+		Result:   domain.GameResultWin,
+		Color:    domain.White,
+		Time:     &gameTime,
+		Opponent: "5",
+		Eco:      "A4",
+		After:    50,
+		Limit:    2,
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, games, 2)
+	assert.Equal(t, 38, games[1].ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGameRepo_Stats(t *testing.T) {
+	db, mock := initMock()
+
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"games", "wins", "losses", "draws", "as_white", "as_black"}).
+		AddRow(10, 5, 3, 2, 6, 4)
+
+	mock.ExpectQuery(`SELECT
+            COUNT(*),
+            COUNT(*) FILTER (WHERE (white_id = $1 AND result = '1-0') OR (black_id = $1 AND result = '0-1')),
+            COUNT(*) FILTER (WHERE (white_id = $1 AND result = '0-1') OR (black_id = $1 AND result = '1-0')),
+            COUNT(*) FILTER (WHERE result = '1/2-1/2'),
+            COUNT(*) FILTER (WHERE white_id = $1),
+            COUNT(*) FILTER (WHERE black_id = $1)
+            FROM game
+            WHERE (white_id = $1 OR black_id = $1) AND result <> ''`).
+		WithArgs("4").
+		WillReturnRows(rows)
+
+	r := NewGameRepo(db)
+
+	stats, err := r.Stats(context.Background(), "4")
+
+	assert.NoError(t, err)
+	assert.Equal(t, domain.PlayerStats{
+		PlayerID: "4",
+		Games:    10,
+		Wins:     5,
+		Losses:   3,
+		Draws:    2,
+		AsWhite:  6,
+		AsBlack:  4,
+	}, stats)
+}
@@ -0,0 +1,99 @@
+package repository_game
+
+import (
+	"context"
+	"database/sql"
+	"log"
+
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+)
+
+type gameGroupRepo struct {
+	db *sql.DB
+}
+
+func NewGameGroupRepo(db *sql.DB) gameGroupRepo {
+	return gameGroupRepo{db}
+}
+
+const insertGroupStmt = `
+    INSERT INTO game_groups (
+        variant,
+        created_at
+    ) VALUES (
+        $1, $2
+    ) RETURNING id`
+
+const getGroupStmt = `
+    SELECT id, variant, result, method, created_at
+    FROM game_groups
+    WHERE id = $1`
+
+const listGroupGameIDsStmt = `SELECT id FROM game WHERE group_id = $1 ORDER BY id`
+
+const updateGroupResultStmt = `UPDATE game_groups SET result = $1, method = $2 WHERE id = $3 AND result = ''`
+
+func (c gameGroupRepo) Insert(ctx context.Context, g domain.GameGroup) (groupID int, err error) {
+	err = c.db.QueryRowContext(ctx, insertGroupStmt, g.Variant, g.CreatedAt).Scan(&groupID)
+	if err != nil {
+		log.Printf("Repo/GameGroup/Insert, error inserting group: %v\n", err)
+		return 0, err
+	}
+
+	return groupID, nil
+}
+
+func (c gameGroupRepo) Get(ctx context.Context, id int) (domain.GameGroup, error) {
+	var g domain.GameGroup
+	err := c.db.QueryRowContext(ctx, getGroupStmt, id).Scan(
+		&g.ID,
+		&g.Variant,
+		&g.Result,
+		&g.Method,
+		&g.CreatedAt,
+	)
+	if err != nil {
+		log.Printf("Repo/GameGroup/Get, error getting group: %v\n", err)
+		return domain.GameGroup{}, err
+	}
+
+	rows, err := c.db.QueryContext(ctx, listGroupGameIDsStmt, id)
+	if err != nil {
+		log.Printf("Repo/GameGroup/Get, error listing games: %v\n", err)
+		return domain.GameGroup{}, err
+	}
+	defer rows.Close()
+
+	g.GameIDs = make([]int, 0, 2)
+	for rows.Next() {
+		var gameID int
+		err := rows.Scan(&gameID)
+		if err != nil {
+			log.Printf("Repo/GameGroup/Get, error scanning game id: %v\n", err)
+			return domain.GameGroup{}, err
+		}
+		g.GameIDs = append(g.GameIDs, gameID)
+	}
+
+	return g, rows.Err()
+}
+
+func (c gameGroupRepo) UpdateResult(
+	ctx context.Context,
+	id int,
+	result string,
+	method string,
+) (updated bool, err error) {
+	res, err := c.db.ExecContext(ctx, updateGroupResultStmt, result, method, id)
+	if err != nil {
+		log.Printf("Repo/GameGroup/UpdateResult, error updating result: %v\n", err)
+		return false, err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected == 1, nil
+}
@@ -0,0 +1,79 @@
+package repository_game
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGameGroupRepo_Insert(t *testing.T) {
+	db, mock := initMock()
+	defer db.Close()
+
+	mock.ExpectQuery(insertGroupStmt).
+		WithArgs(domain.GameGroupVariantBughouse, int64(1000)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+
+	r := NewGameGroupRepo(db)
+
+	id, err := r.Insert(context.Background(), domain.GameGroup{
+		Variant:   domain.GameGroupVariantBughouse,
+		CreatedAt: 1000,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 7, id)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGameGroupRepo_Get(t *testing.T) {
+	db, mock := initMock()
+	defer db.Close()
+
+	mock.ExpectQuery(getGroupStmt).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "variant", "result", "method", "created_at"}).
+			AddRow(7, domain.GameGroupVariantBughouse, "1-0", "Checkmate", 1000))
+	mock.ExpectQuery(listGroupGameIDsStmt).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(20).AddRow(21))
+
+	r := NewGameGroupRepo(db)
+
+	g, err := r.Get(context.Background(), 7)
+	assert.NoError(t, err)
+	assert.Equal(t, domain.GameGroup{
+		ID:        7,
+		Variant:   domain.GameGroupVariantBughouse,
+		GameIDs:   []int{20, 21},
+		Result:    "1-0",
+		Method:    "Checkmate",
+		CreatedAt: 1000,
+	}, g)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGameGroupRepo_UpdateResult(t *testing.T) {
+	db, mock := initMock()
+	defer db.Close()
+
+	mock.ExpectExec(updateGroupResultStmt).
+		WithArgs("0-1", "Resign", 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(updateGroupResultStmt).
+		WithArgs("1-0", "TimeOut", 7).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	r := NewGameGroupRepo(db)
+
+	updated, err := r.UpdateResult(context.Background(), 7, "0-1", "Resign")
+	assert.NoError(t, err)
+	assert.True(t, updated)
+
+	updated, err = r.UpdateResult(context.Background(), 7, "1-0", "TimeOut")
+	assert.NoError(t, err)
+	assert.False(t, updated, "the group already has a result")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
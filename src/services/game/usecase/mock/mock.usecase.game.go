@@ -18,20 +18,52 @@ func (c *MockGameUseCase) Get(ctx context.Context, id int) (domain.Game, error)
 	return res.(domain.Game), args.Error(1)
 }
 
+func (c *MockGameUseCase) GetAsOf(ctx context.Context, id int, at int64) (domain.Game, error) {
+	args := c.Called(ctx, id, at)
+	res := args.Get(0)
+
+	return res.(domain.Game), args.Error(1)
+}
+
 func (c *MockGameUseCase) UpdateOnMove(
 	ctx context.Context,
 	gameID int,
 	playerID string,
 	move string,
+	lag domain.MoveLag,
 	room domain.Room,
 ) (domain.GameChanges, bool, error) {
-	args := c.Called(ctx, gameID, playerID, move, room)
+	args := c.Called(ctx, gameID, playerID, move, lag, room)
 	changes := args.Get(0)
 	updated := args.Get(1)
 
 	return changes.(domain.GameChanges), updated.(bool), args.Error(2)
 }
 
+func (c *MockGameUseCase) Premove(
+	ctx context.Context,
+	gameID int,
+	playerID string,
+	move string,
+) error {
+	args := c.Called(ctx, gameID, playerID, move)
+
+	return args.Error(0)
+}
+
+func (c *MockGameUseCase) UpdateOnPremove(
+	ctx context.Context,
+	gameID int,
+	room domain.Room,
+) (domain.GameChanges, domain.Premove, bool, error) {
+	args := c.Called(ctx, gameID, room)
+	changes := args.Get(0)
+	premove := args.Get(1)
+	updated := args.Get(2)
+
+	return changes.(domain.GameChanges), premove.(domain.Premove), updated.(bool), args.Error(3)
+}
+
 func (c *MockGameUseCase) UpdateDraw(
 	ctx context.Context,
 	gameID int,
@@ -57,3 +89,16 @@ func (c *MockGameUseCase) UpdateResult(
 
 	return changes.(domain.GameChanges), updated.(bool), args.Error(2)
 }
+
+func (c *MockGameUseCase) Berserk(
+	ctx context.Context,
+	gameID int,
+	playerID string,
+	room domain.Room,
+) (domain.GameChanges, bool, error) {
+	args := c.Called(ctx, gameID, playerID, room)
+	changes := args.Get(0)
+	updated := args.Get(1)
+
+	return changes.(domain.GameChanges), updated.(bool), args.Error(2)
+}
@@ -7,10 +7,13 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"sync"
 	"time"
 
 	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	domain_eco "github.com/lookingcoolonavespa/go_crochess_backend/src/domain/eco"
 	domain_timerManager "github.com/lookingcoolonavespa/go_crochess_backend/src/domain/timerManager"
+	domain_variant "github.com/lookingcoolonavespa/go_crochess_backend/src/domain/variant"
 	domain_websocket "github.com/lookingcoolonavespa/go_crochess_backend/src/websocket"
 	"github.com/notnil/chess"
 )
@@ -21,7 +24,72 @@ type gameUseCase struct {
 	db           *sql.DB
 	gameRepo     domain.GameRepo
 	timerManager *domain_timerManager.TimerManager
-	gameCache    map[int]*chess.Game
+	gameCache    map[int]*domain_variant.Game
+	hooks        *gameHooks
+	untimed      *untimedClocks
+	berserk      *berserkGames
+	lag          *lagQuotas
+	premoves     *premoves
+}
+
+// untimedClocks holds the color whose clock doesn't run by game id
+type untimedClocks struct {
+	mutex  sync.RWMutex
+	byGame map[int]chess.Color
+}
+
+func (u *untimedClocks) set(gameID int, color domain.Color) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	if color == domain.White {
+		u.byGame[gameID] = chess.White
+	} else {
+		u.byGame[gameID] = chess.Black
+	}
+}
+
+func (u *untimedClocks) is(gameID int, color chess.Color) bool {
+	u.mutex.RLock()
+	defer u.mutex.RUnlock()
+	untimed, ok := u.byGame[gameID]
+	return ok && untimed == color
+}
+
+func (u *untimedClocks) delete(gameID int) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	delete(u.byGame, gameID)
+}
+
+// berserkGames holds the ids of the games whose players can still go berserk
+type berserkGames struct {
+	mutex  sync.RWMutex
+	byGame map[int]bool
+}
+
+func (b *berserkGames) set(gameID int) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.byGame[gameID] = true
+}
+
+func (b *berserkGames) is(gameID int) bool {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return b.byGame[gameID]
+}
+
+func (b *berserkGames) delete(gameID int) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	delete(b.byGame, gameID)
+}
+
+type gameHooks struct {
+	mutex       sync.RWMutex
+	gameCreated []func(domain.Game)
+	move        []func(domain.Game, domain.GameMove)
+	gameOver    []func(domain.Game)
 }
 
 func NewGameUseCase(
@@ -32,8 +100,78 @@ func NewGameUseCase(
 		db,
 		gameRepo,
 		domain_timerManager.NewTimerManager(),
-		make(map[int]*chess.Game),
+		make(map[int]*domain_variant.Game),
+		&gameHooks{},
+		&untimedClocks{byGame: make(map[int]chess.Color)},
+		&berserkGames{byGame: make(map[int]bool)},
+		&lagQuotas{byGame: make(map[int]map[chess.Color]int)},
+		&premoves{byGame: make(map[int]domain.Premove)},
+	}
+}
+
+// OnGameCreated registers a hook that is called with every new game
+func (c gameUseCase) OnGameCreated(hook func(domain.Game)) {
+	c.hooks.mutex.Lock()
+	defer c.hooks.mutex.Unlock()
+	c.hooks.gameCreated = append(c.hooks.gameCreated, hook)
+}
+
+// OnMove registers a hook that is called with the game as it was before the
+// move and the move itself whenever a move that doesn't end the game is made
+func (c gameUseCase) OnMove(hook func(domain.Game, domain.GameMove)) {
+	c.hooks.mutex.Lock()
+	defer c.hooks.mutex.Unlock()
+	c.hooks.move = append(c.hooks.move, hook)
+}
+
+// OnGameOver registers a hook that is called with the finished game whenever
+// a game ends by a move, a timeout or an updated result.
+func (c gameUseCase) OnGameOver(hook func(domain.Game)) {
+	c.hooks.mutex.Lock()
+	defer c.hooks.mutex.Unlock()
+	c.hooks.gameOver = append(c.hooks.gameOver, hook)
+}
+
+func (c gameUseCase) runGameCreatedHooks(g domain.Game) {
+	c.hooks.mutex.RLock()
+	hooks := c.hooks.gameCreated
+	c.hooks.mutex.RUnlock()
+
+	for _, hook := range hooks {
+		hook(g)
+	}
+}
+
+func (c gameUseCase) runMoveHooks(g domain.Game, move domain.GameMove) {
+	c.hooks.mutex.RLock()
+	hooks := c.hooks.move
+	c.hooks.mutex.RUnlock()
+
+	for _, hook := range hooks {
+		hook(g, move)
+	}
+}
+
+func (c gameUseCase) runGameOverHooks(gameID int) {
+	c.hooks.mutex.RLock()
+	hooks := c.hooks.gameOver
+	c.hooks.mutex.RUnlock()
+
+	if len(hooks) == 0 {
+		return
 	}
+
+	go func() {
+		g, err := c.gameRepo.Get(context.Background(), gameID)
+		if err != nil {
+			log.Printf("Usecase/Game/runGameOverHooks, error getting game %d: %v", gameID, err)
+			return
+		}
+
+		for _, hook := range hooks {
+			hook(g)
+		}
+	}()
 }
 
 func getOnTimeOut(
@@ -60,7 +198,7 @@ func getOnTimeOut(
 			return
 		}
 
-		room.BroadcastMessage(jsonData)
+		room.BroadcastReliably(jsonData)
 	}
 }
 
@@ -69,22 +207,47 @@ func (c gameUseCase) OnAccept(
 	g domain.Game,
 	r domain.Room,
 ) (gameID int, err error) {
+	if g.Variant == "" {
+		g.Variant = domain.GameVariantStandard
+	}
+	if _, err := domain_variant.Get(g.Variant); err != nil {
+		return -1, errors.New(fmt.Sprintf("%s games can not be started on their own", g.Variant))
+	}
+	if g.Variant != domain.GameVariantStandard && (g.WhiteID == "engine" || g.BlackID == "engine") {
+		return -1, errors.New("the engine only plays standard chess")
+	}
+
 	g.TimeStampAtTurnStart = timeNow().UnixMilli()
-	g.WhiteTime = g.Time
-	g.BlackTime = g.Time
+	// clocks that are already set give the players unequal times, like in an
+	// armageddon
+	if g.WhiteTime == 0 {
+		g.WhiteTime = g.Time
+	}
+	if g.BlackTime == 0 {
+		g.BlackTime = g.Time
+	}
 
 	gameID, err = c.gameRepo.Insert(ctx, g)
 	if err != nil {
 		return -1, err
 	}
 
-	if g.WhiteID != "engine" && g.BlackID != "engine" {
+	g.ID = gameID
+	if g.UntimedColor == domain.White || g.UntimedColor == domain.Black {
+		c.untimed.set(gameID, g.UntimedColor)
+	}
+	if g.BerserkAllowed {
+		c.berserk.set(gameID)
+	}
+	c.runGameCreatedHooks(g)
+
+	if g.WhiteID != "engine" && g.BlackID != "engine" && g.UntimedColor != domain.White {
 		c.handleTimer(
 			ctx,
 			getOnTimeOut(r, gameID),
 			gameID,
 			1,
-			intToMillisecondsDuration(g.Time),
+			intToMillisecondsDuration(g.WhiteTime),
 			chess.White,
 			false,
 		)
@@ -102,28 +265,101 @@ func (c gameUseCase) Get(ctx context.Context, gameID int) (domain.Game, error) {
 	return game, nil
 }
 
+// GetAsOf returns the game without the moves made after at, with the clocks
+// and pockets of the last move made before it. A game that had moves left out
+// isn't over yet either.
+func (c gameUseCase) GetAsOf(ctx context.Context, gameID int, at int64) (domain.Game, error) {
+	g, err := c.gameRepo.Get(ctx, gameID)
+	if err != nil {
+		return domain.Game{}, err
+	}
+
+	moves, err := c.gameRepo.ListMoves(ctx, gameID)
+	if err != nil {
+		return domain.Game{}, err
+	}
+
+	shown := 0
+	for shown < len(moves) && moves[shown].TimeStamp <= at {
+		shown++
+	}
+	if shown == len(moves) {
+		return g, nil
+	}
+
+	// the clocks before a color's first move aren't stored
+	g.WhiteTime = g.Time
+	if g.WhiteBerserk {
+		g.WhiteTime /= 2
+	}
+	g.BlackTime = g.Time
+	if g.BlackBerserk {
+		g.BlackTime /= 2
+	}
+
+	uci := make([]string, shown)
+	for i, m := range moves[:shown] {
+		uci[i] = m.UCI
+		if m.Ply%2 == 1 {
+			g.WhiteTime = m.Clock
+		} else {
+			g.BlackTime = m.Clock
+		}
+	}
+	g.Moves = strings.Join(uci, " ")
+	// only games of a variant with pockets have any
+	hasPockets := g.Pockets != ""
+	g.Pockets = ""
+	if shown > 0 {
+		last := moves[shown-1]
+		g.TimeStampAtTurnStart = last.TimeStamp
+		if hasPockets {
+			g.Pockets = pocketsOf(last.FEN)
+		}
+	}
+	g.Result = ""
+	g.Method = ""
+	g.WhiteDrawStatus = false
+	g.BlackDrawStatus = false
+
+	return g, nil
+}
+
+// pocketsOf returns the pockets written in fen, between the brackets after
+// the board
+func pocketsOf(fen string) string {
+	start := strings.Index(fen, "[")
+	end := strings.Index(fen, "]")
+	if start == -1 || end < start {
+		return ""
+	}
+
+	return fen[start+1 : end]
+}
+
 func (c gameUseCase) makeMove(
 	g domain.Game,
 	playerID string,
 	move string,
-) (domain.GameChanges, chess.Color, error) {
+	lag domain.MoveLag,
+) (domain.GameChanges, *domain.GameMove, chess.Color, error) {
 	// makeMove returns the changes that need to be made to game structured as key/value pairs,
-	// the active color, and errors
+	// the move to append to the game's history, the active color, and errors
 	changes := make(domain.GameChanges)
 
 	gameState, ok := c.gameCache[g.ID]
 	if !ok {
-		gameState = chess.NewGame(chess.UseNotation(chess.UCINotation{}))
-		moves := strings.Split(g.Moves, " ")
+		v, err := domain_variant.Get(g.Variant)
+		if err != nil {
+			return nil, nil, chess.NoColor, err
+		}
 
-		for _, m := range moves {
-			if m == "" {
-				break
-			}
-			err := gameState.MoveStr(m)
+		gameState = domain_variant.NewGame(v)
+		for _, m := range strings.Fields(g.Moves) {
+			_, err := gameState.Move(m)
 			if err != nil {
 				log.Printf("Usecase/Game/makeMove, error making move to game state\nmove: %s\nerr: %v", m, err)
-				return nil, chess.NoColor, err
+				return nil, nil, chess.NoColor, err
 			}
 		}
 
@@ -133,31 +369,70 @@ func (c gameUseCase) makeMove(
 	activeColor := gameState.Position().Turn()
 	if activeColor == chess.White && g.WhiteID != playerID ||
 		activeColor == chess.Black && g.BlackID != playerID {
-		return nil, chess.NoColor, errors.New("Invalid player.")
+		return nil, nil, chess.NoColor, errors.New("Invalid player.")
 	}
 
-	err := gameState.MoveStr(move)
+	san, err := gameState.Move(move)
 	if err != nil {
 		log.Printf("Usecase/Game/makeMove, error making move to game state\nmove: %s\nerr: %v", move, err)
-		return nil, chess.NoColor, err
+		return nil, nil, chess.NoColor, err
 	}
 
 	changes[domain.GameWhiteDrawStatusJsonTag] = false
 	changes[domain.GameBlackDrawStatusJsonTag] = false
 
-	outcome := gameState.Outcome()
+	position := gameState.Position()
+	if pocketPosition, ok := position.(domain_variant.PocketPosition); ok {
+		changes[domain.GamePocketsJsonTag] = pocketPosition.Pockets()
+	}
+
+	outcome, method := gameState.Outcome()
 	if outcome != chess.NoOutcome {
 		changes[domain.GameResultJsonTag] = outcome.String()
-		changes[domain.GameMethodJsonTag] = gameState.Method().String()
+		changes[domain.GameMethodJsonTag] = method
 
 	} else {
-		if elgibleDraw := len(gameState.EligibleDraws()) > 1; elgibleDraw {
+		if gameState.CanClaimDraw() {
 			changes[domain.GameWhiteDrawStatusJsonTag] = true
 			changes[domain.GameBlackDrawStatusJsonTag] = true
 		}
 
 	}
 
+	clock, timeStamp, credited := c.chargeClock(g, activeColor, lag, changes)
+	changes[domain.GameMovesJsonTag] = move
+
+	if g.IsStandard() {
+		if opening, ok := domain_eco.Classify(gameState.Moves()); ok && (opening.Code != g.Eco || opening.Name != g.Opening) {
+			changes[domain.GameEcoJsonTag] = opening.Code
+			changes[domain.GameOpeningJsonTag] = opening.Name
+		}
+	}
+
+	record := &domain.GameMove{
+		GameID:    g.ID,
+		Ply:       len(gameState.Moves()),
+		UCI:       move,
+		SAN:       san,
+		FEN:       position.FEN(),
+		Clock:     clock,
+		TimeStamp: timeStamp,
+		Lag:       credited,
+	}
+
+	return changes, record, activeColor.Other(), nil
+}
+
+// chargeClock adds the time the active color spent on its move to changes and
+// returns the color's clock after the move with the time stamp of the move.
+// The part of the time that went to lag is credited back as long as the
+// color's lag quota lasts, and returned as credited.
+func (c gameUseCase) chargeClock(
+	g domain.Game,
+	activeColor chess.Color,
+	lag domain.MoveLag,
+	changes domain.GameChanges,
+) (clock int, timeStamp int64, credited int) {
 	timeSpent := timeNow().UnixMilli() - g.TimeStampAtTurnStart
 
 	var activeTime int
@@ -170,13 +445,18 @@ func (c gameUseCase) makeMove(
 		fieldOfActiveTime = domain.GameBlackTimeJsonTag
 	}
 
-	base := activeTime - int(timeSpent)
-	changes[fieldOfActiveTime] = base + (g.Increment * 1000)
-	changes[domain.GameTimeStampJsonTag] = timeNow().UnixMilli()
+	clock = activeTime
+	if !c.untimed.is(g.ID, activeColor) {
+		credited = c.lag.take(g.ID, activeColor, estimateLag(timeSpent, lag))
 
-	changes[domain.GameMovesJsonTag] = move
+		base := activeTime - int(timeSpent) + credited
+		clock = base + (g.Increment * 1000)
+		changes[fieldOfActiveTime] = clock
+	}
+	timeStamp = timeNow().UnixMilli()
+	changes[domain.GameTimeStampJsonTag] = timeStamp
 
-	return changes, activeColor.Other(), nil
+	return clock, timeStamp, credited
 }
 
 func (c gameUseCase) handleTimer(
@@ -204,13 +484,18 @@ func (c gameUseCase) handleTimer(
 				changes[domain.GameResultJsonTag] = chess.WhiteWon.String()
 			}
 
-			updated, err := c.gameRepo.Update(ctx, gameID, version, changes)
+			updated, err := c.gameRepo.Update(ctx, gameID, version, changes, nil)
 			if err != nil {
 				log.Printf("Usecase/Game/handleTimer, error updating: %v", err)
 			}
 			if updated && err == nil {
 				c.timerManager.StopAndDeleteTimer(gameID)
+				c.untimed.delete(gameID)
+				c.berserk.delete(gameID)
+				c.lag.delete(gameID)
+				c.premoves.delete(gameID)
 				onTimeOut(changes)
+				c.runGameOverHooks(gameID)
 			}
 		})
 	}
@@ -225,6 +510,7 @@ func (c gameUseCase) UpdateOnMove(
 	gameID int,
 	playerID string,
 	move string,
+	lag domain.MoveLag,
 	room domain.Room,
 ) (changes domain.GameChanges, updated bool, err error) {
 	g, err := c.gameRepo.Get(ctx, gameID)
@@ -236,18 +522,35 @@ func (c gameUseCase) UpdateOnMove(
 		return nil, false, nil
 	}
 
-	changes, activeColor, err := c.makeMove(g, playerID, move)
+	return c.updateOnMove(ctx, g, playerID, move, lag, room)
+}
+
+// updateOnMove makes move in g, which was just read from the repo
+func (c gameUseCase) updateOnMove(
+	ctx context.Context,
+	g domain.Game,
+	playerID string,
+	move string,
+	lag domain.MoveLag,
+	room domain.Room,
+) (changes domain.GameChanges, updated bool, err error) {
+	gameID := g.ID
+	changes, record, activeColor, err := c.makeMove(g, playerID, move, lag)
 	if err != nil {
 		return nil, false, err
 	}
 
-	updated, err = c.gameRepo.Update(ctx, gameID, g.Version, changes)
+	updated, err = c.gameRepo.Update(ctx, gameID, g.Version, changes, record)
 	if err != nil {
 		return nil, false, err
 	}
 	if !updated {
 		return nil, false, nil
 	}
+	// a premove left over from before the player's turn started is stale
+	c.premoves.take(gameID, playerID)
+	// going berserk is only allowed before the first move
+	c.berserk.delete(gameID)
 
 	var timerDuration time.Duration
 	if activeColor == chess.White {
@@ -259,6 +562,8 @@ func (c gameUseCase) UpdateOnMove(
 	_, gameOver := changes[domain.GameResultJsonTag]
 
 	if g.WhiteID != "engine" && g.BlackID != "engine" {
+		// the timer of the previous player is stopped the same way when the
+		// next one has no clock
 		c.handleTimer(
 			context.Background(),
 			getOnTimeOut(room, gameID),
@@ -266,10 +571,19 @@ func (c gameUseCase) UpdateOnMove(
 			g.Version+1,
 			timerDuration,
 			activeColor,
-			gameOver,
+			gameOver || c.untimed.is(gameID, activeColor),
 		)
 	}
 
+	if gameOver {
+		c.untimed.delete(gameID)
+		c.lag.delete(gameID)
+		c.premoves.delete(gameID)
+		c.runGameOverHooks(gameID)
+	} else {
+		c.runMoveHooks(g, *record)
+	}
+
 	return changes, true, nil
 }
 
@@ -292,7 +606,7 @@ func (c gameUseCase) UpdateDraw(
 	changes[domain.GameWhiteDrawStatusJsonTag] = whiteDrawStatus
 	changes[domain.GameBlackDrawStatusJsonTag] = blackDrawStatus
 
-	updated, err = c.gameRepo.Update(ctx, gameID, game.Version, changes)
+	updated, err = c.gameRepo.Update(ctx, gameID, game.Version, changes, nil)
 	if err != nil {
 		return nil, false, err
 	}
@@ -319,10 +633,79 @@ func (c gameUseCase) UpdateResult(
 	changes[domain.GameMethodJsonTag] = method
 	changes[domain.GameResultJsonTag] = result
 
-	updated, err = c.gameRepo.Update(ctx, gameID, game.Version, changes)
+	updated, err = c.gameRepo.Update(ctx, gameID, game.Version, changes, nil)
 	if err != nil {
 		return nil, false, err
 	}
 
+	if updated {
+		c.untimed.delete(gameID)
+		c.berserk.delete(gameID)
+		c.lag.delete(gameID)
+		c.premoves.delete(gameID)
+		c.runGameOverHooks(gameID)
+	}
+
 	return changes, updated, nil
 }
+
+// Berserk halves the clock of playerID in an arena game where no move was made
+// yet. It doesn't update anything if the player already went berserk, or in
+// games that didn't allow it when they started.
+func (c gameUseCase) Berserk(
+	ctx context.Context,
+	gameID int,
+	playerID string,
+	room domain.Room,
+) (changes domain.GameChanges, updated bool, err error) {
+	g, err := c.gameRepo.Get(ctx, gameID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if !c.berserk.is(gameID) || g.Moves != "" || g.Result != "" {
+		return nil, false, nil
+	}
+
+	changes = make(domain.GameChanges)
+	switch playerID {
+	case g.WhiteID:
+		if g.WhiteBerserk {
+			return nil, false, nil
+		}
+		g.WhiteTime /= 2
+		changes[domain.GameWhiteTimeJsonTag] = g.WhiteTime
+		changes[domain.GameWhiteBerserkJsonTag] = true
+	case g.BlackID:
+		if g.BlackBerserk {
+			return nil, false, nil
+		}
+		g.BlackTime /= 2
+		changes[domain.GameBlackTimeJsonTag] = g.BlackTime
+		changes[domain.GameBlackBerserkJsonTag] = true
+	default:
+		return nil, false, errors.New("Invalid player.")
+	}
+
+	updated, err = c.gameRepo.Update(ctx, gameID, g.Version, changes, nil)
+	if err != nil || !updated {
+		return nil, false, err
+	}
+
+	// white's timer has to be restarted either way since the timer only
+	// updates the game at the version it was started with
+	if g.WhiteID != "engine" && g.BlackID != "engine" {
+		timeSpent := int(timeNow().UnixMilli() - g.TimeStampAtTurnStart)
+		c.handleTimer(
+			context.Background(),
+			getOnTimeOut(room, gameID),
+			gameID,
+			g.Version+1,
+			intToMillisecondsDuration(g.WhiteTime-timeSpent),
+			chess.White,
+			false,
+		)
+	}
+
+	return changes, true, nil
+}
@@ -11,10 +11,12 @@ import (
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/bxcodec/faker"
 	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	domain_variant "github.com/lookingcoolonavespa/go_crochess_backend/src/domain/variant"
 	"github.com/lookingcoolonavespa/go_crochess_backend/src/services/game/repository/mock"
 	domain_websocket "github.com/lookingcoolonavespa/go_crochess_backend/src/websocket"
 	"github.com/notnil/chess"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func initMock() (*sql.DB, sqlmock.Sqlmock) {
@@ -30,10 +32,11 @@ func TestGameUseCase_UpdateOnMove(t *testing.T) {
 	timeNow = func() time.Time {
 		return time.Date(2023, time.October, 10, 2, 10, 10, 10, time.UTC)
 	}
-	db, mock := initMock()
+	db, _ := initMock()
 
 	mockGameRepo := new(repository_game_mock.GameMockRepo)
 	gameUseCase := NewGameUseCase(db, mockGameRepo)
+	anyMove := mock.AnythingOfType("*domain.GameMove")
 
 	mockGame := domain.Game{
 		ID:                   1,
@@ -45,13 +48,15 @@ func TestGameUseCase_UpdateOnMove(t *testing.T) {
 		WhiteTime:            600,
 		BlackTime:            600,
 		Moves:                "e2e4 e7e5 g1f3 g8f6",
+		Eco:                  "C42",
+		Opening:              "Russian Game",
 		Result:               "",
 		Method:               "",
 		Version:              1,
 	}
 
 	teardown := func(gameID int) {
-		gameUseCase.gameCache = make(map[int]*chess.Game)
+		gameUseCase.gameCache = make(map[int]*domain_variant.Game)
 		gameUseCase.timerManager.StopAndDeleteTimer(gameID)
 	}
 
@@ -64,6 +69,56 @@ func TestGameUseCase_UpdateOnMove(t *testing.T) {
 			domain.GameMovesJsonTag:           move,
 			domain.GameWhiteDrawStatusJsonTag: false,
 			domain.GameBlackDrawStatusJsonTag: false,
+			domain.GameEcoJsonTag:             "C43",
+			domain.GameOpeningJsonTag:         "Russian Game: Modern Attack",
+		}
+
+		record := &domain.GameMove{
+			GameID:    mockGame.ID,
+			Ply:       5,
+			UCI:       move,
+			SAN:       "d4",
+			FEN:       "rnbqkb1r/pppp1ppp/5n2/4p3/3PP3/5N2/PPP2PPP/RNBQKB1R b KQkq d3 0 3",
+			Clock:     mockGame.WhiteTime + (mockGame.Increment * 1000),
+			TimeStamp: timeNow().UnixMilli(),
+		}
+
+		mockGameRepo.On("Get", context.Background(), mockGame.ID).Return(mockGame, nil).Once()
+		mockGameRepo.On("Update",
+			context.Background(),
+			mockGame.ID,
+			mockGame.Version,
+			changes,
+			record,
+		).
+			Return(true, nil).Once()
+
+		_, _, err := gameUseCase.UpdateOnMove(
+			context.Background(),
+			mockGame.ID,
+			mockGame.WhiteID,
+			move,
+			domain.MoveLag{},
+			nil,
+		)
+		assert.NoError(t, err)
+
+		mockGameRepo.AssertExpectations(t)
+
+		teardown(mockGame.ID)
+	})
+
+	t.Run("Doesn't charge an untimed color", func(t *testing.T) {
+		move := "d2d4"
+		gameUseCase.untimed.set(mockGame.ID, domain.White)
+
+		changes := domain.GameChanges{
+			domain.GameTimeStampJsonTag:       timeNow().UnixMilli(),
+			domain.GameMovesJsonTag:           move,
+			domain.GameWhiteDrawStatusJsonTag: false,
+			domain.GameBlackDrawStatusJsonTag: false,
+			domain.GameEcoJsonTag:             "C43",
+			domain.GameOpeningJsonTag:         "Russian Game: Modern Attack",
 		}
 
 		mockGameRepo.On("Get", context.Background(), mockGame.ID).Return(mockGame, nil).Once()
@@ -72,24 +127,63 @@ func TestGameUseCase_UpdateOnMove(t *testing.T) {
 			mockGame.ID,
 			mockGame.Version,
 			changes,
+			mock.MatchedBy(func(m *domain.GameMove) bool {
+				return m.Clock == mockGame.WhiteTime
+			}),
 		).
 			Return(true, nil).Once()
 
-		mock.ExpectBegin()
 		_, _, err := gameUseCase.UpdateOnMove(
 			context.Background(),
 			mockGame.ID,
 			mockGame.WhiteID,
 			move,
+			domain.MoveLag{},
 			nil,
 		)
 		assert.NoError(t, err)
 
 		mockGameRepo.AssertExpectations(t)
 
+		gameUseCase.untimed.delete(mockGame.ID)
 		teardown(mockGame.ID)
 	})
 
+	t.Run("Credits lag back until the quota runs out", func(t *testing.T) {
+		mockGame2 := mockGame
+		mockGame2.TimeStampAtTurnStart = timeNow().UnixMilli() - 2000
+		gameUseCase.lag.take(mockGame.ID, chess.White, int(LagQuota.Milliseconds())-400)
+
+		for _, credited := range []int{300, 100} {
+			mockGameRepo.On("Get", context.Background(), mockGame.ID).Return(mockGame2, nil).Once()
+			mockGameRepo.On("Update",
+				context.Background(),
+				mockGame.ID,
+				mockGame.Version,
+				mock.Anything,
+				mock.MatchedBy(func(m *domain.GameMove) bool {
+					return m.Lag == credited &&
+						m.Clock == mockGame.WhiteTime-2000+credited+(mockGame.Increment*1000)
+				}),
+			).
+				Return(true, nil).Once()
+
+			_, _, err := gameUseCase.UpdateOnMove(
+				context.Background(),
+				mockGame.ID,
+				mockGame.WhiteID,
+				"d2d4",
+				domain.MoveLag{Latency: time.Millisecond * 300},
+				nil,
+			)
+			assert.NoError(t, err)
+			teardown(mockGame.ID)
+		}
+
+		mockGameRepo.AssertExpectations(t)
+		gameUseCase.lag.delete(mockGame.ID)
+	})
+
 	t.Run("Success on checkmate", func(t *testing.T) {
 		mockGame2 := mockGame
 		mockGame2.Moves = "f2f4 e7e5 g2g4"
@@ -103,6 +197,8 @@ func TestGameUseCase_UpdateOnMove(t *testing.T) {
 			domain.GameMethodJsonTag:          chess.Checkmate.String(),
 			domain.GameWhiteDrawStatusJsonTag: false,
 			domain.GameBlackDrawStatusJsonTag: false,
+			domain.GameEcoJsonTag:             "A02",
+			domain.GameOpeningJsonTag:         "Bird Opening: From's Gambit",
 		}
 
 		mockGameRepo.On("Get", context.Background(), mockGame2.ID).Return(mockGame2, nil).Once()
@@ -111,6 +207,7 @@ func TestGameUseCase_UpdateOnMove(t *testing.T) {
 			mockGame2.ID,
 			mockGame2.Version,
 			changes,
+			anyMove,
 		).Return(true, nil).Once()
 
 		gameUseCase := NewGameUseCase(db, mockGameRepo)
@@ -120,6 +217,7 @@ func TestGameUseCase_UpdateOnMove(t *testing.T) {
 			mockGame2.ID,
 			mockGame2.BlackID,
 			move,
+			domain.MoveLag{},
 			nil,
 		)
 		assert.NoError(t, err)
@@ -131,31 +229,34 @@ func TestGameUseCase_UpdateOnMove(t *testing.T) {
 
 	t.Run("Success on fivefold repetition", func(t *testing.T) {
 		mockGame2 := mockGame
-		mockGame2.Moves = "e2e4 e7e5 f1e2 f8e7 e2f1 e7f8 f1e2 f8e7 e2f1 e7f8 f1e2 f8e7 e2f1 e7f8 f1e2 f8e7 e2f1 e7f8 f1e2 f8e7 e2f1"
+		mockGame2.Moves = "e2e4 e7e5 f1e2 f8e7 e2f1 e7f8 f1e2 f8e7 e2f1 e7f8 f1e2 f8e7 e2f1 e7f8 f1e2 f8e7 e2f1 e7f8"
 
-		move := "e7f8"
+		move := "f1e2"
 		changes := domain.GameChanges{
 			domain.GameMovesJsonTag:           move,
 			domain.GameTimeStampJsonTag:       timeNow().UnixMilli(),
-			domain.GameBlackTimeJsonTag:       mockGame.BlackTime + (mockGame.Increment * 1000),
+			domain.GameWhiteTimeJsonTag:       mockGame.WhiteTime + (mockGame.Increment * 1000),
 			domain.GameResultJsonTag:          chess.Draw.String(),
 			domain.GameMethodJsonTag:          chess.FivefoldRepetition.String(),
 			domain.GameWhiteDrawStatusJsonTag: false,
 			domain.GameBlackDrawStatusJsonTag: false,
+			domain.GameEcoJsonTag:             "C20",
+			domain.GameOpeningJsonTag:         "King's Pawn Game",
 		}
 
 		mockGameRepo.On("Get", context.Background(), mockGame2.ID).
 			Return(mockGame2, nil).
 			Once()
-		mockGameRepo.On("Update", context.Background(), mockGame2.ID, mockGame2.Version, changes).
+		mockGameRepo.On("Update", context.Background(), mockGame2.ID, mockGame2.Version, changes, anyMove).
 			Return(true, nil).
 			Once()
 
 		_, _, err := gameUseCase.UpdateOnMove(
 			context.Background(),
 			mockGame2.ID,
-			mockGame2.BlackID,
+			mockGame2.WhiteID,
 			move,
+			domain.MoveLag{},
 			nil,
 		)
 		assert.NoError(t, err)
@@ -176,12 +277,96 @@ func TestGameUseCase_UpdateOnMove(t *testing.T) {
 			domain.GameBlackTimeJsonTag:       mockGame.BlackTime + (mockGame.Increment * 1000),
 			domain.GameWhiteDrawStatusJsonTag: true,
 			domain.GameBlackDrawStatusJsonTag: true,
+			domain.GameEcoJsonTag:             "C20",
+			domain.GameOpeningJsonTag:         "King's Pawn Game",
 		}
 
 		mockGameRepo.On("Get", context.Background(), mockGame2.ID).
 			Return(mockGame2, nil).
 			Once()
-		mockGameRepo.On("Update", context.Background(), mockGame2.ID, mockGame2.Version, changes).
+		mockGameRepo.On("Update", context.Background(), mockGame2.ID, mockGame2.Version, changes, anyMove).
+			Return(true, nil).
+			Once()
+
+		_, _, err := gameUseCase.UpdateOnMove(
+			context.Background(),
+			mockGame2.ID,
+			mockGame2.BlackID,
+			move,
+			domain.MoveLag{},
+			nil,
+		)
+		assert.NoError(t, err)
+
+		mockGameRepo.AssertExpectations(t)
+
+		teardown(mockGame.ID)
+	})
+
+	t.Run("Crazyhouse captures go to the pocket", func(t *testing.T) {
+		mockGame2 := mockGame
+		mockGame2.Variant = domain.GameVariantCrazyhouse
+		mockGame2.Moves = "e2e4 d7d5 e4d5 d8d5"
+		mockGame2.Pockets = "Pp"
+
+		move := "P@e4"
+		changes := domain.GameChanges{
+			domain.GameMovesJsonTag:           move,
+			domain.GameTimeStampJsonTag:       timeNow().UnixMilli(),
+			domain.GameWhiteTimeJsonTag:       mockGame.WhiteTime + (mockGame.Increment * 1000),
+			domain.GamePocketsJsonTag:         "p",
+			domain.GameWhiteDrawStatusJsonTag: false,
+			domain.GameBlackDrawStatusJsonTag: false,
+		}
+		record := &domain.GameMove{
+			GameID:    mockGame2.ID,
+			Ply:       5,
+			UCI:       move,
+			SAN:       "P@e4",
+			FEN:       "rnb1kbnr/ppp1pppp/8/3q4/4P3/8/PPPP1PPP/RNBQKBNR[p] b KQkq - 0 3",
+			Clock:     mockGame.WhiteTime + (mockGame.Increment * 1000),
+			TimeStamp: timeNow().UnixMilli(),
+		}
+
+		mockGameRepo.On("Get", context.Background(), mockGame2.ID).Return(mockGame2, nil).Once()
+		mockGameRepo.On("Update", context.Background(), mockGame2.ID, mockGame2.Version, changes, record).
+			Return(true, nil).
+			Once()
+
+		_, _, err := gameUseCase.UpdateOnMove(
+			context.Background(),
+			mockGame2.ID,
+			mockGame2.WhiteID,
+			move,
+			domain.MoveLag{},
+			nil,
+		)
+		assert.NoError(t, err)
+
+		mockGameRepo.AssertExpectations(t)
+
+		teardown(mockGame.ID)
+	})
+
+	t.Run("Crazyhouse mate needs every drop to be covered", func(t *testing.T) {
+		mockGame2 := mockGame
+		mockGame2.Variant = domain.GameVariantCrazyhouse
+		// white can drop the pawn it captured on g3 or f2
+		mockGame2.Moves = "f2f4 e7e5 f4e5 d7d6 g2g4"
+		mockGame2.Pockets = "P"
+
+		move := "d8h4"
+		changes := domain.GameChanges{
+			domain.GameMovesJsonTag:           move,
+			domain.GameTimeStampJsonTag:       timeNow().UnixMilli(),
+			domain.GameBlackTimeJsonTag:       mockGame.BlackTime + (mockGame.Increment * 1000),
+			domain.GamePocketsJsonTag:         "P",
+			domain.GameWhiteDrawStatusJsonTag: false,
+			domain.GameBlackDrawStatusJsonTag: false,
+		}
+
+		mockGameRepo.On("Get", context.Background(), mockGame2.ID).Return(mockGame2, nil).Once()
+		mockGameRepo.On("Update", context.Background(), mockGame2.ID, mockGame2.Version, changes, anyMove).
 			Return(true, nil).
 			Once()
 
@@ -190,6 +375,80 @@ func TestGameUseCase_UpdateOnMove(t *testing.T) {
 			mockGame2.ID,
 			mockGame2.BlackID,
 			move,
+			domain.MoveLag{},
+			nil,
+		)
+		assert.NoError(t, err)
+
+		mockGameRepo.AssertExpectations(t)
+
+		teardown(mockGame.ID)
+	})
+
+	t.Run("Crazyhouse mate without drops", func(t *testing.T) {
+		mockGame2 := mockGame
+		mockGame2.Variant = domain.GameVariantCrazyhouse
+		mockGame2.Moves = "f2f3 e7e5 g2g4"
+
+		move := "d8h4"
+		changes := domain.GameChanges{
+			domain.GameMovesJsonTag:           move,
+			domain.GameTimeStampJsonTag:       timeNow().UnixMilli(),
+			domain.GameBlackTimeJsonTag:       mockGame.BlackTime + (mockGame.Increment * 1000),
+			domain.GamePocketsJsonTag:         "",
+			domain.GameResultJsonTag:          chess.BlackWon.String(),
+			domain.GameMethodJsonTag:          chess.Checkmate.String(),
+			domain.GameWhiteDrawStatusJsonTag: false,
+			domain.GameBlackDrawStatusJsonTag: false,
+		}
+
+		mockGameRepo.On("Get", context.Background(), mockGame2.ID).Return(mockGame2, nil).Once()
+		mockGameRepo.On("Update", context.Background(), mockGame2.ID, mockGame2.Version, changes, anyMove).
+			Return(true, nil).
+			Once()
+
+		_, _, err := gameUseCase.UpdateOnMove(
+			context.Background(),
+			mockGame2.ID,
+			mockGame2.BlackID,
+			move,
+			domain.MoveLag{},
+			nil,
+		)
+		assert.NoError(t, err)
+
+		mockGameRepo.AssertExpectations(t)
+
+		teardown(mockGame.ID)
+	})
+
+	t.Run("Three-check ends on the third check", func(t *testing.T) {
+		mockGame2 := mockGame
+		mockGame2.Variant = domain.GameVariantThreeCheck
+		mockGame2.Moves = "e2e4 e7e5 f1c4 b8c6 c4f7 e8f7 d1h5 g7g6"
+
+		move := "h5f3"
+		changes := domain.GameChanges{
+			domain.GameMovesJsonTag:           move,
+			domain.GameTimeStampJsonTag:       timeNow().UnixMilli(),
+			domain.GameWhiteTimeJsonTag:       mockGame.WhiteTime + (mockGame.Increment * 1000),
+			domain.GameResultJsonTag:          chess.WhiteWon.String(),
+			domain.GameMethodJsonTag:          domain_variant.MethodThreeChecks,
+			domain.GameWhiteDrawStatusJsonTag: false,
+			domain.GameBlackDrawStatusJsonTag: false,
+		}
+
+		mockGameRepo.On("Get", context.Background(), mockGame2.ID).Return(mockGame2, nil).Once()
+		mockGameRepo.On("Update", context.Background(), mockGame2.ID, mockGame2.Version, changes, anyMove).
+			Return(true, nil).
+			Once()
+
+		_, _, err := gameUseCase.UpdateOnMove(
+			context.Background(),
+			mockGame2.ID,
+			mockGame2.WhiteID,
+			move,
+			domain.MoveLag{},
 			nil,
 		)
 		assert.NoError(t, err)
@@ -207,6 +466,7 @@ func TestGameUseCase_UpdateOnMove(t *testing.T) {
 			mockGame.ID,
 			mockGame.WhiteID,
 			"d4d5",
+			domain.MoveLag{},
 			nil,
 		)
 		assert.Error(t, err)
@@ -225,6 +485,7 @@ func TestGameUseCase_UpdateOnMove(t *testing.T) {
 			mockGame.ID,
 			mockGame.WhiteID,
 			"d2d4",
+			domain.MoveLag{},
 			nil,
 		)
 		assert.Error(t, err)
@@ -242,9 +503,11 @@ func TestGameUseCase_UpdateOnMove(t *testing.T) {
 			domain.GameMovesJsonTag:           move,
 			domain.GameWhiteDrawStatusJsonTag: false,
 			domain.GameBlackDrawStatusJsonTag: false,
+			domain.GameEcoJsonTag:             "C43",
+			domain.GameOpeningJsonTag:         "Russian Game: Modern Attack",
 		}
 		mockGameRepo.On("Get", context.Background(), mockGame.ID).Return(mockGame, nil).Once()
-		mockGameRepo.On("Update", context.Background(), mockGame.ID, mockGame.Version, changes).
+		mockGameRepo.On("Update", context.Background(), mockGame.ID, mockGame.Version, changes, anyMove).
 			Return(false, errors.New("Unexpected")).Once()
 
 		changes, _, err := gameUseCase.UpdateOnMove(
@@ -252,6 +515,7 @@ func TestGameUseCase_UpdateOnMove(t *testing.T) {
 			mockGame.ID,
 			mockGame.WhiteID,
 			move,
+			domain.MoveLag{},
 			nil,
 		)
 		assert.Error(t, err)
@@ -271,10 +535,12 @@ func TestGameUseCase_UpdateOnMove(t *testing.T) {
 			domain.GameMovesJsonTag:           move,
 			domain.GameWhiteDrawStatusJsonTag: false,
 			domain.GameBlackDrawStatusJsonTag: false,
+			domain.GameEcoJsonTag:             "C43",
+			domain.GameOpeningJsonTag:         "Russian Game: Modern Attack",
 		}
 
 		mockGameRepo.On("Get", context.Background(), mockGame.ID).Return(mockGame, nil).Once()
-		mockGameRepo.On("Update", context.Background(), mockGame.ID, mockGame.Version, changes).
+		mockGameRepo.On("Update", context.Background(), mockGame.ID, mockGame.Version, changes, anyMove).
 			Return(true, nil).Once()
 		mockGameRepo.On("Update", context.Background(), mockGame.ID, mockGame.Version+1,
 			domain.GameChanges{
@@ -284,6 +550,7 @@ func TestGameUseCase_UpdateOnMove(t *testing.T) {
 				domain.GameWhiteDrawStatusJsonTag: false,
 				domain.GameBlackDrawStatusJsonTag: false,
 			},
+			(*domain.GameMove)(nil),
 		).Return(true, nil).Once()
 
 		channel := make(chan []byte)
@@ -300,6 +567,7 @@ func TestGameUseCase_UpdateOnMove(t *testing.T) {
 			mockGame.ID,
 			mockGame.WhiteID,
 			move,
+			domain.MoveLag{},
 			room,
 		)
 		assert.NoError(t, err)
@@ -339,6 +607,9 @@ func TestGameUseCase_OnAccept(t *testing.T) {
 	mockGame.BlackID = blackID
 	mockGame.WhiteID = whiteID
 	mockGame.TimeStampAtTurnStart = timeNow().UnixMilli()
+	mockGame.Variant = domain.GameVariantStandard
+	// faker's times are short enough for the timer to run out during the tests
+	mockGame.Time = 600000
 	mockGame.WhiteTime = mockGame.Time
 	mockGame.BlackTime = mockGame.Time
 
@@ -356,6 +627,36 @@ func TestGameUseCase_OnAccept(t *testing.T) {
 		mockGameRepo.AssertExpectations(t)
 	})
 
+	t.Run("Keeps unequal clocks", func(t *testing.T) {
+		armageddon := mockGame
+		armageddon.WhiteTime = 300000
+		armageddon.BlackTime = 240000
+		armageddon.Time = armageddon.WhiteTime
+		mockGameRepo.On("Insert", context.Background(), armageddon).
+			Return(testGameID, nil).
+			Once()
+
+		_, err := gameseeksUseCase.OnAccept(context.Background(), armageddon, nil)
+		assert.NoError(t, err)
+
+		mockGameRepo.AssertExpectations(t)
+	})
+
+	t.Run("Only starts games played on one board", func(t *testing.T) {
+		bughouse := mockGame
+		bughouse.Variant = domain.GameVariantBughouse
+
+		_, err := gameseeksUseCase.OnAccept(context.Background(), bughouse, nil)
+		assert.Error(t, err)
+
+		engineGame := mockGame
+		engineGame.Variant = domain.GameVariantAtomic
+		engineGame.BlackID = "engine"
+
+		_, err = gameseeksUseCase.OnAccept(context.Background(), engineGame, nil)
+		assert.Error(t, err)
+	})
+
 	t.Run("Failed", func(t *testing.T) {
 		mockGameRepo.On("Insert", context.Background(), mockGame).
 			Return(-1, errors.New("Unexpected")).
@@ -367,3 +668,190 @@ func TestGameUseCase_OnAccept(t *testing.T) {
 		mockGameRepo.AssertExpectations(t)
 	})
 }
+
+func TestGameUseCase_OnGameOver(t *testing.T) {
+	db, _ := initMock()
+
+	mockGameRepo := new(repository_game_mock.GameMockRepo)
+	gameUseCase := NewGameUseCase(db, mockGameRepo)
+
+	finishedGame := domain.Game{ID: 12, Result: chess.BlackWon.String(), Method: "Resignation"}
+	changes := domain.GameChanges{
+		domain.GameMethodJsonTag: finishedGame.Method,
+		domain.GameResultJsonTag: finishedGame.Result,
+	}
+
+	mockGameRepo.On("Get", context.Background(), finishedGame.ID).
+		Return(domain.Game{ID: finishedGame.ID, Version: 3}, nil).
+		Once()
+	mockGameRepo.On("Update", context.Background(), finishedGame.ID, 3, changes, (*domain.GameMove)(nil)).
+		Return(true, nil).
+		Once()
+	mockGameRepo.On("Get", context.Background(), finishedGame.ID).
+		Return(finishedGame, nil).
+		Once()
+
+	hookChan := make(chan domain.Game)
+	gameUseCase.OnGameOver(func(g domain.Game) {
+		hookChan <- g
+	})
+
+	_, updated, err := gameUseCase.UpdateResult(
+		context.Background(),
+		finishedGame.ID,
+		finishedGame.Method,
+		finishedGame.Result,
+	)
+	assert.NoError(t, err)
+	assert.True(t, updated)
+
+	select {
+	case g := <-hookChan:
+		assert.Equal(t, finishedGame, g)
+	case <-time.After(time.Second):
+		t.Fatal("game over hook was not called")
+	}
+
+	mockGameRepo.AssertExpectations(t)
+}
+
+func TestGameUseCase_Berserk(t *testing.T) {
+	timeNow = func() time.Time {
+		return time.Date(2023, time.October, 10, 2, 10, 10, 10, time.UTC)
+	}
+	db, _ := initMock()
+
+	game := domain.Game{
+		ID:                   20,
+		WhiteID:              "1",
+		BlackID:              "2",
+		WhiteTime:            180000,
+		BlackTime:            180000,
+		TimeStampAtTurnStart: timeNow().UnixMilli(),
+		Version:              1,
+		TournamentID:         4,
+	}
+
+	t.Run("Halves the clock of the player", func(t *testing.T) {
+		mockGameRepo := new(repository_game_mock.GameMockRepo)
+		gameUseCase := NewGameUseCase(db, mockGameRepo)
+
+		changes := domain.GameChanges{
+			domain.GameBlackTimeJsonTag:    90000,
+			domain.GameBlackBerserkJsonTag: true,
+		}
+		mockGameRepo.On("Get", context.Background(), game.ID).Return(game, nil).Once()
+		mockGameRepo.On("Update", context.Background(), game.ID, game.Version, changes, (*domain.GameMove)(nil)).
+			Return(true, nil).
+			Once()
+		gameUseCase.berserk.set(game.ID)
+
+		result, updated, err := gameUseCase.Berserk(context.Background(), game.ID, "2", nil)
+		assert.NoError(t, err)
+		assert.True(t, updated)
+		assert.Equal(t, changes, result)
+
+		gameUseCase.timerManager.StopAndDeleteTimer(game.ID)
+		mockGameRepo.AssertExpectations(t)
+	})
+
+	t.Run("Doesn't update games outside of arenas or already started", func(t *testing.T) {
+		mockGameRepo := new(repository_game_mock.GameMockRepo)
+		gameUseCase := NewGameUseCase(db, mockGameRepo)
+
+		started := game
+		started.Moves = "e2e4"
+		berserked := game
+		berserked.WhiteBerserk = true
+
+		for _, g := range []domain.Game{started, berserked} {
+			mockGameRepo.On("Get", context.Background(), game.ID).Return(g, nil).Once()
+			gameUseCase.berserk.set(game.ID)
+
+			_, updated, err := gameUseCase.Berserk(context.Background(), game.ID, "1", nil)
+			assert.NoError(t, err)
+			assert.False(t, updated)
+		}
+
+		// a swiss game is part of a tournament too, but it was never allowed
+		gameUseCase.berserk.delete(game.ID)
+		mockGameRepo.On("Get", context.Background(), game.ID).Return(game, nil).Once()
+		_, updated, err := gameUseCase.Berserk(context.Background(), game.ID, "1", nil)
+		assert.NoError(t, err)
+		assert.False(t, updated)
+
+		mockGameRepo.AssertNotCalled(t, "Update")
+	})
+
+	t.Run("Allows it in games started by an arena", func(t *testing.T) {
+		mockGameRepo := new(repository_game_mock.GameMockRepo)
+		gameUseCase := NewGameUseCase(db, mockGameRepo)
+		mockGameRepo.On("Insert", context.Background(), mock.Anything).Return(game.ID, nil).Once()
+
+		_, err := gameUseCase.OnAccept(context.Background(), domain.Game{
+			WhiteID:        "1",
+			BlackID:        "2",
+			Time:           180000,
+			TournamentID:   4,
+			BerserkAllowed: true,
+		}, domain_websocket.NewRoom([]domain.Client{}, "20"))
+		assert.NoError(t, err)
+		assert.True(t, gameUseCase.berserk.is(game.ID))
+
+		gameUseCase.timerManager.StopAndDeleteTimer(game.ID)
+	})
+}
+
+func TestGameUseCase_GetAsOf(t *testing.T) {
+	db, _ := initMock()
+
+	game := domain.Game{
+		ID:                   20,
+		WhiteID:              "1",
+		BlackID:              "2",
+		Time:                 180000,
+		WhiteTime:            175000,
+		BlackTime:            176000,
+		TimeStampAtTurnStart: 4000,
+		Moves:                "e2e4 e7e5 g1f3",
+		Result:               "1-0",
+		Method:               "Resign",
+	}
+	moves := []domain.GameMove{
+		{Ply: 1, UCI: "e2e4", Clock: 179000, TimeStamp: 1000},
+		{Ply: 2, UCI: "e7e5", Clock: 176000, TimeStamp: 2000},
+		{Ply: 3, UCI: "g1f3", Clock: 175000, TimeStamp: 4000},
+	}
+
+	setup := func() gameUseCase {
+		mockGameRepo := new(repository_game_mock.GameMockRepo)
+		mockGameRepo.On("Get", context.Background(), game.ID).Return(game, nil).Once()
+		mockGameRepo.On("ListMoves", context.Background(), game.ID).Return(moves, nil).Once()
+
+		return NewGameUseCase(db, mockGameRepo)
+	}
+
+	t.Run("Leaves out the moves made after the time", func(t *testing.T) {
+		g, err := setup().GetAsOf(context.Background(), game.ID, 3000)
+		assert.NoError(t, err)
+		assert.Equal(t, "e2e4 e7e5", g.Moves)
+		assert.Equal(t, 179000, g.WhiteTime)
+		assert.Equal(t, 176000, g.BlackTime)
+		assert.Equal(t, int64(2000), g.TimeStampAtTurnStart)
+		assert.Equal(t, "", g.Result, "the game wasn't over yet")
+	})
+
+	t.Run("Starts from the initial clocks", func(t *testing.T) {
+		g, err := setup().GetAsOf(context.Background(), game.ID, 500)
+		assert.NoError(t, err)
+		assert.Equal(t, "", g.Moves)
+		assert.Equal(t, 180000, g.WhiteTime)
+		assert.Equal(t, 180000, g.BlackTime)
+	})
+
+	t.Run("Returns the game as it is once every move is old enough", func(t *testing.T) {
+		g, err := setup().GetAsOf(context.Background(), game.ID, 5000)
+		assert.NoError(t, err)
+		assert.Equal(t, game, g)
+	})
+}
@@ -0,0 +1,78 @@
+package usecase_game
+
+import (
+	"sync"
+	"time"
+
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	"github.com/notnil/chess"
+)
+
+// MaxMoveLag is the most lag credited back to a player for one move
+const MaxMoveLag = time.Millisecond * 500
+
+// LagQuota is the most lag credited back to each player over a game, so a
+// connection that claims lag on every move can't buy itself a longer clock
+const LagQuota = time.Second * 5
+
+// lagQuotas holds the milliseconds of lag that can still be credited back to
+// each color by game id. It isn't stored, so a game starts over with a full
+// quota when the server restarts.
+type lagQuotas struct {
+	mutex  sync.Mutex
+	byGame map[int]map[chess.Color]int
+}
+
+// take returns how much of lag can be credited back to color and removes it
+// from the color's quota
+func (q *lagQuotas) take(gameID int, color chess.Color, lag int) int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	quota, ok := q.byGame[gameID]
+	if !ok {
+		quota = map[chess.Color]int{
+			chess.White: int(LagQuota.Milliseconds()),
+			chess.Black: int(LagQuota.Milliseconds()),
+		}
+		q.byGame[gameID] = quota
+	}
+
+	if lag > quota[color] {
+		lag = quota[color]
+	}
+	quota[color] -= lag
+
+	return lag
+}
+
+func (q *lagQuotas) delete(gameID int) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	delete(q.byGame, gameID)
+}
+
+// estimateLag returns how many of the milliseconds a move took are likely to
+// have been spent in transit. The round trip time of the connection is
+// trusted over the client, which can only claim less lag than was measured,
+// or any lag up to MaxMoveLag before the first measurement.
+func estimateLag(timeSpent int64, lag domain.MoveLag) int {
+	estimate := lag.Latency.Milliseconds()
+	if lag.MoveTime > 0 && int64(lag.MoveTime) < timeSpent {
+		reported := timeSpent - int64(lag.MoveTime)
+		if estimate == 0 || reported < estimate {
+			estimate = reported
+		}
+	}
+
+	if estimate > MaxMoveLag.Milliseconds() {
+		estimate = MaxMoveLag.Milliseconds()
+	}
+	if estimate > timeSpent {
+		estimate = timeSpent
+	}
+	if estimate < 0 {
+		estimate = 0
+	}
+
+	return int(estimate)
+}
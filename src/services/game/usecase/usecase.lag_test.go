@@ -0,0 +1,32 @@
+package usecase_game
+
+import (
+	"testing"
+	"time"
+
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEstimateLag(t *testing.T) {
+	tests := []struct {
+		name      string
+		timeSpent int64
+		lag       domain.MoveLag
+		expected  int
+	}{
+		{"Nothing is known", 2000, domain.MoveLag{}, 0},
+		{"Uses the round trip time", 2000, domain.MoveLag{Latency: time.Millisecond * 200}, 200},
+		{"Trusts a client claiming less lag", 2000, domain.MoveLag{Latency: time.Millisecond * 200, MoveTime: 1900}, 100},
+		{"Doesn't trust a client claiming more lag", 2000, domain.MoveLag{Latency: time.Millisecond * 200, MoveTime: 1000}, 200},
+		{"Trusts the client before the first ping", 2000, domain.MoveLag{MoveTime: 1800}, 200},
+		{"Is capped for one move", 2000, domain.MoveLag{Latency: time.Second}, int(MaxMoveLag.Milliseconds())},
+		{"Is never more than the time spent", 100, domain.MoveLag{Latency: time.Millisecond * 200}, 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, estimateLag(tt.timeSpent, tt.lag))
+		})
+	}
+}
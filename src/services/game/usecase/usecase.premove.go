@@ -0,0 +1,121 @@
+package usecase_game
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	domain_variant "github.com/lookingcoolonavespa/go_crochess_backend/src/domain/variant"
+)
+
+// premoves holds the premove waiting to be played by game id. Only the
+// player who isn't on turn can have one, so a game has one at most.
+type premoves struct {
+	mutex  sync.Mutex
+	byGame map[int]domain.Premove
+}
+
+func (p *premoves) set(gameID int, premove domain.Premove) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.byGame[gameID] = premove
+}
+
+// take removes and returns the premove of playerID in the game
+func (p *premoves) take(gameID int, playerID string) (domain.Premove, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	premove, ok := p.byGame[gameID]
+	if !ok || premove.PlayerID != playerID {
+		return domain.Premove{}, false
+	}
+
+	delete(p.byGame, gameID)
+	return premove, true
+}
+
+func (p *premoves) delete(gameID int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	delete(p.byGame, gameID)
+}
+
+// playerOnTurn returns the id of the player whose turn it is in g
+func playerOnTurn(g domain.Game) string {
+	if len(strings.Fields(g.Moves))%2 == 0 {
+		return g.WhiteID
+	}
+
+	return g.BlackID
+}
+
+// Premove stores move to be played for playerID as soon as the opponent
+// moved, replacing an earlier premove. An empty move cancels the premove.
+// Whether the move is legal is only known once it is played.
+func (c gameUseCase) Premove(
+	ctx context.Context,
+	gameID int,
+	playerID string,
+	move string,
+) error {
+	g, err := c.gameRepo.Get(ctx, gameID)
+	if err != nil {
+		return err
+	}
+
+	if g.Result != "" {
+		return nil
+	}
+	if playerID != g.WhiteID && playerID != g.BlackID {
+		return errors.New("Invalid player.")
+	}
+	if playerOnTurn(g) == playerID {
+		return domain.ErrPremoveOnTurn
+	}
+
+	if move == "" {
+		c.premoves.take(gameID, playerID)
+		return nil
+	}
+
+	c.premoves.set(gameID, domain.Premove{PlayerID: playerID, Move: move})
+	return nil
+}
+
+// UpdateOnPremove plays the premove of the player on turn if there is one.
+// The premove is played the moment the turn starts, so it costs no time. A
+// premove that isn't legal anymore is dropped and returned with
+// domain.ErrPremoveIllegal.
+func (c gameUseCase) UpdateOnPremove(
+	ctx context.Context,
+	gameID int,
+	room domain.Room,
+) (changes domain.GameChanges, premove domain.Premove, updated bool, err error) {
+	g, err := c.gameRepo.Get(ctx, gameID)
+	if err != nil {
+		return nil, domain.Premove{}, false, err
+	}
+
+	if g.Result != "" {
+		return nil, domain.Premove{}, false, nil
+	}
+
+	premove, ok := c.premoves.take(gameID, playerOnTurn(g))
+	if !ok {
+		return nil, domain.Premove{}, false, nil
+	}
+
+	g.TimeStampAtTurnStart = timeNow().UnixMilli()
+	changes, updated, err = c.updateOnMove(ctx, g, premove.PlayerID, premove.Move, domain.MoveLag{}, room)
+	if errors.Is(err, domain_variant.ErrIllegalMove) {
+		return nil, premove, false, fmt.Errorf("%w: %s", domain.ErrPremoveIllegal, premove.Move)
+	}
+	if err != nil {
+		return nil, premove, false, err
+	}
+
+	return changes, premove, updated, nil
+}
@@ -0,0 +1,117 @@
+package usecase_game
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	domain_variant "github.com/lookingcoolonavespa/go_crochess_backend/src/domain/variant"
+	repository_game_mock "github.com/lookingcoolonavespa/go_crochess_backend/src/services/game/repository/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGameUseCase_Premove(t *testing.T) {
+	timeNow = func() time.Time {
+		return time.Date(2023, time.October, 10, 2, 10, 10, 10, time.UTC)
+	}
+	db, _ := initMock()
+
+	mockGameRepo := new(repository_game_mock.GameMockRepo)
+	gameUseCase := NewGameUseCase(db, mockGameRepo)
+
+	mockGame := domain.Game{
+		ID:                   1,
+		WhiteID:              "4",
+		BlackID:              "5",
+		Time:                 900000,
+		Increment:            60,
+		TimeStampAtTurnStart: timeNow().UnixMilli(),
+		WhiteTime:            600,
+		BlackTime:            600,
+		Moves:                "e2e4 e7e5 g1f3 g8f6",
+		Version:              1,
+	}
+	// the game after white answered the premove
+	movedGame := mockGame
+	movedGame.Moves += " d2d4"
+	movedGame.Version = 2
+	movedGame.TimeStampAtTurnStart = timeNow().UnixMilli() - 5000
+
+	teardown := func() {
+		gameUseCase.gameCache = make(map[int]*domain_variant.Game)
+		gameUseCase.premoves.delete(mockGame.ID)
+		gameUseCase.timerManager.StopAndDeleteTimer(mockGame.ID)
+	}
+
+	t.Run("Plays the premove without charging time", func(t *testing.T) {
+		mockGameRepo.On("Get", context.Background(), mockGame.ID).Return(mockGame, nil).Once()
+		err := gameUseCase.Premove(context.Background(), mockGame.ID, mockGame.BlackID, "b8c6")
+		assert.NoError(t, err)
+
+		mockGameRepo.On("Get", context.Background(), mockGame.ID).Return(movedGame, nil).Once()
+		mockGameRepo.On("Update",
+			context.Background(),
+			mockGame.ID,
+			movedGame.Version,
+			mock.MatchedBy(func(changes domain.GameChanges) bool {
+				return changes[domain.GameBlackTimeJsonTag] == mockGame.BlackTime+(mockGame.Increment*1000)
+			}),
+			mock.MatchedBy(func(m *domain.GameMove) bool {
+				return m.UCI == "b8c6" && m.Ply == 6
+			}),
+		).
+			Return(true, nil).Once()
+
+		changes, premove, updated, err := gameUseCase.UpdateOnPremove(context.Background(), mockGame.ID, nil)
+		assert.NoError(t, err)
+		assert.True(t, updated)
+		assert.Equal(t, "b8c6", changes[domain.GameMovesJsonTag])
+		assert.Equal(t, domain.Premove{PlayerID: mockGame.BlackID, Move: "b8c6"}, premove)
+
+		mockGameRepo.AssertExpectations(t)
+		teardown()
+	})
+
+	t.Run("Discards a premove that isn't legal anymore", func(t *testing.T) {
+		mockGameRepo.On("Get", context.Background(), mockGame.ID).Return(mockGame, nil).Once()
+		err := gameUseCase.Premove(context.Background(), mockGame.ID, mockGame.BlackID, "d8h4")
+		assert.NoError(t, err)
+
+		mockGameRepo.On("Get", context.Background(), mockGame.ID).Return(movedGame, nil).Twice()
+		_, premove, updated, err := gameUseCase.UpdateOnPremove(context.Background(), mockGame.ID, nil)
+		assert.ErrorIs(t, err, domain.ErrPremoveIllegal)
+		assert.False(t, updated)
+		assert.Equal(t, "d8h4", premove.Move)
+
+		_, _, updated, err = gameUseCase.UpdateOnPremove(context.Background(), mockGame.ID, nil)
+		assert.NoError(t, err)
+		assert.False(t, updated)
+
+		mockGameRepo.AssertExpectations(t)
+		mockGameRepo.AssertNotCalled(t, "Update")
+		teardown()
+	})
+
+	t.Run("Rejects a premove on the player's turn", func(t *testing.T) {
+		mockGameRepo.On("Get", context.Background(), mockGame.ID).Return(mockGame, nil).Once()
+		err := gameUseCase.Premove(context.Background(), mockGame.ID, mockGame.WhiteID, "d2d4")
+		assert.ErrorIs(t, err, domain.ErrPremoveOnTurn)
+
+		mockGameRepo.AssertExpectations(t)
+		teardown()
+	})
+
+	t.Run("Cancels the premove with an empty move", func(t *testing.T) {
+		mockGameRepo.On("Get", context.Background(), mockGame.ID).Return(mockGame, nil).Twice()
+		assert.NoError(t, gameUseCase.Premove(context.Background(), mockGame.ID, mockGame.BlackID, "b8c6"))
+		assert.NoError(t, gameUseCase.Premove(context.Background(), mockGame.ID, mockGame.BlackID, ""))
+
+		_, ok := gameUseCase.premoves.take(mockGame.ID, mockGame.BlackID)
+		assert.False(t, ok)
+
+		mockGameRepo.AssertExpectations(t)
+		teardown()
+	})
+}
@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	domain_variant "github.com/lookingcoolonavespa/go_crochess_backend/src/domain/variant"
 	domain_websocket "github.com/lookingcoolonavespa/go_crochess_backend/src/websocket"
 )
 
@@ -17,9 +18,17 @@ const topicName = domain_websocket.GameseeksTopic
 type GameseeksHandler struct {
 	usecase   domain.GameseeksUseCase
 	repo      domain.GameseeksRepo
+	lobbyChat domain.LobbyChatUseCase
 	gameTopic domain_websocket.TopicWithParam
 }
 
+// LobbyInit is sent to new subscribers with the open gameseeks and the recent
+// lobby chat
+type LobbyInit struct {
+	Gameseeks []domain.Gameseek    `json:"gameseeks"`
+	Chat      []domain.ChatMessage `json:"chat"`
+}
+
 type AcceptedGameseek struct {
 	GameID      int          `json:"game_id"`
 	PlayerColor domain.Color `json:"playerColor"`
@@ -28,11 +37,13 @@ type AcceptedGameseek struct {
 func NewGameseeksHandler(
 	repo domain.GameseeksRepo,
 	usecase domain.GameseeksUseCase,
+	lobbyChat domain.LobbyChatUseCase,
 	gameTopic domain_websocket.TopicWithParam,
 ) GameseeksHandler {
 	handler := GameseeksHandler{
 		usecase,
 		repo,
+		lobbyChat,
 		gameTopic,
 	}
 
@@ -56,10 +67,16 @@ func (g GameseeksHandler) HandlerOnSubscribe(
 		return errors.New(fmt.Sprintf("There was an error retreiving game seeks. %v", err))
 	}
 
+	chat, err := g.lobbyChat.History(ctx, client.GetID())
+	if err != nil {
+		log.Printf("%s : %v", "Handler/Gameseeks/HandlerGetGameseeksList/History/ShouldFindChat", err)
+		return errors.New(fmt.Sprintf("There was an error retreiving the lobby chat. %v", err))
+	}
+
 	err = client.SendMessage(
 		topicName,
 		domain_websocket.InitEvent,
-		list,
+		LobbyInit{list, chat},
 		"Handler/Gameseeks/HandlerGetGameseeksList/List/ShouldEncodeIntoJson : %v",
 	)
 	return err
@@ -91,6 +108,24 @@ func (g GameseeksHandler) HandleGameseekInsert(
 		return errors.New(errorMessage)
 	}
 
+	// seeks from clients that don't know about variants are for standard games
+	if gs.Variant == "" {
+		gs.Variant = domain.GameVariantStandard
+	}
+	// bughouse needs four players, so it can't be sought in the lobby
+	if _, err := domain_variant.Get(gs.Variant); err != nil {
+		errorMessage := fmt.Sprintf("%s can not be played from a gameseek", gs.Variant)
+		err := client.SendError(
+			errorMessage,
+			"GameseeksHandler/HandleGameseekInsert, Failed to convert message to json: %v\n",
+		)
+		if err != nil {
+			return err
+		}
+
+		return errors.New(errorMessage)
+	}
+
 	err := g.repo.Insert(ctx, gs)
 	if err != nil {
 		return errors.New(fmt.Sprintf("Failed to save gameseek: %v", err))
@@ -157,6 +192,7 @@ func (g GameseeksHandler) HandlerAcceptGameseek(
 	}
 
 	gameRoom := domain_websocket.NewRoom([]domain.Client{}, "")
+	gameRoom.SetPlayers(game.WhiteID, game.BlackID)
 	gameID, err := g.usecase.OnAccept(
 		ctx,
 		game,
@@ -232,6 +268,7 @@ func (g GameseeksHandler) HandlerStartEngineGame(
 	}
 
 	gameRoom := domain_websocket.NewRoom([]domain.Client{}, "")
+	gameRoom.SetPlayers(game.WhiteID, game.BlackID)
 	gameID, err := g.usecase.OnAccept(
 		ctx,
 		game,
@@ -300,3 +337,104 @@ func (g GameseeksHandler) HandlerOnUnsubscribe(
 
 	return nil
 }
+
+func (g GameseeksHandler) HandlerChat(
+	ctx context.Context,
+	room domain.Room,
+	client domain.Client,
+	payload []byte,
+) error {
+	type ChatPayload struct {
+		Message string `json:"message"`
+	}
+	var chatPayload ChatPayload
+	err := json.Unmarshal(payload, &chatPayload)
+	if err != nil {
+		log.Printf("Handler/Gameseeks/HandlerChat: failed to unmarshal payload, err: %v\n", err)
+		return err
+	}
+
+	message, err := g.lobbyChat.Send(ctx, client.GetID(), chatPayload.Message)
+	if errors.Is(err, domain.ErrChatMessageEmpty) ||
+		errors.Is(err, domain.ErrChatMessageTooLong) ||
+		errors.Is(err, domain.ErrChatRateLimited) ||
+		errors.Is(err, domain.ErrChatBannedWords) ||
+		errors.Is(err, domain.ErrChatTimedOut) {
+		client.SendError(err.Error(), "Handler/Gameseeks/HandlerChat, Failed to convert message to json: %v\n")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// messages from shadow muted clients only go back to them, so they don't
+	// notice they are muted
+	if message.Hidden {
+		return client.SendMessage(
+			topicName,
+			domain_websocket.ChatEvent,
+			message,
+			"Handler/Gameseeks/HandlerChat: error turning message into json\nerr: %v",
+		)
+	}
+
+	jsonMessage, err := domain_websocket.NewOutboundMessage(
+		topicName,
+		domain_websocket.ChatEvent,
+		message,
+	).ToJSON("Handler/Gameseeks/HandlerChat, error converting message to json, err: %v")
+	if err != nil {
+		return err
+	}
+
+	room.BroadcastMessage(jsonMessage)
+
+	return nil
+}
+
+func (g GameseeksHandler) HandlerModerate(
+	ctx context.Context,
+	room domain.Room,
+	client domain.Client,
+	payload []byte,
+) error {
+	var action domain.ModerationAction
+	err := json.Unmarshal(payload, &action)
+	if err != nil {
+		log.Printf("Handler/Gameseeks/HandlerModerate: failed to unmarshal payload, err: %v\n", err)
+		return err
+	}
+	action.ModeratorID = client.GetID()
+
+	action, err = g.lobbyChat.Moderate(ctx, action)
+	if errors.Is(err, domain.ErrNotModerator) || errors.Is(err, domain.ErrInvalidModeration) {
+		client.SendError(err.Error(), "Handler/Gameseeks/HandlerModerate, Failed to convert message to json: %v\n")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if action.Action == domain.ModerationDelete {
+		type DeletedChat struct {
+			MessageID int `json:"message_id"`
+		}
+		jsonMessage, err := domain_websocket.NewOutboundMessage(
+			topicName,
+			domain_websocket.ChatDeletedEvent,
+			DeletedChat{action.MessageID},
+		).ToJSON("Handler/Gameseeks/HandlerModerate, error converting message to json, err: %v")
+		if err != nil {
+			return err
+		}
+
+		room.BroadcastMessage(jsonMessage)
+	}
+
+	return client.SendMessage(
+		topicName,
+		domain_websocket.ModerateEvent,
+		action,
+		"Handler/Gameseeks/HandlerModerate: error turning action into json\nerr: %v",
+	)
+}
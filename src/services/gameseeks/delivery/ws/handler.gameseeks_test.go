@@ -8,6 +8,7 @@ import (
 
 	"github.com/bxcodec/faker"
 	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	mock_usecase_chat "github.com/lookingcoolonavespa/go_crochess_backend/src/services/chat/usecase/mock"
 	"github.com/lookingcoolonavespa/go_crochess_backend/src/services/gameseeks/repository/mock"
 	mock_usecase_gameseeks "github.com/lookingcoolonavespa/go_crochess_backend/src/services/gameseeks/usecase/mock"
 	domain_websocket "github.com/lookingcoolonavespa/go_crochess_backend/src/websocket"
@@ -28,7 +29,12 @@ func TestGameseeksHandler_HandlerOnSubscribe(t *testing.T) {
 
 	mockRepo.On("List", context.Background()).Return(mockGameseeks, nil).Once()
 
-	r := NewGameseeksHandler(mockRepo, mockUseCase, domain_websocket.TopicWithParam{})
+	mockChat := new(mock_usecase_chat.MockLobbyChatUseCase)
+	mockChat.On("History", context.Background(), "0").Return([]domain.ChatMessage{
+		{ID: 1, Channel: domain.ChatChannelLobby, SenderID: "1", Body: "hi"},
+	}, nil).Once()
+
+	r := NewGameseeksHandler(mockRepo, mockUseCase, mockChat, domain_websocket.TopicWithParam{})
 
 	messageChan := make(chan []byte)
 	client := domain_websocket.NewClient("0", messageChan, nil, nil)
@@ -40,6 +46,7 @@ func TestGameseeksHandler_HandlerOnSubscribe(t *testing.T) {
 	select {
 	case message := <-messageChan:
 		assert.Contains(t, string(message), domain_websocket.InitEvent)
+		assert.Contains(t, string(message), `"chat":[{"id":1`)
 
 	case <-time.After(1 * time.Second):
 		t.Fatal("TestGameseeksHandler_HandlerGetGameseeksList hanging waiting for message")
@@ -56,13 +63,14 @@ func TestGameseeksHandler_HandlerInsertGameseek(t *testing.T) {
 
 	err := faker.FakeData(&mockGameseek)
 	assert.NoError(t, err)
+	mockGameseek.Variant = domain.GameVariantCrazyhouse
 
 	mockRepo := new(repository_gameseeks_mock.GameseeksMockRepo)
 	mockUseCase := new(mock_usecase_gameseeks.GameseeksMockUseCase)
 
 	mockRepo.On("Insert", context.Background(), mockGameseek).Return(nil).Once()
 
-	r := NewGameseeksHandler(mockRepo, mockUseCase, domain_websocket.TopicWithParam{})
+	r := NewGameseeksHandler(mockRepo, mockUseCase, new(mock_usecase_chat.MockLobbyChatUseCase), domain_websocket.TopicWithParam{})
 
 	jsonData, err := json.Marshal(mockGameseek)
 	assert.NoError(t, err)
@@ -91,10 +99,10 @@ func TestGameseeksHandler_HandlerOnUnsubscribe(t *testing.T) {
 		Return(deletedGameseeks, nil).
 		Once()
 
-	r := NewGameseeksHandler(mockRepo, mockUseCase, domain_websocket.TopicWithParam{})
+	r := NewGameseeksHandler(mockRepo, mockUseCase, new(mock_usecase_chat.MockLobbyChatUseCase), domain_websocket.TopicWithParam{})
 
 	subscribedChannel := make(chan []byte)
-	subscribedClient := domain_websocket.NewClient("0", subscribedChannel, nil, nil)
+	subscribedClient := domain_websocket.NewClient("1", subscribedChannel, nil, nil)
 	room := domain_websocket.NewRoom(
 		[]domain.Client{unsubscribeClient, subscribedClient},
 		"",
@@ -119,3 +127,119 @@ func TestGameseeksHandler_HandlerOnUnsubscribe(t *testing.T) {
 
 	mockRepo.AssertExpectations(t)
 }
+
+func TestGameseeksHandler_HandlerChat(t *testing.T) {
+	t.Run("Broadcasts lobby messages", func(t *testing.T) {
+		mockChat := new(mock_usecase_chat.MockLobbyChatUseCase)
+		mockChat.On("Send", context.Background(), "1", "hi").Return(domain.ChatMessage{
+			ID:       3,
+			Channel:  domain.ChatChannelLobby,
+			SenderID: "1",
+			Body:     "hi",
+		}, nil).Once()
+
+		r := NewGameseeksHandler(
+			new(repository_gameseeks_mock.GameseeksMockRepo),
+			new(mock_usecase_gameseeks.GameseeksMockUseCase),
+			mockChat,
+			domain_websocket.TopicWithParam{},
+		)
+
+		senderChan := make(chan []byte, 1)
+		sender := domain_websocket.NewClient("1", senderChan, nil, nil)
+		otherChan := make(chan []byte, 1)
+		other := domain_websocket.NewClient("2", otherChan, nil, nil)
+		room := domain_websocket.NewRoom([]domain.Client{sender, other}, "")
+
+		err := r.HandlerChat(context.Background(), room, sender, []byte(`{"message": "hi"}`))
+		assert.NoError(t, err)
+
+		select {
+		case message := <-otherChan:
+			assert.Contains(t, string(message), domain_websocket.ChatEvent)
+		case <-time.After(time.Second):
+			t.Fatal("message was not broadcast")
+		}
+	})
+
+	t.Run("Only sends shadow muted messages back to their sender", func(t *testing.T) {
+		mockChat := new(mock_usecase_chat.MockLobbyChatUseCase)
+		mockChat.On("Send", context.Background(), "1", "hi").Return(domain.ChatMessage{
+			ID:       3,
+			Channel:  domain.ChatChannelLobby,
+			SenderID: "1",
+			Body:     "hi",
+			Hidden:   true,
+		}, nil).Once()
+
+		r := NewGameseeksHandler(
+			new(repository_gameseeks_mock.GameseeksMockRepo),
+			new(mock_usecase_gameseeks.GameseeksMockUseCase),
+			mockChat,
+			domain_websocket.TopicWithParam{},
+		)
+
+		senderChan := make(chan []byte, 1)
+		sender := domain_websocket.NewClient("1", senderChan, nil, nil)
+		otherChan := make(chan []byte, 1)
+		other := domain_websocket.NewClient("2", otherChan, nil, nil)
+		room := domain_websocket.NewRoom([]domain.Client{sender, other}, "")
+
+		err := r.HandlerChat(context.Background(), room, sender, []byte(`{"message": "hi"}`))
+		assert.NoError(t, err)
+
+		select {
+		case message := <-senderChan:
+			assert.Contains(t, string(message), domain_websocket.ChatEvent)
+		case <-time.After(time.Second):
+			t.Fatal("sender did not get their message back")
+		}
+
+		select {
+		case <-otherChan:
+			t.Fatal("shadow muted message was broadcast")
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+}
+
+func TestGameseeksHandler_HandlerModerate(t *testing.T) {
+	mockChat := new(mock_usecase_chat.MockLobbyChatUseCase)
+	action := domain.ModerationAction{
+		ModeratorID: "mod",
+		Action:      domain.ModerationDelete,
+		MessageID:   3,
+	}
+	mockChat.On("Moderate", context.Background(), action).Return(action, nil).Once()
+
+	r := NewGameseeksHandler(
+		new(repository_gameseeks_mock.GameseeksMockRepo),
+		new(mock_usecase_gameseeks.GameseeksMockUseCase),
+		mockChat,
+		domain_websocket.TopicWithParam{},
+	)
+
+	moderatorChan := make(chan []byte, 2)
+	moderator := domain_websocket.NewClient("mod", moderatorChan, nil, nil)
+	otherChan := make(chan []byte, 1)
+	other := domain_websocket.NewClient("2", otherChan, nil, nil)
+	room := domain_websocket.NewRoom([]domain.Client{moderator, other}, "")
+
+	err := r.HandlerModerate(
+		context.Background(),
+		room,
+		moderator,
+		[]byte(`{"action": "delete", "message_id": 3}`),
+	)
+	assert.NoError(t, err)
+
+	select {
+	case message := <-otherChan:
+		assert.Contains(t, string(message), domain_websocket.ChatDeletedEvent)
+		assert.Contains(t, string(message), `"message_id":3`)
+	case <-time.After(time.Second):
+		t.Fatal("deletion was not broadcast")
+	}
+
+	mockChat.AssertExpectations(t)
+}
@@ -39,6 +39,7 @@ func (c gameseeksRepo) List(ctx context.Context) ([]domain.Gameseek, error) {
 			&gameseek.Time,
 			&gameseek.Increment,
 			&gameseek.Seeker,
+			&gameseek.Variant,
 		)
 		if err != nil {
 			return nil, err
@@ -82,9 +83,10 @@ func (c gameseeksRepo) Insert(ctx context.Context, gs domain.Gameseek) error {
         color,
         time,
         increment,
-        seeker
+        seeker,
+        variant
     ) VALUES (
-        $1, $2, $3, $4
+        $1, $2, $3, $4, $5
     )`,
 	)
 
@@ -95,6 +97,7 @@ func (c gameseeksRepo) Insert(ctx context.Context, gs domain.Gameseek) error {
 		&gs.Time,
 		&gs.Increment,
 		&gs.Seeker,
+		&gs.Variant,
 	)
 	if err != nil {
 		return err
@@ -26,9 +26,9 @@ func TestGameseeksRepository_List(t *testing.T) {
 
 	defer db.Close()
 
-	rows := sqlmock.NewRows([]string{"id", "color", "time", "increment", "seeker"}).
-		AddRow(0, "black", 3000, 0, 5).
-		AddRow(1, "white", 5000, 5, 2)
+	rows := sqlmock.NewRows([]string{"id", "color", "time", "increment", "seeker", "variant"}).
+		AddRow(0, "black", 3000, 0, 5, domain.GameVariantStandard).
+		AddRow(1, "white", 5000, 5, 2, domain.GameVariantCrazyhouse)
 
 	query := fmt.Sprintf(
 		`SELECT * FROM gameseeks`,
@@ -55,9 +55,10 @@ func TestGameseeksRepository_Insert(t *testing.T) {
         color,
         time,
         increment,
-        seeker
+        seeker,
+        variant
     ) VALUES (
-        $1, $2, $3, $4
+        $1, $2, $3, $4, $5
     )`,
 	)
 
@@ -66,7 +67,7 @@ func TestGameseeksRepository_Insert(t *testing.T) {
 	increment := 5
 	seeker := "4"
 
-	mock.ExpectExec(stmt).WithArgs(color, time, increment, seeker).
+	mock.ExpectExec(stmt).WithArgs(color, time, increment, seeker, domain.GameVariantCrazyhouse).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	r := NewGameseeksRepo(db)
@@ -78,6 +79,7 @@ func TestGameseeksRepository_Insert(t *testing.T) {
 			Time:      time,
 			Increment: increment,
 			Seeker:    seeker,
+			Variant:   domain.GameVariantCrazyhouse,
 		})
 
 	assert.NoError(t, err)
@@ -0,0 +1,50 @@
+package delivery_ws_live
+
+import (
+	"context"
+
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	domain_websocket "github.com/lookingcoolonavespa/go_crochess_backend/src/websocket"
+)
+
+const topicName = domain_websocket.LiveTopic
+
+type LiveHandler struct {
+	usecase domain.LiveUseCase
+}
+
+func NewLiveHandler(usecase domain.LiveUseCase) LiveHandler {
+	return LiveHandler{
+		usecase,
+	}
+}
+
+func (l LiveHandler) HandlerOnSubscribe(
+	ctx context.Context,
+	room domain.Room,
+	client domain.Client,
+	_ []byte,
+) error {
+	err := client.Subscribe(room)
+	if err != nil {
+		return err
+	}
+
+	return client.SendMessage(
+		topicName,
+		domain_websocket.InitEvent,
+		l.usecase.List(),
+		"Handler/Live/HandlerOnSubscribe: error turning live games into json\nerr: %v",
+	)
+}
+
+func (l LiveHandler) HandlerOnUnsubscribe(
+	ctx context.Context,
+	room domain.Room,
+	client domain.Client,
+	_ []byte,
+) error {
+	client.Unsubscribe(room)
+
+	return nil
+}
@@ -0,0 +1,171 @@
+package usecase_live
+
+import (
+	"log"
+	"sort"
+	"sync"
+
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	domain_websocket "github.com/lookingcoolonavespa/go_crochess_backend/src/websocket"
+	"github.com/notnil/chess"
+)
+
+const jsonErrorMessage = "UseCase/Live, error converting message to json, err: %v\n"
+
+type liveUseCase struct {
+	room domain.Room
+	// spectators returns how many clients are watching a game
+	spectators func(gameID int) int
+	state      *liveState
+}
+
+type liveState struct {
+	mutex    sync.Mutex
+	games    map[int]*domain.LiveGame
+	featured int
+}
+
+func NewLiveUseCase(
+	room domain.Room,
+	spectators func(gameID int) int,
+) liveUseCase {
+	return liveUseCase{
+		room,
+		spectators,
+		&liveState{games: make(map[int]*domain.LiveGame)},
+	}
+}
+
+func (c liveUseCase) List() domain.LiveGames {
+	c.state.mutex.Lock()
+	defer c.state.mutex.Unlock()
+
+	games := make([]domain.LiveGame, 0, len(c.state.games))
+	for _, g := range c.state.games {
+		g.Spectators = c.spectators(g.ID)
+		games = append(games, *g)
+	}
+	sort.Slice(games, func(i, j int) bool {
+		return games[i].ID < games[j].ID
+	})
+
+	return domain.LiveGames{Games: games, Featured: c.state.featured}
+}
+
+func (c liveUseCase) OnGameCreated(g domain.Game) {
+	liveGame := &domain.LiveGame{
+		ID:                   g.ID,
+		WhiteID:              g.WhiteID,
+		BlackID:              g.BlackID,
+		Time:                 g.Time,
+		Increment:            g.Increment,
+		FEN:                  chess.StartingPosition().String(),
+		WhiteTime:            g.WhiteTime,
+		BlackTime:            g.BlackTime,
+		TimeStampAtTurnStart: g.TimeStampAtTurnStart,
+	}
+
+	c.state.mutex.Lock()
+	c.state.games[g.ID] = liveGame
+	copied := *liveGame
+	featuredChanged := c.updateFeatured()
+	c.state.mutex.Unlock()
+
+	c.broadcast(domain_websocket.InsertEvent, copied)
+	if featuredChanged {
+		c.broadcastFeatured()
+	}
+}
+
+func (c liveUseCase) OnMove(g domain.Game, move domain.GameMove) {
+	c.state.mutex.Lock()
+	liveGame, ok := c.state.games[g.ID]
+	if !ok {
+		c.state.mutex.Unlock()
+		return
+	}
+
+	liveGame.FEN = move.FEN
+	liveGame.LastMove = move.UCI
+	liveGame.TimeStampAtTurnStart = move.TimeStamp
+	// white makes the odd plies
+	if move.Ply%2 == 1 {
+		liveGame.WhiteTime = move.Clock
+	} else {
+		liveGame.BlackTime = move.Clock
+	}
+	liveGame.Spectators = c.spectators(g.ID)
+	copied := *liveGame
+	featuredChanged := c.updateFeatured()
+	c.state.mutex.Unlock()
+
+	c.broadcast(domain_websocket.UpdateEvent, copied)
+	if featuredChanged {
+		c.broadcastFeatured()
+	}
+}
+
+func (c liveUseCase) OnGameOver(g domain.Game) {
+	c.state.mutex.Lock()
+	if _, ok := c.state.games[g.ID]; !ok {
+		c.state.mutex.Unlock()
+		return
+	}
+	delete(c.state.games, g.ID)
+	featuredChanged := c.updateFeatured()
+	c.state.mutex.Unlock()
+
+	c.broadcast(domain_websocket.DeletionEvent, []int{g.ID})
+	if featuredChanged {
+		c.broadcastFeatured()
+	}
+}
+
+// updateFeatured picks the most watched game, keeping the current featured
+// game on ties, and reports whether the featured game changed. It must be
+// called with the mutex held.
+func (c liveUseCase) updateFeatured() bool {
+	featured := 0
+	bestCount := -1
+	_, hasCurrent := c.state.games[c.state.featured]
+	if hasCurrent {
+		featured = c.state.featured
+		bestCount = c.spectators(featured)
+	}
+
+	for id := range c.state.games {
+		count := c.spectators(id)
+		// newer games win ties when there is no featured game to keep
+		if count > bestCount || (!hasCurrent && count == bestCount && id > featured) {
+			featured = id
+			bestCount = count
+		}
+	}
+
+	changed := featured != c.state.featured
+	c.state.featured = featured
+
+	return changed
+}
+
+func (c liveUseCase) broadcastFeatured() {
+	c.state.mutex.Lock()
+	featured := c.state.featured
+	c.state.mutex.Unlock()
+
+	c.broadcast(domain_websocket.FeaturedEvent, featured)
+}
+
+func (c liveUseCase) broadcast(event string, payload interface{}) {
+	jsonData, err := domain_websocket.NewOutboundMessage(
+		domain_websocket.LiveTopic,
+		event,
+		payload,
+	).ToJSON(jsonErrorMessage)
+	if err != nil {
+		log.Printf("UseCase/Live/broadcast, error broadcasting %s: %v", event, err)
+		return
+	}
+
+	c.room.BroadcastMessage(jsonData)
+}
@@ -0,0 +1,63 @@
+package usecase_live
+
+import (
+	"testing"
+
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	domain_websocket "github.com/lookingcoolonavespa/go_crochess_backend/src/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLiveUseCase(t *testing.T) {
+	spectators := make(map[int]int)
+	u := NewLiveUseCase(
+		domain_websocket.NewRoom(make([]domain.Client, 0), ""),
+		func(gameID int) int { return spectators[gameID] },
+	)
+
+	t.Run("Lists created games and keeps the featured game on a tie", func(t *testing.T) {
+		u.OnGameCreated(domain.Game{ID: 1, WhiteID: "w1", BlackID: "b1", WhiteTime: 60000, BlackTime: 60000})
+		u.OnGameCreated(domain.Game{ID: 2, WhiteID: "w2", BlackID: "b2", WhiteTime: 60000, BlackTime: 60000})
+
+		live := u.List()
+		assert.Len(t, live.Games, 2)
+		assert.Equal(t, 1, live.Games[0].ID)
+		assert.Equal(t, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", live.Games[0].FEN)
+		assert.Equal(t, 1, live.Featured)
+	})
+
+	t.Run("Updates position and the mover's clock", func(t *testing.T) {
+		u.OnMove(domain.Game{ID: 1}, domain.GameMove{
+			GameID:    1,
+			Ply:       1,
+			UCI:       "e2e4",
+			FEN:       "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1",
+			Clock:     59000,
+			TimeStamp: 10,
+		})
+
+		game := u.List().Games[0]
+		assert.Equal(t, "e2e4", game.LastMove)
+		assert.Equal(t, "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", game.FEN)
+		assert.Equal(t, 59000, game.WhiteTime)
+		assert.Equal(t, 60000, game.BlackTime)
+		assert.Equal(t, int64(10), game.TimeStampAtTurnStart)
+	})
+
+	t.Run("Features the most watched game", func(t *testing.T) {
+		spectators[2] = 3
+		u.OnMove(domain.Game{ID: 2}, domain.GameMove{GameID: 2, Ply: 1, UCI: "d2d4"})
+
+		live := u.List()
+		assert.Equal(t, 2, live.Featured)
+		assert.Equal(t, 3, live.Games[1].Spectators)
+	})
+
+	t.Run("Removes finished games", func(t *testing.T) {
+		u.OnGameOver(domain.Game{ID: 2})
+
+		live := u.List()
+		assert.Len(t, live.Games, 1)
+		assert.Equal(t, 1, live.Featured)
+	})
+}
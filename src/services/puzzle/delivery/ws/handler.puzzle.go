@@ -0,0 +1,135 @@
+package delivery_ws_puzzle
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	domain_websocket "github.com/lookingcoolonavespa/go_crochess_backend/src/websocket"
+)
+
+const topicName = domain_websocket.PuzzlesTopic
+
+type PuzzleHandler struct {
+	usecase domain.PuzzleUseCase
+}
+
+func NewPuzzleHandler(usecase domain.PuzzleUseCase) PuzzleHandler {
+	return PuzzleHandler{
+		usecase,
+	}
+}
+
+func (p PuzzleHandler) HandlerOnSubscribe(
+	ctx context.Context,
+	room domain.Room,
+	client domain.Client,
+	_ []byte,
+) error {
+	err := client.Subscribe(room)
+	if err != nil {
+		return err
+	}
+
+	return p.sendNextPuzzle(ctx, client, domain_websocket.InitEvent)
+}
+
+func (p PuzzleHandler) HandlerOnUnsubscribe(
+	ctx context.Context,
+	room domain.Room,
+	client domain.Client,
+	_ []byte,
+) error {
+	client.Unsubscribe(room)
+	p.usecase.Abandon(client.GetID())
+
+	return nil
+}
+
+func (p PuzzleHandler) HandlerNextPuzzle(
+	ctx context.Context,
+	room domain.Room,
+	client domain.Client,
+	_ []byte,
+) error {
+	return p.sendNextPuzzle(ctx, client, domain_websocket.NextPuzzleEvent)
+}
+
+func (p PuzzleHandler) HandlerPuzzleMove(
+	ctx context.Context,
+	room domain.Room,
+	client domain.Client,
+	payload []byte,
+) error {
+	type PuzzleMovePayload struct {
+		PuzzleID int    `json:"puzzle_id"`
+		Move     string `json:"move"`
+	}
+	var movePayload PuzzleMovePayload
+	err := json.Unmarshal(payload, &movePayload)
+	if err != nil {
+		log.Printf("Handler/Puzzle/HandlerPuzzleMove: failed to unmarshal payload, err: %v\n", err)
+		return err
+	}
+
+	missingFields := make([]string, 0)
+	if movePayload.PuzzleID == 0 {
+		missingFields = append(missingFields, "puzzle_id")
+	}
+	if movePayload.Move == "" {
+		missingFields = append(missingFields, "move")
+	}
+	if len(missingFields) != 0 {
+		errorMessage := fmt.Sprintf("move is missing the following fields: %v", strings.Join(missingFields, ", "))
+		err = client.SendError(
+			errorMessage,
+			"Handler/Puzzle/HandlerPuzzleMove, Failed to convert message to json: %v\n",
+		)
+		if err != nil {
+			return err
+		}
+
+		return errors.New(errorMessage)
+	}
+
+	result, err := p.usecase.Move(ctx, client.GetID(), movePayload.PuzzleID, movePayload.Move)
+	if err != nil {
+		return client.SendError(
+			err.Error(),
+			"Handler/Puzzle/HandlerPuzzleMove, Failed to convert message to json: %v\n",
+		)
+	}
+
+	return client.SendMessage(
+		topicName,
+		domain_websocket.PuzzleMoveEvent,
+		result,
+		"Handler/Puzzle/HandlerPuzzleMove: error turning result into json\nerr: %v",
+	)
+}
+
+func (p PuzzleHandler) sendNextPuzzle(ctx context.Context, client domain.Client, event string) error {
+	puzzle, err := p.usecase.Next(ctx, client.GetID())
+	if errors.Is(err, sql.ErrNoRows) {
+		return client.SendError(
+			"there are no puzzles left for you to solve",
+			"Handler/Puzzle/sendNextPuzzle, Failed to convert message to json: %v\n",
+		)
+	}
+	if err != nil {
+		log.Printf("Handler/Puzzle/sendNextPuzzle: error getting puzzle\nerr: %v", err)
+		return errors.New(fmt.Sprintf("There was an error retrieving a puzzle. %v", err))
+	}
+
+	return client.SendMessage(
+		topicName,
+		event,
+		puzzle,
+		"Handler/Puzzle/sendNextPuzzle: error turning puzzle into json\nerr: %v",
+	)
+}
@@ -0,0 +1,51 @@
+package repository_puzzle_mock
+
+import (
+	"context"
+
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	"github.com/stretchr/testify/mock"
+)
+
+type PuzzleMockRepo struct {
+	mock.Mock
+}
+
+func (c *PuzzleMockRepo) Get(ctx context.Context, id int) (domain.Puzzle, error) {
+	args := c.Called(ctx, id)
+	result := args.Get(0)
+
+	return result.(domain.Puzzle), args.Error(1)
+}
+
+func (c *PuzzleMockRepo) Next(ctx context.Context, playerID string, rating int) (domain.Puzzle, error) {
+	args := c.Called(ctx, playerID, rating)
+	result := args.Get(0)
+
+	return result.(domain.Puzzle), args.Error(1)
+}
+
+func (c *PuzzleMockRepo) Insert(ctx context.Context, p domain.Puzzle) (int, error) {
+	args := c.Called(ctx, p)
+	result := args.Get(0)
+
+	return result.(int), args.Error(1)
+}
+
+func (c *PuzzleMockRepo) GetPlayerRating(ctx context.Context, playerID string) (int, error) {
+	args := c.Called(ctx, playerID)
+	result := args.Get(0)
+
+	return result.(int), args.Error(1)
+}
+
+func (c *PuzzleMockRepo) RecordAttempt(
+	ctx context.Context,
+	attempt domain.PuzzleAttempt,
+	puzzleRating int,
+	playerRating int,
+) error {
+	args := c.Called(ctx, attempt, puzzleRating, playerRating)
+
+	return args.Error(0)
+}
@@ -0,0 +1,196 @@
+package repository_puzzle
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/lib/pq"
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+)
+
+type puzzleRepo struct {
+	db *sql.DB
+}
+
+func NewPuzzleRepo(db *sql.DB) puzzleRepo {
+	return puzzleRepo{db}
+}
+
+const puzzleColumns = `id,
+        game_id,
+        fen,
+        last_move,
+        solution,
+        themes,
+        rating,
+        attempts,
+        solves`
+
+func scanPuzzle(row *sql.Row) (domain.Puzzle, error) {
+	var p domain.Puzzle
+	err := row.Scan(
+		&p.ID,
+		&p.GameID,
+		&p.FEN,
+		&p.LastMove,
+		pq.Array(&p.Solution),
+		pq.Array(&p.Themes),
+		&p.Rating,
+		&p.Attempts,
+		&p.Solves,
+	)
+
+	return p, err
+}
+
+func (c puzzleRepo) Get(ctx context.Context, id int) (domain.Puzzle, error) {
+	query := fmt.Sprintf(`SELECT %s
+            FROM puzzles
+            WHERE id = $1`,
+		puzzleColumns,
+	)
+
+	p, err := scanPuzzle(c.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		log.Printf("Repo/Puzzle/Get, error getting puzzle: %v\n", err)
+		return domain.Puzzle{}, err
+	}
+
+	return p, nil
+}
+
+func (c puzzleRepo) Next(ctx context.Context, playerID string, rating int) (domain.Puzzle, error) {
+	query := fmt.Sprintf(`SELECT %s
+            FROM puzzles
+            WHERE NOT EXISTS (
+                SELECT 1 FROM puzzle_attempts
+                WHERE puzzle_attempts.puzzle_id = puzzles.id
+                AND puzzle_attempts.player_id = $1
+            )
+            ORDER BY ABS(rating - $2), id
+            LIMIT 1`,
+		puzzleColumns,
+	)
+
+	p, err := scanPuzzle(c.db.QueryRowContext(ctx, query, playerID, rating))
+	if err != nil {
+		log.Printf("Repo/Puzzle/Next, error getting puzzle: %v\n", err)
+		return domain.Puzzle{}, err
+	}
+
+	return p, nil
+}
+
+func (c puzzleRepo) Insert(ctx context.Context, p domain.Puzzle) (puzzleID int, err error) {
+	stmt := `
+    INSERT INTO puzzles (
+        game_id,
+        fen,
+        last_move,
+        solution,
+        themes,
+        rating
+    ) VALUES (
+        $1, $2, $3, $4, $5, $6
+    ) ON CONFLICT (game_id, fen) DO NOTHING
+    RETURNING id`
+
+	err = c.db.QueryRowContext(
+		ctx,
+		stmt,
+		p.GameID,
+		p.FEN,
+		p.LastMove,
+		pq.Array(p.Solution),
+		pq.Array(p.Themes),
+		p.Rating,
+	).Scan(&puzzleID)
+	if errors.Is(err, sql.ErrNoRows) {
+		// the game was already mined
+		return 0, nil
+	}
+	if err != nil {
+		log.Printf("Repo/Puzzle/Insert, error inserting puzzle: %v\n", err)
+		return 0, err
+	}
+
+	return puzzleID, nil
+}
+
+func (c puzzleRepo) GetPlayerRating(ctx context.Context, playerID string) (int, error) {
+	var rating int
+	err := c.db.QueryRowContext(
+		ctx,
+		`SELECT rating FROM puzzle_players WHERE player_id = $1`,
+		playerID,
+	).Scan(&rating)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.DefaultPuzzleRating, nil
+	}
+	if err != nil {
+		log.Printf("Repo/Puzzle/GetPlayerRating, error getting rating: %v\n", err)
+		return 0, err
+	}
+
+	return rating, nil
+}
+
+func (c puzzleRepo) RecordAttempt(
+	ctx context.Context,
+	attempt domain.PuzzleAttempt,
+	puzzleRating int,
+	playerRating int,
+) error {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("Repo/Puzzle/RecordAttempt, error starting transaction: %v\n", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO puzzle_attempts (puzzle_id, player_id, solved, created_at) VALUES ($1, $2, $3, $4)`,
+		attempt.PuzzleID,
+		attempt.PlayerID,
+		attempt.Solved,
+		attempt.CreatedAt,
+	)
+	if err != nil {
+		log.Printf("Repo/Puzzle/RecordAttempt, error inserting attempt: %v\n", err)
+		return err
+	}
+
+	solves := 0
+	if attempt.Solved {
+		solves = 1
+	}
+	_, err = tx.ExecContext(
+		ctx,
+		`UPDATE puzzles SET rating = $1, attempts = attempts + 1, solves = solves + $2 WHERE id = $3`,
+		puzzleRating,
+		solves,
+		attempt.PuzzleID,
+	)
+	if err != nil {
+		log.Printf("Repo/Puzzle/RecordAttempt, error updating puzzle: %v\n", err)
+		return err
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO puzzle_players (player_id, rating) VALUES ($1, $2)
+    ON CONFLICT (player_id) DO UPDATE SET rating = EXCLUDED.rating`,
+		attempt.PlayerID,
+		playerRating,
+	)
+	if err != nil {
+		log.Printf("Repo/Puzzle/RecordAttempt, error updating player rating: %v\n", err)
+		return err
+	}
+
+	return tx.Commit()
+}
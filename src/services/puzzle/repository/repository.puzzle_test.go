@@ -0,0 +1,217 @@
+package repository_puzzle
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func initMock() (*sql.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		log.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	return db, mock
+}
+
+var puzzleRowColumns = []string{
+	"id",
+	"game_id",
+	"fen",
+	"last_move",
+	"solution",
+	"themes",
+	"rating",
+	"attempts",
+	"solves",
+}
+
+var mockPuzzle = domain.Puzzle{
+	ID:       4,
+	GameID:   9,
+	FEN:      "7k/6pp/8/8/8/8/8/RR4K1 w - - 0 1",
+	LastMove: "h7h8",
+	Solution: []string{"a1a8"},
+	Themes:   []string{"mateIn1", "backRank"},
+	Rating:   1650,
+	Attempts: 3,
+	Solves:   2,
+}
+
+func puzzleRows() *sqlmock.Rows {
+	return sqlmock.NewRows(puzzleRowColumns).AddRow(
+		mockPuzzle.ID,
+		mockPuzzle.GameID,
+		mockPuzzle.FEN,
+		mockPuzzle.LastMove,
+		"{a1a8}",
+		"{mateIn1,backRank}",
+		mockPuzzle.Rating,
+		mockPuzzle.Attempts,
+		mockPuzzle.Solves,
+	)
+}
+
+func TestPuzzleRepo_Get(t *testing.T) {
+	db, mock := initMock()
+
+	defer db.Close()
+
+	query := fmt.Sprintf(`SELECT %s
+            FROM puzzles
+            WHERE id = $1`,
+		puzzleColumns,
+	)
+	mock.ExpectQuery(query).WithArgs(mockPuzzle.ID).WillReturnRows(puzzleRows())
+
+	r := NewPuzzleRepo(db)
+
+	p, err := r.Get(context.Background(), mockPuzzle.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, mockPuzzle, p)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPuzzleRepo_Next(t *testing.T) {
+	db, mock := initMock()
+
+	defer db.Close()
+
+	query := fmt.Sprintf(`SELECT %s
+            FROM puzzles
+            WHERE NOT EXISTS (
+                SELECT 1 FROM puzzle_attempts
+                WHERE puzzle_attempts.puzzle_id = puzzles.id
+                AND puzzle_attempts.player_id = $1
+            )
+            ORDER BY ABS(rating - $2), id
+            LIMIT 1`,
+		puzzleColumns,
+	)
+	mock.ExpectQuery(query).WithArgs("7", 1600).WillReturnRows(puzzleRows())
+
+	r := NewPuzzleRepo(db)
+
+	p, err := r.Next(context.Background(), "7", 1600)
+	assert.NoError(t, err)
+	assert.Equal(t, mockPuzzle, p)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPuzzleRepo_Insert(t *testing.T) {
+	stmt := `
+    INSERT INTO puzzles (
+        game_id,
+        fen,
+        last_move,
+        solution,
+        themes,
+        rating
+    ) VALUES (
+        $1, $2, $3, $4, $5, $6
+    ) ON CONFLICT (game_id, fen) DO NOTHING
+    RETURNING id`
+
+	expectInsert := func(mock sqlmock.Sqlmock) *sqlmock.ExpectedQuery {
+		return mock.ExpectQuery(stmt).WithArgs(
+			mockPuzzle.GameID,
+			mockPuzzle.FEN,
+			mockPuzzle.LastMove,
+			pq.Array(mockPuzzle.Solution),
+			pq.Array(mockPuzzle.Themes),
+			mockPuzzle.Rating,
+		)
+	}
+
+	t.Run("Returns the id of the new puzzle", func(t *testing.T) {
+		db, mock := initMock()
+		defer db.Close()
+
+		expectInsert(mock).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(mockPuzzle.ID))
+
+		id, err := NewPuzzleRepo(db).Insert(context.Background(), mockPuzzle)
+		assert.NoError(t, err)
+		assert.Equal(t, mockPuzzle.ID, id)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Skips puzzles that were already mined", func(t *testing.T) {
+		db, mock := initMock()
+		defer db.Close()
+
+		expectInsert(mock).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		id, err := NewPuzzleRepo(db).Insert(context.Background(), mockPuzzle)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, id)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPuzzleRepo_GetPlayerRating(t *testing.T) {
+	query := `SELECT rating FROM puzzle_players WHERE player_id = $1`
+
+	t.Run("Returns the rating of the player", func(t *testing.T) {
+		db, mock := initMock()
+		defer db.Close()
+
+		mock.ExpectQuery(query).WithArgs("7").WillReturnRows(sqlmock.NewRows([]string{"rating"}).AddRow(1720))
+
+		rating, err := NewPuzzleRepo(db).GetPlayerRating(context.Background(), "7")
+		assert.NoError(t, err)
+		assert.Equal(t, 1720, rating)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("New players have the default rating", func(t *testing.T) {
+		db, mock := initMock()
+		defer db.Close()
+
+		mock.ExpectQuery(query).WithArgs("7").WillReturnRows(sqlmock.NewRows([]string{"rating"}))
+
+		rating, err := NewPuzzleRepo(db).GetPlayerRating(context.Background(), "7")
+		assert.NoError(t, err)
+		assert.Equal(t, domain.DefaultPuzzleRating, rating)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPuzzleRepo_RecordAttempt(t *testing.T) {
+	db, mock := initMock()
+
+	defer db.Close()
+
+	attempt := domain.PuzzleAttempt{
+		PuzzleID:  mockPuzzle.ID,
+		PlayerID:  "7",
+		Solved:    true,
+		CreatedAt: 1000,
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO puzzle_attempts (puzzle_id, player_id, solved, created_at) VALUES ($1, $2, $3, $4)`).
+		WithArgs(attempt.PuzzleID, attempt.PlayerID, attempt.Solved, attempt.CreatedAt).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`UPDATE puzzles SET rating = $1, attempts = attempts + 1, solves = solves + $2 WHERE id = $3`).
+		WithArgs(1634, 1, attempt.PuzzleID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO puzzle_players (player_id, rating) VALUES ($1, $2)
+    ON CONFLICT (player_id) DO UPDATE SET rating = EXCLUDED.rating`).
+		WithArgs(attempt.PlayerID, 1516).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	r := NewPuzzleRepo(db)
+
+	err := r.RecordAttempt(context.Background(), attempt, 1634, 1516)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,240 @@
+package usecase_puzzle
+
+import (
+	"fmt"
+	"strings"
+
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	"github.com/notnil/chess"
+)
+
+const (
+	// a position is winning when the side to move is up by this many centipawns
+	winningScore = 300
+	// the opponent's move must have swung the evaluation by this much
+	mistakeSwing = 300
+	// every move but the best has to be at least this much worse
+	uniqueMargin = 200
+	// solutions stop after this many plies even if the line goes on
+	maxSolutionPlies = 7
+	mateScore        = 100000
+)
+
+// score turns an analysis into centipawns so mates can be compared with
+// regular evaluations. Shorter mates score higher.
+func score(a domain.EngineAnalysis) int {
+	switch {
+	case a.Mate > 0:
+		return mateScore - a.Mate*100
+	case a.Mate < 0:
+		return -mateScore - a.Mate*100
+	default:
+		return a.CP
+	}
+}
+
+func uciMoves(moves []*chess.Move) []string {
+	strs := make([]string, len(moves))
+	for i, m := range moves {
+		strs[i] = m.String()
+	}
+
+	return strs
+}
+
+// findPuzzles looks for positions in g where one side made a mistake that
+// left the other side a single winning move.
+func findPuzzles(engine domain.Engine, g domain.Game) ([]domain.Puzzle, error) {
+	gameState := chess.NewGame(chess.UseNotation(chess.UCINotation{}))
+	for _, m := range strings.Fields(g.Moves) {
+		if err := gameState.MoveStr(m); err != nil {
+			return nil, err
+		}
+	}
+
+	positions := gameState.Positions()
+	moves := uciMoves(gameState.Moves())
+
+	analyses := make([]*domain.EngineAnalysis, len(positions))
+	for i, pos := range positions {
+		// finished positions have nothing to analyse
+		if pos.Status() != chess.NoMethod {
+			continue
+		}
+		a, err := engine.Analyse(pos.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		analyses[i] = &a
+	}
+
+	puzzles := make([]domain.Puzzle, 0)
+	for i := 1; i < len(positions); i++ {
+		if analyses[i-1] == nil || analyses[i] == nil {
+			continue
+		}
+
+		// both scores from the point of view of the side to move after the mistake
+		before := -score(*analyses[i-1])
+		after := score(*analyses[i])
+		if after < winningScore || after-before < mistakeSwing {
+			continue
+		}
+
+		solution, err := findSolution(engine, positions[i], *analyses[i])
+		if err != nil {
+			return nil, err
+		}
+		if len(solution) == 0 {
+			continue
+		}
+
+		puzzles = append(puzzles, domain.Puzzle{
+			GameID:   g.ID,
+			FEN:      positions[i].String(),
+			LastMove: moves[i-1],
+			Solution: solution,
+			Themes:   themes(positions[i], solution, *analyses[i]),
+			Rating:   domain.DefaultPuzzleRating,
+		})
+
+		// the moves of the solution can't start another puzzle
+		i += len(solution)
+	}
+
+	return puzzles, nil
+}
+
+// findSolution follows the engine's line from pos for as long as each of the
+// solver's moves is the only winning one. The line always ends on a solver's
+// move and is empty when the first move isn't unique.
+func findSolution(
+	engine domain.Engine,
+	pos *chess.Position,
+	analysis domain.EngineAnalysis,
+) ([]string, error) {
+	solution := make([]string, 0)
+
+	for len(solution) < maxSolutionPlies {
+		unique, err := isUnique(engine, pos, analysis)
+		if err != nil {
+			return nil, err
+		}
+		if !unique {
+			break
+		}
+
+		move, err := chess.UCINotation{}.Decode(pos, analysis.BestMove)
+		if err != nil {
+			return nil, err
+		}
+		solution = append(solution, analysis.BestMove)
+		pos = pos.Update(move)
+		if pos.Status() != chess.NoMethod || len(solution)+2 > maxSolutionPlies {
+			break
+		}
+
+		reply, err := engine.Analyse(pos.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		replyMove, err := chess.UCINotation{}.Decode(pos, reply.BestMove)
+		if err != nil {
+			return nil, err
+		}
+		next := pos.Update(replyMove)
+		if next.Status() != chess.NoMethod {
+			break
+		}
+
+		nextAnalysis, err := engine.Analyse(next.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		if score(nextAnalysis) < winningScore {
+			break
+		}
+
+		// only keep the reply if the solver has another unique move after it
+		unique, err = isUnique(engine, next, nextAnalysis)
+		if err != nil {
+			return nil, err
+		}
+		if !unique {
+			break
+		}
+
+		solution = append(solution, reply.BestMove)
+		pos = next
+		analysis = nextAnalysis
+	}
+
+	return solution, nil
+}
+
+// isUnique reports whether the best move in analysis is winning while every
+// other legal move isn't
+func isUnique(engine domain.Engine, pos *chess.Position, analysis domain.EngineAnalysis) (bool, error) {
+	best := score(analysis)
+	if best < winningScore {
+		return false, nil
+	}
+
+	others := make([]string, 0)
+	for _, m := range pos.ValidMoves() {
+		if m.String() != analysis.BestMove {
+			others = append(others, m.String())
+		}
+	}
+	// a forced move isn't a puzzle
+	if len(others) == 0 {
+		return false, nil
+	}
+
+	second, err := engine.Analyse(pos.String(), others)
+	if err != nil {
+		return false, err
+	}
+
+	secondScore := score(second)
+	return secondScore < winningScore && best-secondScore >= uniqueMargin, nil
+}
+
+func themes(pos *chess.Position, solution []string, analysis domain.EngineAnalysis) []string {
+	themes := make([]string, 0)
+
+	if analysis.Mate > 0 {
+		themes = append(themes, "mate")
+		if analysis.Mate <= 5 {
+			themes = append(themes, fmt.Sprintf("mateIn%d", analysis.Mate))
+		}
+	} else if analysis.CP >= 2*winningScore {
+		themes = append(themes, "crushing")
+	} else {
+		themes = append(themes, "advantage")
+	}
+
+	switch solverMoves := (len(solution) + 1) / 2; {
+	case solverMoves == 1:
+		themes = append(themes, "oneMove")
+	case solverMoves == 2:
+		themes = append(themes, "short")
+	default:
+		themes = append(themes, "long")
+	}
+
+	for i, s := range solution {
+		// promotions are written with a fifth character, e.g. e7e8q
+		if i%2 == 0 && len(s) == 5 {
+			themes = append(themes, "promotion")
+			break
+		}
+	}
+
+	firstMove, err := chess.UCINotation{}.Decode(pos, solution[0])
+	if err == nil && firstMove.HasTag(chess.Capture) {
+		themes = append(themes, "capture")
+	}
+
+	return themes
+}
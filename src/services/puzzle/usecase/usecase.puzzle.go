@@ -0,0 +1,257 @@
+package usecase_puzzle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	"github.com/notnil/chess"
+)
+
+const ratingK = 32
+
+// MiningQueueSize is how many finished games can wait to be mined. Games that
+// end while the queue is full are not mined.
+const MiningQueueSize = 64
+
+var timeNow = time.Now
+
+type puzzleUseCase struct {
+	puzzleRepo domain.PuzzleRepo
+	// engine is nil when no engine is configured, in which case games are not mined
+	engine   domain.Engine
+	sessions *sessions
+	// mining holds the games waiting for the engine, which is slow enough
+	// that it can't run in the game over hooks
+	mining chan domain.Game
+}
+
+// session is the puzzle a player is solving and how far into its solution
+// they are
+type session struct {
+	puzzle domain.Puzzle
+	ply    int
+}
+
+type sessions struct {
+	mutex    sync.Mutex
+	byPlayer map[string]*session
+}
+
+func NewPuzzleUseCase(
+	puzzleRepo domain.PuzzleRepo,
+	engine domain.Engine,
+) puzzleUseCase {
+	c := puzzleUseCase{
+		puzzleRepo,
+		engine,
+		&sessions{byPlayer: make(map[string]*session)},
+		make(chan domain.Game, MiningQueueSize),
+	}
+	if engine != nil {
+		go c.mine()
+	}
+
+	return c
+}
+
+// Next gives the player a new puzzle. A puzzle they were still solving
+// counts as failed, so skipping puzzles doesn't keep their rating up.
+func (c puzzleUseCase) Next(ctx context.Context, playerID string) (domain.Puzzle, error) {
+	c.sessions.mutex.Lock()
+	unfinished, ok := c.sessions.byPlayer[playerID]
+	delete(c.sessions.byPlayer, playerID)
+	c.sessions.mutex.Unlock()
+
+	if ok {
+		_, _, err := c.recordAttempt(ctx, playerID, unfinished.puzzle, false)
+		if err != nil {
+			return domain.Puzzle{}, err
+		}
+	}
+
+	rating, err := c.puzzleRepo.GetPlayerRating(ctx, playerID)
+	if err != nil {
+		return domain.Puzzle{}, err
+	}
+
+	puzzle, err := c.puzzleRepo.Next(ctx, playerID, rating)
+	if err != nil {
+		return domain.Puzzle{}, err
+	}
+
+	c.sessions.mutex.Lock()
+	c.sessions.byPlayer[playerID] = &session{puzzle: puzzle}
+	c.sessions.mutex.Unlock()
+
+	return puzzle, nil
+}
+
+func (c puzzleUseCase) Move(
+	ctx context.Context,
+	playerID string,
+	puzzleID int,
+	move string,
+) (domain.PuzzleMoveResult, error) {
+	c.sessions.mutex.Lock()
+	s, ok := c.sessions.byPlayer[playerID]
+	if !ok || s.puzzle.ID != puzzleID {
+		c.sessions.mutex.Unlock()
+		return domain.PuzzleMoveResult{}, errors.New(fmt.Sprintf("puzzle %d is not being solved", puzzleID))
+	}
+
+	// any mate is as good as the one in the solution
+	mate := s.puzzle.Solution[s.ply] != move && mates(s.puzzle, s.ply, move)
+	correct := s.puzzle.Solution[s.ply] == move || mate
+	// the solver's last move ends the puzzle since solutions end on their move
+	done := !correct || mate || s.ply+1 >= len(s.puzzle.Solution)
+
+	result := domain.PuzzleMoveResult{
+		PuzzleID: puzzleID,
+		Correct:  correct,
+		Done:     done,
+	}
+	if !done {
+		result.Reply = s.puzzle.Solution[s.ply+1]
+		s.ply += 2
+		c.sessions.mutex.Unlock()
+		return result, nil
+	}
+
+	delete(c.sessions.byPlayer, playerID)
+	c.sessions.mutex.Unlock()
+
+	newPlayerRating, newPuzzleRating, err := c.recordAttempt(ctx, playerID, s.puzzle, correct)
+	if err != nil {
+		return domain.PuzzleMoveResult{}, err
+	}
+
+	result.Solution = s.puzzle.Solution
+	result.PlayerRating = newPlayerRating
+	result.PuzzleRating = newPuzzleRating
+
+	return result, nil
+}
+
+// recordAttempt saves the attempt of the player at puzzle and returns the
+// new ratings of the player and the puzzle
+func (c puzzleUseCase) recordAttempt(
+	ctx context.Context,
+	playerID string,
+	puzzle domain.Puzzle,
+	solved bool,
+) (playerRating int, puzzleRating int, err error) {
+	playerRating, err = c.puzzleRepo.GetPlayerRating(ctx, playerID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	playerRating, puzzleRating = updateRatings(playerRating, puzzle.Rating, solved)
+	err = c.puzzleRepo.RecordAttempt(
+		ctx,
+		domain.PuzzleAttempt{
+			PuzzleID:  puzzle.ID,
+			PlayerID:  playerID,
+			Solved:    solved,
+			CreatedAt: timeNow().UnixMilli(),
+		},
+		puzzleRating,
+		playerRating,
+	)
+	if err != nil {
+		log.Printf("Usecase/Puzzle/recordAttempt, error recording attempt: %v", err)
+		return 0, 0, err
+	}
+
+	return playerRating, puzzleRating, nil
+}
+
+// mates reports whether move checkmates in the position of puzzle after the
+// first ply moves of its solution
+func mates(puzzle domain.Puzzle, ply int, move string) bool {
+	fen, err := chess.FEN(puzzle.FEN)
+	if err != nil {
+		return false
+	}
+
+	g := chess.NewGame(fen, chess.UseNotation(chess.UCINotation{}))
+	for _, m := range puzzle.Solution[:ply] {
+		if err := g.MoveStr(m); err != nil {
+			return false
+		}
+	}
+	if err := g.MoveStr(move); err != nil {
+		return false
+	}
+
+	return g.Method() == chess.Checkmate
+}
+
+// Abandon drops the puzzle the player is solving without recording an attempt
+func (c puzzleUseCase) Abandon(playerID string) {
+	c.sessions.mutex.Lock()
+	defer c.sessions.mutex.Unlock()
+	delete(c.sessions.byPlayer, playerID)
+}
+
+// OnGameOver queues g to have its puzzles saved. It doesn't wait for the
+// engine.
+func (c puzzleUseCase) OnGameOver(ctx context.Context, g domain.Game) error {
+	// the engine only analyses standard chess
+	if c.engine == nil || !g.IsStandard() {
+		return nil
+	}
+
+	select {
+	case c.mining <- g:
+		return nil
+	default:
+		return fmt.Errorf("mining queue is full, skipping game %d", g.ID)
+	}
+}
+
+// mine saves the puzzles of the queued games one at a time, since there is
+// only one engine
+func (c puzzleUseCase) mine() {
+	for g := range c.mining {
+		if err := c.savePuzzles(context.Background(), g); err != nil {
+			log.Printf("Usecase/Puzzle/mine, error generating puzzles from game %d: %v", g.ID, err)
+		}
+	}
+}
+
+// savePuzzles saves the puzzles found in g
+func (c puzzleUseCase) savePuzzles(ctx context.Context, g domain.Game) error {
+	puzzles, err := findPuzzles(c.engine, g)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range puzzles {
+		if _, err := c.puzzleRepo.Insert(ctx, p); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// updateRatings returns the new player and puzzle ratings after an attempt,
+// treating the attempt as a game between the player and the puzzle
+func updateRatings(playerRating int, puzzleRating int, solved bool) (int, int) {
+	expected := 1 / (1 + math.Pow(10, float64(puzzleRating-playerRating)/400))
+
+	score := 0.0
+	if solved {
+		score = 1
+	}
+
+	delta := int(math.Round(ratingK * (score - expected)))
+
+	return playerRating + delta, puzzleRating - delta
+}
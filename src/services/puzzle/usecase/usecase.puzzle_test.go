@@ -0,0 +1,271 @@
+package usecase_puzzle
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	repository_puzzle_mock "github.com/lookingcoolonavespa/go_crochess_backend/src/services/puzzle/repository/mock"
+	"github.com/notnil/chess"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// scriptedEngine returns the analyses it was given for a position and a
+// neutral evaluation of the first legal move otherwise
+type scriptedEngine struct {
+	best   map[string]domain.EngineAnalysis
+	others map[string]domain.EngineAnalysis
+}
+
+func (e scriptedEngine) Analyse(fen string, searchMoves []string) (domain.EngineAnalysis, error) {
+	script := e.best
+	if len(searchMoves) > 0 {
+		script = e.others
+	}
+	if a, ok := script[fen]; ok {
+		return a, nil
+	}
+
+	fenOpt, err := chess.FEN(fen)
+	if err != nil {
+		return domain.EngineAnalysis{}, err
+	}
+	moves := chess.NewGame(fenOpt).ValidMoves()
+	if len(searchMoves) > 0 {
+		return domain.EngineAnalysis{BestMove: searchMoves[0]}, nil
+	}
+	if len(moves) == 0 {
+		return domain.EngineAnalysis{}, errors.New("no moves")
+	}
+
+	return domain.EngineAnalysis{BestMove: moves[0].String()}, nil
+}
+
+func fenAfter(t *testing.T, moves ...string) string {
+	g := chess.NewGame(chess.UseNotation(chess.UCINotation{}))
+	for _, m := range moves {
+		assert.NoError(t, g.MoveStr(m))
+	}
+
+	return g.Position().String()
+}
+
+func TestFindPuzzles(t *testing.T) {
+	moves := []string{"e2e4", "e7e5", "d1h5", "b8c6", "f1c4", "g8f6"}
+	mistakeFEN := fenAfter(t, moves...)
+
+	t.Run("Finds mate after a blunder", func(t *testing.T) {
+		engine := scriptedEngine{
+			best:   map[string]domain.EngineAnalysis{mistakeFEN: {BestMove: "h5f7", Mate: 1}},
+			others: map[string]domain.EngineAnalysis{mistakeFEN: {BestMove: "h5e5", CP: 50}},
+		}
+
+		puzzles, err := findPuzzles(engine, domain.Game{ID: 3, Moves: "e2e4 e7e5 d1h5 b8c6 f1c4 g8f6", Result: "1-0"})
+		assert.NoError(t, err)
+
+		assert.Equal(t, []domain.Puzzle{{
+			GameID:   3,
+			FEN:      mistakeFEN,
+			LastMove: "g8f6",
+			Solution: []string{"h5f7"},
+			Themes:   []string{"mate", "mateIn1", "oneMove", "capture"},
+			Rating:   domain.DefaultPuzzleRating,
+		}}, puzzles)
+	})
+
+	t.Run("Skips positions with more than one winning move", func(t *testing.T) {
+		engine := scriptedEngine{
+			best:   map[string]domain.EngineAnalysis{mistakeFEN: {BestMove: "h5f7", Mate: 1}},
+			others: map[string]domain.EngineAnalysis{mistakeFEN: {BestMove: "c4f7", CP: 800}},
+		}
+
+		puzzles, err := findPuzzles(engine, domain.Game{ID: 3, Moves: "e2e4 e7e5 d1h5 b8c6 f1c4 g8f6", Result: "1-0"})
+		assert.NoError(t, err)
+		assert.Empty(t, puzzles)
+	})
+}
+
+// blockedEngine is a scriptedEngine that waits for release before analysing
+type blockedEngine struct {
+	scriptedEngine
+	release chan struct{}
+}
+
+func (e blockedEngine) Analyse(fen string, searchMoves []string) (domain.EngineAnalysis, error) {
+	<-e.release
+	return e.scriptedEngine.Analyse(fen, searchMoves)
+}
+
+func TestPuzzleUseCase_OnGameOver(t *testing.T) {
+	mistakeFEN := fenAfter(t, "e2e4", "e7e5", "d1h5", "b8c6", "f1c4", "g8f6")
+	engine := blockedEngine{
+		scriptedEngine{
+			best:   map[string]domain.EngineAnalysis{mistakeFEN: {BestMove: "h5f7", Mate: 1}},
+			others: map[string]domain.EngineAnalysis{mistakeFEN: {BestMove: "h5e5", CP: 50}},
+		},
+		make(chan struct{}),
+	}
+
+	inserted := make(chan domain.Puzzle, 1)
+	mockRepo := new(repository_puzzle_mock.PuzzleMockRepo)
+	mockRepo.On("Insert", context.Background(), mock.Anything).Return(1, nil).Run(func(args mock.Arguments) {
+		inserted <- args.Get(1).(domain.Puzzle)
+	})
+
+	u := NewPuzzleUseCase(mockRepo, engine)
+
+	err := u.OnGameOver(context.Background(), domain.Game{ID: 3, Moves: "e2e4 e7e5 d1h5 b8c6 f1c4 g8f6", Result: "1-0"})
+	assert.NoError(t, err, "returns before the engine analysed the game")
+
+	close(engine.release)
+	select {
+	case p := <-inserted:
+		assert.Equal(t, mistakeFEN, p.FEN)
+	case <-time.After(time.Second):
+		t.Error("the game was never mined")
+	}
+}
+
+func TestPuzzleUseCase_Move(t *testing.T) {
+	timeNow = func() time.Time {
+		return time.Date(2023, time.October, 10, 2, 10, 10, 10, time.UTC)
+	}
+
+	puzzle := domain.Puzzle{
+		ID:       4,
+		Solution: []string{"d1d8", "e8d8", "a1a8"},
+		Rating:   1500,
+	}
+
+	setup := func() (puzzleUseCase, *repository_puzzle_mock.PuzzleMockRepo) {
+		mockRepo := new(repository_puzzle_mock.PuzzleMockRepo)
+		mockRepo.On("GetPlayerRating", context.Background(), "7").Return(1500, nil)
+		mockRepo.On("Next", context.Background(), "7", 1500).Return(puzzle, nil).Once()
+
+		u := NewPuzzleUseCase(mockRepo, nil)
+		_, err := u.Next(context.Background(), "7")
+		assert.NoError(t, err)
+
+		return u, mockRepo
+	}
+
+	t.Run("Solves every move", func(t *testing.T) {
+		u, mockRepo := setup()
+		mockRepo.On("RecordAttempt", context.Background(), domain.PuzzleAttempt{
+			PuzzleID:  puzzle.ID,
+			PlayerID:  "7",
+			Solved:    true,
+			CreatedAt: timeNow().UnixMilli(),
+		}, 1484, 1516).Return(nil).Once()
+
+		result, err := u.Move(context.Background(), "7", puzzle.ID, "d1d8")
+		assert.NoError(t, err)
+		assert.Equal(t, domain.PuzzleMoveResult{PuzzleID: puzzle.ID, Correct: true, Reply: "e8d8"}, result)
+
+		result, err = u.Move(context.Background(), "7", puzzle.ID, "a1a8")
+		assert.NoError(t, err)
+		assert.Equal(t, domain.PuzzleMoveResult{
+			PuzzleID:     puzzle.ID,
+			Correct:      true,
+			Done:         true,
+			Solution:     puzzle.Solution,
+			PlayerRating: 1516,
+			PuzzleRating: 1484,
+		}, result)
+
+		_, err = u.Move(context.Background(), "7", puzzle.ID, "a1a8")
+		assert.Error(t, err)
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Fails on a wrong move", func(t *testing.T) {
+		u, mockRepo := setup()
+		mockRepo.On("RecordAttempt", context.Background(), domain.PuzzleAttempt{
+			PuzzleID:  puzzle.ID,
+			PlayerID:  "7",
+			Solved:    false,
+			CreatedAt: timeNow().UnixMilli(),
+		}, 1516, 1484).Return(nil).Once()
+
+		result, err := u.Move(context.Background(), "7", puzzle.ID, "a1a8")
+		assert.NoError(t, err)
+		assert.False(t, result.Correct)
+		assert.True(t, result.Done)
+		assert.Equal(t, puzzle.Solution, result.Solution)
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Accepts any mate", func(t *testing.T) {
+		backRank := domain.Puzzle{
+			ID:       5,
+			FEN:      "7k/6pp/8/8/8/8/8/RR4K1 w - - 0 1",
+			Solution: []string{"a1a8"},
+			Rating:   1500,
+		}
+		mockRepo := new(repository_puzzle_mock.PuzzleMockRepo)
+		mockRepo.On("GetPlayerRating", context.Background(), "7").Return(1500, nil)
+		mockRepo.On("Next", context.Background(), "7", 1500).Return(backRank, nil).Once()
+		mockRepo.On("RecordAttempt", context.Background(), domain.PuzzleAttempt{
+			PuzzleID:  backRank.ID,
+			PlayerID:  "7",
+			Solved:    true,
+			CreatedAt: timeNow().UnixMilli(),
+		}, 1484, 1516).Return(nil).Once()
+
+		u := NewPuzzleUseCase(mockRepo, nil)
+		_, err := u.Next(context.Background(), "7")
+		assert.NoError(t, err)
+
+		result, err := u.Move(context.Background(), "7", backRank.ID, "b1b8")
+		assert.NoError(t, err)
+		assert.True(t, result.Correct)
+		assert.True(t, result.Done)
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Skipping a puzzle fails it", func(t *testing.T) {
+		u, mockRepo := setup()
+		mockRepo.On("RecordAttempt", context.Background(), domain.PuzzleAttempt{
+			PuzzleID:  puzzle.ID,
+			PlayerID:  "7",
+			Solved:    false,
+			CreatedAt: timeNow().UnixMilli(),
+		}, 1516, 1484).Return(nil).Once()
+		mockRepo.On("Next", context.Background(), "7", 1500).Return(domain.Puzzle{ID: 6}, nil).Once()
+
+		next, err := u.Next(context.Background(), "7")
+		assert.NoError(t, err)
+		assert.Equal(t, 6, next.ID)
+
+		_, err = u.Move(context.Background(), "7", puzzle.ID, "d1d8")
+		assert.Error(t, err)
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Abandoned puzzles are not recorded", func(t *testing.T) {
+		u, mockRepo := setup()
+		u.Abandon("7")
+
+		_, err := u.Move(context.Background(), "7", puzzle.ID, "d1d8")
+		assert.Error(t, err)
+
+		mockRepo.AssertNotCalled(t, "RecordAttempt")
+	})
+}
+
+func TestUpdateRatings(t *testing.T) {
+	player, puzzle := updateRatings(1200, 1800, true)
+	assert.Equal(t, 1231, player)
+	assert.Equal(t, 1769, puzzle)
+
+	player, puzzle = updateRatings(1200, 1800, false)
+	assert.Equal(t, 1199, player)
+	assert.Equal(t, 1801, puzzle)
+}
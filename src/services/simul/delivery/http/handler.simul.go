@@ -0,0 +1,72 @@
+package delivery_http_simul
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+)
+
+type SimulHandler struct {
+	simuls domain.SimulUseCase
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func NewSimulHandler(simuls domain.SimulUseCase) SimulHandler {
+	return SimulHandler{
+		simuls,
+	}
+}
+
+func (s SimulHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/simuls", s.HandlerListSimuls)
+	router.POST("/api/simuls", s.HandlerCreateSimul)
+}
+
+// HandlerListSimuls returns the simuls that didn't finish yet
+func (s SimulHandler) HandlerListSimuls(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	writeJSON(w, http.StatusOK, s.simuls.List())
+}
+
+// HandlerCreateSimul creates a simul from its host, the host's color and clock
+// and the time control. Players then join it on its simul topic.
+func (s SimulHandler) HandlerCreateSimul(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var body domain.Simul
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "body is not a valid simul")
+		return
+	}
+
+	simul, err := s.simuls.Create(domain.Simul{
+		HostID:    body.HostID,
+		HostColor: body.HostColor,
+		Time:      body.Time,
+		Increment: body.Increment,
+		HostClock: body.HostClock,
+		HostTime:  body.HostTime,
+	})
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, simul)
+}
+
+func writeJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		log.Printf("Handler/HTTP/Simul/writeJSON, error encoding response: %v\n", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, errorResponse{message})
+}
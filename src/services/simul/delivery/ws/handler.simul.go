@@ -0,0 +1,140 @@
+package delivery_ws_simul
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	domain_websocket "github.com/lookingcoolonavespa/go_crochess_backend/src/websocket"
+)
+
+const jsonErrorMessage = "Handler/Simul, Failed to convert message to json: %v\n"
+
+type SimulHandler struct {
+	simuls domain.SimulUseCase
+}
+
+func NewSimulHandler(simuls domain.SimulUseCase) SimulHandler {
+	return SimulHandler{
+		simuls,
+	}
+}
+
+func simulID(room domain.Room) (int, error) {
+	param, err := room.GetParam()
+	if err != nil {
+		log.Printf("Handler/Simul: room is missing param")
+		return 0, err
+	}
+
+	id, err := strconv.Atoi(param)
+	if err != nil {
+		log.Printf("Handler/Simul: param is not a valid int\nroom.Param: %v", param)
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// HandlerOnSubscribe sends the simul with every board to the client, which is
+// the host's combined feed once the simul started
+func (s SimulHandler) HandlerOnSubscribe(
+	ctx context.Context,
+	room domain.Room,
+	client domain.Client,
+	_ []byte,
+) error {
+	id, err := simulID(room)
+	if err != nil {
+		return err
+	}
+
+	state, ok := s.simuls.Get(id)
+	if !ok {
+		return client.SendError(
+			fmt.Sprintf("simul %d is not running", id),
+			jsonErrorMessage,
+		)
+	}
+
+	err = client.Subscribe(room)
+	if err != nil {
+		return err
+	}
+
+	return client.SendMessage(
+		fmt.Sprint(domain_websocket.SimulTopic, "/", id),
+		domain_websocket.InitEvent,
+		state,
+		"Handler/Simul/HandlerOnSubscribe: error turning simul into json\nerr: %v",
+	)
+}
+
+func (s SimulHandler) HandlerOnUnsubscribe(
+	ctx context.Context,
+	room domain.Room,
+	client domain.Client,
+	_ []byte,
+) error {
+	client.Unsubscribe(room)
+	return nil
+}
+
+func (s SimulHandler) HandlerJoin(
+	ctx context.Context,
+	room domain.Room,
+	client domain.Client,
+	_ []byte,
+) error {
+	id, err := simulID(room)
+	if err != nil {
+		return err
+	}
+
+	err = s.simuls.Join(id, client.GetID())
+	if err != nil {
+		return client.SendError(err.Error(), jsonErrorMessage)
+	}
+
+	return nil
+}
+
+func (s SimulHandler) HandlerWithdraw(
+	ctx context.Context,
+	room domain.Room,
+	client domain.Client,
+	_ []byte,
+) error {
+	id, err := simulID(room)
+	if err != nil {
+		return err
+	}
+
+	err = s.simuls.Leave(id, client.GetID())
+	if err != nil {
+		return client.SendError(err.Error(), jsonErrorMessage)
+	}
+
+	return nil
+}
+
+func (s SimulHandler) HandlerStart(
+	ctx context.Context,
+	room domain.Room,
+	client domain.Client,
+	_ []byte,
+) error {
+	id, err := simulID(room)
+	if err != nil {
+		return err
+	}
+
+	err = s.simuls.Start(ctx, id, client.GetID())
+	if err != nil {
+		return client.SendError(err.Error(), jsonErrorMessage)
+	}
+
+	return nil
+}
@@ -0,0 +1,550 @@
+package usecase_simul
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	domain_websocket "github.com/lookingcoolonavespa/go_crochess_backend/src/websocket"
+	"github.com/notnil/chess"
+)
+
+var timeNow = time.Now
+
+const jsonErrorMessage = "UseCase/Simul, error converting message to json, err: %v\n"
+
+// simulUseCase runs simuls. They only live in memory, so a restart drops them
+// while their games go on like any other game.
+type simulUseCase struct {
+	// startGame creates a game and returns its id
+	startGame func(ctx context.Context, g domain.Game) (gameID int, err error)
+	// endGame sets the result of an unfinished game, like when the host's
+	// shared clock runs out
+	endGame func(ctx context.Context, gameID int, result string) error
+	// room returns the room of a simul's topic, if anyone subscribed
+	room   func(simulID int) (domain.Room, bool)
+	simuls *simuls
+}
+
+type simuls struct {
+	mutex  sync.Mutex
+	byID   map[int]*simul
+	byGame map[int]int
+	lastID int
+}
+
+type simul struct {
+	simul  domain.Simul
+	boards []domain.SimulBoard
+	// the shared clock is charged with the time since runningSince whenever
+	// a board changes and timer ends the simul when it runs out
+	runningSince int64
+	timer        *time.Timer
+}
+
+func NewSimulUseCase(
+	startGame func(ctx context.Context, g domain.Game) (gameID int, err error),
+	endGame func(ctx context.Context, gameID int, result string) error,
+	room func(simulID int) (domain.Room, bool),
+) simulUseCase {
+	return simulUseCase{
+		startGame,
+		endGame,
+		room,
+		&simuls{
+			byID:   make(map[int]*simul),
+			byGame: make(map[int]int),
+		},
+	}
+}
+
+func simulTopic(simulID int) string {
+	return fmt.Sprint(domain_websocket.SimulTopic, "/", simulID)
+}
+
+// Create creates a simul. The host plays white and keeps an individual clock
+// unless told otherwise.
+func (c simulUseCase) Create(s domain.Simul) (domain.Simul, error) {
+	if s.HostID == "" || s.Time <= 0 {
+		return domain.Simul{}, errors.New("simul needs a host and a time control")
+	}
+	if s.HostColor == "" {
+		s.HostColor = domain.White
+	}
+	if s.HostColor != domain.White && s.HostColor != domain.Black {
+		return domain.Simul{}, errors.New(fmt.Sprintf("%s is not a color", s.HostColor))
+	}
+	switch s.HostClock {
+	case "":
+		s.HostClock = domain.SimulClockIndividual
+	case domain.SimulClockIndividual, domain.SimulClockNone, domain.SimulClockShared:
+	default:
+		return domain.Simul{}, errors.New(fmt.Sprintf("%s is not a host clock", s.HostClock))
+	}
+	if s.HostClock != domain.SimulClockShared {
+		s.HostTime = 0
+	}
+	s.Status = domain.SimulStatusCreated
+	s.Participants = make([]string, 0)
+
+	c.simuls.mutex.Lock()
+	defer c.simuls.mutex.Unlock()
+	c.simuls.lastID++
+	s.ID = c.simuls.lastID
+	c.simuls.byID[s.ID] = &simul{simul: s}
+
+	return s, nil
+}
+
+func (c simulUseCase) Get(simulID int) (domain.SimulState, bool) {
+	c.simuls.mutex.Lock()
+	defer c.simuls.mutex.Unlock()
+
+	s, ok := c.simuls.byID[simulID]
+	if !ok {
+		return domain.SimulState{}, false
+	}
+
+	return s.state(timeNow().UnixMilli()), true
+}
+
+// List returns the simuls that didn't finish yet
+func (c simulUseCase) List() []domain.Simul {
+	c.simuls.mutex.Lock()
+	defer c.simuls.mutex.Unlock()
+
+	list := make([]domain.Simul, 0, len(c.simuls.byID))
+	for _, s := range c.simuls.byID {
+		list = append(list, s.copySimul())
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ID < list[j].ID
+	})
+
+	return list
+}
+
+func (c simulUseCase) Join(simulID int, playerID string) error {
+	c.simuls.mutex.Lock()
+	s, ok := c.simuls.byID[simulID]
+	if !ok {
+		c.simuls.mutex.Unlock()
+		return domain.ErrSimulNotFound
+	}
+	if s.simul.Status != domain.SimulStatusCreated {
+		c.simuls.mutex.Unlock()
+		return domain.ErrSimulStarted
+	}
+	if playerID == s.simul.HostID {
+		c.simuls.mutex.Unlock()
+		return errors.New("the host can't join their own simul")
+	}
+	for _, id := range s.simul.Participants {
+		if id == playerID {
+			c.simuls.mutex.Unlock()
+			return nil
+		}
+	}
+	s.simul.Participants = append(s.simul.Participants, playerID)
+	joined := s.copySimul()
+	c.simuls.mutex.Unlock()
+
+	c.broadcast(simulID, domain_websocket.JoinEvent, joined)
+	return nil
+}
+
+func (c simulUseCase) Leave(simulID int, playerID string) error {
+	c.simuls.mutex.Lock()
+	s, ok := c.simuls.byID[simulID]
+	if !ok {
+		c.simuls.mutex.Unlock()
+		return domain.ErrSimulNotFound
+	}
+	if s.simul.Status != domain.SimulStatusCreated {
+		c.simuls.mutex.Unlock()
+		return domain.ErrSimulStarted
+	}
+	participants := make([]string, 0, len(s.simul.Participants))
+	for _, id := range s.simul.Participants {
+		if id != playerID {
+			participants = append(participants, id)
+		}
+	}
+	s.simul.Participants = participants
+	left := s.copySimul()
+	c.simuls.mutex.Unlock()
+
+	c.broadcast(simulID, domain_websocket.WithdrawEvent, left)
+	return nil
+}
+
+// Start creates the games of a simul with the host's color fixed. Participants
+// whose game couldn't be created are dropped. With a shared clock, the host
+// gets Time per board unless HostTime was set.
+func (c simulUseCase) Start(ctx context.Context, simulID int, hostID string) error {
+	c.simuls.mutex.Lock()
+	s, ok := c.simuls.byID[simulID]
+	if !ok {
+		c.simuls.mutex.Unlock()
+		return domain.ErrSimulNotFound
+	}
+	if s.simul.HostID != hostID {
+		c.simuls.mutex.Unlock()
+		return domain.ErrNotSimulHost
+	}
+	if s.simul.Status != domain.SimulStatusCreated {
+		c.simuls.mutex.Unlock()
+		return domain.ErrSimulStarted
+	}
+	if len(s.simul.Participants) == 0 {
+		c.simuls.mutex.Unlock()
+		return errors.New("simul has no participants")
+	}
+	s.simul.Status = domain.SimulStatusStarted
+	settings := s.copySimul()
+	c.simuls.mutex.Unlock()
+
+	boards := make([]domain.SimulBoard, 0, len(settings.Participants))
+	participants := make([]string, 0, len(settings.Participants))
+	for _, participantID := range settings.Participants {
+		g := domain.Game{
+			Time:      settings.Time,
+			Increment: settings.Increment,
+		}
+		if settings.HostColor == domain.White {
+			g.WhiteID, g.BlackID = settings.HostID, participantID
+		} else {
+			g.WhiteID, g.BlackID = participantID, settings.HostID
+		}
+		if settings.HostClock != domain.SimulClockIndividual {
+			g.UntimedColor = settings.HostColor
+		}
+
+		gameID, err := c.startGame(ctx, g)
+		if err != nil {
+			log.Printf("Usecase/Simul/Start, error starting game of %s in simul %d: %v", participantID, simulID, err)
+			continue
+		}
+
+		boards = append(boards, domain.SimulBoard{
+			GameID:     gameID,
+			OpponentID: participantID,
+			FEN:        chess.StartingPosition().String(),
+			WhiteTime:  settings.Time,
+			BlackTime:  settings.Time,
+			HostToMove: settings.HostColor == domain.White,
+		})
+		participants = append(participants, participantID)
+	}
+
+	c.simuls.mutex.Lock()
+	if len(boards) == 0 {
+		s.simul.Status = domain.SimulStatusCreated
+		c.simuls.mutex.Unlock()
+		return errors.New("no game of the simul could be started")
+	}
+	s.simul.Participants = participants
+	s.boards = boards
+	for _, b := range boards {
+		c.simuls.byGame[b.GameID] = simulID
+	}
+	if s.simul.HostClock == domain.SimulClockShared {
+		if s.simul.HostTime == 0 {
+			s.simul.HostTime = s.simul.Time * len(boards)
+		}
+		s.runningSince = timeNow().UnixMilli()
+		c.scheduleHostClock(s)
+	}
+	state := s.state(timeNow().UnixMilli())
+	c.simuls.mutex.Unlock()
+
+	c.broadcast(simulID, domain_websocket.StartEvent, state)
+
+	room, ok := c.room(simulID)
+	if !ok {
+		return nil
+	}
+	participantColor := domain.White
+	if settings.HostColor == domain.White {
+		participantColor = domain.Black
+	}
+	for _, b := range boards {
+		client, ok := room.GetClient(b.OpponentID)
+		if !ok {
+			continue
+		}
+
+		client.SendMessage(
+			simulTopic(simulID),
+			domain_websocket.PairedEvent,
+			domain.SimulPairing{
+				SimulID: simulID,
+				GameID:  b.GameID,
+				Color:   participantColor,
+				HostID:  settings.HostID,
+			},
+			jsonErrorMessage,
+		)
+	}
+
+	return nil
+}
+
+// OnMove updates the board of a simul game and sends it to the simul's topic
+func (c simulUseCase) OnMove(g domain.Game, move domain.GameMove) {
+	c.simuls.mutex.Lock()
+	s, idx, ok := c.board(g.ID)
+	if !ok {
+		c.simuls.mutex.Unlock()
+		return
+	}
+
+	now := timeNow().UnixMilli()
+	s.chargeHostClock(now)
+
+	b := &s.boards[idx]
+	whiteMoved := move.Ply%2 == 1
+	b.FEN = move.FEN
+	b.LastMove = move.UCI
+	b.WhiteTime, b.BlackTime = g.WhiteTime, g.BlackTime
+	if whiteMoved {
+		b.WhiteTime = move.Clock
+	} else {
+		b.BlackTime = move.Clock
+	}
+	hostMoved := whiteMoved == (s.simul.HostColor == domain.White)
+	b.HostToMove = !hostMoved
+	if hostMoved && s.simul.HostClock == domain.SimulClockShared {
+		s.simul.HostTime += s.simul.Increment * 1000
+	}
+	c.scheduleHostClock(s)
+
+	board := *b
+	simulID := s.simul.ID
+	c.simuls.mutex.Unlock()
+
+	c.broadcast(simulID, domain_websocket.UpdateEvent, board)
+}
+
+// OnGameOver records the result of a simul game. The simul ends with the
+// results from the host's side once every game is over.
+func (c simulUseCase) OnGameOver(g domain.Game) {
+	c.simuls.mutex.Lock()
+	s, idx, ok := c.board(g.ID)
+	if !ok {
+		c.simuls.mutex.Unlock()
+		return
+	}
+	delete(c.simuls.byGame, g.ID)
+
+	now := timeNow().UnixMilli()
+	s.chargeHostClock(now)
+
+	b := &s.boards[idx]
+	b.Result = g.Result
+	b.HostToMove = false
+	c.scheduleHostClock(s)
+	board := *b
+	simulID := s.simul.ID
+
+	over := true
+	for _, b := range s.boards {
+		if b.Result == "" {
+			over = false
+			break
+		}
+	}
+	var state domain.SimulState
+	if over {
+		s.simul.Status = domain.SimulStatusFinished
+		state = s.state(now)
+		delete(c.simuls.byID, simulID)
+	}
+	c.simuls.mutex.Unlock()
+
+	c.broadcast(simulID, domain_websocket.GameOverEvent, board)
+	if over {
+		c.broadcast(simulID, domain_websocket.SimulEndEvent, state)
+	}
+}
+
+// board finds the simul and the index of the board of a game. It has to be
+// called with the mutex held.
+func (c simulUseCase) board(gameID int) (*simul, int, bool) {
+	simulID, ok := c.simuls.byGame[gameID]
+	if !ok {
+		return nil, 0, false
+	}
+	s, ok := c.simuls.byID[simulID]
+	if !ok {
+		return nil, 0, false
+	}
+	for i, b := range s.boards {
+		if b.GameID == gameID {
+			return s, i, true
+		}
+	}
+
+	return nil, 0, false
+}
+
+// scheduleHostClock restarts the timer of the shared clock, which only runs
+// while a board waits for the host. It has to be called with the mutex held.
+func (c simulUseCase) scheduleHostClock(s *simul) {
+	if s.simul.HostClock != domain.SimulClockShared {
+		return
+	}
+
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	if s.simul.Status != domain.SimulStatusStarted || !s.hostAwaited() {
+		return
+	}
+
+	simulID := s.simul.ID
+	s.timer = time.AfterFunc(
+		time.Duration(s.simul.HostTime)*time.Millisecond,
+		func() { c.onHostTimeOut(simulID) },
+	)
+}
+
+// onHostTimeOut makes the host lose every unfinished game once the shared
+// clock ran out
+func (c simulUseCase) onHostTimeOut(simulID int) {
+	c.simuls.mutex.Lock()
+	s, ok := c.simuls.byID[simulID]
+	if !ok {
+		c.simuls.mutex.Unlock()
+		return
+	}
+	s.chargeHostClock(timeNow().UnixMilli())
+	if s.simul.HostTime > 0 {
+		// the host moved while the timer fired
+		c.scheduleHostClock(s)
+		c.simuls.mutex.Unlock()
+		return
+	}
+	s.simul.HostTime = 0
+	s.timer = nil
+
+	unfinished := make([]int, 0, len(s.boards))
+	for _, b := range s.boards {
+		if b.Result == "" {
+			unfinished = append(unfinished, b.GameID)
+		}
+	}
+	result := chess.WhiteWon.String()
+	if s.simul.HostColor == domain.White {
+		result = chess.BlackWon.String()
+	}
+	c.simuls.mutex.Unlock()
+
+	for _, gameID := range unfinished {
+		err := c.endGame(context.Background(), gameID, result)
+		if err != nil {
+			log.Printf("Usecase/Simul/onHostTimeOut, error ending game %d of simul %d: %v", gameID, simulID, err)
+		}
+	}
+}
+
+func (c simulUseCase) broadcast(simulID int, event string, payload interface{}) {
+	room, ok := c.room(simulID)
+	if !ok {
+		return
+	}
+
+	jsonData, err := domain_websocket.NewOutboundMessage(
+		simulTopic(simulID),
+		event,
+		payload,
+	).ToJSON(jsonErrorMessage)
+	if err != nil {
+		log.Printf("UseCase/Simul/broadcast, error broadcasting %s: %v", event, err)
+		return
+	}
+
+	room.BroadcastMessage(jsonData)
+}
+
+// hostAwaited tells if any board waits for the host's move
+func (s *simul) hostAwaited() bool {
+	for _, b := range s.boards {
+		if b.HostToMove && b.Result == "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// chargeHostClock takes the time since the last change off the shared clock
+// if it was running
+func (s *simul) chargeHostClock(now int64) {
+	if s.simul.HostClock != domain.SimulClockShared {
+		return
+	}
+
+	if s.simul.Status == domain.SimulStatusStarted && s.hostAwaited() {
+		s.simul.HostTime -= int(now - s.runningSince)
+	}
+	s.runningSince = now
+}
+
+func (s *simul) copySimul() domain.Simul {
+	copied := s.simul
+	copied.Participants = append([]string{}, s.simul.Participants...)
+	return copied
+}
+
+// state returns the combined feed of the simul with the shared clock as it
+// is at now
+func (s *simul) state(now int64) domain.SimulState {
+	state := domain.SimulState{
+		Simul:  s.copySimul(),
+		Boards: append([]domain.SimulBoard{}, s.boards...),
+	}
+	if s.simul.HostClock == domain.SimulClockShared &&
+		s.simul.Status == domain.SimulStatusStarted &&
+		s.hostAwaited() {
+		state.HostTime -= int(now - s.runningSince)
+		if state.HostTime < 0 {
+			state.HostTime = 0
+		}
+	}
+	if s.simul.Status == domain.SimulStatusFinished {
+		results := s.results()
+		state.Results = &results
+	}
+
+	return state
+}
+
+func (s *simul) results() domain.SimulResults {
+	hostWon, hostLost := chess.WhiteWon.String(), chess.BlackWon.String()
+	if s.simul.HostColor == domain.Black {
+		hostWon, hostLost = hostLost, hostWon
+	}
+
+	var results domain.SimulResults
+	for _, b := range s.boards {
+		switch b.Result {
+		case hostWon:
+			results.Wins++
+			results.Score++
+		case chess.Draw.String():
+			results.Draws++
+			results.Score += 0.5
+		case hostLost:
+			results.Losses++
+		}
+	}
+
+	return results
+}
@@ -0,0 +1,183 @@
+package usecase_simul
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	domain_websocket "github.com/lookingcoolonavespa/go_crochess_backend/src/websocket"
+	"github.com/notnil/chess"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSimulUseCase(t *testing.T) {
+	now := time.Date(2023, time.October, 10, 2, 10, 10, 10, time.UTC)
+	timeNow = func() time.Time {
+		return now
+	}
+
+	startedGames := make([]domain.Game, 0)
+	room := domain_websocket.NewRoom([]domain.Client{}, "1")
+	u := NewSimulUseCase(
+		func(ctx context.Context, g domain.Game) (int, error) {
+			startedGames = append(startedGames, g)
+			return 100 + len(startedGames), nil
+		},
+		nil,
+		func(simulID int) (domain.Room, bool) {
+			return room, simulID == 1
+		},
+	)
+
+	s, err := u.Create(domain.Simul{HostID: "host", Time: 600000, HostClock: domain.SimulClockNone})
+	assert.NoError(t, err)
+	assert.Equal(t, domain.White, s.HostColor)
+	assert.Equal(t, domain.SimulStatusCreated, s.Status)
+
+	t.Run("Only the host starts the simul", func(t *testing.T) {
+		assert.NoError(t, u.Join(1, "a"))
+		assert.NoError(t, u.Join(1, "b"))
+		assert.NoError(t, u.Join(1, "c"))
+		assert.NoError(t, u.Leave(1, "c"))
+
+		assert.ErrorIs(t, u.Start(context.Background(), 1, "a"), domain.ErrNotSimulHost)
+	})
+
+	t.Run("Creates a game per participant with the host's color", func(t *testing.T) {
+		assert.NoError(t, u.Start(context.Background(), 1, "host"))
+		assert.ErrorIs(t, u.Join(1, "d"), domain.ErrSimulStarted)
+
+		assert.Len(t, startedGames, 2)
+		for i, opponent := range []string{"a", "b"} {
+			assert.Equal(t, "host", startedGames[i].WhiteID)
+			assert.Equal(t, opponent, startedGames[i].BlackID)
+			assert.Equal(t, domain.White, startedGames[i].UntimedColor)
+		}
+	})
+
+	t.Run("Feeds every board", func(t *testing.T) {
+		u.OnMove(domain.Game{ID: 101, WhiteTime: 600000, BlackTime: 600000}, domain.GameMove{
+			GameID: 101,
+			Ply:    1,
+			UCI:    "e2e4",
+			FEN:    "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1",
+			Clock:  600000,
+		})
+
+		state, ok := u.Get(1)
+		assert.True(t, ok)
+		assert.Len(t, state.Boards, 2)
+		assert.Equal(t, "e2e4", state.Boards[0].LastMove)
+		assert.False(t, state.Boards[0].HostToMove)
+		assert.True(t, state.Boards[1].HostToMove)
+	})
+
+	t.Run("Aggregates the results once every game is over", func(t *testing.T) {
+		u.OnGameOver(domain.Game{ID: 101, Result: chess.WhiteWon.String()})
+		state, ok := u.Get(1)
+		assert.True(t, ok)
+		assert.Nil(t, state.Results)
+
+		u.OnGameOver(domain.Game{ID: 102, Result: chess.Draw.String()})
+		_, ok = u.Get(1)
+		assert.False(t, ok)
+	})
+}
+
+func TestSimulUseCase_SharedClock(t *testing.T) {
+	now := time.Date(2023, time.October, 10, 2, 10, 10, 10, time.UTC)
+	var clockMutex sync.Mutex
+	timeNow = func() time.Time {
+		clockMutex.Lock()
+		defer clockMutex.Unlock()
+		return now
+	}
+	advance := func(d time.Duration) {
+		clockMutex.Lock()
+		defer clockMutex.Unlock()
+		now = now.Add(d)
+	}
+
+	ended := make(chan int, 2)
+	u := NewSimulUseCase(
+		func(ctx context.Context, g domain.Game) (int, error) {
+			if g.WhiteID == "a" {
+				return 201, nil
+			}
+			return 202, nil
+		},
+		func(ctx context.Context, gameID int, result string) error {
+			assert.Equal(t, chess.WhiteWon.String(), result)
+			ended <- gameID
+			return nil
+		},
+		func(simulID int) (domain.Room, bool) {
+			return nil, false
+		},
+	)
+
+	_, err := u.Create(domain.Simul{
+		HostID:    "host",
+		HostColor: domain.Black,
+		Time:      600000,
+		HostClock: domain.SimulClockShared,
+		HostTime:  100,
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, u.Join(1, "a"))
+	assert.NoError(t, u.Join(1, "b"))
+	assert.NoError(t, u.Start(context.Background(), 1, "host"))
+
+	t.Run("Doesn't run while the participants think", func(t *testing.T) {
+		advance(time.Minute)
+		state, _ := u.Get(1)
+		assert.Equal(t, 100, state.HostTime)
+	})
+
+	t.Run("Runs while a board waits for the host", func(t *testing.T) {
+		u.OnMove(domain.Game{ID: 201, WhiteTime: 600000, BlackTime: 600000}, domain.GameMove{
+			GameID: 201,
+			Ply:    1,
+			UCI:    "e2e4",
+			Clock:  590000,
+		})
+		advance(40 * time.Millisecond)
+
+		state, _ := u.Get(1)
+		assert.Equal(t, 60, state.HostTime)
+		assert.Equal(t, 590000, state.Boards[0].WhiteTime)
+	})
+
+	t.Run("The host loses the unfinished games when it runs out", func(t *testing.T) {
+		advance(60 * time.Millisecond)
+
+		select {
+		case gameID := <-ended:
+			assert.Equal(t, 201, gameID)
+		case <-time.After(time.Second):
+			t.Fatal("host didn't lose on time")
+		}
+		select {
+		case gameID := <-ended:
+			assert.Equal(t, 202, gameID)
+		case <-time.After(time.Second):
+			t.Fatal("host didn't lose every game")
+		}
+	})
+}
+
+func TestSimul_Results(t *testing.T) {
+	s := simul{
+		simul: domain.Simul{HostColor: domain.Black},
+		boards: []domain.SimulBoard{
+			{Result: chess.BlackWon.String()},
+			{Result: chess.Draw.String()},
+			{Result: chess.WhiteWon.String()},
+			{Result: chess.BlackWon.String()},
+		},
+	}
+
+	assert.Equal(t, domain.SimulResults{Wins: 2, Draws: 1, Losses: 1, Score: 2.5}, s.results())
+}
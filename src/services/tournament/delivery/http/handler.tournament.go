@@ -0,0 +1,115 @@
+package delivery_http_tournament
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+
+	"github.com/julienschmidt/httprouter"
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+)
+
+type TournamentHandler struct {
+	arena  domain.ArenaUseCase
+	swiss  domain.SwissUseCase
+	events domain.ClubEventUseCase
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func NewTournamentHandler(
+	arena domain.ArenaUseCase,
+	swiss domain.SwissUseCase,
+	events domain.ClubEventUseCase,
+) TournamentHandler {
+	return TournamentHandler{
+		arena,
+		swiss,
+		events,
+	}
+}
+
+func (t TournamentHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/tournaments", t.HandlerListTournaments)
+	router.POST("/api/tournaments/swiss", t.HandlerCreateSwiss)
+	router.POST("/api/tournaments/events", t.HandlerCreateEvent)
+}
+
+// HandlerListTournaments returns the upcoming and running tournaments
+func (t TournamentHandler) HandlerListTournaments(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	tournaments := append(t.arena.List(), t.swiss.List()...)
+	tournaments = append(tournaments, t.events.List()...)
+	sort.SliceStable(tournaments, func(i, j int) bool {
+		return tournaments[i].StartsAt < tournaments[j].StartsAt
+	})
+
+	writeJSON(w, http.StatusOK, tournaments)
+}
+
+// HandlerCreateSwiss creates a swiss tournament from its name, time control,
+// number of rounds and start time
+func (t TournamentHandler) HandlerCreateSwiss(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var body domain.Tournament
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "body is not a valid tournament")
+		return
+	}
+
+	tournament, err := t.swiss.Create(r.Context(), domain.Tournament{
+		Name:      body.Name,
+		Time:      body.Time,
+		Increment: body.Increment,
+		StartsAt:  body.StartsAt,
+		Rounds:    body.Rounds,
+	})
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, tournament)
+}
+
+// HandlerCreateEvent creates a round robin or a knockout run by the organizer
+// named in the body. The organizer then controls it on its event topic.
+func (t TournamentHandler) HandlerCreateEvent(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var body domain.Tournament
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "body is not a valid event")
+		return
+	}
+
+	event, err := t.events.Create(r.Context(), domain.Tournament{
+		Kind:                body.Kind,
+		Name:                body.Name,
+		Time:                body.Time,
+		Increment:           body.Increment,
+		StartsAt:            body.StartsAt,
+		OrganizerID:         body.OrganizerID,
+		ArmageddonWhiteTime: body.ArmageddonWhiteTime,
+		ArmageddonBlackTime: body.ArmageddonBlackTime,
+	})
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, event)
+}
+
+func writeJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		log.Printf("Handler/HTTP/Tournament/writeJSON, error encoding response: %v\n", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, errorResponse{message})
+}
@@ -0,0 +1,278 @@
+package delivery_ws_tournament
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	domain_websocket "github.com/lookingcoolonavespa/go_crochess_backend/src/websocket"
+)
+
+type EventHandler struct {
+	events domain.ClubEventUseCase
+}
+
+// EventInit is sent to the clients that subscribe to an event. Challenges are
+// the ones the client has to accept in the current round.
+type EventInit struct {
+	Standings  domain.TournamentStandings `json:"standings"`
+	Round      *domain.TournamentRound    `json:"round,omitempty"`
+	Challenges []domain.EventChallenge    `json:"challenges"`
+}
+
+type playerPayload struct {
+	PlayerID string `json:"player_id"`
+}
+
+type startPayload struct {
+	Seeds []string `json:"seeds"`
+}
+
+type challengePayload struct {
+	ChallengeID int    `json:"challenge_id"`
+	Result      string `json:"result"`
+}
+
+func NewEventHandler(events domain.ClubEventUseCase) EventHandler {
+	return EventHandler{
+		events,
+	}
+}
+
+func eventTopicName(id int) string {
+	return fmt.Sprint(domain_websocket.EventTopic, "/", id)
+}
+
+func (e EventHandler) HandlerOnSubscribe(
+	ctx context.Context,
+	room domain.Room,
+	client domain.Client,
+	_ []byte,
+) error {
+	id, err := tournamentID(room)
+	if err != nil {
+		return err
+	}
+
+	standings, ok := e.events.Standings(id)
+	if !ok {
+		return client.SendError(
+			fmt.Sprintf("event %d is not running", id),
+			jsonErrorMessage,
+		)
+	}
+
+	err = client.Subscribe(room)
+	if err != nil {
+		return err
+	}
+
+	init := EventInit{
+		Standings:  standings,
+		Challenges: e.events.Challenges(id, client.GetID()),
+	}
+	if round, ok := e.events.Round(id, 0); ok {
+		init.Round = &round
+	}
+
+	return client.SendMessage(
+		eventTopicName(id),
+		domain_websocket.InitEvent,
+		init,
+		"Handler/Event/HandlerOnSubscribe: error turning event into json\nerr: %v",
+	)
+}
+
+func (e EventHandler) HandlerOnUnsubscribe(
+	ctx context.Context,
+	room domain.Room,
+	client domain.Client,
+	_ []byte,
+) error {
+	client.Unsubscribe(room)
+	return nil
+}
+
+func (e EventHandler) HandlerAddPlayer(
+	ctx context.Context,
+	room domain.Room,
+	client domain.Client,
+	message []byte,
+) error {
+	return e.handlePlayer(ctx, room, client, message, e.events.AddPlayer)
+}
+
+func (e EventHandler) HandlerRemovePlayer(
+	ctx context.Context,
+	room domain.Room,
+	client domain.Client,
+	message []byte,
+) error {
+	return e.handlePlayer(ctx, room, client, message, e.events.RemovePlayer)
+}
+
+func (e EventHandler) handlePlayer(
+	ctx context.Context,
+	room domain.Room,
+	client domain.Client,
+	message []byte,
+	action func(ctx context.Context, tournamentID int, organizerID string, playerID string) error,
+) error {
+	id, err := tournamentID(room)
+	if err != nil {
+		return err
+	}
+
+	var payload playerPayload
+	err = json.Unmarshal(message, &payload)
+	if err != nil || payload.PlayerID == "" {
+		return e.sendError(client, errors.New("payload needs a player_id"))
+	}
+
+	err = action(ctx, id, client.GetID(), payload.PlayerID)
+	if err != nil {
+		return e.sendError(client, err)
+	}
+
+	return nil
+}
+
+func (e EventHandler) HandlerStart(
+	ctx context.Context,
+	room domain.Room,
+	client domain.Client,
+	message []byte,
+) error {
+	id, err := tournamentID(room)
+	if err != nil {
+		return err
+	}
+
+	var payload startPayload
+	if len(message) > 0 {
+		err = json.Unmarshal(message, &payload)
+		if err != nil {
+			return e.sendError(client, errors.New("seeds must be a list of player ids"))
+		}
+	}
+
+	err = e.events.Start(ctx, id, client.GetID(), payload.Seeds)
+	if err != nil {
+		return e.sendError(client, err)
+	}
+
+	return nil
+}
+
+func (e EventHandler) HandlerNextRound(
+	ctx context.Context,
+	room domain.Room,
+	client domain.Client,
+	_ []byte,
+) error {
+	id, err := tournamentID(room)
+	if err != nil {
+		return err
+	}
+
+	err = e.events.NextRound(ctx, id, client.GetID())
+	if err != nil {
+		return e.sendError(client, err)
+	}
+
+	return nil
+}
+
+func (e EventHandler) HandlerForfeit(
+	ctx context.Context,
+	room domain.Room,
+	client domain.Client,
+	message []byte,
+) error {
+	id, err := tournamentID(room)
+	if err != nil {
+		return err
+	}
+
+	var payload challengePayload
+	err = json.Unmarshal(message, &payload)
+	if err != nil {
+		return e.sendError(client, errors.New("payload needs a challenge_id and a result"))
+	}
+
+	err = e.events.Forfeit(ctx, id, client.GetID(), payload.ChallengeID, payload.Result)
+	if err != nil {
+		return e.sendError(client, err)
+	}
+
+	return nil
+}
+
+func (e EventHandler) HandlerAcceptChallenge(
+	ctx context.Context,
+	room domain.Room,
+	client domain.Client,
+	message []byte,
+) error {
+	id, err := tournamentID(room)
+	if err != nil {
+		return err
+	}
+
+	var payload challengePayload
+	err = json.Unmarshal(message, &payload)
+	if err != nil {
+		return e.sendError(client, errors.New("payload needs a challenge_id"))
+	}
+
+	err = e.events.AcceptChallenge(ctx, id, client.GetID(), payload.ChallengeID)
+	if err != nil {
+		return e.sendError(client, err)
+	}
+
+	return nil
+}
+
+// HandlerPairings sends the games of a round, the current one if the payload
+// doesn't name one
+func (e EventHandler) HandlerPairings(
+	ctx context.Context,
+	room domain.Room,
+	client domain.Client,
+	message []byte,
+) error {
+	id, err := tournamentID(room)
+	if err != nil {
+		return err
+	}
+
+	var payload struct {
+		Round int `json:"round"`
+	}
+	if len(message) > 0 {
+		err = json.Unmarshal(message, &payload)
+		if err != nil {
+			return e.sendError(client, errors.New("round must be an integer"))
+		}
+	}
+
+	round, ok := e.events.Round(id, payload.Round)
+	if !ok {
+		return e.sendError(client, errors.New(fmt.Sprintf("event %d has no round %d", id, payload.Round)))
+	}
+
+	return client.SendMessage(
+		eventTopicName(id),
+		domain_websocket.PairingsEvent,
+		round,
+		jsonErrorMessage,
+	)
+}
+
+// sendError tells the client why their request failed without closing the
+// connection
+func (e EventHandler) sendError(client domain.Client, err error) error {
+	return client.SendError(err.Error(), jsonErrorMessage)
+}
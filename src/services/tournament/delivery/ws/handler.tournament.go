@@ -0,0 +1,197 @@
+package delivery_ws_tournament
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	domain_websocket "github.com/lookingcoolonavespa/go_crochess_backend/src/websocket"
+)
+
+const baseTopicName = domain_websocket.TournamentTopic
+const jsonErrorMessage = "Handler/Tournament, Failed to convert message to json: %v\n"
+
+type TournamentHandler struct {
+	arena domain.ArenaUseCase
+	swiss domain.SwissUseCase
+}
+
+func NewTournamentHandler(arena domain.ArenaUseCase, swiss domain.SwissUseCase) TournamentHandler {
+	return TournamentHandler{
+		arena,
+		swiss,
+	}
+}
+
+// tournament returns the use case running the tournament, nil if no
+// tournament with that id is running
+func (t TournamentHandler) tournament(id int) domain.TournamentUseCase {
+	if _, ok := t.arena.Standings(id); ok {
+		return t.arena
+	}
+	if _, ok := t.swiss.Standings(id); ok {
+		return t.swiss
+	}
+
+	return nil
+}
+
+func tournamentID(room domain.Room) (int, error) {
+	param, err := room.GetParam()
+	if err != nil {
+		log.Printf("Handler/Tournament: room is missing param")
+		return 0, err
+	}
+
+	id, err := strconv.Atoi(param)
+	if err != nil {
+		log.Printf("Handler/Tournament: param is not a valid int\nroom.Param: %v", param)
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (t TournamentHandler) HandlerOnSubscribe(
+	ctx context.Context,
+	room domain.Room,
+	client domain.Client,
+	_ []byte,
+) error {
+	id, err := tournamentID(room)
+	if err != nil {
+		return err
+	}
+
+	tournament := t.tournament(id)
+	if tournament == nil {
+		return client.SendError(
+			fmt.Sprintf("tournament %d is not running", id),
+			jsonErrorMessage,
+		)
+	}
+
+	standings, ok := tournament.Standings(id)
+	if !ok {
+		return client.SendError(
+			fmt.Sprintf("tournament %d is not running", id),
+			jsonErrorMessage,
+		)
+	}
+
+	err = client.Subscribe(room)
+	if err != nil {
+		return err
+	}
+
+	return client.SendMessage(
+		fmt.Sprint(baseTopicName, "/", id),
+		domain_websocket.InitEvent,
+		standings,
+		"Handler/Tournament/HandlerOnSubscribe: error turning standings into json\nerr: %v",
+	)
+}
+
+func (t TournamentHandler) HandlerOnUnsubscribe(
+	ctx context.Context,
+	room domain.Room,
+	client domain.Client,
+	_ []byte,
+) error {
+	client.Unsubscribe(room)
+	return nil
+}
+
+func (t TournamentHandler) HandlerJoin(
+	ctx context.Context,
+	room domain.Room,
+	client domain.Client,
+	_ []byte,
+) error {
+	id, err := tournamentID(room)
+	if err != nil {
+		return err
+	}
+
+	tournament := t.tournament(id)
+	if tournament == nil {
+		return t.sendError(client, errors.New(fmt.Sprintf("tournament %d is not running", id)))
+	}
+
+	err = tournament.Join(ctx, id, client.GetID())
+	if err != nil {
+		return t.sendError(client, err)
+	}
+
+	return nil
+}
+
+func (t TournamentHandler) HandlerWithdraw(
+	ctx context.Context,
+	room domain.Room,
+	client domain.Client,
+	_ []byte,
+) error {
+	id, err := tournamentID(room)
+	if err != nil {
+		return err
+	}
+
+	tournament := t.tournament(id)
+	if tournament == nil {
+		return t.sendError(client, errors.New(fmt.Sprintf("tournament %d is not running", id)))
+	}
+
+	err = tournament.Withdraw(ctx, id, client.GetID())
+	if err != nil {
+		return t.sendError(client, err)
+	}
+
+	return nil
+}
+
+// HandlerPairings sends the pairings of a swiss round, the current one if
+// the payload doesn't name one
+func (t TournamentHandler) HandlerPairings(
+	ctx context.Context,
+	room domain.Room,
+	client domain.Client,
+	message []byte,
+) error {
+	id, err := tournamentID(room)
+	if err != nil {
+		return err
+	}
+
+	var payload struct {
+		Round int `json:"round"`
+	}
+	if len(message) > 0 {
+		err = json.Unmarshal(message, &payload)
+		if err != nil {
+			return t.sendError(client, errors.New("round must be an integer"))
+		}
+	}
+
+	round, ok := t.swiss.Round(id, payload.Round)
+	if !ok {
+		return t.sendError(client, errors.New(fmt.Sprintf("swiss %d has no round %d", id, payload.Round)))
+	}
+
+	return client.SendMessage(
+		fmt.Sprint(baseTopicName, "/", id),
+		domain_websocket.PairingsEvent,
+		round,
+		jsonErrorMessage,
+	)
+}
+
+// sendError tells the client why their request failed without closing the
+// connection
+func (t TournamentHandler) sendError(client domain.Client, err error) error {
+	return client.SendError(err.Error(), jsonErrorMessage)
+}
@@ -0,0 +1,71 @@
+package repository_tournament_mock
+
+import (
+	"context"
+
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	"github.com/stretchr/testify/mock"
+)
+
+type TournamentMockRepo struct {
+	mock.Mock
+}
+
+func (c *TournamentMockRepo) Insert(ctx context.Context, t domain.Tournament) (int, error) {
+	args := c.Called(ctx, t)
+	result := args.Get(0)
+
+	return result.(int), args.Error(1)
+}
+
+func (c *TournamentMockRepo) UpdateStatus(ctx context.Context, tournamentID int, status string) error {
+	args := c.Called(ctx, tournamentID, status)
+
+	return args.Error(0)
+}
+
+func (c *TournamentMockRepo) ListUnfinished(ctx context.Context, kind string) ([]domain.Tournament, error) {
+	args := c.Called(ctx, kind)
+	result := args.Get(0)
+
+	return result.([]domain.Tournament), args.Error(1)
+}
+
+func (c *TournamentMockRepo) ListPlayers(ctx context.Context, tournamentID int) ([]domain.TournamentPlayer, error) {
+	args := c.Called(ctx, tournamentID)
+	result := args.Get(0)
+
+	return result.([]domain.TournamentPlayer), args.Error(1)
+}
+
+func (c *TournamentMockRepo) SavePlayer(ctx context.Context, p domain.TournamentPlayer) error {
+	args := c.Called(ctx, p)
+
+	return args.Error(0)
+}
+
+func (c *TournamentMockRepo) InsertGames(ctx context.Context, games []domain.TournamentGame) ([]int, error) {
+	args := c.Called(ctx, games)
+	result := args.Get(0)
+
+	return result.([]int), args.Error(1)
+}
+
+func (c *TournamentMockRepo) ListGames(ctx context.Context, tournamentID int) ([]domain.TournamentGame, error) {
+	args := c.Called(ctx, tournamentID)
+	result := args.Get(0)
+
+	return result.([]domain.TournamentGame), args.Error(1)
+}
+
+func (c *TournamentMockRepo) SetGameResult(ctx context.Context, gameID int, result string) error {
+	args := c.Called(ctx, gameID, result)
+
+	return args.Error(0)
+}
+
+func (c *TournamentMockRepo) UpdateGame(ctx context.Context, g domain.TournamentGame) error {
+	args := c.Called(ctx, g)
+
+	return args.Error(0)
+}
@@ -0,0 +1,326 @@
+package repository_tournament
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+)
+
+type tournamentRepo struct {
+	db *sql.DB
+}
+
+func NewTournamentRepo(db *sql.DB) tournamentRepo {
+	return tournamentRepo{db}
+}
+
+const tournamentColumns = `id,
+        kind,
+        name,
+        time,
+        increment,
+        starts_at,
+        ends_at,
+        rounds,
+        status,
+        organizer_id,
+        armageddon_white_time,
+        armageddon_black_time`
+
+const insertStmt = `
+    INSERT INTO tournaments (
+        kind,
+        name,
+        time,
+        increment,
+        starts_at,
+        ends_at,
+        rounds,
+        status,
+        organizer_id,
+        armageddon_white_time,
+        armageddon_black_time
+    ) VALUES (
+        $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+    ) RETURNING id`
+
+const updateStatusStmt = `UPDATE tournaments SET status = $1 WHERE id = $2`
+
+const savePlayerStmt = `
+    INSERT INTO tournament_players (
+        tournament_id,
+        player_id,
+        score,
+        results,
+        withdrawn
+    ) VALUES (
+        $1, $2, $3, $4, $5
+    ) ON CONFLICT (tournament_id, player_id) DO UPDATE SET
+        score = EXCLUDED.score,
+        results = EXCLUDED.results,
+        withdrawn = EXCLUDED.withdrawn`
+
+const listPlayersQuery = `SELECT tournament_id, player_id, score, results, withdrawn
+    FROM tournament_players
+    WHERE tournament_id = $1
+    ORDER BY score DESC, player_id`
+
+const insertGameStmt = `
+    INSERT INTO tournament_games (
+        tournament_id,
+        round,
+        white_id,
+        black_id,
+        game_id,
+        result,
+        armageddon
+    ) VALUES (
+        $1, $2, $3, $4, $5, $6, $7
+    ) RETURNING id`
+
+const listGamesQuery = `SELECT id, tournament_id, round, white_id, black_id, COALESCE(game_id, 0), result, armageddon
+    FROM tournament_games
+    WHERE tournament_id = $1
+    ORDER BY round, id`
+
+const setGameResultStmt = `UPDATE tournament_games SET result = $1 WHERE game_id = $2`
+
+const updateGameStmt = `UPDATE tournament_games SET game_id = $1, result = $2 WHERE id = $3`
+
+func (c tournamentRepo) Insert(ctx context.Context, t domain.Tournament) (tournamentID int, err error) {
+	err = c.db.QueryRowContext(
+		ctx,
+		insertStmt,
+		t.Kind,
+		t.Name,
+		t.Time,
+		t.Increment,
+		t.StartsAt,
+		t.EndsAt,
+		t.Rounds,
+		t.Status,
+		t.OrganizerID,
+		t.ArmageddonWhiteTime,
+		t.ArmageddonBlackTime,
+	).Scan(&tournamentID)
+	if err != nil {
+		log.Printf("Repo/Tournament/Insert, error inserting tournament: %v\n", err)
+		return 0, err
+	}
+
+	return tournamentID, nil
+}
+
+func (c tournamentRepo) UpdateStatus(ctx context.Context, tournamentID int, status string) error {
+	_, err := c.db.ExecContext(ctx, updateStatusStmt, status, tournamentID)
+	if err != nil {
+		log.Printf("Repo/Tournament/UpdateStatus, error updating tournament: %v\n", err)
+		return err
+	}
+
+	return nil
+}
+
+func (c tournamentRepo) ListUnfinished(ctx context.Context, kind string) ([]domain.Tournament, error) {
+	query := fmt.Sprintf(`SELECT %s
+            FROM tournaments
+            WHERE kind = $1 AND status <> $2
+            ORDER BY starts_at`,
+		tournamentColumns,
+	)
+
+	rows, err := c.db.QueryContext(ctx, query, kind, domain.TournamentStatusFinished)
+	if err != nil {
+		log.Printf("Repo/Tournament/ListUnfinished, error querying tournaments: %v\n", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	tournaments := make([]domain.Tournament, 0)
+	for rows.Next() {
+		var t domain.Tournament
+		err := rows.Scan(
+			&t.ID,
+			&t.Kind,
+			&t.Name,
+			&t.Time,
+			&t.Increment,
+			&t.StartsAt,
+			&t.EndsAt,
+			&t.Rounds,
+			&t.Status,
+			&t.OrganizerID,
+			&t.ArmageddonWhiteTime,
+			&t.ArmageddonBlackTime,
+		)
+		if err != nil {
+			log.Printf("Repo/Tournament/ListUnfinished, error scanning tournament: %v\n", err)
+			return nil, err
+		}
+
+		tournaments = append(tournaments, t)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Repo/Tournament/ListUnfinished, error iterating tournaments: %v\n", err)
+		return nil, err
+	}
+
+	return tournaments, nil
+}
+
+func (c tournamentRepo) ListPlayers(ctx context.Context, tournamentID int) ([]domain.TournamentPlayer, error) {
+	rows, err := c.db.QueryContext(ctx, listPlayersQuery, tournamentID)
+	if err != nil {
+		log.Printf("Repo/Tournament/ListPlayers, error querying players: %v\n", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	players := make([]domain.TournamentPlayer, 0)
+	for rows.Next() {
+		var p domain.TournamentPlayer
+		err := rows.Scan(
+			&p.TournamentID,
+			&p.PlayerID,
+			&p.Score,
+			&p.Results,
+			&p.Withdrawn,
+		)
+		if err != nil {
+			log.Printf("Repo/Tournament/ListPlayers, error scanning player: %v\n", err)
+			return nil, err
+		}
+
+		players = append(players, p)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Repo/Tournament/ListPlayers, error iterating players: %v\n", err)
+		return nil, err
+	}
+
+	return players, nil
+}
+
+func (c tournamentRepo) SavePlayer(ctx context.Context, p domain.TournamentPlayer) error {
+	_, err := c.db.ExecContext(
+		ctx,
+		savePlayerStmt,
+		p.TournamentID,
+		p.PlayerID,
+		p.Score,
+		p.Results,
+		p.Withdrawn,
+	)
+	if err != nil {
+		log.Printf("Repo/Tournament/SavePlayer, error saving player: %v\n", err)
+		return err
+	}
+
+	return nil
+}
+
+func (c tournamentRepo) InsertGames(ctx context.Context, games []domain.TournamentGame) ([]int, error) {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("Repo/Tournament/InsertGames, error starting transaction: %v\n", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, insertGameStmt)
+	if err != nil {
+		log.Printf("Repo/Tournament/InsertGames, error preparing statement: %v\n", err)
+		return nil, err
+	}
+	defer stmt.Close()
+
+	ids := make([]int, len(games))
+	for i, g := range games {
+		err := stmt.QueryRowContext(
+			ctx,
+			g.TournamentID,
+			g.Round,
+			g.WhiteID,
+			g.BlackID,
+			// byes and challenges that weren't accepted don't have a game
+			nullGameID(g.GameID),
+			g.Result,
+			g.Armageddon,
+		).Scan(&ids[i])
+		if err != nil {
+			log.Printf("Repo/Tournament/InsertGames, error inserting game: %v\n", err)
+			return nil, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("Repo/Tournament/InsertGames, error committing: %v\n", err)
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func nullGameID(gameID int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(gameID), Valid: gameID != 0}
+}
+
+func (c tournamentRepo) ListGames(ctx context.Context, tournamentID int) ([]domain.TournamentGame, error) {
+	rows, err := c.db.QueryContext(ctx, listGamesQuery, tournamentID)
+	if err != nil {
+		log.Printf("Repo/Tournament/ListGames, error querying games: %v\n", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	games := make([]domain.TournamentGame, 0)
+	for rows.Next() {
+		var g domain.TournamentGame
+		err := rows.Scan(
+			&g.ID,
+			&g.TournamentID,
+			&g.Round,
+			&g.WhiteID,
+			&g.BlackID,
+			&g.GameID,
+			&g.Result,
+			&g.Armageddon,
+		)
+		if err != nil {
+			log.Printf("Repo/Tournament/ListGames, error scanning game: %v\n", err)
+			return nil, err
+		}
+
+		games = append(games, g)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Repo/Tournament/ListGames, error iterating games: %v\n", err)
+		return nil, err
+	}
+
+	return games, nil
+}
+
+func (c tournamentRepo) SetGameResult(ctx context.Context, gameID int, result string) error {
+	_, err := c.db.ExecContext(ctx, setGameResultStmt, result, gameID)
+	if err != nil {
+		log.Printf("Repo/Tournament/SetGameResult, error updating game: %v\n", err)
+		return err
+	}
+
+	return nil
+}
+
+func (c tournamentRepo) UpdateGame(ctx context.Context, g domain.TournamentGame) error {
+	_, err := c.db.ExecContext(ctx, updateGameStmt, nullGameID(g.GameID), g.Result, g.ID)
+	if err != nil {
+		log.Printf("Repo/Tournament/UpdateGame, error updating game: %v\n", err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,159 @@
+package repository_tournament
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func initMock() (*sql.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		log.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	return db, mock
+}
+
+func TestTournamentRepo_Insert(t *testing.T) {
+	db, mock := initMock()
+
+	defer db.Close()
+
+	tournament := domain.Tournament{
+		Kind:      domain.TournamentKindArena,
+		Name:      "Hourly Blitz Arena",
+		Time:      180000,
+		Increment: 0,
+		StartsAt:  1000,
+		EndsAt:    2000,
+		Status:    domain.TournamentStatusCreated,
+	}
+
+	mock.ExpectQuery(insertStmt).
+		WithArgs(
+			tournament.Kind,
+			tournament.Name,
+			tournament.Time,
+			tournament.Increment,
+			tournament.StartsAt,
+			tournament.EndsAt,
+			tournament.Rounds,
+			tournament.Status,
+			tournament.OrganizerID,
+			tournament.ArmageddonWhiteTime,
+			tournament.ArmageddonBlackTime,
+		).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+
+	r := NewTournamentRepo(db)
+
+	id, err := r.Insert(context.Background(), tournament)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, id)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTournamentRepo_ListUnfinished(t *testing.T) {
+	db, mock := initMock()
+
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"id", "kind", "name", "time", "increment", "starts_at", "ends_at", "rounds", "status",
+		"organizer_id", "armageddon_white_time", "armageddon_black_time"}).
+		AddRow(5, domain.TournamentKindArena, "Hourly Blitz Arena", 180000, 0, 1000, 2000, 0, domain.TournamentStatusStarted, "", 0, 0)
+
+	query := fmt.Sprintf(`SELECT %s
+            FROM tournaments
+            WHERE kind = $1 AND status <> $2
+            ORDER BY starts_at`,
+		tournamentColumns,
+	)
+	mock.ExpectQuery(query).
+		WithArgs(domain.TournamentKindArena, domain.TournamentStatusFinished).
+		WillReturnRows(rows)
+
+	r := NewTournamentRepo(db)
+
+	tournaments, err := r.ListUnfinished(context.Background(), domain.TournamentKindArena)
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.Tournament{{
+		ID:        5,
+		Kind:      domain.TournamentKindArena,
+		Name:      "Hourly Blitz Arena",
+		Time:      180000,
+		Increment: 0,
+		StartsAt:  1000,
+		EndsAt:    2000,
+		Status:    domain.TournamentStatusStarted,
+	}}, tournaments)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTournamentRepo_SavePlayer(t *testing.T) {
+	db, mock := initMock()
+
+	defer db.Close()
+
+	p := domain.TournamentPlayer{TournamentID: 5, PlayerID: "abc", Score: 6, Results: "WWL"}
+
+	mock.ExpectExec(savePlayerStmt).
+		WithArgs(p.TournamentID, p.PlayerID, p.Score, p.Results, p.Withdrawn).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	r := NewTournamentRepo(db)
+
+	err := r.SavePlayer(context.Background(), p)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTournamentRepo_InsertGames(t *testing.T) {
+	db, mock := initMock()
+
+	defer db.Close()
+
+	games := []domain.TournamentGame{
+		{TournamentID: 5, Round: 2, WhiteID: "a", BlackID: "b", GameID: 30},
+		{TournamentID: 5, Round: 2, WhiteID: "c", Result: "1-0"},
+	}
+
+	mock.ExpectBegin()
+	prepared := mock.ExpectPrepare(insertGameStmt)
+	prepared.ExpectQuery().
+		WithArgs(5, 2, "a", "b", 30, "", false).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(11))
+	prepared.ExpectQuery().
+		WithArgs(5, 2, "c", "", nil, "1-0", false).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(12))
+	mock.ExpectCommit()
+
+	r := NewTournamentRepo(db)
+
+	ids, err := r.InsertGames(context.Background(), games)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{11, 12}, ids)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTournamentRepo_UpdateGame(t *testing.T) {
+	db, mock := initMock()
+
+	defer db.Close()
+
+	mock.ExpectExec(updateGameStmt).
+		WithArgs(40, "", 11).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	r := NewTournamentRepo(db)
+
+	err := r.UpdateGame(context.Background(), domain.TournamentGame{ID: 11, GameID: 40})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,59 @@
+package usecase_tournament
+
+import (
+	"fmt"
+	"log"
+
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	domain_websocket "github.com/lookingcoolonavespa/go_crochess_backend/src/websocket"
+)
+
+const jsonErrorMessage = "UseCase/Tournament, error converting message to json, err: %v\n"
+
+func tournamentTopic(tournamentID int) string {
+	return fmt.Sprint(domain_websocket.TournamentTopic, "/", tournamentID)
+}
+
+// notifyPairing tells a player about their game if they are connected to the
+// tournament's topic
+func notifyPairing(room domain.Room, pairing domain.TournamentPairing, playerID string) {
+	client, ok := room.GetClient(playerID)
+	if !ok {
+		return
+	}
+
+	client.SendMessage(
+		tournamentTopic(pairing.TournamentID),
+		domain_websocket.PairedEvent,
+		pairing,
+		jsonErrorMessage,
+	)
+}
+
+func broadcast(
+	room func(tournamentID int) (domain.Room, bool),
+	tournamentID int,
+	event string,
+	payload interface{},
+) {
+	r, ok := room(tournamentID)
+	if !ok {
+		return
+	}
+
+	broadcastOn(r, tournamentTopic(tournamentID), event, payload)
+}
+
+func broadcastOn(room domain.Room, topic string, event string, payload interface{}) {
+	jsonData, err := domain_websocket.NewOutboundMessage(
+		topic,
+		event,
+		payload,
+	).ToJSON(jsonErrorMessage)
+	if err != nil {
+		log.Printf("UseCase/Tournament/broadcastOn, error broadcasting %s: %v", event, err)
+		return
+	}
+
+	room.BroadcastMessage(jsonData)
+}
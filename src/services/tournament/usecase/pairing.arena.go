@@ -0,0 +1,63 @@
+package usecase_tournament
+
+import "sort"
+
+type arenaPairing struct {
+	white *arenaPlayer
+	black *arenaPlayer
+}
+
+// pairPlayers pairs each idle player, best ranked first, with the next
+// closest ranked player that wasn't their last opponent. Two players only
+// play each other twice in a row when they are the last ones left unpaired.
+func pairPlayers(idle []*arenaPlayer) []arenaPairing {
+	sort.Slice(idle, func(i, j int) bool {
+		if idle[i].Score != idle[j].Score {
+			return idle[i].Score > idle[j].Score
+		}
+		return idle[i].PlayerID < idle[j].PlayerID
+	})
+
+	paired := make([]bool, len(idle))
+	unpaired := len(idle)
+	pairings := make([]arenaPairing, 0, len(idle)/2)
+	for i, player := range idle {
+		if paired[i] {
+			continue
+		}
+
+		opponent := -1
+		for j := i + 1; j < len(idle); j++ {
+			if paired[j] {
+				continue
+			}
+			if idle[j].PlayerID != player.lastOpponent {
+				opponent = j
+				break
+			}
+			if opponent == -1 && unpaired == 2 {
+				opponent = j
+			}
+		}
+		if opponent == -1 {
+			continue
+		}
+
+		paired[i] = true
+		paired[opponent] = true
+		unpaired -= 2
+		pairings = append(pairings, assignColors(player, idle[opponent]))
+	}
+
+	return pairings
+}
+
+// assignColors gives white to the player that had it the least, or to a
+// when they had it as often
+func assignColors(a *arenaPlayer, b *arenaPlayer) arenaPairing {
+	if b.colorBalance < a.colorBalance {
+		return arenaPairing{white: b, black: a}
+	}
+
+	return arenaPairing{white: a, black: b}
+}
@@ -0,0 +1,139 @@
+package usecase_tournament
+
+import (
+	"sort"
+
+	"github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+)
+
+// swissPairingBudget bounds the backtracking of a round's pairing. When it
+// runs out, the round is paired again allowing rematches.
+const swissPairingBudget = 10000
+
+type swissPlayer struct {
+	domain.TournamentPlayer
+	opponents map[string]bool
+	hadBye    bool
+	// colorBalance is how many more games the player had white than black
+	colorBalance int
+	lastColor    domain.Color
+}
+
+type swissPairing struct {
+	white *swissPlayer
+	black *swissPlayer
+}
+
+// pairSwiss pairs a round with a simplified Dutch system. Players are ranked
+// by score, then id. The lowest ranked player that didn't have a bye yet
+// gets it when the number of players is odd. Within a score group the top
+// half plays the bottom half; players that can't be paired in their group
+// float down to the next one. Rematches are avoided by backtracking and only
+// allowed when there is no other way to pair the round.
+func pairSwiss(players []*swissPlayer) (pairings []swissPairing, bye *swissPlayer) {
+	ranked := make([]*swissPlayer, len(players))
+	copy(ranked, players)
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].PlayerID < ranked[j].PlayerID
+	})
+
+	if len(ranked)%2 == 1 {
+		byeIdx := len(ranked) - 1
+		for i := len(ranked) - 1; i >= 0; i-- {
+			if !ranked[i].hadBye {
+				byeIdx = i
+				break
+			}
+		}
+		bye = ranked[byeIdx]
+		ranked = append(ranked[:byeIdx:byeIdx], ranked[byeIdx+1:]...)
+	}
+
+	budget := swissPairingBudget
+	pairings, ok := pairRemaining(ranked, false, &budget)
+	if !ok {
+		budget = swissPairingBudget
+		pairings, _ = pairRemaining(ranked, true, &budget)
+	}
+
+	return pairings, bye
+}
+
+// pairRemaining pairs the first of the ranked players with the best
+// candidate that lets the rest be paired too
+func pairRemaining(ranked []*swissPlayer, allowRematch bool, budget *int) ([]swissPairing, bool) {
+	if len(ranked) == 0 {
+		return []swissPairing{}, true
+	}
+	*budget--
+	if *budget < 0 {
+		return nil, false
+	}
+
+	player := ranked[0]
+	for _, i := range candidateOrder(ranked) {
+		opponent := ranked[i]
+		if !allowRematch && player.opponents[opponent.PlayerID] {
+			continue
+		}
+
+		rest := make([]*swissPlayer, 0, len(ranked)-2)
+		rest = append(rest, ranked[1:i]...)
+		rest = append(rest, ranked[i+1:]...)
+
+		pairings, ok := pairRemaining(rest, allowRematch, budget)
+		if ok {
+			return append([]swissPairing{assignSwissColors(player, opponent)}, pairings...), true
+		}
+		if *budget < 0 {
+			return nil, false
+		}
+	}
+
+	return nil, false
+}
+
+// candidateOrder returns the indexes of the opponents of ranked[0], best
+// first: the bottom half of its score group, the lower score groups, then
+// the top half of its own group from the bottom up
+func candidateOrder(ranked []*swissPlayer) []int {
+	groupSize := 1
+	for groupSize < len(ranked) && ranked[groupSize].Score == ranked[0].Score {
+		groupSize++
+	}
+	half := groupSize / 2
+	if half == 0 {
+		half = 1
+	}
+
+	order := make([]int, 0, len(ranked)-1)
+	for i := half; i < len(ranked); i++ {
+		order = append(order, i)
+	}
+	for i := half - 1; i >= 1; i-- {
+		order = append(order, i)
+	}
+
+	return order
+}
+
+// assignSwissColors gives white to the player that had it the least. When
+// they had it as often, the player that had black last gets white, and the
+// higher ranked a otherwise.
+func assignSwissColors(a *swissPlayer, b *swissPlayer) swissPairing {
+	if a.colorBalance != b.colorBalance {
+		if b.colorBalance < a.colorBalance {
+			return swissPairing{white: b, black: a}
+		}
+		return swissPairing{white: a, black: b}
+	}
+
+	if a.lastColor == domain.White && b.lastColor != domain.White {
+		return swissPairing{white: b, black: a}
+	}
+
+	return swissPairing{white: a, black: b}
+}
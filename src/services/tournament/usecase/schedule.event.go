@@ -0,0 +1,115 @@
+package usecase_tournament
+
+import "github.com/notnil/chess"
+
+// eventPairing is a scheduled game, an empty black is a bye
+type eventPairing struct {
+	white string
+	black string
+}
+
+// bergerRounds schedules a round robin with the Berger tables. seeds are the
+// players by rank. A bye is added when their number is odd, so the players
+// paired with it sit the round out.
+func bergerRounds(seeds []string) [][]eventPairing {
+	players := append([]string{}, seeds...)
+	if len(players)%2 == 1 {
+		players = append(players, "")
+	}
+	n := len(players)
+	half := n / 2
+
+	// numbers are 1 based like in the tables, n stays in place and the other
+	// numbers move up by n/2 every round
+	shift := func(x int) int {
+		return (x-1+half)%(n-1) + 1
+	}
+
+	pairs := make([][2]int, half)
+	for i := range pairs {
+		pairs[i] = [2]int{i + 1, n - i}
+	}
+
+	rounds := make([][]eventPairing, 0, n-1)
+	for round := 1; round < n; round++ {
+		if round > 1 {
+			// pairs[0] holds n, which alternates colors every round
+			other := pairs[0][0]
+			if other == n {
+				other = pairs[0][1]
+			}
+			other = shift(other)
+			if round%2 == 1 {
+				pairs[0] = [2]int{other, n}
+			} else {
+				pairs[0] = [2]int{n, other}
+			}
+
+			for i := 1; i < half; i++ {
+				pairs[i] = [2]int{shift(pairs[i][0]), shift(pairs[i][1])}
+			}
+		}
+
+		pairings := make([]eventPairing, 0, half)
+		for _, pair := range pairs {
+			white, black := players[pair[0]-1], players[pair[1]-1]
+			if white == "" || black == "" {
+				continue
+			}
+			pairings = append(pairings, eventPairing{white, black})
+		}
+		rounds = append(rounds, pairings)
+	}
+
+	return rounds
+}
+
+// knockoutBracket pairs the first round of a knockout so the best seeds can
+// only meet in the last rounds. The bracket is filled up to a power of two
+// with byes, which go to the best seeds.
+func knockoutBracket(seeds []string) []eventPairing {
+	order := []int{1}
+	for len(order) < len(seeds) {
+		size := len(order) * 2
+		next := make([]int, 0, size)
+		for _, seed := range order {
+			next = append(next, seed, size+1-seed)
+		}
+		order = next
+	}
+
+	pairings := make([]eventPairing, 0, len(order)/2)
+	for i := 0; i+1 < len(order); i += 2 {
+		pairing := eventPairing{white: seeds[order[i]-1]}
+		if order[i+1] <= len(seeds) {
+			pairing.black = seeds[order[i+1]-1]
+		}
+		pairings = append(pairings, pairing)
+	}
+
+	return pairings
+}
+
+// knockoutRound pairs the winners of the matches of the previous round in
+// bracket order
+func knockoutRound(winners []string) []eventPairing {
+	pairings := make([]eventPairing, 0, len(winners)/2)
+	for i := 0; i+1 < len(winners); i += 2 {
+		pairings = append(pairings, eventPairing{winners[i], winners[i+1]})
+	}
+
+	return pairings
+}
+
+// matchWinner returns the winner of the deciding game of a knockout match,
+// false if it wasn't decisive
+func matchWinner(white string, black string, result string) (string, bool) {
+	switch result {
+	case chess.WhiteWon.String():
+		return white, true
+	case chess.BlackWon.String():
+		return black, true
+	}
+
+	return "", false
+}
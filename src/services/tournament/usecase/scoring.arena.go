@@ -0,0 +1,61 @@
+package usecase_tournament
+
+import (
+	"github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	"github.com/notnil/chess"
+)
+
+const (
+	winPoints  = 2
+	drawPoints = 1
+	// berserkMinPlies is how many plies a berserk win needs to earn the
+	// berserk point, so it can't be farmed with quick aborts
+	berserkMinPlies = 14
+)
+
+// onFire reports whether the last two games of a player were wins, which
+// doubles the points of their next game
+func onFire(results string) bool {
+	n := len(results)
+	return n >= 2 &&
+		results[n-1] == domain.TournamentWin &&
+		results[n-2] == domain.TournamentWin
+}
+
+// score returns the points a player earns for result given their results
+// before the game
+func score(results string, result byte, berserk bool, plies int) int {
+	var points int
+	switch result {
+	case domain.TournamentWin:
+		points = winPoints
+	case domain.TournamentDraw:
+		points = drawPoints
+	default:
+		return 0
+	}
+
+	if onFire(results) {
+		points *= 2
+	}
+	if result == domain.TournamentWin && berserk && plies >= berserkMinPlies {
+		points++
+	}
+
+	return points
+}
+
+// gameResults returns the tournament results of white and black, false if
+// the game has no decisive or drawn result
+func gameResults(result string) (white byte, black byte, ok bool) {
+	switch result {
+	case chess.WhiteWon.String():
+		return domain.TournamentWin, domain.TournamentLoss, true
+	case chess.BlackWon.String():
+		return domain.TournamentLoss, domain.TournamentWin, true
+	case chess.Draw.String():
+		return domain.TournamentDraw, domain.TournamentDraw, true
+	}
+
+	return 0, 0, false
+}
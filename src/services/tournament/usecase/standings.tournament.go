@@ -0,0 +1,90 @@
+package usecase_tournament
+
+import (
+	"sort"
+
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	"github.com/notnil/chess"
+)
+
+// rankStandings ranks players by score, Buchholz, Sonneborn-Berger and id.
+// Buchholz is the sum of the scores of a player's opponents and
+// Sonneborn-Berger the scores of the opponents they beat plus half the
+// scores of the ones they drew. Players of a game without a result are
+// playing it, unless it wasn't started yet.
+func rankStandings(
+	players []domain.TournamentPlayer,
+	games []domain.TournamentGame,
+) []domain.TournamentStanding {
+	standings := make([]domain.TournamentStanding, len(players))
+	byID := make(map[string]*domain.TournamentStanding, len(players))
+	for i, p := range players {
+		standings[i] = domain.TournamentStanding{TournamentPlayer: p}
+		byID[p.PlayerID] = &standings[i]
+	}
+
+	for _, g := range games {
+		if g.BlackID == "" {
+			continue
+		}
+		white, whiteFound := byID[g.WhiteID]
+		black, blackFound := byID[g.BlackID]
+		if !whiteFound || !blackFound {
+			continue
+		}
+
+		if g.Result == "" {
+			if g.GameID != 0 {
+				white.Playing = true
+				black.Playing = true
+			}
+			continue
+		}
+
+		whiteScore := float64(white.Score)
+		blackScore := float64(black.Score)
+		white.Buchholz += blackScore
+		black.Buchholz += whiteScore
+
+		switch g.Result {
+		case chess.WhiteWon.String():
+			white.SonnebornBerger += blackScore
+		case chess.BlackWon.String():
+			black.SonnebornBerger += whiteScore
+		case chess.Draw.String():
+			white.SonnebornBerger += blackScore / 2
+			black.SonnebornBerger += whiteScore / 2
+		}
+	}
+
+	sort.Slice(standings, func(i, j int) bool {
+		a, b := standings[i], standings[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Buchholz != b.Buchholz {
+			return a.Buchholz > b.Buchholz
+		}
+		if a.SonnebornBerger != b.SonnebornBerger {
+			return a.SonnebornBerger > b.SonnebornBerger
+		}
+		return a.PlayerID < b.PlayerID
+	})
+	for i := range standings {
+		standings[i].Rank = i + 1
+	}
+
+	return standings
+}
+
+// addHalfPoints scores a result of a swiss tournament or a club event, in
+// half points
+func addHalfPoints(p *domain.TournamentPlayer, result byte) {
+	switch result {
+	case domain.TournamentWin:
+		p.Score += winPoints
+	case domain.TournamentDraw:
+		p.Score += drawPoints
+	}
+	p.Results += string(result)
+}
@@ -0,0 +1,421 @@
+package usecase_tournament
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	domain_websocket "github.com/lookingcoolonavespa/go_crochess_backend/src/websocket"
+)
+
+var timeNow = time.Now
+
+// ArenaSchedule describes the arena created every hour, starting on the hour
+type ArenaSchedule struct {
+	Name      string
+	Time      int
+	Increment int
+	Duration  time.Duration
+}
+
+type arenaUseCase struct {
+	tournamentRepo domain.TournamentRepo
+	// startGame creates a tournament game and returns its id
+	startGame func(ctx context.Context, g domain.Game) (gameID int, err error)
+	// room returns the room of a tournament's topic, if anyone subscribed
+	room func(tournamentID int) (domain.Room, bool)
+	// schedule is nil when arenas are only created by hand
+	schedule *ArenaSchedule
+	arenas   *arenas
+}
+
+type arenas struct {
+	mutex sync.Mutex
+	byID  map[int]*arena
+}
+
+type arena struct {
+	tournament domain.Tournament
+	players    map[string]*arenaPlayer
+}
+
+type arenaPlayer struct {
+	domain.TournamentPlayer
+	playing      bool
+	lastOpponent string
+	// colorBalance is how many more games the player had white than black
+	colorBalance int
+}
+
+func NewArenaUseCase(
+	tournamentRepo domain.TournamentRepo,
+	startGame func(ctx context.Context, g domain.Game) (gameID int, err error),
+	room func(tournamentID int) (domain.Room, bool),
+	schedule *ArenaSchedule,
+) arenaUseCase {
+	return arenaUseCase{
+		tournamentRepo,
+		startGame,
+		room,
+		schedule,
+		&arenas{byID: make(map[int]*arena)},
+	}
+}
+
+func (c arenaUseCase) Create(ctx context.Context, t domain.Tournament) (domain.Tournament, error) {
+	if t.Time <= 0 || t.EndsAt <= t.StartsAt {
+		return domain.Tournament{}, errors.New("arena needs a time control and has to end after it starts")
+	}
+
+	t.Kind = domain.TournamentKindArena
+	t.Status = domain.TournamentStatusCreated
+
+	id, err := c.tournamentRepo.Insert(ctx, t)
+	if err != nil {
+		return domain.Tournament{}, err
+	}
+	t.ID = id
+
+	c.arenas.mutex.Lock()
+	c.arenas.byID[id] = &arena{t, make(map[string]*arenaPlayer)}
+	c.arenas.mutex.Unlock()
+
+	return t, nil
+}
+
+func (c arenaUseCase) Load(ctx context.Context) error {
+	tournaments, err := c.tournamentRepo.ListUnfinished(ctx, domain.TournamentKindArena)
+	if err != nil {
+		return err
+	}
+
+	for _, t := range tournaments {
+		players, err := c.tournamentRepo.ListPlayers(ctx, t.ID)
+		if err != nil {
+			return err
+		}
+
+		a := &arena{t, make(map[string]*arenaPlayer, len(players))}
+		for _, p := range players {
+			a.players[p.PlayerID] = &arenaPlayer{TournamentPlayer: p}
+		}
+
+		c.arenas.mutex.Lock()
+		c.arenas.byID[t.ID] = a
+		c.arenas.mutex.Unlock()
+	}
+
+	return nil
+}
+
+func (c arenaUseCase) List() []domain.Tournament {
+	c.arenas.mutex.Lock()
+	defer c.arenas.mutex.Unlock()
+
+	tournaments := make([]domain.Tournament, 0, len(c.arenas.byID))
+	for _, a := range c.arenas.byID {
+		tournaments = append(tournaments, a.tournament)
+	}
+	sort.Slice(tournaments, func(i, j int) bool {
+		return tournaments[i].StartsAt < tournaments[j].StartsAt
+	})
+
+	return tournaments
+}
+
+func (c arenaUseCase) Standings(tournamentID int) (domain.TournamentStandings, bool) {
+	c.arenas.mutex.Lock()
+	defer c.arenas.mutex.Unlock()
+
+	a, ok := c.arenas.byID[tournamentID]
+	if !ok {
+		return domain.TournamentStandings{}, false
+	}
+
+	return a.standings(), true
+}
+
+// standings must be called with the mutex held
+func (a *arena) standings() domain.TournamentStandings {
+	standings := make([]domain.TournamentStanding, 0, len(a.players))
+	for _, p := range a.players {
+		standings = append(standings, domain.TournamentStanding{
+			TournamentPlayer: p.TournamentPlayer,
+			Fire:             onFire(p.Results),
+			Playing:          p.playing,
+		})
+	}
+	sort.Slice(standings, func(i, j int) bool {
+		if standings[i].Score != standings[j].Score {
+			return standings[i].Score > standings[j].Score
+		}
+		return standings[i].PlayerID < standings[j].PlayerID
+	})
+	for i := range standings {
+		standings[i].Rank = i + 1
+	}
+
+	return domain.TournamentStandings{Tournament: a.tournament, Standings: standings}
+}
+
+func (c arenaUseCase) Join(ctx context.Context, tournamentID int, playerID string) error {
+	return c.setWithdrawn(ctx, tournamentID, playerID, false)
+}
+
+// Withdraw takes a player out of the pairings. Their score is kept and they
+// can join again while the arena is running.
+func (c arenaUseCase) Withdraw(ctx context.Context, tournamentID int, playerID string) error {
+	return c.setWithdrawn(ctx, tournamentID, playerID, true)
+}
+
+func (c arenaUseCase) setWithdrawn(
+	ctx context.Context,
+	tournamentID int,
+	playerID string,
+	withdrawn bool,
+) error {
+	c.arenas.mutex.Lock()
+	a, ok := c.arenas.byID[tournamentID]
+	if !ok || a.tournament.Status == domain.TournamentStatusFinished {
+		c.arenas.mutex.Unlock()
+		return errors.New(fmt.Sprintf("arena %d is not running", tournamentID))
+	}
+
+	p, ok := a.players[playerID]
+	if !ok {
+		if withdrawn {
+			c.arenas.mutex.Unlock()
+			return errors.New(fmt.Sprintf("player %s did not join arena %d", playerID, tournamentID))
+		}
+		p = &arenaPlayer{TournamentPlayer: domain.TournamentPlayer{
+			TournamentID: tournamentID,
+			PlayerID:     playerID,
+		}}
+		a.players[playerID] = p
+	}
+	p.Withdrawn = withdrawn
+	saved := p.TournamentPlayer
+	standings := a.standings()
+	c.arenas.mutex.Unlock()
+
+	err := c.tournamentRepo.SavePlayer(ctx, saved)
+	if err != nil {
+		return err
+	}
+
+	broadcast(c.room, tournamentID, domain_websocket.StandingsEvent, standings)
+
+	return nil
+}
+
+func (c arenaUseCase) OnGameOver(ctx context.Context, g domain.Game) error {
+	if g.TournamentID == 0 {
+		return nil
+	}
+
+	whiteResult, blackResult, ok := gameResults(g.Result)
+
+	c.arenas.mutex.Lock()
+	a, found := c.arenas.byID[g.TournamentID]
+	if !found || a.tournament.Status != domain.TournamentStatusStarted {
+		c.arenas.mutex.Unlock()
+		return nil
+	}
+	white, whiteFound := a.players[g.WhiteID]
+	black, blackFound := a.players[g.BlackID]
+	if !whiteFound || !blackFound {
+		c.arenas.mutex.Unlock()
+		return nil
+	}
+
+	white.playing = false
+	black.playing = false
+	if ok {
+		plies := len(strings.Fields(g.Moves))
+		white.addResult(whiteResult, g.WhiteBerserk, plies)
+		black.addResult(blackResult, g.BlackBerserk, plies)
+	}
+	saved := []domain.TournamentPlayer{white.TournamentPlayer, black.TournamentPlayer}
+	standings := a.standings()
+	c.arenas.mutex.Unlock()
+
+	if ok {
+		for _, p := range saved {
+			err := c.tournamentRepo.SavePlayer(ctx, p)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	broadcast(c.room, g.TournamentID, domain_websocket.StandingsEvent, standings)
+
+	return nil
+}
+
+func (p *arenaPlayer) addResult(result byte, berserk bool, plies int) {
+	p.Score += score(p.Results, result, berserk, plies)
+	p.Results += string(result)
+}
+
+func (c arenaUseCase) Tick(ctx context.Context) {
+	now := timeNow()
+
+	if c.schedule != nil {
+		c.scheduleNext(ctx, now)
+	}
+
+	c.arenas.mutex.Lock()
+	ids := make([]int, 0, len(c.arenas.byID))
+	for id := range c.arenas.byID {
+		ids = append(ids, id)
+	}
+	c.arenas.mutex.Unlock()
+
+	for _, id := range ids {
+		c.tickArena(ctx, id, now.UnixMilli())
+	}
+}
+
+// scheduleNext creates the arena of the next hour if it doesn't exist yet
+func (c arenaUseCase) scheduleNext(ctx context.Context, now time.Time) {
+	startsAt := now.Truncate(time.Hour).Add(time.Hour)
+
+	c.arenas.mutex.Lock()
+	for _, a := range c.arenas.byID {
+		if a.tournament.Name == c.schedule.Name && a.tournament.StartsAt == startsAt.UnixMilli() {
+			c.arenas.mutex.Unlock()
+			return
+		}
+	}
+	c.arenas.mutex.Unlock()
+
+	_, err := c.Create(ctx, domain.Tournament{
+		Name:      c.schedule.Name,
+		Time:      c.schedule.Time,
+		Increment: c.schedule.Increment,
+		StartsAt:  startsAt.UnixMilli(),
+		EndsAt:    startsAt.Add(c.schedule.Duration).UnixMilli(),
+	})
+	if err != nil {
+		log.Printf("UseCase/Arena/scheduleNext, error creating arena: %v", err)
+	}
+}
+
+func (c arenaUseCase) tickArena(ctx context.Context, tournamentID int, now int64) {
+	c.arenas.mutex.Lock()
+	a, ok := c.arenas.byID[tournamentID]
+	if !ok {
+		c.arenas.mutex.Unlock()
+		return
+	}
+
+	switch {
+	case a.tournament.Status == domain.TournamentStatusCreated && now >= a.tournament.StartsAt:
+		a.tournament.Status = domain.TournamentStatusStarted
+		standings := a.standings()
+		c.arenas.mutex.Unlock()
+
+		c.updateStatus(ctx, tournamentID, domain.TournamentStatusStarted)
+		broadcast(c.room, tournamentID, domain_websocket.TournamentStartEvent, standings)
+	case a.tournament.Status == domain.TournamentStatusStarted && now >= a.tournament.EndsAt:
+		// games still being played when the arena ends don't count
+		a.tournament.Status = domain.TournamentStatusFinished
+		standings := a.standings()
+		delete(c.arenas.byID, tournamentID)
+		c.arenas.mutex.Unlock()
+
+		c.updateStatus(ctx, tournamentID, domain.TournamentStatusFinished)
+		broadcast(c.room, tournamentID, domain_websocket.TournamentEndEvent, standings)
+	case a.tournament.Status == domain.TournamentStatusStarted:
+		c.arenas.mutex.Unlock()
+
+		c.pair(ctx, tournamentID)
+	default:
+		c.arenas.mutex.Unlock()
+	}
+}
+
+func (c arenaUseCase) updateStatus(ctx context.Context, tournamentID int, status string) {
+	err := c.tournamentRepo.UpdateStatus(ctx, tournamentID, status)
+	if err != nil {
+		log.Printf("UseCase/Arena/updateStatus, error updating arena %d: %v", tournamentID, err)
+	}
+}
+
+// pair starts games between the idle players of an arena that are connected
+// to its topic
+func (c arenaUseCase) pair(ctx context.Context, tournamentID int) {
+	room, ok := c.room(tournamentID)
+	if !ok {
+		return
+	}
+
+	c.arenas.mutex.Lock()
+	a, ok := c.arenas.byID[tournamentID]
+	if !ok {
+		c.arenas.mutex.Unlock()
+		return
+	}
+
+	idle := make([]*arenaPlayer, 0)
+	for id, p := range a.players {
+		if _, connected := room.GetClient(id); connected && !p.playing && !p.Withdrawn {
+			idle = append(idle, p)
+		}
+	}
+	pairings := pairPlayers(idle)
+	for _, pairing := range pairings {
+		pairing.white.playing = true
+		pairing.black.playing = true
+	}
+	t := a.tournament
+	c.arenas.mutex.Unlock()
+
+	for _, pairing := range pairings {
+		gameID, err := c.startGame(ctx, domain.Game{
+			WhiteID:        pairing.white.PlayerID,
+			BlackID:        pairing.black.PlayerID,
+			Time:           t.Time,
+			Increment:      t.Increment,
+			TournamentID:   tournamentID,
+			BerserkAllowed: true,
+		})
+
+		c.arenas.mutex.Lock()
+		if err != nil {
+			pairing.white.playing = false
+			pairing.black.playing = false
+		} else {
+			pairing.white.lastOpponent = pairing.black.PlayerID
+			pairing.black.lastOpponent = pairing.white.PlayerID
+			pairing.white.colorBalance++
+			pairing.black.colorBalance--
+		}
+		c.arenas.mutex.Unlock()
+
+		if err != nil {
+			log.Printf("UseCase/Arena/pair, error starting game in arena %d: %v", tournamentID, err)
+			continue
+		}
+
+		notifyPairing(room, domain.TournamentPairing{
+			TournamentID: tournamentID,
+			GameID:       gameID,
+			Color:        domain.White,
+			OpponentID:   pairing.black.PlayerID,
+		}, pairing.white.PlayerID)
+		notifyPairing(room, domain.TournamentPairing{
+			TournamentID: tournamentID,
+			GameID:       gameID,
+			Color:        domain.Black,
+			OpponentID:   pairing.white.PlayerID,
+		}, pairing.black.PlayerID)
+	}
+}
@@ -0,0 +1,210 @@
+package usecase_tournament
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	repository_tournament_mock "github.com/lookingcoolonavespa/go_crochess_backend/src/services/tournament/repository/mock"
+	domain_websocket "github.com/lookingcoolonavespa/go_crochess_backend/src/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestScore(t *testing.T) {
+	assert.Equal(t, 2, score("", domain.TournamentWin, false, 0))
+	assert.Equal(t, 1, score("L", domain.TournamentDraw, false, 0))
+	assert.Equal(t, 0, score("WW", domain.TournamentLoss, true, 40))
+	assert.Equal(t, 4, score("WW", domain.TournamentWin, false, 0), "streaks double the points")
+	assert.Equal(t, 2, score("LWW", domain.TournamentDraw, false, 0), "streaks double draws too")
+	assert.Equal(t, 2, score("WWD", domain.TournamentWin, false, 0), "a draw ends the streak")
+	assert.Equal(t, 3, score("", domain.TournamentWin, true, berserkMinPlies), "berserk wins get a point")
+	assert.Equal(t, 2, score("", domain.TournamentWin, true, berserkMinPlies-1), "short berserk wins don't")
+	assert.Equal(t, 5, score("WW", domain.TournamentWin, true, berserkMinPlies), "the berserk point isn't doubled")
+}
+
+func TestPairPlayers(t *testing.T) {
+	newPlayer := func(id string, score int, lastOpponent string, colorBalance int) *arenaPlayer {
+		return &arenaPlayer{
+			TournamentPlayer: domain.TournamentPlayer{PlayerID: id, Score: score},
+			lastOpponent:     lastOpponent,
+			colorBalance:     colorBalance,
+		}
+	}
+
+	t.Run("Pairs players ranked closest", func(t *testing.T) {
+		pairings := pairPlayers([]*arenaPlayer{
+			newPlayer("a", 0, "", 0),
+			newPlayer("b", 6, "", 0),
+			newPlayer("c", 2, "", 1),
+			newPlayer("d", 5, "", 0),
+		})
+
+		assert.Len(t, pairings, 2)
+		assert.Equal(t, "b", pairings[0].white.PlayerID)
+		assert.Equal(t, "d", pairings[0].black.PlayerID)
+		assert.Equal(t, "a", pairings[1].white.PlayerID, "c had white more often")
+		assert.Equal(t, "c", pairings[1].black.PlayerID)
+	})
+
+	t.Run("Avoids rematches", func(t *testing.T) {
+		pairings := pairPlayers([]*arenaPlayer{
+			newPlayer("a", 4, "b", 0),
+			newPlayer("b", 4, "a", 0),
+			newPlayer("c", 0, "", 0),
+		})
+
+		assert.Len(t, pairings, 1)
+		assert.Equal(t, "a", pairings[0].white.PlayerID)
+		assert.Equal(t, "c", pairings[0].black.PlayerID)
+	})
+
+	t.Run("Allows a rematch between the last two players", func(t *testing.T) {
+		pairings := pairPlayers([]*arenaPlayer{
+			newPlayer("a", 4, "b", 1),
+			newPlayer("b", 4, "a", -1),
+		})
+
+		assert.Len(t, pairings, 1)
+		assert.Equal(t, "b", pairings[0].white.PlayerID)
+	})
+}
+
+func TestArenaUseCase(t *testing.T) {
+	now := time.Date(2023, time.October, 10, 2, 10, 10, 10, time.UTC)
+	timeNow = func() time.Time {
+		return now
+	}
+
+	tournament := domain.Tournament{
+		Name:      "Blitz",
+		Time:      180000,
+		StartsAt:  now.Add(time.Minute).UnixMilli(),
+		EndsAt:    now.Add(time.Hour).UnixMilli(),
+		Kind:      domain.TournamentKindArena,
+		Status:    domain.TournamentStatusCreated,
+		Increment: 0,
+	}
+
+	mockRepo := new(repository_tournament_mock.TournamentMockRepo)
+	mockRepo.On("Insert", context.Background(), tournament).Return(3, nil).Once()
+	mockRepo.On("SavePlayer", context.Background(), mock.Anything).Return(nil)
+	mockRepo.On("UpdateStatus", context.Background(), 3, mock.Anything).Return(nil)
+
+	room := domain_websocket.NewRoom([]domain.Client{
+		domain_websocket.NewClient("1", make(chan []byte, 10), nil, nil),
+		domain_websocket.NewClient("2", make(chan []byte, 10), nil, nil),
+	}, "3")
+
+	startedGames := make([]domain.Game, 0)
+	u := NewArenaUseCase(
+		mockRepo,
+		func(ctx context.Context, g domain.Game) (int, error) {
+			startedGames = append(startedGames, g)
+			return 100 + len(startedGames), nil
+		},
+		func(tournamentID int) (domain.Room, bool) {
+			return room, tournamentID == 3
+		},
+		nil,
+	)
+
+	created, err := u.Create(context.Background(), domain.Tournament{
+		Name:     tournament.Name,
+		Time:     tournament.Time,
+		StartsAt: tournament.StartsAt,
+		EndsAt:   tournament.EndsAt,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, created.ID)
+
+	assert.NoError(t, u.Join(context.Background(), 3, "1"))
+	assert.NoError(t, u.Join(context.Background(), 3, "2"))
+
+	t.Run("Doesn't pair before the start", func(t *testing.T) {
+		u.Tick(context.Background())
+		assert.Empty(t, startedGames)
+	})
+
+	t.Run("Pairs idle players once started", func(t *testing.T) {
+		now = now.Add(time.Minute)
+		u.Tick(context.Background())
+		u.Tick(context.Background())
+
+		assert.Len(t, startedGames, 1)
+		assert.Equal(t, 3, startedGames[0].TournamentID)
+		assert.Equal(t, tournament.Time, startedGames[0].Time)
+
+		standings, ok := u.Standings(3)
+		assert.True(t, ok)
+		assert.Equal(t, domain.TournamentStatusStarted, standings.Tournament.Status)
+		assert.True(t, standings.Standings[0].Playing)
+	})
+
+	t.Run("Scores finished games", func(t *testing.T) {
+		g := startedGames[0]
+		g.Result = "1-0"
+		g.BlackBerserk = true
+		assert.NoError(t, u.OnGameOver(context.Background(), g))
+
+		standings, _ := u.Standings(3)
+		assert.Equal(t, g.WhiteID, standings.Standings[0].PlayerID)
+		assert.Equal(t, 2, standings.Standings[0].Score)
+		assert.Equal(t, "W", standings.Standings[0].Results)
+		assert.Equal(t, 0, standings.Standings[1].Score)
+		assert.False(t, standings.Standings[1].Playing)
+	})
+
+	t.Run("Doesn't pair withdrawn players", func(t *testing.T) {
+		assert.NoError(t, u.Withdraw(context.Background(), 3, "2"))
+		u.Tick(context.Background())
+		assert.Len(t, startedGames, 1)
+	})
+
+	t.Run("Ends at the scheduled time", func(t *testing.T) {
+		now = now.Add(time.Hour)
+		u.Tick(context.Background())
+
+		_, ok := u.Standings(3)
+		assert.False(t, ok)
+		mockRepo.AssertCalled(t, "UpdateStatus", context.Background(), 3, domain.TournamentStatusFinished)
+	})
+}
+
+func TestArenaUseCase_Schedule(t *testing.T) {
+	now := time.Date(2023, time.October, 10, 2, 10, 10, 10, time.UTC)
+	timeNow = func() time.Time {
+		return now
+	}
+
+	startsAt := time.Date(2023, time.October, 10, 3, 0, 0, 0, time.UTC)
+	mockRepo := new(repository_tournament_mock.TournamentMockRepo)
+	mockRepo.On("Insert", context.Background(), domain.Tournament{
+		Kind:      domain.TournamentKindArena,
+		Name:      "Hourly Blitz Arena",
+		Time:      180000,
+		Increment: 2000,
+		StartsAt:  startsAt.UnixMilli(),
+		EndsAt:    startsAt.Add(57 * time.Minute).UnixMilli(),
+		Status:    domain.TournamentStatusCreated,
+	}).Return(1, nil).Once()
+
+	u := NewArenaUseCase(
+		mockRepo,
+		nil,
+		func(int) (domain.Room, bool) { return nil, false },
+		&ArenaSchedule{
+			Name:      "Hourly Blitz Arena",
+			Time:      180000,
+			Increment: 2000,
+			Duration:  57 * time.Minute,
+		},
+	)
+
+	u.Tick(context.Background())
+	u.Tick(context.Background())
+
+	assert.Len(t, u.List(), 1)
+	mockRepo.AssertExpectations(t)
+}
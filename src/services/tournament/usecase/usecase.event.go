@@ -0,0 +1,909 @@
+package usecase_tournament
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	domain_websocket "github.com/lookingcoolonavespa/go_crochess_backend/src/websocket"
+	"github.com/notnil/chess"
+)
+
+// noResult is recorded for games that ended without a result, like aborted
+// ones, and for double forfeits
+const noResult = "0-0"
+
+// eventUseCase runs round robins and knockouts. Their games are scheduled by
+// round and sent to the players as direct challenges, on the event's topic
+// and in the lobby. Scores are in half points like in swiss tournaments.
+type eventUseCase struct {
+	tournamentRepo domain.TournamentRepo
+	// startGame creates a tournament game and returns its id
+	startGame func(ctx context.Context, g domain.Game) (gameID int, err error)
+	// room returns the room of an event's topic, if anyone subscribed
+	room func(tournamentID int) (domain.Room, bool)
+	// lobby is the room of the gameseeks topic
+	lobby  domain.Room
+	events *clubEvents
+}
+
+type clubEvents struct {
+	mutex sync.Mutex
+	byID  map[int]*clubEvent
+}
+
+type clubEvent struct {
+	tournament domain.Tournament
+	players    map[string]*domain.TournamentPlayer
+	// games holds the scheduled games of every round so far, and of every
+	// round of a round robin
+	games []domain.TournamentGame
+	round int
+	// accepted holds the players that accepted each pending challenge
+	accepted map[int]map[string]bool
+}
+
+func NewEventUseCase(
+	tournamentRepo domain.TournamentRepo,
+	startGame func(ctx context.Context, g domain.Game) (gameID int, err error),
+	room func(tournamentID int) (domain.Room, bool),
+	lobby domain.Room,
+) eventUseCase {
+	return eventUseCase{
+		tournamentRepo,
+		startGame,
+		room,
+		lobby,
+		&clubEvents{byID: make(map[int]*clubEvent)},
+	}
+}
+
+func newClubEvent(t domain.Tournament) *clubEvent {
+	return &clubEvent{
+		tournament: t,
+		players:    make(map[string]*domain.TournamentPlayer),
+		games:      make([]domain.TournamentGame, 0),
+		accepted:   make(map[int]map[string]bool),
+	}
+}
+
+func eventTopic(tournamentID int) string {
+	return fmt.Sprint(domain_websocket.EventTopic, "/", tournamentID)
+}
+
+// Create creates a round robin or a knockout. The armageddon of a knockout
+// defaults to the event's time for white and four fifths of it for black.
+func (c eventUseCase) Create(ctx context.Context, t domain.Tournament) (domain.Tournament, error) {
+	if t.Kind != domain.TournamentKindRoundRobin && t.Kind != domain.TournamentKindKnockout {
+		return domain.Tournament{}, errors.New(fmt.Sprintf("%s is not a kind of event", t.Kind))
+	}
+	if t.Time <= 0 || t.OrganizerID == "" {
+		return domain.Tournament{}, errors.New("event needs a time control and an organizer")
+	}
+
+	if t.Kind == domain.TournamentKindKnockout {
+		if t.ArmageddonWhiteTime == 0 {
+			t.ArmageddonWhiteTime = t.Time
+		}
+		if t.ArmageddonBlackTime == 0 {
+			t.ArmageddonBlackTime = t.ArmageddonWhiteTime * 4 / 5
+		}
+		if t.ArmageddonBlackTime >= t.ArmageddonWhiteTime {
+			return domain.Tournament{}, errors.New("black has to get less time than white in an armageddon")
+		}
+	} else {
+		t.ArmageddonWhiteTime = 0
+		t.ArmageddonBlackTime = 0
+	}
+	if t.StartsAt == 0 {
+		t.StartsAt = timeNow().UnixMilli()
+	}
+	t.EndsAt = 0
+	t.Rounds = 0
+	t.Status = domain.TournamentStatusCreated
+
+	id, err := c.tournamentRepo.Insert(ctx, t)
+	if err != nil {
+		return domain.Tournament{}, err
+	}
+	t.ID = id
+
+	c.events.mutex.Lock()
+	c.events.byID[id] = newClubEvent(t)
+	c.events.mutex.Unlock()
+
+	return t, nil
+}
+
+// Load restores the unfinished events. Challenges that were accepted by only
+// one of the players have to be accepted again.
+func (c eventUseCase) Load(ctx context.Context) error {
+	for _, kind := range []string{domain.TournamentKindRoundRobin, domain.TournamentKindKnockout} {
+		tournaments, err := c.tournamentRepo.ListUnfinished(ctx, kind)
+		if err != nil {
+			return err
+		}
+
+		for _, t := range tournaments {
+			players, err := c.tournamentRepo.ListPlayers(ctx, t.ID)
+			if err != nil {
+				return err
+			}
+			games, err := c.tournamentRepo.ListGames(ctx, t.ID)
+			if err != nil {
+				return err
+			}
+
+			e := newClubEvent(t)
+			for i := range players {
+				e.players[players[i].PlayerID] = &players[i]
+			}
+			e.games = games
+			e.round = loadedRound(t, games)
+
+			c.events.mutex.Lock()
+			c.events.byID[t.ID] = e
+			c.events.mutex.Unlock()
+		}
+	}
+
+	return nil
+}
+
+// loadedRound returns the current round of an event from its games. Every
+// round of a round robin is scheduled when it starts, so its current round
+// is the last one with a game that started.
+func loadedRound(t domain.Tournament, games []domain.TournamentGame) int {
+	var round int
+	for _, g := range games {
+		if t.Kind == domain.TournamentKindRoundRobin && g.GameID == 0 && g.Result == "" {
+			continue
+		}
+		if g.Round > round {
+			round = g.Round
+		}
+	}
+	if round == 0 && len(games) > 0 {
+		round = 1
+	}
+
+	return round
+}
+
+func (c eventUseCase) List() []domain.Tournament {
+	c.events.mutex.Lock()
+	defer c.events.mutex.Unlock()
+
+	tournaments := make([]domain.Tournament, 0, len(c.events.byID))
+	for _, e := range c.events.byID {
+		tournaments = append(tournaments, e.tournament)
+	}
+	sort.Slice(tournaments, func(i, j int) bool {
+		return tournaments[i].StartsAt < tournaments[j].StartsAt
+	})
+
+	return tournaments
+}
+
+func (c eventUseCase) Standings(tournamentID int) (domain.TournamentStandings, bool) {
+	c.events.mutex.Lock()
+	defer c.events.mutex.Unlock()
+
+	e, ok := c.events.byID[tournamentID]
+	if !ok {
+		return domain.TournamentStandings{}, false
+	}
+
+	return e.standings(), true
+}
+
+// standings must be called with the mutex held
+func (e *clubEvent) standings() domain.TournamentStandings {
+	players := make([]domain.TournamentPlayer, 0, len(e.players))
+	for _, p := range e.players {
+		players = append(players, *p)
+	}
+
+	return domain.TournamentStandings{
+		Tournament: e.tournament,
+		Round:      e.round,
+		Standings:  rankStandings(players, e.games),
+	}
+}
+
+func (c eventUseCase) Round(tournamentID int, round int) (domain.TournamentRound, bool) {
+	c.events.mutex.Lock()
+	defer c.events.mutex.Unlock()
+
+	e, ok := c.events.byID[tournamentID]
+	if !ok {
+		return domain.TournamentRound{}, false
+	}
+	if round == 0 {
+		round = e.round
+	}
+	if round <= 0 || round > e.lastRound() {
+		return domain.TournamentRound{}, false
+	}
+
+	return e.roundGames(round), true
+}
+
+// lastRound returns the last round scheduled so far. It must be called with
+// the mutex held.
+func (e *clubEvent) lastRound() int {
+	var round int
+	for _, g := range e.games {
+		if g.Round > round {
+			round = g.Round
+		}
+	}
+
+	return round
+}
+
+// roundGames must be called with the mutex held
+func (e *clubEvent) roundGames(round int) domain.TournamentRound {
+	games := make([]domain.TournamentGame, 0)
+	for _, g := range e.games {
+		if g.Round == round {
+			games = append(games, g)
+		}
+	}
+
+	return domain.TournamentRound{TournamentID: e.tournament.ID, Round: round, Games: games}
+}
+
+// organized returns the event if organizerID organizes it. It must be
+// called with the mutex held.
+func (c eventUseCase) organized(tournamentID int, organizerID string) (*clubEvent, error) {
+	e, ok := c.events.byID[tournamentID]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("event %d is not running", tournamentID))
+	}
+	if e.tournament.OrganizerID != organizerID {
+		return nil, domain.ErrNotOrganizer
+	}
+
+	return e, nil
+}
+
+func (c eventUseCase) AddPlayer(ctx context.Context, tournamentID int, organizerID string, playerID string) error {
+	return c.setWithdrawn(ctx, tournamentID, organizerID, playerID, false)
+}
+
+// RemovePlayer takes a player out of an event that didn't start yet
+func (c eventUseCase) RemovePlayer(ctx context.Context, tournamentID int, organizerID string, playerID string) error {
+	return c.setWithdrawn(ctx, tournamentID, organizerID, playerID, true)
+}
+
+func (c eventUseCase) setWithdrawn(
+	ctx context.Context,
+	tournamentID int,
+	organizerID string,
+	playerID string,
+	withdrawn bool,
+) error {
+	c.events.mutex.Lock()
+	e, err := c.organized(tournamentID, organizerID)
+	if err != nil {
+		c.events.mutex.Unlock()
+		return err
+	}
+	if e.tournament.Status != domain.TournamentStatusCreated {
+		c.events.mutex.Unlock()
+		return domain.ErrEventAlreadyStarted
+	}
+
+	p, ok := e.players[playerID]
+	if !ok {
+		if withdrawn {
+			c.events.mutex.Unlock()
+			return errors.New(fmt.Sprintf("player %s is not in event %d", playerID, tournamentID))
+		}
+		p = &domain.TournamentPlayer{TournamentID: tournamentID, PlayerID: playerID}
+		e.players[playerID] = p
+	}
+	p.Withdrawn = withdrawn
+	saved := *p
+	standings := e.standings()
+	c.events.mutex.Unlock()
+
+	err = c.tournamentRepo.SavePlayer(ctx, saved)
+	if err != nil {
+		return err
+	}
+
+	c.broadcast(tournamentID, domain_websocket.StandingsEvent, standings)
+
+	return nil
+}
+
+// Start schedules every round of a round robin, or the first round of a
+// knockout, and challenges the players of the first round
+func (c eventUseCase) Start(ctx context.Context, tournamentID int, organizerID string, seeds []string) error {
+	c.events.mutex.Lock()
+	e, err := c.organized(tournamentID, organizerID)
+	if err != nil {
+		c.events.mutex.Unlock()
+		return err
+	}
+	if e.tournament.Status != domain.TournamentStatusCreated {
+		c.events.mutex.Unlock()
+		return domain.ErrEventAlreadyStarted
+	}
+
+	seeds, err = e.seeds(seeds)
+	if err != nil {
+		c.events.mutex.Unlock()
+		return err
+	}
+
+	var games []domain.TournamentGame
+	if e.tournament.Kind == domain.TournamentKindRoundRobin {
+		for i, round := range bergerRounds(seeds) {
+			games = append(games, e.scheduleGames(i+1, round)...)
+		}
+	} else {
+		games = e.scheduleGames(1, knockoutBracket(seeds))
+	}
+	// the event is marked as started before saving its schedule so it can't
+	// be started twice
+	e.tournament.Status = domain.TournamentStatusStarted
+	c.events.mutex.Unlock()
+
+	ids, err := c.tournamentRepo.InsertGames(ctx, games)
+	if err != nil {
+		c.events.mutex.Lock()
+		e.tournament.Status = domain.TournamentStatusCreated
+		c.events.mutex.Unlock()
+		return err
+	}
+	for i := range games {
+		games[i].ID = ids[i]
+	}
+	c.updateStatus(ctx, tournamentID, domain.TournamentStatusStarted)
+
+	c.events.mutex.Lock()
+	e.games = games
+	e.round = 1
+	standings := e.standings()
+	round := e.roundGames(1)
+	c.events.mutex.Unlock()
+
+	c.broadcast(tournamentID, domain_websocket.TournamentStartEvent, standings)
+	c.publishRound(round)
+
+	return nil
+}
+
+// seeds checks that the seeds are the players of the event, or ranks the
+// players by id when there are none. It must be called with the mutex held.
+func (e *clubEvent) seeds(seeds []string) ([]string, error) {
+	active := make([]string, 0, len(e.players))
+	for id, p := range e.players {
+		if !p.Withdrawn {
+			active = append(active, id)
+		}
+	}
+	if len(active) < 2 {
+		return nil, errors.New("event needs at least two players")
+	}
+
+	if len(seeds) == 0 {
+		sort.Strings(active)
+		return active, nil
+	}
+
+	seeded := make(map[string]bool, len(seeds))
+	for _, id := range seeds {
+		p, ok := e.players[id]
+		if !ok || p.Withdrawn || seeded[id] {
+			return nil, errors.New(fmt.Sprintf("seed %s is not a player of the event", id))
+		}
+		seeded[id] = true
+	}
+	if len(seeded) != len(active) {
+		return nil, errors.New("every player of the event has to be seeded")
+	}
+
+	return seeds, nil
+}
+
+// scheduleGames turns the pairings of a round into games. A bye of a
+// knockout sends its player through to the next round.
+func (e *clubEvent) scheduleGames(round int, pairings []eventPairing) []domain.TournamentGame {
+	games := make([]domain.TournamentGame, 0, len(pairings))
+	for _, p := range pairings {
+		g := domain.TournamentGame{
+			TournamentID: e.tournament.ID,
+			Round:        round,
+			WhiteID:      p.white,
+			BlackID:      p.black,
+		}
+		if p.black == "" {
+			g.Result = chess.WhiteWon.String()
+		}
+		games = append(games, g)
+	}
+
+	return games
+}
+
+// NextRound challenges the players of the next round once every game of the
+// current one is over, or ends the event after its last round
+func (c eventUseCase) NextRound(ctx context.Context, tournamentID int, organizerID string) error {
+	c.events.mutex.Lock()
+	e, err := c.organized(tournamentID, organizerID)
+	if err != nil {
+		c.events.mutex.Unlock()
+		return err
+	}
+	if e.tournament.Status != domain.TournamentStatusStarted {
+		c.events.mutex.Unlock()
+		return errors.New(fmt.Sprintf("event %d is not running", tournamentID))
+	}
+
+	if e.tournament.Kind == domain.TournamentKindRoundRobin {
+		for _, g := range e.roundGames(e.round).Games {
+			if g.Result == "" {
+				c.events.mutex.Unlock()
+				return domain.ErrRoundNotOver
+			}
+		}
+		if e.round >= e.lastRound() {
+			c.events.mutex.Unlock()
+			c.finish(ctx, tournamentID)
+			return nil
+		}
+
+		e.round++
+		round := e.roundGames(e.round)
+		standings := e.standings()
+		c.events.mutex.Unlock()
+
+		c.broadcast(tournamentID, domain_websocket.StandingsEvent, standings)
+		c.publishRound(round)
+		return nil
+	}
+
+	winners, ok := e.knockoutWinners()
+	if !ok {
+		c.events.mutex.Unlock()
+		return domain.ErrRoundNotOver
+	}
+	if len(winners) == 1 {
+		c.events.mutex.Unlock()
+		c.finish(ctx, tournamentID)
+		return nil
+	}
+	games := e.scheduleGames(e.round+1, knockoutRound(winners))
+	c.events.mutex.Unlock()
+
+	ids, err := c.tournamentRepo.InsertGames(ctx, games)
+	if err != nil {
+		return err
+	}
+	for i := range games {
+		games[i].ID = ids[i]
+	}
+
+	c.events.mutex.Lock()
+	e.games = append(e.games, games...)
+	e.round++
+	round := e.roundGames(e.round)
+	c.events.mutex.Unlock()
+
+	c.publishRound(round)
+
+	return nil
+}
+
+// knockoutWinners returns the winners of the matches of the current round in
+// bracket order, false while a match isn't decided. A match is decided by
+// its armageddon when its game was drawn. It must be called with the mutex
+// held.
+func (e *clubEvent) knockoutWinners() ([]string, bool) {
+	round := e.roundGames(e.round).Games
+
+	winners := make([]string, 0, len(round))
+	for _, g := range round {
+		if g.Armageddon {
+			continue
+		}
+		if g.BlackID == "" {
+			winners = append(winners, g.WhiteID)
+			continue
+		}
+
+		deciding := g
+		for _, a := range round {
+			if a.Armageddon && a.WhiteID == g.BlackID && a.BlackID == g.WhiteID {
+				deciding = a
+			}
+		}
+		winner, ok := matchWinner(deciding.WhiteID, deciding.BlackID, deciding.Result)
+		if !ok {
+			return nil, false
+		}
+		winners = append(winners, winner)
+	}
+
+	return winners, true
+}
+
+func (c eventUseCase) finish(ctx context.Context, tournamentID int) {
+	c.events.mutex.Lock()
+	e, ok := c.events.byID[tournamentID]
+	if !ok {
+		c.events.mutex.Unlock()
+		return
+	}
+	e.tournament.Status = domain.TournamentStatusFinished
+	standings := e.standings()
+	delete(c.events.byID, tournamentID)
+	c.events.mutex.Unlock()
+
+	c.updateStatus(ctx, tournamentID, domain.TournamentStatusFinished)
+	c.broadcast(tournamentID, domain_websocket.TournamentEndEvent, standings)
+}
+
+// Forfeit sets the result of a game of the current round that wasn't played
+// or ended without a result
+func (c eventUseCase) Forfeit(
+	ctx context.Context,
+	tournamentID int,
+	organizerID string,
+	challengeID int,
+	result string,
+) error {
+	if _, _, ok := gameResults(result); !ok && result != noResult {
+		return errors.New(fmt.Sprintf("%s is not a result", result))
+	}
+
+	c.events.mutex.Lock()
+	e, err := c.organized(tournamentID, organizerID)
+	if err != nil {
+		c.events.mutex.Unlock()
+		return err
+	}
+	idx := e.gameIndex(challengeID)
+	if idx == -1 || e.games[idx].Round != e.round ||
+		(e.games[idx].Result != "" && e.games[idx].Result != noResult) {
+		c.events.mutex.Unlock()
+		return domain.ErrChallengeNotFound
+	}
+	delete(e.accepted, challengeID)
+	c.events.mutex.Unlock()
+
+	return c.recordResult(ctx, tournamentID, challengeID, result)
+}
+
+// gameIndex returns the index of a game in the schedule, -1 if it isn't in
+// it. It must be called with the mutex held.
+func (e *clubEvent) gameIndex(challengeID int) int {
+	for i, g := range e.games {
+		if g.ID == challengeID {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// AcceptChallenge starts the game of a challenge once both of its players
+// accepted it
+func (c eventUseCase) AcceptChallenge(ctx context.Context, tournamentID int, playerID string, challengeID int) error {
+	c.events.mutex.Lock()
+	e, ok := c.events.byID[tournamentID]
+	if !ok || e.tournament.Status != domain.TournamentStatusStarted {
+		c.events.mutex.Unlock()
+		return domain.ErrChallengeNotFound
+	}
+	idx := e.gameIndex(challengeID)
+	if idx == -1 {
+		c.events.mutex.Unlock()
+		return domain.ErrChallengeNotFound
+	}
+	g := e.games[idx]
+	if g.Round != e.round || g.GameID != 0 || g.Result != "" ||
+		(g.WhiteID != playerID && g.BlackID != playerID) {
+		c.events.mutex.Unlock()
+		return domain.ErrChallengeNotFound
+	}
+
+	accepted, ok := e.accepted[challengeID]
+	if !ok {
+		accepted = make(map[string]bool, 2)
+		e.accepted[challengeID] = accepted
+	}
+	accepted[playerID] = true
+	if !accepted[g.WhiteID] || !accepted[g.BlackID] {
+		c.events.mutex.Unlock()
+		return nil
+	}
+	delete(e.accepted, challengeID)
+	challenge := e.challenge(g)
+	c.events.mutex.Unlock()
+
+	gameID, err := c.startGame(ctx, domain.Game{
+		WhiteID:      g.WhiteID,
+		BlackID:      g.BlackID,
+		Time:         challenge.WhiteTime,
+		Increment:    challenge.Increment,
+		WhiteTime:    challenge.WhiteTime,
+		BlackTime:    challenge.BlackTime,
+		TournamentID: tournamentID,
+	})
+	if err != nil {
+		return err
+	}
+
+	c.events.mutex.Lock()
+	if idx = e.gameIndex(challengeID); idx != -1 {
+		e.games[idx].GameID = gameID
+		g = e.games[idx]
+	}
+	round := e.roundGames(e.round)
+	c.events.mutex.Unlock()
+
+	g.GameID = gameID
+	err = c.tournamentRepo.UpdateGame(ctx, g)
+	if err != nil {
+		return err
+	}
+
+	c.broadcast(tournamentID, domain_websocket.PairingsEvent, round)
+	c.sendToPlayer(g.WhiteID, tournamentID, domain_websocket.PairedEvent, domain.TournamentPairing{
+		TournamentID: tournamentID,
+		GameID:       gameID,
+		Color:        domain.White,
+		OpponentID:   g.BlackID,
+	})
+	c.sendToPlayer(g.BlackID, tournamentID, domain_websocket.PairedEvent, domain.TournamentPairing{
+		TournamentID: tournamentID,
+		GameID:       gameID,
+		Color:        domain.Black,
+		OpponentID:   g.WhiteID,
+	})
+
+	return nil
+}
+
+// challenge must be called with the mutex held
+func (e *clubEvent) challenge(g domain.TournamentGame) domain.EventChallenge {
+	challenge := domain.EventChallenge{
+		ID:           g.ID,
+		TournamentID: g.TournamentID,
+		Round:        g.Round,
+		WhiteID:      g.WhiteID,
+		BlackID:      g.BlackID,
+		WhiteTime:    e.tournament.Time,
+		BlackTime:    e.tournament.Time,
+		Increment:    e.tournament.Increment,
+		Armageddon:   g.Armageddon,
+	}
+	if g.Armageddon {
+		challenge.WhiteTime = e.tournament.ArmageddonWhiteTime
+		challenge.BlackTime = e.tournament.ArmageddonBlackTime
+	}
+
+	return challenge
+}
+
+// OnGameOver records the result of an event game. A drawn armageddon is won
+// by black.
+func (c eventUseCase) OnGameOver(ctx context.Context, g domain.Game) error {
+	if g.TournamentID == 0 {
+		return nil
+	}
+
+	c.events.mutex.Lock()
+	e, ok := c.events.byID[g.TournamentID]
+	if !ok {
+		c.events.mutex.Unlock()
+		return nil
+	}
+	challengeID := 0
+	armageddon := false
+	for _, tg := range e.games {
+		if tg.GameID == g.ID && tg.Result == "" {
+			challengeID = tg.ID
+			armageddon = tg.Armageddon
+			break
+		}
+	}
+	c.events.mutex.Unlock()
+	if challengeID == 0 {
+		return nil
+	}
+
+	result := g.Result
+	if _, _, ok := gameResults(result); !ok {
+		result = noResult
+	} else if armageddon && result == chess.Draw.String() {
+		result = chess.BlackWon.String()
+	}
+
+	return c.recordResult(ctx, g.TournamentID, challengeID, result)
+}
+
+// dropLastResult removes the loss recorded for a game without a result, which
+// is the last result of its players since only games of the current round can
+// be forfeited and no game follows one without a result in a round. The loss
+// didn't give any points.
+func dropLastResult(p *domain.TournamentPlayer) {
+	if p.Results != "" {
+		p.Results = p.Results[:len(p.Results)-1]
+	}
+}
+
+// recordResult scores a game of the schedule. A drawn knockout game is
+// followed by an armageddon with colors reversed.
+func (c eventUseCase) recordResult(ctx context.Context, tournamentID int, challengeID int, result string) error {
+	c.events.mutex.Lock()
+	e, ok := c.events.byID[tournamentID]
+	if !ok {
+		c.events.mutex.Unlock()
+		return nil
+	}
+	idx := e.gameIndex(challengeID)
+	if idx == -1 {
+		c.events.mutex.Unlock()
+		return domain.ErrChallengeNotFound
+	}
+	// a forfeit can override a game that ended without a result
+	overridden := e.games[idx].Result == noResult
+	e.games[idx].Result = result
+	g := e.games[idx]
+
+	saved := make([]domain.TournamentPlayer, 0, 2)
+	whiteResult, blackResult, scored := gameResults(result)
+	if !scored {
+		whiteResult, blackResult = domain.TournamentLoss, domain.TournamentLoss
+	}
+	if white, ok := e.players[g.WhiteID]; ok {
+		if overridden {
+			dropLastResult(white)
+		}
+		addHalfPoints(white, whiteResult)
+		saved = append(saved, *white)
+	}
+	if black, ok := e.players[g.BlackID]; ok {
+		if overridden {
+			dropLastResult(black)
+		}
+		addHalfPoints(black, blackResult)
+		saved = append(saved, *black)
+	}
+
+	var armageddon []domain.TournamentGame
+	if e.tournament.Kind == domain.TournamentKindKnockout && !g.Armageddon && result == chess.Draw.String() {
+		armageddon = []domain.TournamentGame{{
+			TournamentID: tournamentID,
+			Round:        g.Round,
+			WhiteID:      g.BlackID,
+			BlackID:      g.WhiteID,
+			Armageddon:   true,
+		}}
+	}
+	standings := e.standings()
+	c.events.mutex.Unlock()
+
+	err := c.tournamentRepo.UpdateGame(ctx, g)
+	if err != nil {
+		return err
+	}
+	for _, p := range saved {
+		err := c.tournamentRepo.SavePlayer(ctx, p)
+		if err != nil {
+			return err
+		}
+	}
+
+	if armageddon != nil {
+		ids, err := c.tournamentRepo.InsertGames(ctx, armageddon)
+		if err != nil {
+			return err
+		}
+		armageddon[0].ID = ids[0]
+
+		c.events.mutex.Lock()
+		e.games = append(e.games, armageddon[0])
+		c.events.mutex.Unlock()
+	}
+
+	c.events.mutex.Lock()
+	round := e.roundGames(g.Round)
+	c.events.mutex.Unlock()
+
+	c.broadcast(tournamentID, domain_websocket.StandingsEvent, standings)
+	c.publishRound(round)
+
+	return nil
+}
+
+// publishRound broadcasts the games of a round and challenges the players of
+// the games that weren't accepted yet
+func (c eventUseCase) publishRound(round domain.TournamentRound) {
+	c.broadcast(round.TournamentID, domain_websocket.PairingsEvent, round)
+
+	c.events.mutex.Lock()
+	e, ok := c.events.byID[round.TournamentID]
+	if !ok {
+		c.events.mutex.Unlock()
+		return
+	}
+	challenges := make([]domain.EventChallenge, 0, len(round.Games))
+	for _, g := range round.Games {
+		if g.BlackID != "" && g.GameID == 0 && g.Result == "" {
+			challenges = append(challenges, e.challenge(g))
+		}
+	}
+	c.events.mutex.Unlock()
+
+	for _, challenge := range challenges {
+		c.sendToPlayer(challenge.WhiteID, round.TournamentID, domain_websocket.ChallengeEvent, challenge)
+		c.sendToPlayer(challenge.BlackID, round.TournamentID, domain_websocket.ChallengeEvent, challenge)
+	}
+}
+
+// Challenges returns the challenges of the current round a player didn't
+// accept yet
+func (c eventUseCase) Challenges(tournamentID int, playerID string) []domain.EventChallenge {
+	c.events.mutex.Lock()
+	defer c.events.mutex.Unlock()
+
+	challenges := make([]domain.EventChallenge, 0)
+	e, ok := c.events.byID[tournamentID]
+	if !ok || e.tournament.Status != domain.TournamentStatusStarted {
+		return challenges
+	}
+
+	for _, g := range e.roundGames(e.round).Games {
+		if g.BlackID == "" || g.GameID != 0 || g.Result != "" || e.accepted[g.ID][playerID] {
+			continue
+		}
+		if g.WhiteID == playerID || g.BlackID == playerID {
+			challenges = append(challenges, e.challenge(g))
+		}
+	}
+
+	return challenges
+}
+
+// sendToPlayer sends a message to a player on the event's topic and in the
+// lobby, wherever they are connected
+func (c eventUseCase) sendToPlayer(playerID string, tournamentID int, event string, payload interface{}) {
+	if room, ok := c.room(tournamentID); ok {
+		if client, ok := room.GetClient(playerID); ok {
+			client.SendMessage(eventTopic(tournamentID), event, payload, jsonErrorMessage)
+		}
+	}
+
+	if c.lobby != nil {
+		if client, ok := c.lobby.GetClient(playerID); ok {
+			client.SendMessage(domain_websocket.GameseeksTopic, event, payload, jsonErrorMessage)
+		}
+	}
+}
+
+func (c eventUseCase) broadcast(tournamentID int, event string, payload interface{}) {
+	room, ok := c.room(tournamentID)
+	if !ok {
+		return
+	}
+
+	broadcastOn(room, eventTopic(tournamentID), event, payload)
+}
+
+func (c eventUseCase) updateStatus(ctx context.Context, tournamentID int, status string) {
+	err := c.tournamentRepo.UpdateStatus(ctx, tournamentID, status)
+	if err != nil {
+		log.Printf("UseCase/Event/updateStatus, error updating event %d: %v", tournamentID, err)
+	}
+}
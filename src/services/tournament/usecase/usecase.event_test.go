@@ -0,0 +1,237 @@
+package usecase_tournament
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	repository_tournament_mock "github.com/lookingcoolonavespa/go_crochess_backend/src/services/tournament/repository/mock"
+	domain_websocket "github.com/lookingcoolonavespa/go_crochess_backend/src/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestBergerRounds(t *testing.T) {
+	t.Run("Follows the Berger tables", func(t *testing.T) {
+		rounds := bergerRounds([]string{"1", "2", "3", "4"})
+
+		assert.Equal(t, [][]eventPairing{
+			{{"1", "4"}, {"2", "3"}},
+			{{"4", "3"}, {"1", "2"}},
+			{{"2", "4"}, {"3", "1"}},
+		}, rounds)
+	})
+
+	t.Run("Everyone plays everyone once", func(t *testing.T) {
+		seeds := []string{"a", "b", "c", "d", "e"}
+		rounds := bergerRounds(seeds)
+		assert.Len(t, rounds, 5, "a bye is added")
+
+		played := make(map[[2]string]int)
+		for _, round := range rounds {
+			assert.Len(t, round, 2)
+			for _, p := range round {
+				if p.white > p.black {
+					played[[2]string{p.black, p.white}]++
+				} else {
+					played[[2]string{p.white, p.black}]++
+				}
+			}
+		}
+		assert.Len(t, played, 10)
+		for _, n := range played {
+			assert.Equal(t, 1, n)
+		}
+	})
+}
+
+func TestKnockoutBracket(t *testing.T) {
+	pairings := knockoutBracket([]string{"1", "2", "3", "4", "5"})
+
+	assert.Equal(t, []eventPairing{
+		{"1", ""},
+		{"4", "5"},
+		{"2", ""},
+		{"3", ""},
+	}, pairings)
+}
+
+func TestEventUseCase_Knockout(t *testing.T) {
+	timeNow = func() time.Time {
+		return time.Date(2023, time.October, 10, 2, 10, 10, 10, time.UTC)
+	}
+
+	mockRepo := new(repository_tournament_mock.TournamentMockRepo)
+	mockRepo.On("Insert", context.Background(), mock.Anything).Return(6, nil).Once()
+	mockRepo.On("SavePlayer", context.Background(), mock.Anything).Return(nil)
+	mockRepo.On("UpdateStatus", context.Background(), 6, mock.Anything).Return(nil)
+	mockRepo.On("UpdateGame", context.Background(), mock.Anything).Return(nil)
+	mockRepo.On("InsertGames", context.Background(), mock.Anything).Return([]int{1}, nil).Once()
+	mockRepo.On("InsertGames", context.Background(), mock.Anything).Return([]int{2}, nil).Once()
+
+	playerChan := make(chan []byte, 10)
+	room := domain_websocket.NewRoom([]domain.Client{
+		domain_websocket.NewClient("a", playerChan, nil, nil),
+	}, "6")
+
+	startedGames := make([]domain.Game, 0)
+	u := NewEventUseCase(
+		mockRepo,
+		func(ctx context.Context, g domain.Game) (int, error) {
+			startedGames = append(startedGames, g)
+			return 100 + len(startedGames), nil
+		},
+		func(tournamentID int) (domain.Room, bool) {
+			return room, tournamentID == 6
+		},
+		nil,
+	)
+
+	created, err := u.Create(context.Background(), domain.Tournament{
+		Kind:        domain.TournamentKindKnockout,
+		Name:        "Club Knockout",
+		Time:        300000,
+		OrganizerID: "org",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 300000, created.ArmageddonWhiteTime)
+	assert.Equal(t, 240000, created.ArmageddonBlackTime)
+
+	t.Run("Only the organizer controls the event", func(t *testing.T) {
+		err := u.AddPlayer(context.Background(), 6, "a", "a")
+		assert.ErrorIs(t, err, domain.ErrNotOrganizer)
+	})
+
+	assert.NoError(t, u.AddPlayer(context.Background(), 6, "org", "a"))
+	assert.NoError(t, u.AddPlayer(context.Background(), 6, "org", "b"))
+
+	t.Run("Challenges the players when it starts", func(t *testing.T) {
+		assert.NoError(t, u.Start(context.Background(), 6, "org", nil))
+
+		challenges := u.Challenges(6, "a")
+		assert.Len(t, challenges, 1)
+		assert.Equal(t, 1, challenges[0].ID)
+		assert.Equal(t, "b", challenges[0].BlackID)
+
+		timeout := time.After(time.Second)
+		for challenged := false; !challenged; {
+			select {
+			case message := <-playerChan:
+				challenged = strings.Contains(string(message), `"event":"`+domain_websocket.ChallengeEvent+`"`)
+			case <-timeout:
+				t.Fatal("player was not challenged")
+			}
+		}
+	})
+
+	t.Run("Starts the game once both players accepted", func(t *testing.T) {
+		assert.NoError(t, u.AcceptChallenge(context.Background(), 6, "a", 1))
+		assert.Empty(t, startedGames)
+
+		assert.NoError(t, u.AcceptChallenge(context.Background(), 6, "b", 1))
+		assert.Len(t, startedGames, 1)
+		assert.Equal(t, 6, startedGames[0].TournamentID)
+	})
+
+	t.Run("Adds an armageddon after a draw", func(t *testing.T) {
+		g := startedGames[0]
+		g.ID = 101
+		g.Result = "1/2-1/2"
+		assert.NoError(t, u.OnGameOver(context.Background(), g))
+
+		assert.ErrorIs(t, u.NextRound(context.Background(), 6, "org"), domain.ErrRoundNotOver)
+
+		challenges := u.Challenges(6, "b")
+		assert.Len(t, challenges, 1)
+		assert.True(t, challenges[0].Armageddon)
+		assert.Equal(t, "b", challenges[0].WhiteID, "colors are reversed")
+		assert.Equal(t, 300000, challenges[0].WhiteTime)
+		assert.Equal(t, 240000, challenges[0].BlackTime)
+	})
+
+	t.Run("Black wins a drawn armageddon", func(t *testing.T) {
+		assert.NoError(t, u.AcceptChallenge(context.Background(), 6, "a", 2))
+		assert.NoError(t, u.AcceptChallenge(context.Background(), 6, "b", 2))
+		assert.Len(t, startedGames, 2)
+		assert.Equal(t, 240000, startedGames[1].BlackTime)
+
+		g := startedGames[1]
+		g.ID = 102
+		g.Result = "1/2-1/2"
+		assert.NoError(t, u.OnGameOver(context.Background(), g))
+
+		round, _ := u.Round(6, 0)
+		assert.Equal(t, "0-1", round.Games[1].Result)
+	})
+
+	t.Run("Ends when one player is left", func(t *testing.T) {
+		assert.NoError(t, u.NextRound(context.Background(), 6, "org"))
+
+		_, ok := u.Standings(6)
+		assert.False(t, ok)
+		mockRepo.AssertCalled(t, "UpdateStatus", context.Background(), 6, domain.TournamentStatusFinished)
+	})
+}
+
+func TestEventUseCase_Forfeit(t *testing.T) {
+	timeNow = func() time.Time {
+		return time.Date(2023, time.October, 10, 2, 10, 10, 10, time.UTC)
+	}
+
+	mockRepo := new(repository_tournament_mock.TournamentMockRepo)
+	mockRepo.On("Insert", context.Background(), mock.Anything).Return(7, nil).Once()
+	mockRepo.On("SavePlayer", context.Background(), mock.Anything).Return(nil)
+	mockRepo.On("UpdateStatus", context.Background(), 7, mock.Anything).Return(nil)
+	mockRepo.On("UpdateGame", context.Background(), mock.Anything).Return(nil)
+	mockRepo.On("InsertGames", context.Background(), mock.Anything).Return([]int{1}, nil).Once()
+
+	startedGames := make([]domain.Game, 0)
+	u := NewEventUseCase(
+		mockRepo,
+		func(ctx context.Context, g domain.Game) (int, error) {
+			startedGames = append(startedGames, g)
+			return 100 + len(startedGames), nil
+		},
+		func(int) (domain.Room, bool) { return nil, false },
+		nil,
+	)
+
+	_, err := u.Create(context.Background(), domain.Tournament{
+		Kind:        domain.TournamentKindRoundRobin,
+		Name:        "Club Round Robin",
+		Time:        300000,
+		OrganizerID: "org",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, u.AddPlayer(context.Background(), 7, "org", "a"))
+	assert.NoError(t, u.AddPlayer(context.Background(), 7, "org", "b"))
+	assert.NoError(t, u.Start(context.Background(), 7, "org", nil))
+
+	t.Run("Abort then forfeit scores the game once", func(t *testing.T) {
+		assert.NoError(t, u.AcceptChallenge(context.Background(), 7, "a", 1))
+		assert.NoError(t, u.AcceptChallenge(context.Background(), 7, "b", 1))
+		assert.Len(t, startedGames, 1)
+
+		g := startedGames[0]
+		g.ID = 101
+		assert.NoError(t, u.OnGameOver(context.Background(), g), "aborted without a result")
+
+		round, _ := u.Round(7, 0)
+		white := round.Games[0].WhiteID
+		assert.NoError(t, u.Forfeit(context.Background(), 7, "org", 1, "1-0"))
+
+		standings, ok := u.Standings(7)
+		assert.True(t, ok)
+		for _, p := range standings.Standings {
+			if p.PlayerID == white {
+				assert.Equal(t, 2, p.Score)
+				assert.Equal(t, "W", p.Results)
+			} else {
+				assert.Equal(t, 0, p.Score)
+				assert.Equal(t, "L", p.Results)
+			}
+		}
+	})
+}
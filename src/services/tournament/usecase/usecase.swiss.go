@@ -0,0 +1,632 @@
+package usecase_tournament
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	domain_websocket "github.com/lookingcoolonavespa/go_crochess_backend/src/websocket"
+	"github.com/notnil/chess"
+)
+
+// swissUseCase runs swiss tournaments. Scores are in half points: a win or a
+// bye is worth winPoints and a draw drawPoints.
+type swissUseCase struct {
+	tournamentRepo domain.TournamentRepo
+	// startGame creates a tournament game and returns its id
+	startGame func(ctx context.Context, g domain.Game) (gameID int, err error)
+	// room returns the room of a tournament's topic, if anyone subscribed
+	room    func(tournamentID int) (domain.Room, bool)
+	swisses *swisses
+}
+
+type swisses struct {
+	mutex sync.Mutex
+	byID  map[int]*swiss
+}
+
+type swiss struct {
+	tournament domain.Tournament
+	players    map[string]*swissPlayer
+	// games holds the pairings of every round so far
+	games []domain.TournamentGame
+	round int
+	// unsaved holds the ids of the games whose started game couldn't be
+	// saved, which Tick saves again so they aren't started twice after a
+	// restart
+	unsaved map[int]bool
+}
+
+func NewSwissUseCase(
+	tournamentRepo domain.TournamentRepo,
+	startGame func(ctx context.Context, g domain.Game) (gameID int, err error),
+	room func(tournamentID int) (domain.Room, bool),
+) swissUseCase {
+	return swissUseCase{
+		tournamentRepo,
+		startGame,
+		room,
+		&swisses{byID: make(map[int]*swiss)},
+	}
+}
+
+func newSwiss(t domain.Tournament) *swiss {
+	return &swiss{
+		tournament: t,
+		players:    make(map[string]*swissPlayer),
+		games:      make([]domain.TournamentGame, 0),
+		unsaved:    make(map[int]bool),
+	}
+}
+
+func newSwissPlayer(p domain.TournamentPlayer) *swissPlayer {
+	return &swissPlayer{TournamentPlayer: p, opponents: make(map[string]bool)}
+}
+
+func (c swissUseCase) Create(ctx context.Context, t domain.Tournament) (domain.Tournament, error) {
+	if t.Time <= 0 || t.Rounds <= 0 {
+		return domain.Tournament{}, errors.New("swiss needs a time control and at least one round")
+	}
+
+	t.Kind = domain.TournamentKindSwiss
+	t.Status = domain.TournamentStatusCreated
+	t.EndsAt = 0
+
+	id, err := c.tournamentRepo.Insert(ctx, t)
+	if err != nil {
+		return domain.Tournament{}, err
+	}
+	t.ID = id
+
+	c.swisses.mutex.Lock()
+	c.swisses.byID[id] = newSwiss(t)
+	c.swisses.mutex.Unlock()
+
+	return t, nil
+}
+
+// Load restores the players and pairings of the unfinished swiss tournaments.
+// Games that were being played keep their pairing and are scored whenever
+// they end.
+func (c swissUseCase) Load(ctx context.Context) error {
+	tournaments, err := c.tournamentRepo.ListUnfinished(ctx, domain.TournamentKindSwiss)
+	if err != nil {
+		return err
+	}
+
+	for _, t := range tournaments {
+		players, err := c.tournamentRepo.ListPlayers(ctx, t.ID)
+		if err != nil {
+			return err
+		}
+		games, err := c.tournamentRepo.ListGames(ctx, t.ID)
+		if err != nil {
+			return err
+		}
+
+		s := newSwiss(t)
+		for _, p := range players {
+			s.players[p.PlayerID] = newSwissPlayer(p)
+		}
+		for _, g := range games {
+			s.addGame(g)
+		}
+
+		c.swisses.mutex.Lock()
+		c.swisses.byID[t.ID] = s
+		c.swisses.mutex.Unlock()
+	}
+
+	return nil
+}
+
+// addGame records a pairing in the pairing history of its players. It must
+// be called with the mutex held.
+func (s *swiss) addGame(g domain.TournamentGame) {
+	s.games = append(s.games, g)
+	if g.Round > s.round {
+		s.round = g.Round
+	}
+
+	white, ok := s.players[g.WhiteID]
+	if !ok {
+		return
+	}
+	if g.BlackID == "" {
+		white.hadBye = true
+		return
+	}
+	black, ok := s.players[g.BlackID]
+	if !ok {
+		return
+	}
+
+	white.opponents[black.PlayerID] = true
+	black.opponents[white.PlayerID] = true
+	white.colorBalance++
+	black.colorBalance--
+	white.lastColor = domain.White
+	black.lastColor = domain.Black
+}
+
+func (c swissUseCase) List() []domain.Tournament {
+	c.swisses.mutex.Lock()
+	defer c.swisses.mutex.Unlock()
+
+	tournaments := make([]domain.Tournament, 0, len(c.swisses.byID))
+	for _, s := range c.swisses.byID {
+		tournaments = append(tournaments, s.tournament)
+	}
+	sort.Slice(tournaments, func(i, j int) bool {
+		return tournaments[i].StartsAt < tournaments[j].StartsAt
+	})
+
+	return tournaments
+}
+
+func (c swissUseCase) Standings(tournamentID int) (domain.TournamentStandings, bool) {
+	c.swisses.mutex.Lock()
+	defer c.swisses.mutex.Unlock()
+
+	s, ok := c.swisses.byID[tournamentID]
+	if !ok {
+		return domain.TournamentStandings{}, false
+	}
+
+	return s.standings(), true
+}
+
+// standings must be called with the mutex held
+func (s *swiss) standings() domain.TournamentStandings {
+	players := make([]domain.TournamentPlayer, 0, len(s.players))
+	for _, p := range s.players {
+		players = append(players, p.TournamentPlayer)
+	}
+
+	return domain.TournamentStandings{
+		Tournament: s.tournament,
+		Round:      s.round,
+		Standings:  rankStandings(players, s.games),
+	}
+}
+
+func (c swissUseCase) Round(tournamentID int, round int) (domain.TournamentRound, bool) {
+	c.swisses.mutex.Lock()
+	defer c.swisses.mutex.Unlock()
+
+	s, ok := c.swisses.byID[tournamentID]
+	if !ok {
+		return domain.TournamentRound{}, false
+	}
+	if round == 0 {
+		round = s.round
+	}
+	if round <= 0 || round > s.round {
+		return domain.TournamentRound{}, false
+	}
+
+	return s.roundPairings(round), true
+}
+
+// roundPairings must be called with the mutex held
+func (s *swiss) roundPairings(round int) domain.TournamentRound {
+	games := make([]domain.TournamentGame, 0)
+	for _, g := range s.games {
+		if g.Round == round {
+			games = append(games, g)
+		}
+	}
+
+	return domain.TournamentRound{TournamentID: s.tournament.ID, Round: round, Games: games}
+}
+
+// Join adds a player to the tournament. Players can join late as long as a
+// round is left for them to play, starting with no points.
+func (c swissUseCase) Join(ctx context.Context, tournamentID int, playerID string) error {
+	c.swisses.mutex.Lock()
+	s, ok := c.swisses.byID[tournamentID]
+	if !ok || s.round >= s.tournament.Rounds {
+		c.swisses.mutex.Unlock()
+		return errors.New(fmt.Sprintf("swiss %d is not open to new players", tournamentID))
+	}
+
+	p, ok := s.players[playerID]
+	if !ok {
+		p = newSwissPlayer(domain.TournamentPlayer{
+			TournamentID: tournamentID,
+			PlayerID:     playerID,
+		})
+		s.players[playerID] = p
+	}
+	p.Withdrawn = false
+	saved := p.TournamentPlayer
+	standings := s.standings()
+	c.swisses.mutex.Unlock()
+
+	err := c.tournamentRepo.SavePlayer(ctx, saved)
+	if err != nil {
+		return err
+	}
+
+	broadcast(c.room, tournamentID, domain_websocket.StandingsEvent, standings)
+
+	return nil
+}
+
+// Withdraw leaves a player out of the next rounds. A game they are playing
+// still counts.
+func (c swissUseCase) Withdraw(ctx context.Context, tournamentID int, playerID string) error {
+	c.swisses.mutex.Lock()
+	s, ok := c.swisses.byID[tournamentID]
+	if !ok {
+		c.swisses.mutex.Unlock()
+		return errors.New(fmt.Sprintf("swiss %d is not running", tournamentID))
+	}
+
+	p, ok := s.players[playerID]
+	if !ok {
+		c.swisses.mutex.Unlock()
+		return errors.New(fmt.Sprintf("player %s did not join swiss %d", playerID, tournamentID))
+	}
+	p.Withdrawn = true
+	saved := p.TournamentPlayer
+	standings := s.standings()
+	c.swisses.mutex.Unlock()
+
+	err := c.tournamentRepo.SavePlayer(ctx, saved)
+	if err != nil {
+		return err
+	}
+
+	broadcast(c.room, tournamentID, domain_websocket.StandingsEvent, standings)
+
+	return nil
+}
+
+// OnGameOver records the result of a swiss game. Games that end without a
+// result, like aborted ones, count as a loss for both players so the round
+// can still finish.
+func (c swissUseCase) OnGameOver(ctx context.Context, g domain.Game) error {
+	if g.TournamentID == 0 {
+		return nil
+	}
+
+	c.swisses.mutex.Lock()
+	s, found := c.swisses.byID[g.TournamentID]
+	if !found || s.tournament.Status != domain.TournamentStatusStarted {
+		c.swisses.mutex.Unlock()
+		return nil
+	}
+
+	idx := -1
+	for i, tg := range s.games {
+		if tg.GameID == g.ID && tg.Result == "" {
+			idx = i
+			break
+		}
+	}
+	if idx == -1 {
+		c.swisses.mutex.Unlock()
+		return nil
+	}
+
+	whiteResult, blackResult, ok := gameResults(g.Result)
+	result := g.Result
+	if !ok {
+		whiteResult, blackResult = domain.TournamentLoss, domain.TournamentLoss
+		result = "0-0"
+	}
+	s.games[idx].Result = result
+
+	saved := make([]domain.TournamentPlayer, 0, 2)
+	if white, ok := s.players[g.WhiteID]; ok {
+		addHalfPoints(&white.TournamentPlayer, whiteResult)
+		saved = append(saved, white.TournamentPlayer)
+	}
+	if black, ok := s.players[g.BlackID]; ok {
+		addHalfPoints(&black.TournamentPlayer, blackResult)
+		saved = append(saved, black.TournamentPlayer)
+	}
+	standings := s.standings()
+	c.swisses.mutex.Unlock()
+
+	err := c.tournamentRepo.SetGameResult(ctx, g.ID, result)
+	if err != nil {
+		return err
+	}
+	for _, p := range saved {
+		err := c.tournamentRepo.SavePlayer(ctx, p)
+		if err != nil {
+			return err
+		}
+	}
+
+	broadcast(c.room, g.TournamentID, domain_websocket.StandingsEvent, standings)
+
+	return nil
+}
+
+// Tick starts the tournaments that are due, starts or saves the games that
+// failed to start or be saved before and pairs the next round once every game of the current one
+// is over
+func (c swissUseCase) Tick(ctx context.Context) {
+	now := timeNow().UnixMilli()
+
+	c.swisses.mutex.Lock()
+	ids := make([]int, 0, len(c.swisses.byID))
+	for id := range c.swisses.byID {
+		ids = append(ids, id)
+	}
+	c.swisses.mutex.Unlock()
+
+	for _, id := range ids {
+		c.saveGames(ctx, id)
+		c.tickSwiss(ctx, id, now)
+	}
+}
+
+// saveGames saves the started games that couldn't be saved before
+func (c swissUseCase) saveGames(ctx context.Context, tournamentID int) {
+	c.swisses.mutex.Lock()
+	s, ok := c.swisses.byID[tournamentID]
+	if !ok || len(s.unsaved) == 0 {
+		c.swisses.mutex.Unlock()
+		return
+	}
+	unsaved := make([]domain.TournamentGame, 0, len(s.unsaved))
+	for _, g := range s.games {
+		if s.unsaved[g.ID] {
+			unsaved = append(unsaved, g)
+		}
+	}
+	c.swisses.mutex.Unlock()
+
+	for _, g := range unsaved {
+		err := c.tournamentRepo.UpdateGame(ctx, g)
+		if err != nil {
+			log.Printf("UseCase/Swiss/saveGames, error saving game %d of swiss %d: %v", g.GameID, tournamentID, err)
+			continue
+		}
+
+		c.swisses.mutex.Lock()
+		delete(s.unsaved, g.ID)
+		c.swisses.mutex.Unlock()
+	}
+}
+
+func (c swissUseCase) tickSwiss(ctx context.Context, tournamentID int, now int64) {
+	c.swisses.mutex.Lock()
+	s, ok := c.swisses.byID[tournamentID]
+	if !ok {
+		c.swisses.mutex.Unlock()
+		return
+	}
+
+	switch {
+	case s.tournament.Status == domain.TournamentStatusCreated && now >= s.tournament.StartsAt:
+		s.tournament.Status = domain.TournamentStatusStarted
+		standings := s.standings()
+		c.swisses.mutex.Unlock()
+
+		c.updateStatus(ctx, tournamentID, domain.TournamentStatusStarted)
+		broadcast(c.room, tournamentID, domain_websocket.TournamentStartEvent, standings)
+	case s.tournament.Status == domain.TournamentStatusStarted && len(s.unstartedGames()) > 0:
+		c.swisses.mutex.Unlock()
+
+		c.startGames(ctx, tournamentID)
+	case s.tournament.Status == domain.TournamentStatusStarted && s.roundOver():
+		if s.round >= s.tournament.Rounds || s.activePlayers() < 2 {
+			s.tournament.Status = domain.TournamentStatusFinished
+			standings := s.standings()
+			delete(c.swisses.byID, tournamentID)
+			c.swisses.mutex.Unlock()
+
+			c.updateStatus(ctx, tournamentID, domain.TournamentStatusFinished)
+			broadcast(c.room, tournamentID, domain_websocket.TournamentEndEvent, standings)
+			return
+		}
+		c.swisses.mutex.Unlock()
+
+		c.pairRound(ctx, tournamentID)
+	default:
+		c.swisses.mutex.Unlock()
+	}
+}
+
+// roundOver must be called with the mutex held
+func (s *swiss) roundOver() bool {
+	for _, g := range s.games {
+		if g.Round == s.round && g.Result == "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// activePlayers must be called with the mutex held
+func (s *swiss) activePlayers() int {
+	var n int
+	for _, p := range s.players {
+		if !p.Withdrawn {
+			n++
+		}
+	}
+
+	return n
+}
+
+func (c swissUseCase) updateStatus(ctx context.Context, tournamentID int, status string) {
+	err := c.tournamentRepo.UpdateStatus(ctx, tournamentID, status)
+	if err != nil {
+		log.Printf("UseCase/Swiss/updateStatus, error updating swiss %d: %v", tournamentID, err)
+	}
+}
+
+// pairRound pairs the players that didn't withdraw, saves the pairings and
+// starts their games. A bye is scored as a win straight away. The round is
+// saved before any game starts so a failed save doesn't leave games behind
+// that get started again on the next Tick.
+func (c swissUseCase) pairRound(ctx context.Context, tournamentID int) {
+	c.swisses.mutex.Lock()
+	s, ok := c.swisses.byID[tournamentID]
+	if !ok {
+		c.swisses.mutex.Unlock()
+		return
+	}
+
+	active := make([]*swissPlayer, 0, len(s.players))
+	for _, p := range s.players {
+		if !p.Withdrawn {
+			active = append(active, p)
+		}
+	}
+	pairings, bye := pairSwiss(active)
+	round := s.round + 1
+	c.swisses.mutex.Unlock()
+
+	games := make([]domain.TournamentGame, 0, len(pairings)+1)
+	for _, pairing := range pairings {
+		games = append(games, domain.TournamentGame{
+			TournamentID: tournamentID,
+			Round:        round,
+			WhiteID:      pairing.white.PlayerID,
+			BlackID:      pairing.black.PlayerID,
+		})
+	}
+	if bye != nil {
+		games = append(games, domain.TournamentGame{
+			TournamentID: tournamentID,
+			Round:        round,
+			WhiteID:      bye.PlayerID,
+			Result:       chess.WhiteWon.String(),
+		})
+	}
+
+	ids, err := c.tournamentRepo.InsertGames(ctx, games)
+	if err != nil {
+		log.Printf("UseCase/Swiss/pairRound, error saving round %d of swiss %d: %v", round, tournamentID, err)
+		return
+	}
+	for i := range games {
+		games[i].ID = ids[i]
+	}
+
+	c.swisses.mutex.Lock()
+	for _, g := range games {
+		s.addGame(g)
+	}
+	var byePlayer *domain.TournamentPlayer
+	if bye != nil {
+		addHalfPoints(&bye.TournamentPlayer, domain.TournamentWin)
+		saved := bye.TournamentPlayer
+		byePlayer = &saved
+	}
+	c.swisses.mutex.Unlock()
+
+	if byePlayer != nil {
+		err := c.tournamentRepo.SavePlayer(ctx, *byePlayer)
+		if err != nil {
+			log.Printf("UseCase/Swiss/pairRound, error scoring bye in swiss %d: %v", tournamentID, err)
+		}
+	}
+
+	c.startGames(ctx, tournamentID)
+}
+
+// startGames starts the games of the current round that weren't started yet
+// and publishes the pairings. A game that fails to start is left for the
+// next Tick to try again, and so is saving a started game.
+func (c swissUseCase) startGames(ctx context.Context, tournamentID int) {
+	c.swisses.mutex.Lock()
+	s, ok := c.swisses.byID[tournamentID]
+	if !ok {
+		c.swisses.mutex.Unlock()
+		return
+	}
+	unstarted := s.unstartedGames()
+	t := s.tournament
+	c.swisses.mutex.Unlock()
+
+	started := make([]domain.TournamentGame, 0, len(unstarted))
+	for _, g := range unstarted {
+		gameID, err := c.startGame(ctx, domain.Game{
+			WhiteID:      g.WhiteID,
+			BlackID:      g.BlackID,
+			Time:         t.Time,
+			Increment:    t.Increment,
+			TournamentID: tournamentID,
+		})
+		if err != nil {
+			log.Printf("UseCase/Swiss/startGames, error starting game in swiss %d: %v", tournamentID, err)
+			continue
+		}
+
+		// the game is recorded right away since it can end before the rest
+		// of the round started
+		g.GameID = gameID
+		c.swisses.mutex.Lock()
+		for i := range s.games {
+			if s.games[i].ID == g.ID {
+				s.games[i].GameID = gameID
+			}
+		}
+		c.swisses.mutex.Unlock()
+		started = append(started, g)
+
+		err = c.tournamentRepo.UpdateGame(ctx, g)
+		if err != nil {
+			log.Printf("UseCase/Swiss/startGames, error saving game %d of swiss %d: %v", gameID, tournamentID, err)
+			c.swisses.mutex.Lock()
+			s.unsaved[g.ID] = true
+			c.swisses.mutex.Unlock()
+		}
+	}
+	if len(started) == 0 {
+		return
+	}
+
+	c.swisses.mutex.Lock()
+	pairingsMessage := s.roundPairings(s.round)
+	standings := s.standings()
+	c.swisses.mutex.Unlock()
+
+	broadcast(c.room, tournamentID, domain_websocket.PairingsEvent, pairingsMessage)
+	broadcast(c.room, tournamentID, domain_websocket.StandingsEvent, standings)
+
+	room, ok := c.room(tournamentID)
+	if !ok {
+		return
+	}
+	for _, g := range started {
+		notifyPairing(room, domain.TournamentPairing{
+			TournamentID: tournamentID,
+			GameID:       g.GameID,
+			Color:        domain.White,
+			OpponentID:   g.BlackID,
+		}, g.WhiteID)
+		notifyPairing(room, domain.TournamentPairing{
+			TournamentID: tournamentID,
+			GameID:       g.GameID,
+			Color:        domain.Black,
+			OpponentID:   g.WhiteID,
+		}, g.BlackID)
+	}
+}
+
+// unstartedGames returns the games of the current round that are paired but
+// have no game yet. It must be called with the mutex held.
+func (s *swiss) unstartedGames() []domain.TournamentGame {
+	unstarted := make([]domain.TournamentGame, 0)
+	for _, g := range s.games {
+		if g.Round == s.round && g.BlackID != "" && g.GameID == 0 && g.Result == "" {
+			unstarted = append(unstarted, g)
+		}
+	}
+
+	return unstarted
+}
@@ -0,0 +1,382 @@
+package usecase_tournament
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	domain "github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	repository_tournament_mock "github.com/lookingcoolonavespa/go_crochess_backend/src/services/tournament/repository/mock"
+	domain_websocket "github.com/lookingcoolonavespa/go_crochess_backend/src/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPairSwiss(t *testing.T) {
+	newPlayer := func(id string, score int, opponents ...string) *swissPlayer {
+		p := newSwissPlayer(domain.TournamentPlayer{PlayerID: id, Score: score})
+		for _, o := range opponents {
+			p.opponents[o] = true
+		}
+		return p
+	}
+
+	t.Run("Pairs the top half of a score group with the bottom half", func(t *testing.T) {
+		pairings, bye := pairSwiss([]*swissPlayer{
+			newPlayer("a", 0),
+			newPlayer("b", 0),
+			newPlayer("c", 0),
+			newPlayer("d", 0),
+		})
+
+		assert.Nil(t, bye)
+		assert.Len(t, pairings, 2)
+		assert.Equal(t, "a", pairings[0].white.PlayerID)
+		assert.Equal(t, "c", pairings[0].black.PlayerID)
+		assert.Equal(t, "b", pairings[1].white.PlayerID)
+		assert.Equal(t, "d", pairings[1].black.PlayerID)
+	})
+
+	t.Run("Gives the bye to the lowest ranked player without one", func(t *testing.T) {
+		c := newPlayer("c", 0)
+		c.hadBye = true
+		_, bye := pairSwiss([]*swissPlayer{
+			newPlayer("a", 4),
+			newPlayer("b", 2),
+			c,
+		})
+
+		assert.Equal(t, "b", bye.PlayerID)
+	})
+
+	t.Run("Backtracks to avoid rematches", func(t *testing.T) {
+		pairings, _ := pairSwiss([]*swissPlayer{
+			newPlayer("a", 4, "c"),
+			newPlayer("b", 4, "d"),
+			newPlayer("c", 2, "a"),
+			newPlayer("d", 2, "b"),
+		})
+
+		assert.Len(t, pairings, 2)
+		for _, p := range pairings {
+			assert.False(t, p.white.opponents[p.black.PlayerID])
+		}
+	})
+
+	t.Run("Allows a rematch when there is no other way", func(t *testing.T) {
+		pairings, _ := pairSwiss([]*swissPlayer{
+			newPlayer("a", 2, "b"),
+			newPlayer("b", 0, "a"),
+		})
+
+		assert.Len(t, pairings, 1)
+	})
+
+	t.Run("Balances colors", func(t *testing.T) {
+		a := newPlayer("a", 0)
+		a.colorBalance = 1
+		a.lastColor = domain.White
+		b := newPlayer("b", 0)
+		c := newPlayer("c", 0)
+		c.colorBalance = 1
+		c.lastColor = domain.Black
+		d := newPlayer("d", 0)
+		d.colorBalance = 1
+		d.lastColor = domain.White
+
+		assert.Equal(t, "b", assignSwissColors(a, b).white.PlayerID, "a had white more often")
+		assert.Equal(t, "c", assignSwissColors(a, c).white.PlayerID, "c had black last")
+		assert.Equal(t, "a", assignSwissColors(a, d).white.PlayerID, "the higher ranked player gets white")
+	})
+}
+
+func TestSwissUseCase(t *testing.T) {
+	now := time.Date(2023, time.October, 10, 2, 10, 10, 10, time.UTC)
+	timeNow = func() time.Time {
+		return now
+	}
+
+	tournament := domain.Tournament{
+		Kind:     domain.TournamentKindSwiss,
+		Name:     "Club Swiss",
+		Time:     600000,
+		StartsAt: now.Add(time.Minute).UnixMilli(),
+		Rounds:   2,
+		Status:   domain.TournamentStatusCreated,
+	}
+
+	mockRepo := new(repository_tournament_mock.TournamentMockRepo)
+	mockRepo.On("Insert", context.Background(), tournament).Return(4, nil).Once()
+	mockRepo.On("SavePlayer", context.Background(), mock.Anything).Return(nil)
+	mockRepo.On("UpdateStatus", context.Background(), 4, mock.Anything).Return(nil)
+	mockRepo.On("InsertGames", context.Background(), mock.Anything).Return([]int{1, 2}, nil).Once()
+	mockRepo.On("InsertGames", context.Background(), mock.Anything).Return([]int{3, 4}, nil).Once()
+	mockRepo.On("SetGameResult", context.Background(), mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("UpdateGame", context.Background(), mock.Anything).Return(nil)
+
+	room := domain_websocket.NewRoom([]domain.Client{}, "4")
+
+	startedGames := make([]domain.Game, 0)
+	u := NewSwissUseCase(
+		mockRepo,
+		func(ctx context.Context, g domain.Game) (int, error) {
+			startedGames = append(startedGames, g)
+			g.ID = 100 + len(startedGames)
+			startedGames[len(startedGames)-1] = g
+			return g.ID, nil
+		},
+		func(tournamentID int) (domain.Room, bool) {
+			return room, tournamentID == 4
+		},
+	)
+
+	_, err := u.Create(context.Background(), domain.Tournament{
+		Name:     tournament.Name,
+		Time:     tournament.Time,
+		StartsAt: tournament.StartsAt,
+		Rounds:   tournament.Rounds,
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, u.Join(context.Background(), 4, "a"))
+	assert.NoError(t, u.Join(context.Background(), 4, "b"))
+	assert.NoError(t, u.Join(context.Background(), 4, "c"))
+
+	t.Run("Pairs the first round once started", func(t *testing.T) {
+		now = now.Add(time.Minute)
+		u.Tick(context.Background())
+		u.Tick(context.Background())
+
+		assert.Len(t, startedGames, 1)
+		assert.Equal(t, 4, startedGames[0].TournamentID)
+
+		round, ok := u.Round(4, 0)
+		assert.True(t, ok)
+		assert.Equal(t, 1, round.Round)
+		assert.Len(t, round.Games, 2, "one game and a bye")
+
+		standings, _ := u.Standings(4)
+		assert.Equal(t, "c", standings.Standings[0].PlayerID, "the bye is worth a win")
+		assert.Equal(t, winPoints, standings.Standings[0].Score)
+	})
+
+	t.Run("Waits for every game of the round", func(t *testing.T) {
+		u.Tick(context.Background())
+		assert.Len(t, startedGames, 1)
+	})
+
+	t.Run("Lets players join late", func(t *testing.T) {
+		assert.NoError(t, u.Join(context.Background(), 4, "d"))
+	})
+
+	t.Run("Pairs the next round when the round is over", func(t *testing.T) {
+		g := startedGames[0]
+		g.Result = "1/2-1/2"
+		assert.NoError(t, u.OnGameOver(context.Background(), g))
+		mockRepo.AssertCalled(t, "SetGameResult", context.Background(), g.ID, "1/2-1/2")
+
+		u.Tick(context.Background())
+
+		assert.Len(t, startedGames, 3)
+		round, _ := u.Round(4, 0)
+		assert.Equal(t, 2, round.Round)
+		for _, tg := range round.Games {
+			rematch := (tg.WhiteID == g.WhiteID && tg.BlackID == g.BlackID) ||
+				(tg.WhiteID == g.BlackID && tg.BlackID == g.WhiteID)
+			assert.False(t, rematch)
+		}
+	})
+
+	t.Run("Ends after the last round", func(t *testing.T) {
+		for _, g := range startedGames[1:] {
+			g.Result = "1-0"
+			assert.NoError(t, u.OnGameOver(context.Background(), g))
+		}
+		u.Tick(context.Background())
+
+		_, ok := u.Standings(4)
+		assert.False(t, ok)
+		mockRepo.AssertCalled(t, "UpdateStatus", context.Background(), 4, domain.TournamentStatusFinished)
+	})
+}
+
+func TestSwissUseCase_PairRound(t *testing.T) {
+	now := time.Date(2023, time.October, 10, 2, 10, 10, 10, time.UTC)
+	timeNow = func() time.Time {
+		return now
+	}
+
+	mockRepo := new(repository_tournament_mock.TournamentMockRepo)
+	mockRepo.On("Insert", context.Background(), mock.Anything).Return(4, nil).Once()
+	mockRepo.On("SavePlayer", context.Background(), mock.Anything).Return(nil)
+	mockRepo.On("UpdateStatus", context.Background(), 4, mock.Anything).Return(nil)
+	mockRepo.On("InsertGames", context.Background(), mock.Anything).Return([]int{}, errors.New("")).Once()
+	mockRepo.On("InsertGames", context.Background(), mock.Anything).Return([]int{1, 2}, nil).Once()
+	mockRepo.On("UpdateGame", context.Background(), mock.Anything).Return(nil)
+
+	startErr := errors.New("")
+	startedGames := make([]domain.Game, 0)
+	u := NewSwissUseCase(
+		mockRepo,
+		func(ctx context.Context, g domain.Game) (int, error) {
+			if startErr != nil {
+				return 0, startErr
+			}
+			startedGames = append(startedGames, g)
+			return 100 + len(startedGames), nil
+		},
+		func(int) (domain.Room, bool) { return nil, false },
+	)
+
+	_, err := u.Create(context.Background(), domain.Tournament{
+		Name:     "Club Swiss",
+		Time:     600000,
+		StartsAt: now.Add(time.Minute).UnixMilli(),
+		Rounds:   2,
+	})
+	assert.NoError(t, err)
+	for _, playerID := range []string{"a", "b", "c"} {
+		assert.NoError(t, u.Join(context.Background(), 4, playerID))
+	}
+	now = now.Add(time.Minute)
+
+	t.Run("Starts no games when the round couldn't be saved", func(t *testing.T) {
+		startErr = nil
+		u.Tick(context.Background())
+		u.Tick(context.Background())
+
+		assert.Empty(t, startedGames)
+		standings, _ := u.Standings(4)
+		assert.Equal(t, 0, standings.Round)
+	})
+
+	t.Run("Keeps a pairing whose game failed to start", func(t *testing.T) {
+		startErr = errors.New("")
+		u.Tick(context.Background())
+
+		round, ok := u.Round(4, 0)
+		assert.True(t, ok)
+		assert.Len(t, round.Games, 2, "one game and a bye")
+		mockRepo.AssertNotCalled(t, "UpdateGame", context.Background(), mock.Anything)
+	})
+
+	t.Run("Starts the game on the next tick", func(t *testing.T) {
+		startErr = nil
+		u.Tick(context.Background())
+		u.Tick(context.Background())
+
+		assert.Len(t, startedGames, 1)
+		round, _ := u.Round(4, 0)
+		assert.Equal(t, 1, round.Round)
+		for _, g := range round.Games {
+			if g.BlackID != "" {
+				assert.Equal(t, 101, g.GameID)
+			}
+		}
+		mockRepo.AssertNumberOfCalls(t, "UpdateGame", 1)
+	})
+}
+
+func TestSwissUseCase_StartGames(t *testing.T) {
+	now := time.Date(2023, time.October, 10, 2, 10, 10, 10, time.UTC)
+	timeNow = func() time.Time {
+		return now
+	}
+
+	mockRepo := new(repository_tournament_mock.TournamentMockRepo)
+	mockRepo.On("Insert", context.Background(), mock.Anything).Return(4, nil).Once()
+	mockRepo.On("SavePlayer", context.Background(), mock.Anything).Return(nil)
+	mockRepo.On("UpdateStatus", context.Background(), 4, mock.Anything).Return(nil)
+	mockRepo.On("InsertGames", context.Background(), mock.Anything).Return([]int{1, 2}, nil).Once()
+	mockRepo.On("SetGameResult", context.Background(), mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("UpdateGame", context.Background(), mock.Anything).Return(errors.New("")).Once()
+	mockRepo.On("UpdateGame", context.Background(), mock.Anything).Return(nil)
+
+	var u swissUseCase
+	startedGames := make([]domain.Game, 0)
+	u = NewSwissUseCase(
+		mockRepo,
+		func(ctx context.Context, g domain.Game) (int, error) {
+			startedGames = append(startedGames, g)
+			if len(startedGames) == 2 {
+				// the first game ends while the second one starts
+				first := startedGames[0]
+				first.ID = 101
+				first.Result = "1-0"
+				assert.NoError(t, u.OnGameOver(ctx, first))
+			}
+			return 100 + len(startedGames), nil
+		},
+		func(int) (domain.Room, bool) { return nil, false },
+	)
+
+	_, err := u.Create(context.Background(), domain.Tournament{
+		Name:     "Club Swiss",
+		Time:     600000,
+		StartsAt: now.Add(time.Minute).UnixMilli(),
+		Rounds:   2,
+	})
+	assert.NoError(t, err)
+	for _, playerID := range []string{"a", "b", "c", "d"} {
+		assert.NoError(t, u.Join(context.Background(), 4, playerID))
+	}
+	now = now.Add(time.Minute)
+	u.Tick(context.Background())
+	u.Tick(context.Background())
+
+	t.Run("Scores a game that ends before the round started", func(t *testing.T) {
+		assert.Len(t, startedGames, 2)
+		round, _ := u.Round(4, 1)
+		assert.Equal(t, "1-0", round.Games[0].Result)
+
+		standings, _ := u.Standings(4)
+		scores := 0
+		for _, p := range standings.Standings {
+			scores += p.Score
+		}
+		assert.Equal(t, 2, scores, "a win is two half points")
+	})
+
+	t.Run("Saves a started game again on the next tick", func(t *testing.T) {
+		mockRepo.AssertNumberOfCalls(t, "UpdateGame", 2)
+		u.Tick(context.Background())
+		mockRepo.AssertNumberOfCalls(t, "UpdateGame", 3)
+		u.Tick(context.Background())
+		mockRepo.AssertNumberOfCalls(t, "UpdateGame", 3)
+		assert.Len(t, startedGames, 2, "the game isn't started again")
+	})
+}
+
+func TestSwissUseCase_Load(t *testing.T) {
+	tournament := domain.Tournament{
+		ID:     2,
+		Kind:   domain.TournamentKindSwiss,
+		Rounds: 3,
+		Status: domain.TournamentStatusStarted,
+	}
+
+	mockRepo := new(repository_tournament_mock.TournamentMockRepo)
+	mockRepo.On("ListUnfinished", context.Background(), domain.TournamentKindSwiss).
+		Return([]domain.Tournament{tournament}, nil).Once()
+	mockRepo.On("ListPlayers", context.Background(), 2).Return([]domain.TournamentPlayer{
+		{TournamentID: 2, PlayerID: "a", Score: 2},
+		{TournamentID: 2, PlayerID: "b"},
+	}, nil).Once()
+	mockRepo.On("ListGames", context.Background(), 2).Return([]domain.TournamentGame{
+		{TournamentID: 2, Round: 1, WhiteID: "a", BlackID: "b", GameID: 7, Result: "1-0"},
+		{TournamentID: 2, Round: 2, WhiteID: "b", BlackID: "a", GameID: 9},
+	}, nil).Once()
+
+	u := NewSwissUseCase(mockRepo, nil, func(int) (domain.Room, bool) { return nil, false })
+	assert.NoError(t, u.Load(context.Background()))
+
+	standings, ok := u.Standings(2)
+	assert.True(t, ok)
+	assert.Equal(t, 2, standings.Round)
+	assert.Equal(t, "a", standings.Standings[0].PlayerID)
+	assert.True(t, standings.Standings[0].Playing)
+	assert.Equal(t, float64(2), standings.Standings[1].Buchholz)
+
+	mockRepo.AssertExpectations(t)
+}
@@ -4,7 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
 	"nhooyr.io/websocket"
@@ -12,14 +17,120 @@ import (
 
 const (
 	NormalCloseMessage = "web socket connection closing gracefully..."
+	// MaxRetransmits is how many times a message that isn't acked is sent
+	// again before giving up on it
+	MaxRetransmits = 5
 )
 
+// ackTimeout is how long a client has to ack a message before it is sent
+// again
+var ackTimeout = time.Second * 3
+
+// messageIDs is the id of the last message sent with SendReliably
+var messageIDs uint64
+
+var ErrPongTimeout = errors.New("client did not answer a ping in time")
+
 type Client struct {
 	id       string
 	conn     *websocket.Conn
 	send     chan []byte
 	wsServer *WebSocketServer
-	rooms    map[domain.Room]bool
+	rooms    *subscriptions
+	pending  *pendingMessages
+	queue    *outboundQueue
+	// latency is the round trip time of the last ping in nanoseconds
+	latency *atomic.Int64
+	uploads *uploads
+	limiter *rateLimiter
+}
+
+// subscriptions holds the rooms a client is subscribed to. The read pump
+// changes them while the connection can be closed from other goroutines.
+type subscriptions struct {
+	mutex sync.Mutex
+	rooms map[domain.Room]bool
+}
+
+// add returns false if the client was already subscribed to room
+func (s *subscriptions) add(room domain.Room) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.rooms[room] {
+		return false
+	}
+
+	s.rooms[room] = true
+	return true
+}
+
+func (s *subscriptions) remove(room domain.Room) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.rooms, room)
+}
+
+func (s *subscriptions) list() []domain.Room {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	rooms := make([]domain.Room, 0, len(s.rooms))
+	for room := range s.rooms {
+		rooms = append(rooms, room)
+	}
+
+	return rooms
+}
+
+// pendingMessages holds the retransmission timers of the messages a client
+// didn't ack yet by message id
+type pendingMessages struct {
+	mutex  sync.Mutex
+	timers map[string]*time.Timer
+}
+
+// add sends a message again with resend every ackTimeout until it is acked,
+// at most MaxRetransmits times
+func (p *pendingMessages) add(id string, resend func()) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	attempts := 0
+	var retransmit func()
+	retransmit = func() {
+		p.mutex.Lock()
+		defer p.mutex.Unlock()
+		if _, ok := p.timers[id]; !ok {
+			return
+		}
+		if attempts == MaxRetransmits {
+			log.Printf("Client/retransmit, message %s was never acked", id)
+			delete(p.timers, id)
+			return
+		}
+
+		attempts++
+		resend()
+		p.timers[id] = time.AfterFunc(ackTimeout, retransmit)
+	}
+	p.timers[id] = time.AfterFunc(ackTimeout, retransmit)
+}
+
+func (p *pendingMessages) ack(id string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if timer, ok := p.timers[id]; ok {
+		timer.Stop()
+		delete(p.timers, id)
+	}
+}
+
+func (p *pendingMessages) clear() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for id, timer := range p.timers {
+		timer.Stop()
+		delete(p.timers, id)
+	}
 }
 
 func NewClient(
@@ -33,7 +144,12 @@ func NewClient(
 		conn,
 		sendChan,
 		wsServer,
-		make(map[domain.Room]bool, 0),
+		&subscriptions{rooms: make(map[domain.Room]bool)},
+		&pendingMessages{timers: make(map[string]*time.Timer)},
+		newOutboundQueue(),
+		new(atomic.Int64),
+		newUploads(),
+		newRateLimiter(),
 	}
 }
 
@@ -46,25 +162,73 @@ func (c *Client) Subscribe(room domain.Room) error {
 	if err != nil {
 		return err
 	}
-	_, subscribed := c.rooms[room]
-	if subscribed {
+	if !c.rooms.add(room) {
 		return errors.New("client already has this subscription")
 	}
 
-	c.rooms[room] = true
-
 	return nil
 }
 
+// resume subscribes c to room again after a reconnect and replays the
+// messages it missed since seq
+func (c *Client) resume(room *Room, seq uint64) bool {
+	room.release(c)
+	if !room.resume(c, seq) {
+		return false
+	}
+
+	c.rooms.add(room)
+	return true
+}
+
 func (c *Client) Unsubscribe(room domain.Room) {
 	room.UnregisterClient(c)
-	if _, ok := c.rooms[room]; ok {
-		delete(c.rooms, room)
-	}
+	c.rooms.remove(room)
 }
 
+// SendBytes queues bytes to be written to the client without waiting for it.
+// A client that stays too far behind is disconnected.
 func (c *Client) SendBytes(bytes []byte) {
-	c.send <- bytes
+	err := c.queue.push(bytes)
+	if err != nil {
+		log.Printf("Client/SendBytes, disconnecting client %s: %v", c.id, err)
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+			defer cancel()
+			c.HandleClose(ctx, err)
+		}()
+		return
+	}
+
+	c.queue.forward(c.send)
+}
+
+// QueueDepth returns how many messages are waiting to be written to the
+// client
+func (c *Client) QueueDepth() int {
+	return c.queue.depth()
+}
+
+// SendReliably sends message with a message id the client has to ack, and
+// sends it again until the client does. A message can arrive more than once,
+// so clients drop the ones with an id they already got.
+func (c *Client) SendReliably(message []byte) {
+	id := strconv.FormatUint(atomic.AddUint64(&messageIDs, 1), 10)
+	identified, ok := withField(message, "id", []byte(strconv.Quote(id)))
+	if !ok {
+		c.SendBytes(message)
+		return
+	}
+
+	c.pending.add(id, func() {
+		c.SendBytes(identified)
+	})
+	c.SendBytes(identified)
+}
+
+// ack stops the retransmission of the message with id
+func (c *Client) ack(id string) {
+	c.pending.ack(id)
 }
 
 func (c *Client) SendMessage(topic string, event string, payload interface{}, logFormat string) error {
@@ -77,7 +241,7 @@ func (c *Client) SendMessage(topic string, event string, payload interface{}, lo
 	if err != nil {
 		return err
 	} else {
-		go c.SendBytes(message)
+		c.SendBytes(message)
 		return nil
 	}
 }
@@ -90,10 +254,16 @@ func (c *Client) HandleClose(ctx context.Context, err error) {
 	if err == nil {
 		return
 	}
+	// the read and write pumps and a slow client can all close the
+	// connection
+	if !c.queue.close() {
+		return
+	}
 
 	defer func() {
+		c.pending.clear()
 		c.wsServer.unregisterClient(ctx, c)
-		for room := range c.rooms {
+		for _, room := range c.rooms.list() {
 			if room != nil {
 				c.Unsubscribe(room)
 			}
@@ -132,20 +302,35 @@ func (c *Client) HandleClose(ctx context.Context, err error) {
 func (c *Client) ReadPump(
 	ctx context.Context,
 ) {
+	limit := c.wsServer.maxMessageSize
 	for {
 		_, r, err := c.conn.Reader(ctx)
 		if err != nil {
 			c.HandleClose(ctx, err)
 			break
 		}
-		buffer := make([]byte, 10000)
-		messageLen, err := r.Read(buffer)
+		message, err := io.ReadAll(io.LimitReader(r, limit+1))
 		if err != nil {
 			c.HandleClose(ctx, err)
 			break
 		}
 
-		err = c.wsServer.router.HandleWSMessage(ctx, c, buffer[0:messageLen])
+		if int64(len(message)) > limit {
+			// the rest of the message is read so the next one can be
+			_, err = io.Copy(io.Discard, r)
+			if err != nil {
+				c.HandleClose(ctx, err)
+				break
+			}
+
+			c.SendError(
+				fmt.Sprintf("message is larger than %d bytes, send it in chunks", limit),
+				"Client/ReadPump, error converting error message to json: %v",
+			)
+			continue
+		}
+
+		err = c.wsServer.router.HandleWSMessage(ctx, c, message)
 		if err != nil {
 			c.HandleClose(ctx, err)
 			break
@@ -153,6 +338,43 @@ func (c *Client) ReadPump(
 	}
 }
 
+// PingPump pings the client every period and closes the connection if a pong
+// doesn't come back within timeout, which cleans up connections that went
+// away without closing
+func (c *Client) PingPump(ctx context.Context, period time.Duration, timeout time.Duration) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			pingCtx, cancel := context.WithTimeout(ctx, timeout)
+			start := time.Now()
+			err := c.conn.Ping(pingCtx)
+			cancel()
+			if err != nil {
+				if ctx.Err() == nil {
+					err = fmt.Errorf("%w: %v", ErrPongTimeout, err)
+				}
+				c.HandleClose(ctx, err)
+				return
+			}
+
+			c.latency.Store(int64(time.Since(start)))
+		case <-c.queue.done:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Latency returns the round trip time of the last ping, 0 before the first
+// pong
+func (c *Client) Latency() time.Duration {
+	return time.Duration(c.latency.Load())
+}
+
 func (c *Client) WritePump(ctx context.Context) {
 	for {
 		select {
@@ -169,6 +391,8 @@ func (c *Client) WritePump(ctx context.Context) {
 				c.HandleClose(ctx, err)
 				return
 			}
+		case <-c.queue.done:
+			return
 		}
 	}
 }
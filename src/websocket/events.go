@@ -14,4 +14,36 @@ const (
 	GameOverEvent        = "game over"
 	TimeOutEvent         = "time out"
 	StartEngineGameEvent = "start engine game"
+	NextPuzzleEvent      = "next puzzle"
+	PuzzleMoveEvent      = "puzzle move"
+	ViewersEvent         = "viewers"
+	UpdateEvent          = "update"
+	FeaturedEvent        = "featured"
+	ChatEvent            = "chat"
+	ChatHistoryEvent     = "chat history"
+	MuteChatEvent        = "mute chat"
+	ModerateEvent        = "moderate"
+	ChatDeletedEvent     = "chat deleted"
+	BerserkEvent         = "berserk"
+	JoinEvent            = "join"
+	WithdrawEvent        = "withdraw"
+	StandingsEvent       = "standings"
+	PairedEvent          = "paired"
+	TournamentStartEvent = "tournament start"
+	TournamentEndEvent   = "tournament end"
+	PairingsEvent        = "pairings"
+	AddPlayerEvent       = "add player"
+	RemovePlayerEvent    = "remove player"
+	StartEvent           = "start"
+	NextRoundEvent       = "next round"
+	ForfeitEvent         = "forfeit"
+	ChallengeEvent       = "challenge"
+	AcceptChallengeEvent = "accept challenge"
+	SimulEndEvent        = "simul end"
+	ResumeEvent          = "resume"
+	AckEvent             = "ack"
+	PremoveEvent         = "premove"
+	DiscardPremoveEvent  = "discard premove"
+	ChunkEvent           = "chunk"
+	RateLimitedEvent     = "rate limited"
 )
@@ -1,6 +1,7 @@
 package domain_websocket
 
 import (
+	"bytes"
 	"encoding/json"
 	"log"
 )
@@ -46,3 +47,33 @@ func (m OutboundMessage) ToJSON(logFormat string) ([]byte, error) {
 
 	return jsonData, nil
 }
+
+// ResumePayload is the payload of a resume message, with the sequence number
+// of the last message the client got on the topic before it reconnected
+type ResumePayload struct {
+	Seq uint64 `json:"seq"`
+}
+
+// AckPayload is the payload of an ack message, with the id of a message sent
+// with Client.SendReliably
+type AckPayload struct {
+	ID string `json:"id"`
+}
+
+// withField adds a field to a message that is a json object. It returns
+// false with message as it is for any other message.
+func withField(message []byte, key string, value []byte) ([]byte, bool) {
+	if len(message) < 2 || message[0] != '{' || bytes.Equal(bytes.TrimSpace(message[1:]), []byte("}")) {
+		return message, false
+	}
+
+	withField := make([]byte, 0, len(message)+len(key)+len(value)+4)
+	withField = append(withField, '{', '"')
+	withField = append(withField, key...)
+	withField = append(withField, '"', ':')
+	withField = append(withField, value...)
+	withField = append(withField, ',')
+	withField = append(withField, message[1:]...)
+
+	return withField, true
+}
@@ -0,0 +1,80 @@
+package domain_websocket
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"time"
+
+	"github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+)
+
+// Middleware wraps the handler of event in topic, which is the name of the
+// topic without its param. It can run code around the handler or not call
+// it at all, like to check the client is allowed to send the event.
+type Middleware = func(topic string, event string, next TopicEventHandler) TopicEventHandler
+
+var (
+	handledEvents  = expvar.NewMap("websocket_handled_events")
+	failedEvents   = expvar.NewMap("websocket_failed_events")
+	eventDurations = expvar.NewMap("websocket_event_durations_ms")
+)
+
+// chain wraps handler in every middleware, the first one outermost
+func chain(
+	topic string,
+	event string,
+	handler TopicEventHandler,
+	middleware ...[]Middleware,
+) TopicEventHandler {
+	all := make([]Middleware, 0)
+	for _, m := range middleware {
+		all = append(all, m...)
+	}
+
+	for i := len(all) - 1; i >= 0; i-- {
+		handler = all[i](topic, event, handler)
+	}
+
+	return handler
+}
+
+// Recover turns a panicking handler into an error sent to the client, so
+// one bad message doesn't take the server down
+func Recover(topic string, event string, next TopicEventHandler) TopicEventHandler {
+	return func(ctx context.Context, room domain.Room, client domain.Client, payload []byte) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("Middleware/Recover: handler of %s in %s panicked: %v\n%s", event, topic, r, debug.Stack())
+				err = client.SendError(
+					fmt.Sprintf(`something went wrong handling "%s"`, event),
+					"Middleware/Recover: error transforming error message to json\nerr: %v",
+				)
+			}
+		}()
+
+		return next(ctx, room, client, payload)
+	}
+}
+
+// Metrics counts the messages of every event and the ones that failed, and
+// adds up the time spent handling them, published under
+// websocket_handled_events, websocket_failed_events and
+// websocket_event_durations_ms
+func Metrics(topic string, event string, next TopicEventHandler) TopicEventHandler {
+	key := topic + " " + event
+	return func(ctx context.Context, room domain.Room, client domain.Client, payload []byte) error {
+		start := time.Now()
+		err := next(ctx, room, client, payload)
+
+		handledEvents.Add(key, 1)
+		eventDurations.Add(key, time.Since(start).Milliseconds())
+		if err != nil {
+			failedEvents.Add(key, 1)
+		}
+
+		return err
+	}
+}
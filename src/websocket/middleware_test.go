@@ -0,0 +1,128 @@
+package domain_websocket
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	// record adds name to calls when the handler it wraps is called
+	record := func(calls *[]string, name string) Middleware {
+		return func(topic string, event string, next TopicEventHandler) TopicEventHandler {
+			return func(ctx context.Context, room domain.Room, client domain.Client, payload []byte) error {
+				*calls = append(*calls, name+" "+topic+" "+event)
+				return next(ctx, room, client, payload)
+			}
+		}
+	}
+
+	setup := func(handler TopicEventHandler) (Topic, *Client) {
+		topic, err := NewTopic("topic")
+		assert.NoError(t, err)
+		topic.RegisterEvent(SubscribeEvent, func(ctx context.Context, room domain.Room, client domain.Client, payload []byte) error {
+			client.Subscribe(room)
+			return handler(ctx, room, client, payload)
+		})
+		topic.RegisterEvent(InsertEvent, handler)
+
+		return topic, NewClient("0", make(chan []byte, 1), nil, nil)
+	}
+
+	t.Run("Wraps handlers globally, then by topic, then by event", func(t *testing.T) {
+		calls := make([]string, 0)
+		topic, client := setup(func(context.Context, domain.Room, domain.Client, []byte) error {
+			calls = append(calls, "handler")
+			return nil
+		})
+		topic.Use(record(&calls, "topic"))
+		topic.UseForEvent(InsertEvent, record(&calls, "event"))
+
+		r, err := NewWebSocketRouter()
+		assert.NoError(t, err)
+		r.PushNewRoute(topic)
+		r.Use(record(&calls, "global"))
+
+		err = r.HandleWSMessage(context.Background(), client, []byte(`{"topic": "topic", "event": "subscribe"}`))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"global topic subscribe", "topic topic subscribe", "handler"}, calls)
+
+		calls = calls[:0]
+		err = r.HandleWSMessage(context.Background(), client, []byte(`{"topic": "topic", "event": "insert"}`))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"global topic insert", "topic topic insert", "event topic insert", "handler"}, calls)
+	})
+
+	t.Run("Can stop a message from reaching the handler", func(t *testing.T) {
+		called := false
+		topic, client := setup(func(context.Context, domain.Room, domain.Client, []byte) error {
+			called = true
+			return nil
+		})
+		denied := errors.New("denied")
+		topic.Use(func(topic string, event string, next TopicEventHandler) TopicEventHandler {
+			return func(context.Context, domain.Room, domain.Client, []byte) error {
+				return denied
+			}
+		})
+
+		err := topic.HandleWSMessage(context.Background(), client, SubscribeEvent, nil, "topic")
+		assert.ErrorIs(t, err, denied)
+		assert.False(t, called)
+	})
+
+	t.Run("Recover sends a panic to the client as an error", func(t *testing.T) {
+		topic, client := setup(func(context.Context, domain.Room, domain.Client, []byte) error {
+			panic("oops")
+		})
+		topic.Use(Recover)
+
+		err := topic.HandleWSMessage(context.Background(), client, SubscribeEvent, nil, "topic")
+		assert.NoError(t, err)
+		assert.Contains(t, string(<-client.send), `something went wrong handling \"subscribe\"`)
+	})
+
+	t.Run("Recover covers everything the router does with a message", func(t *testing.T) {
+		r, err := NewWebSocketRouter()
+		assert.NoError(t, err)
+		r.PushNewRoute(panickingTopic{})
+		r.Use(Recover)
+		client := NewClient("0", make(chan []byte, 1), nil, nil)
+
+		err = r.HandleWSMessage(context.Background(), client, []byte(`{"topic": "panic", "event": "insert"}`))
+		assert.NoError(t, err)
+		assert.Contains(t, string(<-client.send), `something went wrong handling \"insert\"`)
+	})
+
+	t.Run("Metrics counts the messages that failed", func(t *testing.T) {
+		topic, client := setup(func(_ context.Context, _ domain.Room, _ domain.Client, payload []byte) error {
+			if payload != nil {
+				return errors.New("failed")
+			}
+			return nil
+		})
+		topic.UseForEvent(InsertEvent, Metrics)
+
+		topic.HandleWSMessage(context.Background(), client, SubscribeEvent, nil, "topic")
+		topic.HandleWSMessage(context.Background(), client, InsertEvent, nil, "topic")
+		topic.HandleWSMessage(context.Background(), client, InsertEvent, []byte("{}"), "topic")
+
+		assert.Equal(t, "2", handledEvents.Get("topic insert").String())
+		assert.Equal(t, "1", failedEvents.Get("topic insert").String())
+		assert.Nil(t, handledEvents.Get("topic subscribe"))
+	})
+}
+
+// panickingTopic panics before any of its handlers is called
+type panickingTopic struct{}
+
+func (panickingTopic) HandleWSMessage(context.Context, *Client, string, []byte, string) error {
+	panic("oops")
+}
+func (panickingTopic) RegisterEvent(string, TopicEventHandler) {}
+func (panickingTopic) Use(...Middleware)                       {}
+func (panickingTopic) UseForEvent(string, ...Middleware)       {}
+func (panickingTopic) match(topic string) bool                 { return topic == "panic" }
@@ -0,0 +1,227 @@
+package domain_websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"sync"
+	"time"
+)
+
+// MaxQueuedMessages is how many messages can wait to be written to a client
+// before it counts as slow
+const MaxQueuedMessages = 256
+
+// slowClientGrace is how long a client can stay over MaxQueuedMessages before
+// it is disconnected. A client with twice as many messages is disconnected
+// right away.
+var slowClientGrace = time.Second * 10
+
+var ErrSlowClient = errors.New("client is not keeping up with its messages")
+
+var timeNow = time.Now
+
+var (
+	queuedMessages    = expvar.NewInt("websocket_queued_messages")
+	coalescedMessages = expvar.NewInt("websocket_coalesced_messages")
+	droppedMessages   = expvar.NewInt("websocket_dropped_messages")
+	slowClients       = expvar.NewInt("websocket_slow_clients")
+)
+
+type deliveryPolicy int
+
+const (
+	// deliverAlways queues a message however far behind the client is
+	deliverAlways deliveryPolicy = iota
+	// deliverNewest replaces a queued message of the same topic, event and
+	// payload id, for messages that carry the whole state like clocks or
+	// counts. Rooms send these without a sequence number, and a message
+	// with one is never replaced, or the client would see a gap.
+	deliverNewest
+	// deliverIfRoom drops a message when the queue is full
+	deliverIfRoom
+)
+
+var deliveryPolicies = map[string]deliveryPolicy{
+	ViewersEvent:   deliverNewest,
+	UpdateEvent:    deliverNewest,
+	FeaturedEvent:  deliverNewest,
+	StandingsEvent: deliverNewest,
+	ChatEvent:      deliverIfRoom,
+}
+
+// messageHeader is what a queue needs to know of an outbound message
+type messageHeader struct {
+	Topic   string          `json:"topic"`
+	Event   string          `json:"event"`
+	Seq     json.RawMessage `json:"seq"`
+	Payload struct {
+		ID json.RawMessage `json:"id"`
+	} `json:"payload"`
+}
+
+func parseHeader(message []byte) messageHeader {
+	var header messageHeader
+	json.Unmarshal(message, &header)
+	return header
+}
+
+// snapshot reports whether the message carries the whole state of something,
+// so only the newest one with the same key matters
+func (h messageHeader) snapshot() bool {
+	return deliveryPolicies[h.Event] == deliverNewest
+}
+
+// key is the topic, event and payload id of the message
+func (h messageHeader) key() string {
+	return h.Topic + " " + h.Event + " " + string(h.Payload.ID)
+}
+
+type queuedMessage struct {
+	// key is the topic, event and payload id of a message that is delivered
+	// with deliverNewest
+	key     string
+	message []byte
+}
+
+// outboundQueue holds the messages waiting to be written to a client
+type outboundQueue struct {
+	mutex    sync.Mutex
+	messages []queuedMessage
+	// overSince is when the queue went over MaxQueuedMessages
+	overSince time.Time
+	closed    bool
+	ready     chan struct{}
+	done      chan struct{}
+	start     sync.Once
+}
+
+func newOutboundQueue() *outboundQueue {
+	return &outboundQueue{
+		messages: make([]queuedMessage, 0),
+		ready:    make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+}
+
+// push queues message by the delivery policy of its event. It returns
+// ErrSlowClient if the client stayed over MaxQueuedMessages for too long.
+func (q *outboundQueue) push(message []byte) error {
+	header := parseHeader(message)
+	policy := deliveryPolicies[header.Event]
+	if policy == deliverNewest && header.Seq != nil {
+		policy = deliverAlways
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.closed {
+		return nil
+	}
+
+	key := ""
+	if policy == deliverNewest {
+		key = header.key()
+		for i, queued := range q.messages {
+			if queued.key == key {
+				q.messages = append(q.messages[:i], q.messages[i+1:]...)
+				queuedMessages.Add(-1)
+				coalescedMessages.Add(1)
+				break
+			}
+		}
+	}
+
+	if len(q.messages) < MaxQueuedMessages {
+		q.overSince = time.Time{}
+	} else {
+		if policy == deliverIfRoom {
+			droppedMessages.Add(1)
+			return nil
+		}
+
+		if q.overSince.IsZero() {
+			q.overSince = timeNow()
+		} else if timeNow().Sub(q.overSince) > slowClientGrace || len(q.messages) >= 2*MaxQueuedMessages {
+			slowClients.Add(1)
+			return ErrSlowClient
+		}
+	}
+
+	q.messages = append(q.messages, queuedMessage{key, message})
+	queuedMessages.Add(1)
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// pop waits for the next message. It returns false once the queue is closed.
+func (q *outboundQueue) pop() ([]byte, bool) {
+	for {
+		q.mutex.Lock()
+		if q.closed {
+			q.mutex.Unlock()
+			return nil, false
+		}
+		if len(q.messages) > 0 {
+			message := q.messages[0].message
+			q.messages = q.messages[1:]
+			queuedMessages.Add(-1)
+			q.mutex.Unlock()
+			return message, true
+		}
+		q.mutex.Unlock()
+
+		select {
+		case <-q.ready:
+		case <-q.done:
+			return nil, false
+		}
+	}
+}
+
+// forward writes the queued messages to send until the queue is closed. It
+// only runs once per queue.
+func (q *outboundQueue) forward(send chan<- []byte) {
+	q.start.Do(func() {
+		go func() {
+			for {
+				message, ok := q.pop()
+				if !ok {
+					return
+				}
+
+				select {
+				case send <- message:
+				case <-q.done:
+					return
+				}
+			}
+		}()
+	})
+}
+
+func (q *outboundQueue) depth() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return len(q.messages)
+}
+
+// close drops the queued messages and stops forwarding. It returns false if
+// the queue was already closed.
+func (q *outboundQueue) close() bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.closed {
+		return false
+	}
+
+	q.closed = true
+	queuedMessages.Add(int64(-len(q.messages)))
+	q.messages = nil
+	close(q.done)
+	return true
+}
@@ -0,0 +1,145 @@
+package domain_websocket
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutboundQueue(t *testing.T) {
+	message := func(event string, n int) []byte {
+		return []byte(fmt.Sprintf(`{"topic":"game/1","event":"%s","payload":%d}`, event, n))
+	}
+
+	t.Run("Keeps the order of the messages", func(t *testing.T) {
+		q := newOutboundQueue()
+		send := make(chan []byte)
+		for i := 0; i < 3; i++ {
+			assert.NoError(t, q.push(message(MakeMoveEvent, i)))
+		}
+		q.forward(send)
+
+		for i := 0; i < 3; i++ {
+			assert.Equal(t, message(MakeMoveEvent, i), <-send)
+		}
+		q.close()
+	})
+
+	t.Run("Only keeps the newest state", func(t *testing.T) {
+		q := newOutboundQueue()
+		assert.NoError(t, q.push(message(ViewersEvent, 1)))
+		assert.NoError(t, q.push(message(MakeMoveEvent, 1)))
+		assert.NoError(t, q.push(message(ViewersEvent, 2)))
+		assert.Equal(t, 2, q.depth())
+
+		first, _ := q.pop()
+		second, _ := q.pop()
+		assert.Equal(t, message(MakeMoveEvent, 1), first)
+		assert.Equal(t, message(ViewersEvent, 2), second)
+	})
+
+	t.Run("Keeps the newest state of every game", func(t *testing.T) {
+		update := func(gameID int, fen string) []byte {
+			return []byte(fmt.Sprintf(`{"topic":"live","event":"%s","payload":{"id":%d,"fen":"%s"}}`, UpdateEvent, gameID, fen))
+		}
+
+		q := newOutboundQueue()
+		assert.NoError(t, q.push(update(1, "a")))
+		assert.NoError(t, q.push(update(2, "b")))
+		assert.NoError(t, q.push(update(1, "c")))
+		assert.Equal(t, 2, q.depth())
+
+		first, _ := q.pop()
+		second, _ := q.pop()
+		assert.Equal(t, update(2, "b"), first)
+		assert.Equal(t, update(1, "c"), second)
+	})
+
+	t.Run("Never replaces messages with a sequence number", func(t *testing.T) {
+		q := newOutboundQueue()
+		for i := 1; i <= 2; i++ {
+			assert.NoError(t, q.push([]byte(fmt.Sprintf(`{"seq":%d,"topic":"game/1","event":"%s","payload":%d}`, i, ViewersEvent, i))))
+		}
+
+		assert.Equal(t, 2, q.depth())
+	})
+
+	t.Run("Drops chat messages when full but never moves", func(t *testing.T) {
+		q := newOutboundQueue()
+		for i := 0; i < MaxQueuedMessages; i++ {
+			assert.NoError(t, q.push(message(MakeMoveEvent, i)))
+		}
+
+		assert.NoError(t, q.push(message(ChatEvent, 0)))
+		assert.Equal(t, MaxQueuedMessages, q.depth())
+
+		assert.NoError(t, q.push(message(MakeMoveEvent, MaxQueuedMessages)))
+		assert.Equal(t, MaxQueuedMessages+1, q.depth())
+	})
+
+	t.Run("A client that stays over the limit is slow", func(t *testing.T) {
+		defer func() { timeNow = time.Now }()
+		now := time.Now()
+		timeNow = func() time.Time { return now }
+
+		q := newOutboundQueue()
+		for i := 0; i <= MaxQueuedMessages; i++ {
+			assert.NoError(t, q.push(message(MakeMoveEvent, i)))
+		}
+
+		now = now.Add(slowClientGrace + time.Second)
+		assert.ErrorIs(t, q.push(message(MakeMoveEvent, 0)), ErrSlowClient)
+	})
+
+	t.Run("A client that catches up is not slow", func(t *testing.T) {
+		defer func() { timeNow = time.Now }()
+		now := time.Now()
+		timeNow = func() time.Time { return now }
+
+		q := newOutboundQueue()
+		for i := 0; i <= MaxQueuedMessages; i++ {
+			assert.NoError(t, q.push(message(MakeMoveEvent, i)))
+		}
+		q.pop()
+		q.pop()
+
+		now = now.Add(slowClientGrace + time.Second)
+		assert.NoError(t, q.push(message(MakeMoveEvent, 0)))
+	})
+}
+
+func TestRoom_Snapshots(t *testing.T) {
+	viewers := func(n int) []byte {
+		return []byte(fmt.Sprintf(`{"topic":"game/1","event":"%s","payload":{"spectators":%d}}`, ViewersEvent, n))
+	}
+
+	t.Run("Coalesces state broadcast to a client that is behind", func(t *testing.T) {
+		send := make(chan []byte)
+		client := NewClient("0", send, nil, nil)
+		room := NewRoom([]domain.Client{client}, "1")
+
+		room.BroadcastMessage([]byte(fmt.Sprintf(`{"topic":"game/1","event":"%s"}`, MakeMoveEvent)))
+		// the move is waiting to be written, so the rest stays queued
+		assert.Eventually(t, func() bool { return client.QueueDepth() == 0 }, time.Second, time.Millisecond)
+		for i := 1; i <= 3; i++ {
+			room.BroadcastMessage(viewers(i))
+		}
+		assert.Equal(t, 1, client.QueueDepth())
+
+		assert.Equal(t, fmt.Sprintf(`{"seq":1,"topic":"game/1","event":"%s"}`, MakeMoveEvent), string(<-send))
+		assert.Equal(t, string(viewers(3)), string(<-send))
+	})
+
+	t.Run("Sends the newest state to resuming clients", func(t *testing.T) {
+		room := NewRoom([]domain.Client{}, "1")
+		room.BroadcastMessage(viewers(1))
+		room.BroadcastMessage(viewers(2))
+
+		client := NewClient("0", make(chan []byte, 2), nil, nil)
+		assert.True(t, room.resume(client, 0))
+		assert.Equal(t, string(viewers(2)), string(<-client.send))
+	})
+}
@@ -0,0 +1,163 @@
+package domain_websocket
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
+)
+
+// MaxRateLimitViolations is how many messages over their rate limit a client
+// can send within rateLimitWindow before it is disconnected
+const MaxRateLimitViolations = 10
+
+var rateLimitWindow = time.Minute
+
+var ErrRateLimited = errors.New("client kept sending messages over their rate limit")
+
+// RateLimit lets Events messages through every Per. Up to Events messages
+// can be sent at once after a quiet period.
+type RateLimit struct {
+	Events int
+	Per    time.Duration
+}
+
+// RateLimitedPayload is the payload of the error a client gets for a message
+// over its rate limit. Topic is the name of the topic without its param, and
+// RetryAfter is how many milliseconds until the message would be let through.
+type RateLimitedPayload struct {
+	Message    string `json:"message"`
+	Topic      string `json:"topic"`
+	Event      string `json:"event"`
+	RetryAfter int64  `json:"retry_after"`
+}
+
+// rateLimitKey is the key of the limit of an event in a topic. An empty
+// topic or event stands for every topic or event.
+func rateLimitKey(topic string, event string) string {
+	return topic + " " + event
+}
+
+// limitFor returns the key and limit that apply to an event sent to topic,
+// preferring the limit of the event in the topic over the limit of the whole
+// topic over the limit of the event in every topic
+func limitFor(limits map[string]RateLimit, topic string, event string) (string, RateLimit, bool) {
+	base := strings.SplitN(topic, "/", 2)[0]
+	for _, key := range []string{
+		rateLimitKey(base, event),
+		rateLimitKey(base, ""),
+		rateLimitKey("", event),
+	} {
+		if limit, ok := limits[key]; ok {
+			return key, limit, true
+		}
+	}
+
+	return "", RateLimit{}, false
+}
+
+// RateLimiter returns middleware that holds every client to limits, which
+// has the limits by rateLimitKey. A message over its limit is answered with
+// when to send it again instead of being handled, and ErrRateLimited is
+// returned once the client kept going over its limits.
+func RateLimiter(limits map[string]RateLimit) Middleware {
+	return func(topic string, event string, next TopicEventHandler) TopicEventHandler {
+		key, limit, limited := limitFor(limits, topic, event)
+		return func(ctx context.Context, room domain.Room, client domain.Client, payload []byte) error {
+			c, ok := client.(*Client)
+			if !limited || !ok {
+				return next(ctx, room, client, payload)
+			}
+
+			allowed, retryAfter, err := c.limiter.allow(key, limit)
+			if err != nil {
+				return err
+			}
+			if !allowed {
+				return client.SendMessage(
+					ErrorEvent,
+					RateLimitedEvent,
+					RateLimitedPayload{
+						"sending messages too fast, slow down",
+						topic,
+						event,
+						retryAfter.Milliseconds(),
+					},
+					"Middleware/RateLimiter: error transforming error message to json\nerr: %v",
+				)
+			}
+
+			return next(ctx, room, client, payload)
+		}
+	}
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// take takes a token from the bucket if there is one, or returns how long it
+// takes for the next one
+func (b *tokenBucket) take(limit RateLimit, now time.Time) (bool, time.Duration) {
+	interval := limit.Per / time.Duration(limit.Events)
+	b.tokens += float64(now.Sub(b.last)) / float64(interval)
+	if b.tokens > float64(limit.Events) {
+		b.tokens = float64(limit.Events)
+	}
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	return false, time.Duration((1 - b.tokens) * float64(interval))
+}
+
+// rateLimiter holds the token buckets of a client by limit key and the
+// messages it sent over their limits
+type rateLimiter struct {
+	mutex           sync.Mutex
+	buckets         map[string]*tokenBucket
+	violations      int
+	violationsSince time.Time
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{buckets: make(map[string]*tokenBucket)}
+}
+
+// allow returns whether a message under the limit of key can be sent, how
+// long until it could be if not, and ErrRateLimited once the client sent
+// too many messages over their limits
+func (l *rateLimiter) allow(key string, limit RateLimit) (bool, time.Duration, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := timeNow()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(limit.Events), last: now}
+		l.buckets[key] = b
+	}
+
+	allowed, retryAfter := b.take(limit, now)
+	if allowed {
+		return true, 0, nil
+	}
+
+	if now.Sub(l.violationsSince) > rateLimitWindow {
+		l.violations = 0
+		l.violationsSince = now
+	}
+	l.violations++
+	if l.violations > MaxRateLimitViolations {
+		return false, retryAfter, ErrRateLimited
+	}
+
+	return false, retryAfter, nil
+}
@@ -3,15 +3,43 @@ package domain_websocket
 import (
 	"errors"
 	"fmt"
+	"strconv"
 	"sync"
 
 	"github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
 )
 
+// RoomHistorySize is how many broadcast messages a room keeps for clients
+// that resume after a reconnect
+const RoomHistorySize = 128
+
 type Room struct {
 	clients map[string]domain.Client
-	param   string
-	mutex   sync.Mutex
+	// players are the ids of the clients playing in the room, every other
+	// client is a spectator
+	players                map[string]bool
+	param                  string
+	mutex                  sync.Mutex
+	onSpectatorCountChange func(count int)
+	// seq is the sequence number of the last broadcast message, history
+	// holds the last RoomHistorySize of them with history[seq%RoomHistorySize]
+	// being the newest
+	seq     uint64
+	history [RoomHistorySize]sequencedMessage
+	// snapshots holds the newest of the messages that carry a whole state,
+	// which are sent without a sequence number so clients can skip the
+	// older ones, by messageHeader.key. snapshotCount orders them so the
+	// oldest can be dropped once there are more than RoomHistorySize.
+	snapshots     map[string]sequencedMessage
+	snapshotCount uint64
+}
+
+// sequencedMessage is a broadcast message with its sequence number and the
+// clients it was sent to
+type sequencedMessage struct {
+	seq     uint64
+	message []byte
+	include func(id string, player bool) bool
 }
 
 func NewRoom(clients []domain.Client, param string) *Room {
@@ -20,41 +48,248 @@ func NewRoom(clients []domain.Client, param string) *Room {
 		clientMap[client.GetID()] = client
 	}
 	return &Room{
-		clientMap,
-		param,
-		sync.Mutex{},
+		clients:   clientMap,
+		players:   make(map[string]bool),
+		param:     param,
+		snapshots: make(map[string]sequencedMessage),
 	}
 }
 
 func (r *Room) BroadcastMessage(message []byte) {
+	r.BroadcastWhere(message, func(string, bool) bool {
+		return true
+	})
+}
+
+func (r *Room) BroadcastToPlayers(message []byte) {
+	r.BroadcastWhere(message, func(_ string, player bool) bool {
+		return player
+	})
+}
+
+func (r *Room) BroadcastToSpectators(message []byte) {
+	r.BroadcastWhere(message, func(_ string, player bool) bool {
+		return !player
+	})
+}
+
+// BroadcastWhere sends message to the clients include returns true for.
+// include is called with the mutex held so it must not use the room.
+func (r *Room) BroadcastWhere(message []byte, include func(id string, player bool) bool) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
-	for _, client := range r.clients {
-		go client.SendBytes(message)
+	message = r.sequence(message, include)
+	for id, client := range r.clients {
+		if include(id, r.players[id]) {
+			client.SendBytes(message)
+		}
 	}
 }
 
-func (r *Room) RegisterClient(client domain.Client) error {
+// sequence gives message the next sequence number of the room and keeps it
+// for resuming clients. Messages that aren't json objects are sent as they
+// are, and snapshots are kept without a sequence number so the queue of a
+// client can replace the ones it didn't write yet. It must be called with the
+// mutex held.
+func (r *Room) sequence(message []byte, include func(id string, player bool) bool) []byte {
+	if header := parseHeader(message); header.snapshot() {
+		r.keepSnapshot(header.key(), message, include)
+		return message
+	}
+
+	stamped, ok := withField(message, "seq", strconv.AppendUint(nil, r.seq+1, 10))
+	if !ok {
+		return message
+	}
+
+	r.seq++
+	r.history[r.seq%RoomHistorySize] = sequencedMessage{r.seq, stamped, include}
+	return stamped
+}
+
+// keepSnapshot must be called with the mutex held
+func (r *Room) keepSnapshot(key string, message []byte, include func(id string, player bool) bool) {
+	r.snapshotCount++
+	r.snapshots[key] = sequencedMessage{r.snapshotCount, message, include}
+	if len(r.snapshots) <= RoomHistorySize {
+		return
+	}
+
+	oldestKey := key
+	for k, s := range r.snapshots {
+		if s.seq < r.snapshots[oldestKey].seq {
+			oldestKey = k
+		}
+	}
+	delete(r.snapshots, oldestKey)
+}
+
+// resume registers client again after its connection dropped and sends it
+// the messages after seq it would have gotten, then the newest snapshots
+// since it can't tell which of them it missed. It returns false without
+// registering client if the room doesn't have all of them anymore.
+func (r *Room) resume(client domain.Client, seq uint64) bool {
+	r.mutex.Lock()
+	oldest := uint64(1)
+	if r.seq > RoomHistorySize {
+		oldest = r.seq - RoomHistorySize + 1
+	}
+	if seq > r.seq || seq+1 < oldest {
+		r.mutex.Unlock()
+		return false
+	}
+
+	_, registered := r.clients[client.GetID()]
+	r.clients[client.GetID()] = client
+	notify := func() {}
+	if !registered {
+		notify = r.spectatorCountNotifier(client.GetID())
+	}
+
+	player := r.players[client.GetID()]
+	for s := seq + 1; s <= r.seq; s++ {
+		m := r.history[s%RoomHistorySize]
+		if m.include(client.GetID(), player) {
+			client.SendBytes(m.message)
+		}
+	}
+	for _, s := range r.snapshots {
+		if s.include(client.GetID(), player) {
+			client.SendBytes(s.message)
+		}
+	}
+	r.mutex.Unlock()
+
+	notify()
+
+	return true
+}
+
+// release unregisters a client with the id of client that is left over from
+// a connection that dropped, so client can take its place
+func (r *Room) release(client domain.Client) {
+	if stale, ok := r.GetClient(client.GetID()); ok && stale != client {
+		r.UnregisterClient(stale)
+	}
+}
+
+// BroadcastReliably sends message to the players with Client.SendReliably and
+// to the spectators like BroadcastMessage
+func (r *Room) BroadcastReliably(message []byte) {
+	r.broadcastReliablyWhere(message, func(string, bool) bool {
+		return true
+	})
+}
+
+// BroadcastToPlayersReliably is BroadcastToPlayers with Client.SendReliably
+func (r *Room) BroadcastToPlayersReliably(message []byte) {
+	r.broadcastReliablyWhere(message, func(_ string, player bool) bool {
+		return player
+	})
+}
+
+func (r *Room) broadcastReliablyWhere(message []byte, include func(id string, player bool) bool) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
+	message = r.sequence(message, include)
+	for id, client := range r.clients {
+		if !include(id, r.players[id]) {
+			continue
+		}
+
+		if r.players[id] {
+			client.SendReliably(message)
+		} else {
+			client.SendBytes(message)
+		}
+	}
+}
+
+func (r *Room) RegisterClient(client domain.Client) error {
+	r.mutex.Lock()
 	_, ok := r.clients[client.GetID()]
 	if ok {
+		r.mutex.Unlock()
 		return errors.New(fmt.Sprintf(`a client with the id "%s" already exists`, client.GetID()))
 	}
 
 	r.clients[client.GetID()] = client
+	notify := r.spectatorCountNotifier(client.GetID())
+	r.mutex.Unlock()
+
+	notify()
 
 	return nil
 }
 
 func (r *Room) UnregisterClient(client domain.Client) {
 	r.mutex.Lock()
-	defer r.mutex.Unlock()
-	if _, ok := r.clients[client.GetID()]; ok {
+	notify := func() {}
+	// a client that resumed on a new connection replaced the one that is
+	// unregistered when the old connection closes
+	if registered, ok := r.clients[client.GetID()]; ok && registered == client {
 		delete(r.clients, client.GetID())
+		notify = r.spectatorCountNotifier(client.GetID())
+	}
+	r.mutex.Unlock()
+
+	notify()
+}
+
+// spectatorCountNotifier returns a func calling the spectator count callback
+// if the client with id is a spectator. It must be called with the mutex held
+// and the returned func without it, since the callback usually broadcasts.
+func (r *Room) spectatorCountNotifier(id string) func() {
+	callback := r.onSpectatorCountChange
+	if r.players[id] || callback == nil {
+		return func() {}
+	}
+
+	count := r.countSpectators()
+	return func() {
+		callback(count)
 	}
 }
 
+func (r *Room) SetPlayers(ids ...string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, id := range ids {
+		r.players[id] = true
+	}
+}
+
+func (r *Room) IsPlayer(id string) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.players[id]
+}
+
+func (r *Room) CountSpectators() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.countSpectators()
+}
+
+func (r *Room) countSpectators() int {
+	count := 0
+	for id := range r.clients {
+		if !r.players[id] {
+			count++
+		}
+	}
+
+	return count
+}
+
+// OnSpectatorCountChange registers callback to be called with the number of
+// spectators whenever a spectator joins or leaves the room
+func (r *Room) OnSpectatorCountChange(callback func(count int)) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.onSpectatorCountChange = callback
+}
+
 func (r *Room) ChangeParam(param string) {
 	r.param = param
 }
@@ -68,6 +303,8 @@ func (r *Room) GetParam() (string, error) {
 }
 
 func (r *Room) GetClient(id string) (domain.Client, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
 	client, ok := r.clients[id]
 	return client, ok
 }
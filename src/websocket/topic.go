@@ -2,16 +2,21 @@ package domain_websocket
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"regexp"
 	"strings"
+	"sync"
 
 	"github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
 )
 
 type TopicEventHandler = func(context.Context, domain.Room, domain.Client, []byte) error
 
+// Topic routes the messages of a topic to the handlers of their events. The
+// middleware passed to HandleWSMessage wraps the handler before the
+// middleware of the topic, which wraps it before the middleware of the event.
 type Topic interface {
 	HandleWSMessage(
 		ctx context.Context,
@@ -21,9 +26,56 @@ type Topic interface {
 		topicName string,
 	) error
 	RegisterEvent(event string, handleFunc TopicEventHandler)
+	// Use adds middleware to every event of the topic
+	Use(middleware ...Middleware)
+	// UseForEvent adds middleware to event only
+	UseForEvent(event string, middleware ...Middleware)
 	match(string) bool
 }
 
+// handleResume replays the messages a reconnected client missed in room. A
+// client that missed more than the room keeps, or whose room is gone, is
+// subscribed again and gets a new init message instead.
+func handleResume(
+	client *Client,
+	room *Room,
+	payload []byte,
+	subscribe func() error,
+) error {
+	var resume ResumePayload
+	err := json.Unmarshal(payload, &resume)
+	if err != nil {
+		return client.SendError(
+			"resume needs the sequence number of the last message",
+			"Topic/handleResume: error transforming error message to json\nerr: %v",
+		)
+	}
+
+	if room != nil {
+		if client.resume(room, resume.Seq) {
+			return nil
+		}
+		room.release(client)
+	}
+
+	return subscribe()
+}
+
+// handleAck stops the retransmission of the message a client acked
+func handleAck(client *Client, payload []byte) error {
+	var ack AckPayload
+	err := json.Unmarshal(payload, &ack)
+	if err != nil || ack.ID == "" {
+		return client.SendError(
+			"ack needs the id of the message",
+			"Topic/handleAck: error transforming error message to json\nerr: %v",
+		)
+	}
+
+	client.ack(ack.ID)
+	return nil
+}
+
 func NewTopic(
 	// pattern follows the format: topic/param where param should be omitted
 	// if there are no params
@@ -70,19 +122,22 @@ func NewTopic(
 
 	if len(patternSplit) > 1 {
 		return TopicWithParam{
-				name:      topic,
-				matcher:   topicRE,
-				findParam: paramMatcher,
-				rooms:     make(map[string]*Room),
-				events:    make(map[string]TopicEventHandler),
+				name:       topic,
+				matcher:    topicRE,
+				findParam:  paramMatcher,
+				roomsMutex: new(sync.RWMutex),
+				rooms:      make(map[string]*Room),
+				events:     make(map[string]TopicEventHandler),
+				middleware: make(map[string][]Middleware),
 			},
 			nil
 	} else {
 		return TopicWithoutParm{
-				name:    topic,
-				matcher: topicRE,
-				room:    NewRoom(make([]domain.Client, 0), ""),
-				events:  make(map[string]TopicEventHandler),
+				name:       topic,
+				matcher:    topicRE,
+				room:       NewRoom(make([]domain.Client, 0), ""),
+				events:     make(map[string]TopicEventHandler),
+				middleware: make(map[string][]Middleware),
 			},
 			nil
 	}
@@ -1,6 +1,12 @@
 package domain_websocket
 
 const (
-	GameTopic      = "game"
-	GameseeksTopic = "gameseeks"
+	GameTopic       = "game"
+	GameseeksTopic  = "gameseeks"
+	PuzzlesTopic    = "puzzles"
+	LiveTopic       = "live"
+	TournamentTopic = "tournament"
+	EventTopic      = "event"
+	SimulTopic      = "simul"
+	BughouseTopic   = "bughouse"
 )
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"regexp"
+	"sync"
 
 	"github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
 )
@@ -12,8 +13,14 @@ type TopicWithParam struct {
 	name      string
 	matcher   *regexp.Regexp
 	findParam func(string) string
-	rooms     map[string]*Room
-	events    map[string]TopicEventHandler
+	// roomsMutex guards rooms, which gets new rooms while messages of other
+	// rooms are handled
+	roomsMutex *sync.RWMutex
+	rooms      map[string]*Room
+	events     map[string]TopicEventHandler
+	// middleware holds the middleware of the events by event, and of the
+	// whole topic under ""
+	middleware map[string][]Middleware
 }
 
 func (tp TopicWithParam) match(str string) bool {
@@ -27,6 +34,16 @@ func (tp TopicWithParam) HandleWSMessage(
 	payload []byte,
 	topicName string,
 ) error {
+	if event == AckEvent {
+		return handleAck(client, payload)
+	}
+	if event == ResumeEvent {
+		room, _ := tp.room(tp.findParam(topicName))
+		return handleResume(client, room, payload, func() error {
+			return tp.HandleWSMessage(ctx, client, SubscribeEvent, nil, topicName)
+		})
+	}
+
 	handleFunc, ok := tp.events[event]
 	if !ok {
 		err := client.SendError(
@@ -38,16 +55,15 @@ func (tp TopicWithParam) HandleWSMessage(
 	}
 
 	param := tp.findParam(topicName)
-	room, ok := tp.rooms[param]
+	room, ok := tp.room(param)
 	if event == SubscribeEvent && !ok {
-		room = NewRoom([]domain.Client{client}, param)
-		err := tp.PushNewRoom(room)
-		if err != nil {
-			return err
-		}
+		room = tp.roomOrNew(param, client)
 	}
 
-	_, subscribed := room.clients[client.GetID()]
+	subscribed := false
+	if room != nil {
+		_, subscribed = room.GetClient(client.GetID())
+	}
 	if event != SubscribeEvent && !subscribed {
 		err := client.SendError(
 			fmt.Sprintf(`you are not subscribed to "%s/%s"`, tp.name, param),
@@ -56,6 +72,7 @@ func (tp TopicWithParam) HandleWSMessage(
 		return err
 	}
 
+	handleFunc = chain(tp.name, event, handleFunc, tp.middleware[""], tp.middleware[event])
 	internalErr := handleFunc(ctx, room, client, payload)
 	return internalErr
 
@@ -65,12 +82,52 @@ func (tp TopicWithParam) RegisterEvent(event string, handleFunc TopicEventHandle
 	tp.events[event] = handleFunc
 }
 
+func (tp TopicWithParam) Use(middleware ...Middleware) {
+	tp.middleware[""] = append(tp.middleware[""], middleware...)
+}
+
+func (tp TopicWithParam) UseForEvent(event string, middleware ...Middleware) {
+	tp.middleware[event] = append(tp.middleware[event], middleware...)
+}
+
 func (tp TopicWithParam) PushNewRoom(room *Room) error {
 	param, err := room.GetParam()
 	if err != nil {
 		return err
 	}
 
+	tp.roomsMutex.Lock()
+	defer tp.roomsMutex.Unlock()
 	tp.rooms[param] = room
 	return nil
 }
+
+func (tp TopicWithParam) GetRoom(param string) (domain.Room, bool) {
+	room, ok := tp.room(param)
+	if !ok {
+		return nil, false
+	}
+
+	return room, true
+}
+
+func (tp TopicWithParam) room(param string) (*Room, bool) {
+	tp.roomsMutex.RLock()
+	defer tp.roomsMutex.RUnlock()
+	room, ok := tp.rooms[param]
+	return room, ok
+}
+
+// roomOrNew returns the room of param, creating it with client in it if no
+// one subscribed to it yet
+func (tp TopicWithParam) roomOrNew(param string, client *Client) *Room {
+	tp.roomsMutex.Lock()
+	defer tp.roomsMutex.Unlock()
+	room, ok := tp.rooms[param]
+	if !ok {
+		room = NewRoom([]domain.Client{client}, param)
+		tp.rooms[param] = room
+	}
+
+	return room
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"regexp"
+
+	"github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
 )
 
 type TopicWithoutParm struct {
@@ -11,6 +13,9 @@ type TopicWithoutParm struct {
 	matcher *regexp.Regexp
 	room    *Room
 	events  map[string]TopicEventHandler
+	// middleware holds the middleware of the events by event, and of the
+	// whole topic under ""
+	middleware map[string][]Middleware
 }
 
 func (twp TopicWithoutParm) match(str string) bool {
@@ -22,8 +27,17 @@ func (twp TopicWithoutParm) HandleWSMessage(
 	client *Client,
 	event string,
 	payload []byte,
-	_ string,
+	topicName string,
 ) error {
+	if event == AckEvent {
+		return handleAck(client, payload)
+	}
+	if event == ResumeEvent {
+		return handleResume(client, twp.room, payload, func() error {
+			return twp.HandleWSMessage(ctx, client, SubscribeEvent, nil, topicName)
+		})
+	}
+
 	handleFunc, ok := twp.events[event]
 	if !ok {
 		err := client.SendError(
@@ -42,6 +56,7 @@ func (twp TopicWithoutParm) HandleWSMessage(
 		return err
 	}
 
+	handleFunc = chain(twp.name, event, handleFunc, twp.middleware[""], twp.middleware[event])
 	internalError := handleFunc(ctx, twp.room, client, payload)
 	return internalError
 }
@@ -49,3 +64,15 @@ func (twp TopicWithoutParm) HandleWSMessage(
 func (twp TopicWithoutParm) RegisterEvent(event string, handleFunc TopicEventHandler) {
 	twp.events[event] = handleFunc
 }
+
+func (twp TopicWithoutParm) Use(middleware ...Middleware) {
+	twp.middleware[""] = append(twp.middleware[""], middleware...)
+}
+
+func (twp TopicWithoutParm) UseForEvent(event string, middleware ...Middleware) {
+	twp.middleware[event] = append(twp.middleware[event], middleware...)
+}
+
+func (twp TopicWithoutParm) GetRoom() domain.Room {
+	return twp.room
+}
@@ -2,7 +2,12 @@ package domain_websocket
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
 	"github.com/stretchr/testify/assert"
@@ -213,6 +218,12 @@ func TestTopic_TopicWithParam_HandleWSMessage(t *testing.T) {
 			testParams: MessageParams{"topic/paramb", "event"},
 			shouldErr:  true,
 		},
+		{
+			name:       "fail on a room no one subscribed to",
+			baseParams: MessageParams{"topic/param", "event"},
+			testParams: MessageParams{"topic/paramb", "event"},
+			shouldErr:  true,
+		},
 		{
 			name:       "fail on invalid event",
 			baseParams: MessageParams{"topic/param", SubscribeEvent},
@@ -265,3 +276,151 @@ func TestTopic_TopicWithParam_HandleWSMessage(t *testing.T) {
 		)
 	}
 }
+
+// run with -race to catch rooms being read while others are added
+func TestTopic_TopicWithParam_Rooms(t *testing.T) {
+	topic, err := NewTopic("topic/param")
+	assert.NoError(t, err)
+	topic.RegisterEvent(SubscribeEvent, func(context.Context, domain.Room, domain.Client, []byte) error {
+		return nil
+	})
+	tp := topic.(TopicWithParam)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		param := strconv.Itoa(i)
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, tp.PushNewRoom(NewRoom([]domain.Client{}, param)))
+		}()
+		go func() {
+			defer wg.Done()
+			tp.GetRoom(param)
+		}()
+		go func() {
+			defer wg.Done()
+			client := NewClient(param, make(chan []byte, 1), nil, nil)
+			err := tp.HandleWSMessage(context.Background(), client, SubscribeEvent, nil, "topic/sub"+param)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	for i := 0; i < 20; i++ {
+		_, ok := tp.GetRoom(strconv.Itoa(i))
+		assert.True(t, ok)
+		_, ok = tp.GetRoom("sub" + strconv.Itoa(i))
+		assert.True(t, ok)
+	}
+}
+
+func TestTopic_Resume(t *testing.T) {
+	topic, err := NewTopic("topic/param")
+	assert.NoError(t, err)
+	topic.RegisterEvent(SubscribeEvent, func(ctx context.Context, room domain.Room, client domain.Client, _ []byte) error {
+		err := client.Subscribe(room)
+		if err != nil {
+			return err
+		}
+		return client.SendMessage("topic/param", InitEvent, nil, "%v")
+	})
+
+	resume := func(client *Client, seq int) string {
+		err := topic.HandleWSMessage(
+			context.Background(),
+			client,
+			ResumeEvent,
+			[]byte(fmt.Sprintf(`{"seq":%d}`, seq)),
+			"topic/param",
+		)
+		assert.NoError(t, err)
+		return string(<-client.send)
+	}
+
+	err = topic.(TopicWithParam).PushNewRoom(NewRoom([]domain.Client{}, "param"))
+	assert.NoError(t, err)
+
+	dropped := NewClient("0", make(chan []byte, RoomHistorySize+8), nil, nil)
+	err = topic.HandleWSMessage(context.Background(), dropped, SubscribeEvent, nil, "topic/param")
+	assert.NoError(t, err)
+	assert.Contains(t, string(<-dropped.send), InitEvent)
+
+	room, _ := topic.(TopicWithParam).GetRoom("param")
+	room.BroadcastMessage([]byte(`{"topic":"topic/param","event":"first"}`))
+	room.BroadcastMessage([]byte(`{"topic":"topic/param","event":"second"}`))
+	assert.Contains(t, []string{string(<-dropped.send), string(<-dropped.send)},
+		`{"seq":1,"topic":"topic/param","event":"first"}`)
+
+	t.Run("Replays the missed messages", func(t *testing.T) {
+		reconnected := NewClient("0", make(chan []byte, 8), nil, nil)
+		assert.Equal(t, `{"seq":2,"topic":"topic/param","event":"second"}`, resume(reconnected, 1))
+
+		dropped.Unsubscribe(room)
+		registered, ok := room.GetClient("0")
+		assert.True(t, ok)
+		assert.Equal(t, reconnected, registered)
+	})
+
+	t.Run("Falls back to init after the history rolled over", func(t *testing.T) {
+		for i := 0; i < RoomHistorySize; i++ {
+			room.BroadcastWhere([]byte(`{"event":"filler"}`), func(string, bool) bool {
+				return false
+			})
+		}
+
+		reconnected := NewClient("0", make(chan []byte, 8), nil, nil)
+		assert.Contains(t, resume(reconnected, 1), InitEvent)
+	})
+
+	t.Run("Falls back to init for an unknown sequence number", func(t *testing.T) {
+		reconnected := NewClient("0", make(chan []byte, 8), nil, nil)
+		assert.Contains(t, resume(reconnected, RoomHistorySize+100), InitEvent)
+	})
+}
+
+func TestTopic_Ack(t *testing.T) {
+	defer func(timeout time.Duration) { ackTimeout = timeout }(ackTimeout)
+	ackTimeout = time.Millisecond * 20
+
+	topic, err := NewTopic("topic/param")
+	assert.NoError(t, err)
+	room := NewRoom([]domain.Client{}, "param")
+	err = topic.(TopicWithParam).PushNewRoom(room)
+	assert.NoError(t, err)
+
+	player := NewClient("0", make(chan []byte, 8), nil, nil)
+	spectator := NewClient("1", make(chan []byte, 8), nil, nil)
+	assert.NoError(t, player.Subscribe(room))
+	assert.NoError(t, spectator.Subscribe(room))
+	room.SetPlayers(player.GetID())
+
+	room.BroadcastReliably([]byte(`{"topic":"topic/param","event":"make move"}`))
+
+	sent := <-player.send
+	var message struct {
+		ID string `json:"id"`
+	}
+	assert.NoError(t, json.Unmarshal(sent, &message))
+	assert.NotEmpty(t, message.ID)
+	assert.NotContains(t, string(<-spectator.send), `"id"`)
+
+	assert.Equal(t, sent, <-player.send, "a message that isn't acked is sent again")
+
+	err = topic.HandleWSMessage(
+		context.Background(),
+		player,
+		AckEvent,
+		[]byte(fmt.Sprintf(`{"id":"%s"}`, message.ID)),
+		"topic/param",
+	)
+	assert.NoError(t, err)
+
+	// a retransmission can be on its way while the ack is handled
+	time.Sleep(ackTimeout * 3)
+	for len(player.send) > 0 {
+		<-player.send
+	}
+	time.Sleep(ackTimeout * 3)
+	assert.Empty(t, player.send)
+}
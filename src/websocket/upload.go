@@ -0,0 +1,103 @@
+package domain_websocket
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MaxUploadSize is how large a payload sent in chunks can get in bytes
+const MaxUploadSize = 1 << 22
+
+// maxUploads is how many chunked uploads a client can have going at once
+const maxUploads = 4
+
+// uploadTimeout is how long an upload can go without a chunk before it is
+// dropped
+var uploadTimeout = time.Minute
+
+var (
+	ErrUploadTooLarge = fmt.Errorf("upload is larger than %d bytes", MaxUploadSize)
+	ErrTooManyUploads = fmt.Errorf("more than %d uploads at once", maxUploads)
+	ErrInvalidChunk   = errors.New("chunk is not the next one of its upload")
+)
+
+// ChunkPayload is the payload of a chunk message. A payload too large for
+// one message is split into Total chunks sent in order with the same ID, and
+// once Data of every chunk is put together it is handled as the payload of
+// a message with the topic of the chunks and Event.
+type ChunkPayload struct {
+	ID    string `json:"id"`
+	Event string `json:"event"`
+	Index int    `json:"index"`
+	Total int    `json:"total"`
+	Data  string `json:"data"`
+}
+
+type upload struct {
+	topic     string
+	event     string
+	total     int
+	received  int
+	data      []byte
+	lastChunk time.Time
+}
+
+// uploads holds the chunked uploads of a client that didn't get all their
+// chunks yet by id
+type uploads struct {
+	mutex sync.Mutex
+	byID  map[string]*upload
+}
+
+func newUploads() *uploads {
+	return &uploads{byID: make(map[string]*upload)}
+}
+
+// add adds chunk to its upload and returns the whole payload with the event
+// it was sent for once the last chunk arrived. An upload is dropped when one
+// of its chunks is rejected.
+func (u *uploads) add(topic string, chunk ChunkPayload) (payload []byte, event string, done bool, err error) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	now := timeNow()
+	for id, up := range u.byID {
+		if now.Sub(up.lastChunk) > uploadTimeout {
+			delete(u.byID, id)
+		}
+	}
+
+	up, ok := u.byID[chunk.ID]
+	if !ok {
+		if chunk.Index != 0 || chunk.Total < 1 || chunk.Event == "" {
+			return nil, "", false, ErrInvalidChunk
+		}
+		if len(u.byID) >= maxUploads {
+			return nil, "", false, ErrTooManyUploads
+		}
+
+		up = &upload{topic: topic, event: chunk.Event, total: chunk.Total}
+		u.byID[chunk.ID] = up
+	}
+
+	if chunk.Index != up.received || chunk.Total != up.total || topic != up.topic {
+		delete(u.byID, chunk.ID)
+		return nil, "", false, ErrInvalidChunk
+	}
+	if len(up.data)+len(chunk.Data) > MaxUploadSize {
+		delete(u.byID, chunk.ID)
+		return nil, "", false, ErrUploadTooLarge
+	}
+
+	up.data = append(up.data, chunk.Data...)
+	up.received++
+	up.lastChunk = now
+	if up.received < up.total {
+		return nil, "", false, nil
+	}
+
+	delete(u.byID, chunk.ID)
+	return up.data, up.event, true, nil
+}
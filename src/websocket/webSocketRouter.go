@@ -6,16 +6,25 @@ import (
 	"fmt"
 	"log"
 	"strings"
+
+	"github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
 )
 
 type WebSocketRouter struct {
 	topics []Topic
+	// limits holds the rate limits of the events of the topics by
+	// rateLimitKey
+	limits map[string]RateLimit
+	// middleware wraps the handling of every message
+	middleware []Middleware
 }
 
 func NewWebSocketRouter() (WebSocketRouter, error) {
 
 	return WebSocketRouter{
 			make([]Topic, 0),
+			make(map[string]RateLimit),
+			make([]Middleware, 0),
 		},
 		nil
 }
@@ -24,6 +33,21 @@ func (r *WebSocketRouter) PushNewRoute(topic Topic) {
 	r.topics = append(r.topics, topic)
 }
 
+// Use adds middleware around the handling of every message, including the
+// ones no topic handles. The room passed to it is nil since the room of a
+// message is only known to its topic.
+func (r *WebSocketRouter) Use(middleware ...Middleware) {
+	r.middleware = append(r.middleware, middleware...)
+}
+
+// SetRateLimit limits how often each client can send event to topic, which
+// is the name of a topic without its param. An empty event limits every
+// event of topic that has no limit of its own, and an empty topic limits
+// event in every topic.
+func (r *WebSocketRouter) SetRateLimit(topic string, event string, limit RateLimit) {
+	r.limits[rateLimitKey(topic, event)] = limit
+}
+
 func (r WebSocketRouter) HandleWSMessage(ctx context.Context, client *Client, jsonMessage []byte) error {
 	var message InboundMessage
 	err := json.Unmarshal(jsonMessage, &message)
@@ -42,6 +66,56 @@ func (r WebSocketRouter) HandleWSMessage(ctx context.Context, client *Client, js
 		return err
 	}
 
+	return r.handle(ctx, client, message)
+}
+
+// handle runs message through the rate limits and the middleware of the
+// router before dispatching it
+func (r WebSocketRouter) handle(ctx context.Context, client *Client, message InboundMessage) error {
+	dispatch := func(ctx context.Context, _ domain.Room, _ domain.Client, payload []byte) error {
+		message.Payload = payload
+		return r.dispatch(ctx, client, message)
+	}
+	handler := chain(
+		strings.SplitN(message.Topic, "/", 2)[0],
+		message.Event,
+		dispatch,
+		[]Middleware{RateLimiter(r.limits)},
+		r.middleware,
+	)
+
+	return handler(ctx, nil, client, message.Payload)
+}
+
+// dispatch puts chunks together and hands message to the topic it was sent to.
+// A message put together from chunks goes to its topic right away, the
+// middleware of the router already ran for each of its chunks.
+func (r WebSocketRouter) dispatch(ctx context.Context, client *Client, message InboundMessage) error {
+	if message.Event == ChunkEvent {
+		var chunk ChunkPayload
+		err := json.Unmarshal(message.Payload, &chunk)
+		if err != nil {
+			return client.SendError(
+				"chunk payload is not valid",
+				"WebSocketRouter/dispatch: error transforming error message to json\nerr: %v",
+			)
+		}
+
+		payload, event, done, err := client.uploads.add(message.Topic, chunk)
+		if err != nil {
+			return client.SendError(
+				err.Error(),
+				"WebSocketRouter/dispatch: error transforming error message to json\nerr: %v",
+			)
+		}
+		if !done {
+			return nil
+		}
+
+		message.Event = event
+		message.Payload = payload
+	}
+
 	for _, topic := range r.topics {
 		if topic.match(message.Topic) {
 			internalErr := topic.HandleWSMessage(
@@ -55,9 +129,9 @@ func (r WebSocketRouter) HandleWSMessage(ctx context.Context, client *Client, js
 		}
 	}
 
-	err = client.SendError(
+	err := client.SendError(
 		fmt.Sprintf(`"%s" is not a valid topic`, message.Topic),
-		"WebSocketRouter/HandleWSMessage: error transforming error message to json\nerr: %v",
+		"WebSocketRouter/dispatch: error transforming error message to json\nerr: %v",
 	)
 	return err
 }
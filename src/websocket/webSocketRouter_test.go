@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/lookingcoolonavespa/go_crochess_backend/src/domain"
 	"github.com/stretchr/testify/assert"
@@ -123,3 +125,162 @@ func TestWebSocketRouter_HandleWSMessage(t *testing.T) {
 		)
 	}
 }
+
+func TestWebSocketRouter_Chunks(t *testing.T) {
+	expected := fmt.Sprintf(`{"topic": "%s", "event": "%s", "payload": {"pgn": "1. e4 e5"}}`, testTopic, SubscribeEvent)
+	chunk := func(id string, index int, total int, data string) []byte {
+		payload, err := json.Marshal(ChunkPayload{id, SubscribeEvent, index, total, data})
+		assert.NoError(t, err)
+		return []byte(fmt.Sprintf(`{"topic": "%s", "event": "%s", "payload": %s}`, testTopic, ChunkEvent, payload))
+	}
+
+	t.Run("Handles the payload once every chunk arrived", func(t *testing.T) {
+		r, successChan, _ := setupWebSocketRouter(t, expected)
+		client := NewClient("0", make(chan []byte), nil, nil)
+
+		for i, data := range []string{`{"pgn": `, `"1. e4`, ` e5"}`} {
+			err := r.HandleWSMessage(context.Background(), client, chunk("a", i, 3, data))
+			assert.NoError(t, err)
+		}
+
+		select {
+		case <-successChan:
+		case <-time.After(time.Second):
+			t.Fatal("the payload was never handled")
+		}
+	})
+
+	t.Run("Runs the router middleware once per chunk", func(t *testing.T) {
+		r, successChan, _ := setupWebSocketRouter(t, expected)
+		runs := 0
+		r.Use(func(topic string, event string, next TopicEventHandler) TopicEventHandler {
+			return func(ctx context.Context, room domain.Room, client domain.Client, payload []byte) error {
+				runs++
+				return next(ctx, room, client, payload)
+			}
+		})
+		client := NewClient("0", make(chan []byte), nil, nil)
+
+		for i, data := range []string{`{"pgn": `, `"1. e4`, ` e5"}`} {
+			err := r.HandleWSMessage(context.Background(), client, chunk("a", i, 3, data))
+			assert.NoError(t, err)
+		}
+
+		select {
+		case <-successChan:
+		case <-time.After(time.Second):
+			t.Fatal("the payload was never handled")
+		}
+		assert.Equal(t, 3, runs, "the put together message isn't run through it again")
+	})
+
+	t.Run("Rejects chunks out of order", func(t *testing.T) {
+		r, _, _ := setupWebSocketRouter(t, expected)
+		errChan := make(chan []byte, 1)
+		client := NewClient("0", errChan, nil, nil)
+
+		r.HandleWSMessage(context.Background(), client, chunk("a", 0, 3, `{"pgn": `))
+		r.HandleWSMessage(context.Background(), client, chunk("a", 2, 3, ` e5"}`))
+
+		select {
+		case err := <-errChan:
+			assert.Contains(t, string(err), ErrInvalidChunk.Error())
+		case <-time.After(time.Second):
+			t.Fatal("client was not sent an error")
+		}
+	})
+
+	t.Run("Rejects uploads that are too large", func(t *testing.T) {
+		r, _, _ := setupWebSocketRouter(t, expected)
+		errChan := make(chan []byte, 1)
+		client := NewClient("0", errChan, nil, nil)
+
+		data := strings.Repeat("a", MaxUploadSize/2+1)
+		r.HandleWSMessage(context.Background(), client, chunk("a", 0, 2, data))
+		r.HandleWSMessage(context.Background(), client, chunk("a", 1, 2, data))
+
+		select {
+		case err := <-errChan:
+			assert.Contains(t, string(err), ErrUploadTooLarge.Error())
+		case <-time.After(time.Second):
+			t.Fatal("client was not sent an error")
+		}
+	})
+}
+
+func TestWebSocketRouter_RateLimit(t *testing.T) {
+	defer func() { timeNow = time.Now }()
+	now := time.Now()
+	timeNow = func() time.Time { return now }
+
+	message := []byte(fmt.Sprintf(`{"topic": "%s", "event": "%s", "payload": "hi"}`, testTopic, SubscribeEvent))
+	setup := func(limit RateLimit) (WebSocketRouter, chan string, *Client, chan []byte) {
+		r, successChan, _ := setupWebSocketRouter(t, string(message))
+		r.SetRateLimit(testTopic, SubscribeEvent, limit)
+		errChan := make(chan []byte, MaxRateLimitViolations+1)
+		return r, successChan, NewClient("0", errChan, nil, nil), errChan
+	}
+
+	t.Run("Lets messages through until the bucket is empty", func(t *testing.T) {
+		r, successChan, client, errChan := setup(RateLimit{Events: 2, Per: time.Minute})
+
+		for i := 0; i < 2; i++ {
+			assert.NoError(t, r.HandleWSMessage(context.Background(), client, message))
+			<-successChan
+		}
+		assert.NoError(t, r.HandleWSMessage(context.Background(), client, message))
+
+		select {
+		case err := <-errChan:
+			assert.Contains(t, string(err), RateLimitedEvent)
+			assert.Contains(t, string(err), `"retry_after":30000`)
+		case <-time.After(time.Second):
+			t.Fatal("client was not sent an error")
+		}
+	})
+
+	t.Run("Refills the bucket over time", func(t *testing.T) {
+		r, successChan, client, _ := setup(RateLimit{Events: 1, Per: time.Second})
+
+		assert.NoError(t, r.HandleWSMessage(context.Background(), client, message))
+		<-successChan
+		now = now.Add(time.Second)
+		assert.NoError(t, r.HandleWSMessage(context.Background(), client, message))
+
+		select {
+		case <-successChan:
+		case <-time.After(time.Second):
+			t.Fatal("message was not let through")
+		}
+	})
+
+	t.Run("Disconnects clients that keep going over the limit", func(t *testing.T) {
+		r, successChan, client, _ := setup(RateLimit{Events: 1, Per: time.Minute})
+
+		assert.NoError(t, r.HandleWSMessage(context.Background(), client, message))
+		<-successChan
+		for i := 0; i < MaxRateLimitViolations; i++ {
+			assert.NoError(t, r.HandleWSMessage(context.Background(), client, message))
+		}
+
+		err := r.HandleWSMessage(context.Background(), client, message)
+		assert.ErrorIs(t, err, ErrRateLimited)
+	})
+
+	t.Run("Prefers the limit of the event over the limit of the topic", func(t *testing.T) {
+		limits := map[string]RateLimit{
+			rateLimitKey("game", ""):        {Events: 1, Per: time.Second},
+			rateLimitKey("game", ChatEvent): {Events: 2, Per: time.Second},
+			rateLimitKey("", ChatEvent):     {Events: 3, Per: time.Second},
+		}
+
+		_, limit, _ := limitFor(limits, "game/1", ChatEvent)
+		assert.Equal(t, 2, limit.Events)
+		_, limit, _ = limitFor(limits, "game/1", MakeMoveEvent)
+		assert.Equal(t, 1, limit.Events)
+		_, limit, _ = limitFor(limits, "gameseeks", ChatEvent)
+		assert.Equal(t, 3, limit.Events)
+		_, _, ok := limitFor(limits, "gameseeks", InsertEvent)
+		assert.False(t, ok)
+	})
+}
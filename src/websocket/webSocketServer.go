@@ -15,7 +15,15 @@ import (
 )
 
 const (
-	PingPeriod = time.Second * 30
+	PingPeriod  = time.Second * 30
+	PongTimeout = time.Second * 10
+	// MaxMessageSize is how large a message from a client can be in bytes.
+	// Larger payloads are sent in chunks.
+	MaxMessageSize = 1 << 16
+	// tooLargeFactor is how many times the largest message a message can be
+	// and still get an error back, messages larger than that close the
+	// connection
+	tooLargeFactor = 4
 )
 
 type WebSocketServer struct {
@@ -23,13 +31,41 @@ type WebSocketServer struct {
 	router        WebSocketRouter
 	mutex         sync.Mutex
 	gameseeksRepo domain.GameseeksRepo
+	// pingPeriod is how often clients are pinged, and pongTimeout how long
+	// they have to answer before their connection counts as dead
+	pingPeriod     time.Duration
+	pongTimeout    time.Duration
+	maxMessageSize int64
 }
 
-func NewWebSocketServer(r WebSocketRouter, gameseeksRepo domain.GameseeksRepo) WebSocketServer {
+// NewWebSocketServer returns a server pinging its clients every pingPeriod
+// and reading messages up to maxMessageSize bytes from them. A zero
+// pingPeriod, pongTimeout or maxMessageSize uses PingPeriod, PongTimeout or
+// MaxMessageSize.
+func NewWebSocketServer(
+	r WebSocketRouter,
+	gameseeksRepo domain.GameseeksRepo,
+	pingPeriod time.Duration,
+	pongTimeout time.Duration,
+	maxMessageSize int64,
+) WebSocketServer {
+	if pingPeriod <= 0 {
+		pingPeriod = PingPeriod
+	}
+	if pongTimeout <= 0 {
+		pongTimeout = PongTimeout
+	}
+	if maxMessageSize <= 0 {
+		maxMessageSize = MaxMessageSize
+	}
+
 	return WebSocketServer{
-		conns:         make(map[*Client]bool),
-		router:        r,
-		gameseeksRepo: gameseeksRepo,
+		conns:          make(map[*Client]bool),
+		router:         r,
+		gameseeksRepo:  gameseeksRepo,
+		pingPeriod:     pingPeriod,
+		pongTimeout:    pongTimeout,
+		maxMessageSize: maxMessageSize,
 	}
 }
 
@@ -44,6 +80,7 @@ func (s *WebSocketServer) HandleWS(w http.ResponseWriter, r *http.Request) {
 		log.Printf("%v", err)
 		return
 	}
+	conn.SetReadLimit(s.maxMessageSize * tooLargeFactor)
 
 	uid := r.URL.Query().Get("uid")
 	client := NewClient(uid, make(chan []byte), conn, s)
@@ -51,6 +88,7 @@ func (s *WebSocketServer) HandleWS(w http.ResponseWriter, r *http.Request) {
 
 	go client.ReadPump(r.Context())
 	go client.WritePump(r.Context())
+	go client.PingPump(r.Context(), s.pingPeriod, s.pongTimeout)
 
 	s.registerClient(client)
 
@@ -79,6 +117,51 @@ func (s *WebSocketServer) unregisterClient(ctx context.Context, client *Client)
 	s.gameseeksRepo.DeleteFromSeeker(ctx, client.GetID())
 }
 
+// ConnectionStats sums up the connected clients for monitoring. It doesn't
+// say anything about a single client.
+type ConnectionStats struct {
+	Clients int `json:"clients"`
+	// QueuedMessages is how many messages are waiting to be written over
+	// every client, and MaxQueueDepth how many the furthest behind client has
+	QueuedMessages int `json:"queued_messages"`
+	MaxQueueDepth  int `json:"max_queue_depth"`
+	// MeasuredClients is how many clients answered a ping, the latencies are
+	// over them in milliseconds
+	MeasuredClients int   `json:"measured_clients"`
+	MeanLatency     int64 `json:"mean_latency_ms"`
+	MaxLatency      int64 `json:"max_latency_ms"`
+}
+
+// Stats returns the ConnectionStats of the connected clients
+func (s *WebSocketServer) Stats() ConnectionStats {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	stats := ConnectionStats{Clients: len(s.conns)}
+	var total time.Duration
+	for client := range s.conns {
+		depth := client.QueueDepth()
+		stats.QueuedMessages += depth
+		if depth > stats.MaxQueueDepth {
+			stats.MaxQueueDepth = depth
+		}
+
+		latency := client.Latency()
+		if latency == 0 {
+			continue
+		}
+		stats.MeasuredClients++
+		total += latency
+		if latency.Milliseconds() > stats.MaxLatency {
+			stats.MaxLatency = latency.Milliseconds()
+		}
+	}
+	if stats.MeasuredClients > 0 {
+		stats.MeanLatency = (total / time.Duration(stats.MeasuredClients)).Milliseconds()
+	}
+
+	return stats
+}
+
 func (s *WebSocketServer) Close() {
 	for client := range s.conns {
 		client.HandleClose(context.Background(), context.Canceled)
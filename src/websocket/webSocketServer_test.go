@@ -0,0 +1,99 @@
+package domain_websocket
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	repository_gameseeks_mock "github.com/lookingcoolonavespa/go_crochess_backend/src/services/gameseeks/repository/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"nhooyr.io/websocket"
+)
+
+func TestWebSocketServer_Heartbeat(t *testing.T) {
+	router, err := NewWebSocketRouter()
+	assert.NoError(t, err)
+
+	setup := func(t *testing.T, uid string, repo *repository_gameseeks_mock.GameseeksMockRepo) (*WebSocketServer, *websocket.Conn) {
+		s := NewWebSocketServer(router, repo, time.Millisecond*20, time.Millisecond*50, 0)
+		server := httptest.NewServer(http.HandlerFunc(s.HandleWS))
+		t.Cleanup(server.Close)
+
+		conn, _, err := websocket.Dial(
+			context.Background(),
+			strings.Replace(server.URL, "http", "ws", 1)+"/ws?uid="+uid,
+			nil,
+		)
+		assert.NoError(t, err)
+		t.Cleanup(func() { conn.Close(websocket.StatusNormalClosure, "") })
+
+		return &s, conn
+	}
+
+	t.Run("Measures the latency of clients that answer", func(t *testing.T) {
+		repo := new(repository_gameseeks_mock.GameseeksMockRepo)
+		repo.On("DeleteFromSeeker", mock.Anything, "1").Return([]int{}, nil).Maybe()
+		s, conn := setup(t, "1", repo)
+		// pongs are only sent while the connection is read
+		conn.CloseRead(context.Background())
+
+		assert.Eventually(t, func() bool {
+			return s.Stats().MeasuredClients == 1
+		}, time.Second, time.Millisecond*10)
+	})
+
+	t.Run("Cleans up clients that don't answer", func(t *testing.T) {
+		repo := new(repository_gameseeks_mock.GameseeksMockRepo)
+		deleted := make(chan bool, 1)
+		repo.On("DeleteFromSeeker", mock.Anything, "2").
+			Return([]int{}, nil).
+			Run(func(mock.Arguments) { deleted <- true }).
+			Once()
+		s, _ := setup(t, "2", repo)
+
+		select {
+		case <-deleted:
+		case <-time.After(time.Second * 10):
+			assert.Fail(t, "client was never cleaned up")
+		}
+		assert.Equal(t, ConnectionStats{}, s.Stats())
+		repo.AssertExpectations(t)
+	})
+}
+
+func TestWebSocketServer_MaxMessageSize(t *testing.T) {
+	router, err := NewWebSocketRouter()
+	assert.NoError(t, err)
+	repo := new(repository_gameseeks_mock.GameseeksMockRepo)
+	repo.On("DeleteFromSeeker", mock.Anything, "1").Return([]int{}, nil).Maybe()
+
+	s := NewWebSocketServer(router, repo, time.Minute, time.Minute, 64)
+	server := httptest.NewServer(http.HandlerFunc(s.HandleWS))
+	t.Cleanup(server.Close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	conn, _, err := websocket.Dial(ctx, strings.Replace(server.URL, "http", "ws", 1)+"/ws?uid=1", nil)
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close(websocket.StatusNormalClosure, "") })
+
+	read := func() string {
+		_, message, err := conn.Read(ctx)
+		assert.NoError(t, err)
+		return string(message)
+	}
+
+	large := `{"topic": "game/1", "event": "make move", "payload": "` + strings.Repeat("a", 100) + `"}`
+	assert.NoError(t, conn.Write(ctx, websocket.MessageText, []byte(large)))
+	message := read()
+	assert.Contains(t, message, ErrorEvent)
+	assert.Contains(t, message, "larger than 64 bytes")
+
+	// the connection is still read after a message that was too large
+	assert.NoError(t, conn.Write(ctx, websocket.MessageText, []byte(`{"topic": "none", "event": "x"}`)))
+	assert.Contains(t, read(), "not a valid topic")
+}